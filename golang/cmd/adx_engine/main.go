@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
+	"os"
 
 	"github.com/echoface/admux/internal/adx_engine/adxmetric"
 	"github.com/echoface/admux/internal/adx_engine/adxserver"
 	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/dspbidder"
+	"github.com/echoface/admux/internal/adx_engine/feature"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -17,12 +23,61 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	configFile, err := config.AdxConfigFile()
+	if err != nil {
+		log.Fatalf("Failed to locate config file: %v", err)
+	}
+	os.Exit(run(cfg, configFile))
+}
+
+// run serves until SIGINT/SIGTERM and returns the exit status, 0 when the shutdown
+// drained and flushed everything
+func run(cfg *config.AdxServerConfig, configFile string) int {
 
 	// Initialize application context
 	appCtx := adxserver.NewAppContext(cfg)
 
-	// Initialize ADX server with pipeline
+	// Load DSP configs and register bidders
+	indexMgr, err := dspbidder.NewBidderIndexManager(cfg, appCtx.GetMetricsRegistry())
+	if err != nil {
+		log.Fatalf("Failed to create DSP index manager: %v", err)
+	}
+	if err := indexMgr.Start(); err != nil {
+		log.Fatalf("Failed to start DSP index manager: %v", err)
+	}
+	// stopped by Serve on shutdown
+	appCtx.SetBidderIndexManager(indexMgr)
+
+	// Validate the SSPs and serve their adapters, reloaded when the config file changes
+	sspReloader, err := adxserver.NewSSPReloader(appCtx, configFile, func() ([]config.SSPConfig, error) {
+		reloaded, err := config.LoadAdxConfig()
+		if err != nil {
+			return nil, err
+		}
+		return reloaded.SSPs, nil
+	})
+	if err != nil {
+		log.Fatalf("Invalid SSP configs: %v", err)
+	}
+	if err := sspReloader.Watch(); err != nil {
+		log.Printf("SSP config hot reload disabled: %v", err)
+	}
+	defer sspReloader.Close()
+
+	// Initialize ADX server with pipeline, flushed by Serve on shutdown
 	adxServer := adxserver.NewAdxServer(appCtx)
+	sspReloader.OnReload(adxServer.AllowSSPLabels)
+	if ipGeoCfg := cfg.Features.IPGeo; ipGeoCfg.Enabled {
+		ipGeoDB, err := feature.NewIPGeoDB(ipGeoCfg.DBPath, ipGeoCfg.ReloadInterval)
+		if err != nil {
+			log.Fatalf("Failed to load IP geo db: %v", err)
+		}
+		ipGeoDB.Watch()
+		defer ipGeoDB.Close()
+		if err := adxServer.FeatureRegistry().Register(feature.NewIPGeoProvider(ipGeoDB)); err != nil {
+			log.Fatalf("Failed to register IP geo feature provider: %v", err)
+		}
+	}
 	bidHandler := adxserver.NewBidHandler(adxServer, appCtx)
 
 	// Initialize health handler
@@ -49,7 +104,18 @@ func main() {
 	r.GET("/health/ready", healthHandler.ReadinessProbe)
 
 	// Prometheus metrics endpoint
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(
+		prometheus.Gatherers{prometheus.DefaultGatherer, appCtx.GetMetricsRegistry()},
+		promhttp.HandlerOpts{},
+	)))
+
+	// Admin endpoints, token authenticated and audited
+	adminHandler, err := adxserver.NewAdminHandler(appCtx, adxServer, sspReloader)
+	if err != nil {
+		log.Fatalf("Failed to create admin handler: %v", err)
+	}
+	defer adminHandler.Close()
+	adminHandler.Register(r)
 
 	// RTB bid endpoints
 	r.POST("/bid/rtb/v1", bidHandler.HandleAdMuxBid)
@@ -57,12 +123,20 @@ func main() {
 	// SSP-specific endpoints
 	r.POST("/bid/kuaishou", bidHandler.HandleKuaishouBid)
 
-	log.Println("ADMUX ADX Server starting on port 8080")
-	log.Printf("Health check: http://localhost:8080/health")
-	log.Printf("Metrics: http://localhost:8080/metrics")
+	ln, err := net.Listen("tcp", appCtx.HTTPServer.Addr)
+	if err != nil {
+		log.Printf("Failed to listen on %s: %v", appCtx.HTTPServer.Addr, err)
+		return 1
+	}
+	log.Printf("ADMUX ADX Server listening on %s", ln.Addr())
+	log.Printf("Health check: http://%s/health", ln.Addr())
+	log.Printf("Metrics: http://%s/metrics", ln.Addr())
 
-	// Use the HTTP server from app context
-	if err := appCtx.HTTPServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatal("Failed to start ADX server:", err)
+	// Use the HTTP server from app context, drained on SIGINT/SIGTERM
+	if err := adxserver.Serve(context.Background(), appCtx, adxServer, ln); err != nil {
+		log.Printf("ADX server stopped with errors: %v", err)
+		return 1
 	}
+	log.Println("ADX server stopped")
+	return 0
 }
@@ -0,0 +1,60 @@
+// adx_loadgen drives synthesized SSP traffic against an ADX at a target QPS and
+// reports latency percentiles, bid/no-bid/error rates and response validation
+// failures per SSP protocol.
+//
+// Requests are built from JSON templates or from SSP requests captured in a bid
+// log, and are paced open-loop: a slow ADX does not lower the offered load, and
+// latency is measured from each request's scheduled send time.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/echoface/admux/internal/adx_engine/loadgen"
+)
+
+func main() {
+	qps := flag.Float64("qps", 0, "override the configured total qps")
+	duration := flag.Duration("duration", 0, "override the configured duration")
+	baseURL := flag.String("base-url", "", "override the configured adx base url")
+	flag.Parse()
+
+	// Load configuration based on RUN_TYPE
+	cfg, err := loadgen.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	opts := cfg.LoadGen
+	if *qps > 0 {
+		opts.QPS = *qps
+	}
+	if *duration > 0 {
+		opts.Duration = *duration
+	}
+	if *baseURL != "" {
+		opts.BaseURL = *baseURL
+	}
+
+	runner, err := loadgen.NewRunner(opts)
+	if err != nil {
+		log.Fatalf("Failed to create load generator: %v", err)
+	}
+
+	// Stop scheduling on interrupt and still print the partial report
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Sending traffic to %s", opts.BaseURL)
+	report := runner.Run(ctx)
+	report.WriteText(os.Stdout)
+
+	if report.HasFailures() {
+		os.Exit(1)
+	}
+}
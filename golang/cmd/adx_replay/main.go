@@ -0,0 +1,165 @@
+// adx_replay replays captured bid log records through the current SSP adapters and
+// bid pipeline, and reports field-level differences against a baseline per SSP.
+//
+// The baseline defaults to the captured records themselves (the build that served the
+// traffic); pass -baseline with the -out of a previous replay to diff two builds.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/replay"
+)
+
+func main() {
+	input := flag.String("input", "", "captured bid log (JSONL) to replay")
+	baselinePath := flag.String("baseline", "", "replay output of the baseline build, defaults to the captured records")
+	outPath := flag.String("out", "", "write the replayed records (JSONL), usable as a later -baseline")
+	dspMode := flag.String("dsp-mode", replay.DSPModeCaptured, "dsp responses: captured|simulated")
+	dsps := flag.String("dsps", "", "comma separated dsp ids bidding in simulated mode")
+	ignore := flag.String("ignore", "", "comma separated field paths to ignore, e.g. response.seatbid[].bid[].ext,ssp_response")
+	ignoreParams := flag.String("ignore-url-params", "ts,sig", "comma separated query params ignored in url fields")
+	verbose := flag.Bool("verbose", false, "print every field difference")
+	flag.Parse()
+
+	if *input == "" {
+		log.Fatal("-input is required")
+	}
+
+	// Load configuration based on RUN_TYPE, the SSP configs select the adapters
+	cfg, err := config.LoadAdxConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	runner, err := replay.NewRunner(cfg, replay.Options{DSPMode: *dspMode, DSPs: splitList(*dsps)})
+	if err != nil {
+		log.Fatalf("Failed to create replay runner: %v", err)
+	}
+	defer runner.Close()
+
+	baselines := map[string]*bidlog.Record{}
+	if *baselinePath != "" {
+		if baselines, err = loadBaselines(*baselinePath); err != nil {
+			log.Fatalf("Failed to load baseline: %v", err)
+		}
+	}
+
+	var out *bufio.Writer
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("Failed to create output: %v", err)
+		}
+		defer file.Close()
+		out = bufio.NewWriter(file)
+		defer out.Flush()
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		log.Fatalf("Failed to open input: %v", err)
+	}
+	defer file.Close()
+
+	diffOpts := &replay.DiffOptions{IgnoreFields: splitList(*ignore), IgnoreURLParams: splitList(*ignoreParams)}
+	report := replay.NewReport()
+	err = bidlog.ReadRecords(file, func(record *bidlog.Record) error {
+		baseline := record
+		if *baselinePath != "" {
+			if baseline = baselines[recordKey(record)]; baseline == nil {
+				log.Printf("no baseline for ssp=%s request=%s, skipped", record.SSPID, record.RequestID)
+				return nil
+			}
+		}
+
+		current, err := runner.Replay(context.Background(), record)
+		if err != nil {
+			log.Printf("replay ssp=%s request=%s failed: %v", record.SSPID, record.RequestID, err)
+			report.Add(record.SSPID, nil, err)
+			return nil
+		}
+		if out != nil {
+			if err := writeRecord(out, current); err != nil {
+				return err
+			}
+		}
+
+		diffs, err := replay.Compare(baseline, current, diffOpts)
+		if err != nil {
+			log.Printf("diff ssp=%s request=%s failed: %v", record.SSPID, record.RequestID, err)
+			report.Add(record.SSPID, nil, err)
+			return nil
+		}
+		report.Add(record.SSPID, diffs, nil)
+		if *verbose {
+			for _, diff := range diffs {
+				fmt.Printf("%s %s %s: %q -> %q\n", record.SSPID, record.RequestID, diff.Path, diff.Baseline, diff.Current)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to replay %s: %v", *input, err)
+	}
+
+	if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+	if report.HasDiff() {
+		// flush the deferred writers before exiting non-zero
+		if out != nil {
+			out.Flush()
+		}
+		os.Exit(1)
+	}
+}
+
+// loadBaselines indexes the baseline records by ssp and request id
+func loadBaselines(path string) (map[string]*bidlog.Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	baselines := make(map[string]*bidlog.Record)
+	err = bidlog.ReadRecords(file, func(record *bidlog.Record) error {
+		baselines[recordKey(record)] = record
+		return nil
+	})
+	return baselines, err
+}
+
+func recordKey(record *bidlog.Record) string {
+	return record.SSPID + "/" + record.RequestID
+}
+
+func writeRecord(w *bufio.Writer, record *bidlog.Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/echoface/admux/internal/adx_engine/dspbidder/stub"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+)
+
+func main() {
+	// Load configuration based on RUN_TYPE
+	cfg, err := stub.LoadSimulatorConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	prom := cfg.Monitoring.Prometheus
+	registry := prometheus.NewRegistry()
+
+	simulator, err := stub.NewSimulator(cfg.Simulator, metrics.NewDSPSimulatorMetrics(prom.Namespace, prom.Subsystem, registry))
+	if err != nil {
+		log.Fatalf("Failed to create DSP simulator: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(gin.Recovery())
+	simulator.RegisterRoutes(r)
+
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+	})
+	r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(
+		prometheus.Gatherers{prometheus.DefaultGatherer, registry},
+		promhttp.HandlerOpts{},
+	)))
+
+	log.Printf("DSP simulator listening on %s", cfg.GetAddress())
+	if err := r.Run(cfg.GetAddress()); err != nil {
+		log.Fatalf("DSP simulator stopped: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/echoface/admux/internal/event_tracking/config"
+	"github.com/echoface/admux/internal/event_tracking/tracker"
+	"github.com/echoface/admux/pkg/notifier"
+)
+
+func main() {
+	// Load configuration based on RUN_TYPE
+	cfg, err := config.LoadTrackingConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	eventLog, err := tracker.NewEventLog(cfg.Tracking.EventLog)
+	if err != nil {
+		log.Fatalf("Failed to open event log: %v", err)
+	}
+	defer eventLog.Close()
+
+	prom := cfg.Monitoring.Prometheus
+	registry := prometheus.NewRegistry()
+
+	// Forwards the DSP nurl/burl carried by the win/billing events
+	notices, err := notifier.New(cfg.Tracking.Notifier, notifier.NewMetrics(prom.Namespace, prom.Subsystem, registry))
+	if err != nil {
+		log.Fatalf("Failed to create DSP notifier: %v", err)
+	}
+
+	server, err := tracker.NewServer(&cfg.Tracking, eventLog, notices, prom.Namespace, prom.Subsystem, registry)
+	if err != nil {
+		log.Fatalf("Failed to create tracking server: %v", err)
+	}
+
+	r := gin.Default()
+	server.RegisterRoutes(r)
+
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+	})
+	r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(
+		prometheus.Gatherers{prometheus.DefaultGatherer, registry},
+		promhttp.HandlerOpts{},
+	)))
+
+	httpServer := &http.Server{
+		Addr:         cfg.GetAddress(),
+		Handler:      r,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	}
+
+	go func() {
+		log.Printf("ADMUX Tracking Server starting on %s", httpServer.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start tracking server: %v", err)
+		}
+	}()
+
+	// Stop accepting events before closing the event log
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("Tracking server shutdown error: %v", err)
+	}
+	if err := notices.Close(ctx); err != nil {
+		log.Printf("DSP notifier shutdown error: %v", err)
+	}
+}
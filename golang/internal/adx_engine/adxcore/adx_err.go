@@ -1,8 +1,15 @@
 package adxcore
 
+import "errors"
+
 var (
 	// Predefined errors
 	ErrMissingSSID = NewAdxError(1000, "missing ssid parameter")
+
+	// ErrSSPUnauthorized is wrapped by errors of requests failing the SSP's authentication
+	ErrSSPUnauthorized = errors.New("ssp request unauthorized")
+	// ErrSSPForbidden is wrapped by errors of requests from sources not allowed for the SSP
+	ErrSSPForbidden = errors.New("ssp request source forbidden")
 )
 
 type (
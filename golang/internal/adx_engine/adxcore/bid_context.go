@@ -6,6 +6,7 @@ import (
 
 	"github.com/echoface/admux/internal/adx_engine/config"
 	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"github.com/echoface/admux/pkg/tracking"
 )
 
 type (
@@ -25,15 +26,26 @@ type (
 		SSPID     string            // SSP标识符
 		SSPConfig *config.SSPConfig // SSP配置信息
 
+		// 特征补全阶段产出的特征，定向、过滤等阶段读取
+		Features *Features
+
 		// 竞价处理状态
 		BidStartTime     time.Time // 处理开始时间
 		ResponseTime     time.Time // 响应时间
 		ProcessingStages []string  // 已处理的管道阶段
 		ProcessingErrors []error   // 处理过程中的错误
 
+		// Debug 可信调用方的调试请求，记录定向原因和DSP原始响应并随响应返回
+		Debug     bool
+		Targeting []TargetingDecision
+
+		// 广播阶段各DSP的调用结果
+		BidderCalls []*BidderCall
+
 		// 竞价候选者
 		Candidates         []*BidCandidate // 所有DSP竞价响应
 		FilteredCandidates []*BidCandidate // 过滤后的竞价候选者
+		RejectedCandidates []*BidCandidate // 被过滤掉的候选，FilterReason 为原因
 
 		// 响应构建
 		Response *admux_rtb.BidResponse // 最终响应
@@ -43,7 +55,55 @@ type (
 	BidCandidate struct {
 		Response *admux_rtb.BidResponse
 
-		CPMPrice int64
+		// BidderID 出价的DSP，广播阶段未由bidder填写时补全
+		BidderID string
+		// Seat/Bid DSP响应中的单个出价，模拟bidder可能为空
+		Seat string
+		Bid  *admux_rtb.BidResponse_SeatBid_Bid
+
+		CPMPrice int64 // 出价CPM，单位分
+
+		// FilterReason 被过滤阶段淘汰的原因
+		FilterReason string
+
+		// 竞价结果，由构建响应阶段填写
+		Won        bool
+		LossReason tracking.LossReason
+		// Notice DSP的通知地址(宏已替换)，胜出时为 nurl/burl，失败或被过滤时为 lurl；
+		// 开启监测时胜出者的 nurl/burl 保留价格宏、lurl 保留失败原因宏，由监测服务按SSP回传值替换
+		Notice NoticeURLs
+		// Tracking 下发给SSP的ADX监测地址，仅胜出的出价有值
+		Tracking TrackingURLs
+	}
+
+	// BidderCall 单个DSP的一次竞价调用
+	BidderCall struct {
+		BidderID string
+		// Request 实际下发给DSP的请求(按DSP裁剪过设备ID)
+		Request    *admux_rtb.BidRequest
+		Candidates []*BidCandidate
+		Latency    time.Duration
+		Err        error
+
+		// RawStatus/RawResponse DSP的原始HTTP响应，仅调试请求记录
+		RawStatus   int
+		RawResponse []byte
+	}
+
+	// NoticeURLs DSP通知地址
+	NoticeURLs struct {
+		Win     string
+		Billing string
+		Loss    string
+	}
+
+	// TrackingURLs ADX签名监测地址，SSP适配器按各自协议下发
+	TrackingURLs struct {
+		Win     string
+		Billing string
+		Loss    string
+		Imp     string
+		Click   string
 	}
 )
 
@@ -52,6 +112,7 @@ func NewBidRequestCtx(parent context.Context, request *admux_rtb.BidRequest) *Bi
 	return &BidRequestCtx{
 		Context:            parent,
 		Request:            request,
+		Features:           NewFeatures(),
 		BidStartTime:       time.Now(),
 		ProcessingStages:   make([]string, 0),
 		ProcessingErrors:   make([]error, 0),
@@ -76,6 +137,45 @@ func (ctx *BidRequestCtx) SetResponse(response *admux_rtb.BidResponse) {
 	ctx.ResponseTime = time.Now()
 }
 
+// WithContext returns a shallow copy of ctx carrying c, e.g. the span of a single DSP call
+func (ctx *BidRequestCtx) WithContext(c context.Context) *BidRequestCtx {
+	view := *ctx
+	view.Context = c
+	return &view
+}
+
+// ForBidder returns the view of the request sent to a bidder: when the request carries
+// normalized device ids, the request is replaced by a copy holding only the id forms the
+// bidder is allowed to receive. The returned context shares everything else with ctx.
+func (ctx *BidRequestCtx) ForBidder(info *BidderInfo) *BidRequestCtx {
+	ids, ok := GetFeature(ctx.Features, FeatureDeviceIDs)
+	if !ok || ids == nil || info == nil {
+		return ctx
+	}
+
+	view := *ctx
+	view.Request = ids.ApplyTo(ctx.Request, info.AllowedDeviceIDs)
+	return &view
+}
+
+// RejectCandidate records a candidate removed by the filter stage
+func (ctx *BidRequestCtx) RejectCandidate(candidate *BidCandidate, reason string, loss tracking.LossReason) {
+	candidate.FilterReason = reason
+	candidate.LossReason = loss
+	ctx.RejectedCandidates = append(ctx.RejectedCandidates, candidate)
+}
+
+// Winners returns the candidates that won the auction, in ranking order
+func (ctx *BidRequestCtx) Winners() []*BidCandidate {
+	var winners []*BidCandidate
+	for _, candidate := range ctx.GetCandidates() {
+		if candidate.Won {
+			winners = append(winners, candidate)
+		}
+	}
+	return winners
+}
+
 // SetSSPInfo sets SSP-related information
 func (ctx *BidRequestCtx) SetSSPInfo(sspID string, sspConfig *config.SSPConfig) {
 	ctx.SSPID = sspID
@@ -0,0 +1,69 @@
+package adxcore
+
+import (
+	"context"
+	"sync"
+)
+
+// Targeting reasons recorded for debug requests
+const (
+	TargetingMatched    = "matched"     // DSP索引定向命中
+	TargetingNoIndex    = "no_index"    // 未启用DSP索引，广播给所有bidder
+	TargetingNotMatched = "not_matched" // 已注册但定向未命中
+	TargetingInactive   = "inactive"    // 定向命中但没有活跃的bidder
+	TargetingUnhealthy  = "unhealthy"   // 健康检查或熔断跳过
+	TargetingPaused     = "paused"      // 定向命中但已被运维暂停
+)
+
+// TargetingDecision why a DSP was or was not called
+type TargetingDecision struct {
+	BidderID string `json:"dsp_id"`
+	Selected bool   `json:"selected"`
+	Reason   string `json:"reason"`
+}
+
+// RecordTargeting records a targeting decision, only debug requests keep them
+func (ctx *BidRequestCtx) RecordTargeting(bidderID string, selected bool, reason string) {
+	if !ctx.Debug {
+		return
+	}
+	ctx.Targeting = append(ctx.Targeting, TargetingDecision{BidderID: bidderID, Selected: selected, Reason: reason})
+}
+
+// ResponseCapture holds the raw response of a DSP call, bidders fill it through
+// CaptureResponse when the call context carries one
+type ResponseCapture struct {
+	mu     sync.Mutex
+	status int
+	body   []byte
+}
+
+type responseCaptureKey struct{}
+
+// WithResponseCapture returns a context capturing the raw DSP response, used by debug requests
+func WithResponseCapture(parent context.Context) (context.Context, *ResponseCapture) {
+	capture := &ResponseCapture{}
+	return context.WithValue(parent, responseCaptureKey{}, capture), capture
+}
+
+// CaptureResponse records the raw DSP response when ctx captures it, the last attempt wins on retries
+func CaptureResponse(ctx context.Context, status int, body []byte) {
+	capture, ok := ctx.Value(responseCaptureKey{}).(*ResponseCapture)
+	if !ok {
+		return
+	}
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	capture.status = status
+	capture.body = body
+}
+
+// Get returns the captured http status and body, status is 0 when nothing was captured
+func (c *ResponseCapture) Get() (int, []byte) {
+	if c == nil {
+		return 0, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status, c.body
+}
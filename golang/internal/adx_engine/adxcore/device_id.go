@@ -0,0 +1,155 @@
+package adxcore
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// 设备ID类型。OpenRTB 中没有对应字段的ID(IMEI明文、OAID、CAID等)通过 user.eids 传递，
+// eid.source 即ID类型
+const (
+	DeviceIDIMEI         = "imei"
+	DeviceIDIMEIMD5      = "imei_md5"
+	DeviceIDOAID         = "oaid"
+	DeviceIDOAIDMD5      = "oaid_md5"
+	DeviceIDAndroidID    = "android_id"
+	DeviceIDAndroidIDMD5 = "android_id_md5"
+	DeviceIDIDFA         = "idfa"
+	DeviceIDIDFAMD5      = "idfa_md5"
+	DeviceIDCAID         = "caid"
+)
+
+// knownDeviceIDTypes 所有设备ID类型
+var knownDeviceIDTypes = map[string]struct{}{
+	DeviceIDIMEI: {}, DeviceIDIMEIMD5: {},
+	DeviceIDOAID: {}, DeviceIDOAIDMD5: {},
+	DeviceIDAndroidID: {}, DeviceIDAndroidIDMD5: {},
+	DeviceIDIDFA: {}, DeviceIDIDFAMD5: {},
+	DeviceIDCAID: {},
+}
+
+// DefaultAllowedDeviceIDs DSP未配置可接收的ID形式时使用：广告标识符和各类MD5，不下发IMEI、Android ID明文
+var DefaultAllowedDeviceIDs = []string{
+	DeviceIDIDFA, DeviceIDIDFAMD5,
+	DeviceIDOAID, DeviceIDOAIDMD5,
+	DeviceIDIMEIMD5, DeviceIDAndroidIDMD5,
+	DeviceIDCAID,
+}
+
+// IsKnownDeviceIDType 判断是否为已知的设备ID类型
+func IsKnownDeviceIDType(idType string) bool {
+	_, ok := knownDeviceIDTypes[idType]
+	return ok
+}
+
+// FeatureDeviceIDs 归一化后的设备ID，由设备ID特征提供者写入
+var FeatureDeviceIDs = FeatureKey[*DeviceIDs]("device_ids")
+
+// DeviceIDs 归一化后的设备ID集合
+type DeviceIDs struct {
+	// Values 可用的设备ID(含补全的MD5形式)，key 为ID类型
+	Values map[string]string `json:"values"`
+
+	// Zeroed 全零(未授权)的ID类型，Invalid 格式非法的ID类型，均不在 Values 中
+	Zeroed  []string `json:"zeroed,omitempty"`
+	Invalid []string `json:"invalid,omitempty"`
+
+	// LimitAdTracking 设备开启了限制广告追踪，Limited 中的广告标识符(IDFA/OAID)不可用于定向和下发
+	LimitAdTracking bool     `json:"limit_ad_tracking"`
+	Limited         []string `json:"limited,omitempty"`
+
+	// CanonicalKey 用户唯一键，用户画像以此为键查询，格式 {id_type}:{value}，无可用ID时为空
+	CanonicalKey string `json:"canonical_key,omitempty"`
+}
+
+// Get 获取指定类型的设备ID
+func (d *DeviceIDs) Get(idType string) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+	value, ok := d.Values[idType]
+	return value, ok && value != ""
+}
+
+// Allowed 返回允许下发的设备ID，allowed 为空时使用 DefaultAllowedDeviceIDs
+func (d *DeviceIDs) Allowed(allowed []string) map[string]string {
+	if len(allowed) == 0 {
+		allowed = DefaultAllowedDeviceIDs
+	}
+
+	result := make(map[string]string, len(allowed))
+	for _, idType := range allowed {
+		if value, ok := d.Get(idType); ok {
+			result[idType] = value
+		}
+	}
+	return result
+}
+
+// ApplyTo 返回只携带允许下发的设备ID的请求副本，原请求不被修改：
+// ifa 按系统填写 IDFA/OAID，didmd5/dpidmd5 填写 IMEI/Android ID 的MD5，其余形式写入 user.eids；
+// 无法确认来源的 didsha1/dpidsha1 以及原有的设备ID类 eids 会被移除
+func (d *DeviceIDs) ApplyTo(req *admux_rtb.BidRequest, allowed []string) *admux_rtb.BidRequest {
+	if req == nil {
+		return nil
+	}
+
+	out := proto.Clone(req).(*admux_rtb.BidRequest)
+	ids := d.Allowed(allowed)
+
+	if device := out.GetDevice(); device != nil {
+		device.Ifa, device.Didmd5, device.Dpidmd5 = nil, nil, nil
+		device.Didsha1, device.Dpidsha1 = nil, nil
+
+		ifaType := DeviceIDOAID
+		if strings.EqualFold(device.GetOs(), "ios") {
+			ifaType = DeviceIDIDFA
+		}
+		if value, ok := ids[ifaType]; ok {
+			device.Ifa = proto.String(value)
+			delete(ids, ifaType)
+		}
+		if value, ok := ids[DeviceIDIMEIMD5]; ok {
+			device.Didmd5 = proto.String(value)
+			delete(ids, DeviceIDIMEIMD5)
+		}
+		if value, ok := ids[DeviceIDAndroidIDMD5]; ok {
+			device.Dpidmd5 = proto.String(value)
+			delete(ids, DeviceIDAndroidIDMD5)
+		}
+	}
+
+	if user := out.GetUser(); user != nil {
+		eids := user.Eids[:0]
+		for _, eid := range user.GetEids() {
+			if !IsKnownDeviceIDType(eid.GetSource()) {
+				eids = append(eids, eid)
+			}
+		}
+		user.Eids = eids
+	}
+	if len(ids) == 0 {
+		return out
+	}
+
+	idTypes := make([]string, 0, len(ids))
+	for idType := range ids {
+		idTypes = append(idTypes, idType)
+	}
+	sort.Strings(idTypes)
+
+	if out.User == nil {
+		out.User = &admux_rtb.BidRequest_User{}
+	}
+	for _, idType := range idTypes {
+		out.User.Eids = append(out.User.Eids, &admux_rtb.BidRequest_User_EID{
+			Source: proto.String(idType),
+			Uids:   []*admux_rtb.BidRequest_User_EID_UID{{Id: proto.String(ids[idType])}},
+		})
+	}
+	return out
+}
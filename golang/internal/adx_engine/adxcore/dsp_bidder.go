@@ -3,6 +3,8 @@ package adxcore
 import (
 	"fmt"
 	"sync"
+
+	"github.com/echoface/admux/pkg/pricecrypto"
 )
 
 type BidderInfo struct {
@@ -10,6 +12,12 @@ type BidderInfo struct {
 
 	QPS      int    `json:"qps,omitempty" yaml:"qps"`
 	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint"`
+
+	// AllowedDeviceIDs device id forms the bidder may receive, see DeviceIDs.ApplyTo
+	AllowedDeviceIDs []string `json:"allowed_device_ids,omitempty" yaml:"allowed_device_ids"`
+
+	// PriceCodec encrypts the price macros in the bidder's notice urls and adm, nil means plain text
+	PriceCodec pricecrypto.Codec `json:"-" yaml:"-"`
 }
 
 // Bidder defines the interface that all DSP bidders must implement
@@ -36,12 +44,9 @@ func NewBidderFactory() *BidderFactory {
 
 // RegisterBidder registers a bidder with the factory
 func (f *BidderFactory) RegisterBidder(bidder Bidder) error {
-	if bidder == nil {
-		return fmt.Errorf("cannot register nil bidder")
-	}
-	bidderID := bidder.GetInfo().ID
-	if bidderID == "" {
-		return fmt.Errorf("bidder ID cannot be empty")
+	bidderID, err := validBidderID(bidder)
+	if err != nil {
+		return err
 	}
 
 	f.mu.Lock()
@@ -55,6 +60,58 @@ func (f *BidderFactory) RegisterBidder(bidder Bidder) error {
 	return nil
 }
 
+// ReplaceBidder registers a bidder, atomically replacing any bidder with the same ID.
+// It returns the replaced bidder, or nil if the ID was not registered before.
+// In-flight requests keep using the bidder instance they already obtained.
+func (f *BidderFactory) ReplaceBidder(bidder Bidder) (Bidder, error) {
+	bidderID, err := validBidderID(bidder)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	old := f.bidders[bidderID]
+	f.bidders[bidderID] = bidder
+	return old, nil
+}
+
+// SwapBidders atomically replaces the whole registration set with the given bidders.
+// Bidders absent from the new set are unregistered; readers observe either the old
+// set or the new one, never a mix. Nothing is changed if any bidder is invalid.
+func (f *BidderFactory) SwapBidders(bidders []Bidder) error {
+	next := make(map[string]Bidder, len(bidders))
+	for _, bidder := range bidders {
+		bidderID, err := validBidderID(bidder)
+		if err != nil {
+			return err
+		}
+		if _, exists := next[bidderID]; exists {
+			return fmt.Errorf("duplicate bidder ID '%s'", bidderID)
+		}
+		next[bidderID] = bidder
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.bidders = next
+	return nil
+}
+
+// validBidderID returns the bidder ID, or an error if the bidder can not be registered
+func validBidderID(bidder Bidder) (string, error) {
+	if bidder == nil {
+		return "", fmt.Errorf("cannot register nil bidder")
+	}
+	bidderID := bidder.GetInfo().ID
+	if bidderID == "" {
+		return "", fmt.Errorf("bidder ID cannot be empty")
+	}
+	return bidderID, nil
+}
+
 // GetBidder retrieves a bidder by its ID
 func (f *BidderFactory) GetBidder(bidderID string) (Bidder, error) {
 	f.mu.RLock()
@@ -0,0 +1,65 @@
+package adxcore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBidder struct {
+	info *BidderInfo
+}
+
+func (b *fakeBidder) GetInfo() *BidderInfo { return b.info }
+
+func (b *fakeBidder) SendBidRequest(*BidRequestCtx) ([]*BidCandidate, error) { return nil, nil }
+
+func newFakeBidder(id, endpoint string) *fakeBidder {
+	return &fakeBidder{info: &BidderInfo{ID: id, Endpoint: endpoint}}
+}
+
+func TestBidderFactory_ReplaceBidder(t *testing.T) {
+	factory := NewBidderFactory()
+
+	old, err := factory.ReplaceBidder(newFakeBidder("dsp_001", "http://v1"))
+	require.NoError(t, err)
+	assert.Nil(t, old)
+
+	old, err = factory.ReplaceBidder(newFakeBidder("dsp_001", "http://v2"))
+	require.NoError(t, err)
+	assert.Equal(t, "http://v1", old.GetInfo().Endpoint)
+
+	bidder, err := factory.GetBidder("dsp_001")
+	require.NoError(t, err)
+	assert.Equal(t, "http://v2", bidder.GetInfo().Endpoint)
+
+	_, err = factory.ReplaceBidder(nil)
+	assert.Error(t, err)
+}
+
+func TestBidderFactory_SwapBidders(t *testing.T) {
+	factory := NewBidderFactory()
+	require.NoError(t, factory.RegisterBidder(newFakeBidder("dsp_001", "http://v1")))
+	require.NoError(t, factory.RegisterBidder(newFakeBidder("dsp_002", "http://v1")))
+
+	require.NoError(t, factory.SwapBidders([]Bidder{
+		newFakeBidder("dsp_002", "http://v2"),
+		newFakeBidder("dsp_003", "http://v2"),
+	}))
+	assert.False(t, factory.HasBidder("dsp_001"))
+	assert.Equal(t, 2, factory.BidderCount())
+
+	bidder, err := factory.GetBidder("dsp_002")
+	require.NoError(t, err)
+	assert.Equal(t, "http://v2", bidder.GetInfo().Endpoint)
+
+	// 非法集合不会修改现有注册
+	err = factory.SwapBidders([]Bidder{
+		newFakeBidder("dsp_004", "http://v3"),
+		newFakeBidder("dsp_004", "http://v3"),
+	})
+	assert.Error(t, err)
+	assert.True(t, factory.HasBidder("dsp_003"))
+	assert.False(t, factory.HasBidder("dsp_004"))
+}
@@ -1 +1,319 @@
 package adxcore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// FeatureKey 带类型的特征键，保证同一个特征的写入和读取类型一致
+type FeatureKey[T any] string
+
+// Features 请求级特征集合，由各 FeatureProvider 补全，供定向、过滤等阶段读取
+type Features struct {
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+// NewFeatures 创建空特征集合
+func NewFeatures() *Features {
+	return &Features{values: make(map[string]any)}
+}
+
+// SetFeature 写入特征
+func SetFeature[T any](fs *Features, key FeatureKey[T], value T) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.values[string(key)] = value
+}
+
+// GetFeature 读取特征，特征不存在或类型不匹配时返回false
+func GetFeature[T any](fs *Features, key FeatureKey[T]) (T, bool) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	value, ok := fs.values[string(key)].(T)
+	return value, ok
+}
+
+// Keys 返回所有特征键(已排序)
+func (fs *Features) Keys() []string {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	keys := make([]string, 0, len(fs.values))
+	for key := range fs.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Merge 将另一个特征集合合并进来，同名特征以 other 为准
+func (fs *Features) Merge(other *Features) {
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for key, value := range other.values {
+		fs.values[key] = value
+	}
+}
+
+// TargetingAssignments 将已注册定向字段对应的特征转换为索引查询条件
+// 定向特征统一使用 FeatureKey[[]string]，键名即定向字段名(见 targeting_field.go)
+func (fs *Features) TargetingAssignments() map[string][]string {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	assignments := make(map[string][]string)
+	for key, value := range fs.values {
+		values, ok := value.([]string)
+		if !ok || len(values) == 0 || !IsKnownTargetingField(key) {
+			continue
+		}
+		assignments[key] = values
+	}
+	return assignments
+}
+
+// FeatureProvider 特征补全提供者
+// Provide 与其他提供者并发执行，读取的是请求快照，补全的特征写入 out；
+// 超时或失败的提供者产出的特征会被整体丢弃，不影响其他提供者。
+// 快照的 Features 为空，只有声明了依赖(见 FeatureDependent)的提供者能读到依赖产出的特征
+type FeatureProvider interface {
+	// Name 提供者名称，用于日志和监控
+	Name() string
+
+	// Provide 补全特征，ctx 携带整个特征补全阶段共享的截止时间
+	Provide(ctx context.Context, bidCtx *BidRequestCtx, out *Features) error
+}
+
+// FeatureDependent 可选接口，需要读取其他提供者产出特征的提供者实现。
+// 依赖的提供者全部结束后才执行，快照的 Features 中是其中成功者的产出；
+// 依赖必须先于自身注册，因此不会出现循环依赖
+type FeatureDependent interface {
+	DependsOn() []string
+}
+
+// RequestEnricher 可选接口，需要回写 OpenRTB 请求(如补全 Device.Geo)的提供者实现。
+// 所有提供者完成后按注册顺序串行调用，只对成功的提供者调用
+type RequestEnricher interface {
+	Enrich(bidCtx *BidRequestCtx, features *Features)
+}
+
+// FeatureObserver 特征补全观测回调，用于上报每个提供者的耗时和错误
+type FeatureObserver interface {
+	ObserveProvider(provider string, latency time.Duration, err error)
+}
+
+// ErrFeatureTimeout 提供者未在截止时间内完成
+var ErrFeatureTimeout = fmt.Errorf("feature provider timeout")
+
+// FeatureRegistry 特征提供者注册表，在共享截止时间内并发执行所有提供者
+type FeatureRegistry struct {
+	timeout  time.Duration
+	observer FeatureObserver
+
+	mu        sync.RWMutex
+	providers []FeatureProvider
+}
+
+// NewFeatureRegistry 创建特征提供者注册表，observer 可以为nil
+func NewFeatureRegistry(timeout time.Duration, observer FeatureObserver) *FeatureRegistry {
+	return &FeatureRegistry{
+		timeout:  timeout,
+		observer: observer,
+	}
+}
+
+// Register 注册特征提供者，名称重复时返回错误
+func (r *FeatureRegistry) Register(provider FeatureProvider) error {
+	if provider == nil {
+		return fmt.Errorf("cannot register nil feature provider")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.providers {
+		if p.Name() == provider.Name() {
+			return fmt.Errorf("feature provider '%s' is already registered", provider.Name())
+		}
+	}
+	for _, dep := range providerDependencies(provider) {
+		if !slices.ContainsFunc(r.providers, func(p FeatureProvider) bool { return p.Name() == dep }) {
+			return fmt.Errorf("feature provider '%s' depends on unregistered provider '%s'", provider.Name(), dep)
+		}
+	}
+	r.providers = append(r.providers, provider)
+	return nil
+}
+
+// providerDependencies 返回提供者声明的依赖
+func providerDependencies(provider FeatureProvider) []string {
+	if dependent, ok := provider.(FeatureDependent); ok {
+		return dependent.DependsOn()
+	}
+	return nil
+}
+
+// Providers 返回已注册的提供者
+func (r *FeatureRegistry) Providers() []FeatureProvider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	providers := make([]FeatureProvider, len(r.providers))
+	copy(providers, r.providers)
+	return providers
+}
+
+type featureResult struct {
+	features *Features
+	latency  time.Duration
+	err      error
+}
+
+// providerRun 单个提供者的执行状态，done 关闭后 result 可读
+type providerRun struct {
+	done   chan struct{}
+	result featureResult
+}
+
+// Complete 并发执行所有提供者并将成功的结果合并到 bidCtx.Features。
+// 单个提供者失败或超时只记录错误，不会中断竞价流程
+func (r *FeatureRegistry) Complete(bidCtx *BidRequestCtx) {
+	providers := r.Providers()
+	if len(providers) == 0 {
+		return
+	}
+
+	ctx := context.Context(bidCtx)
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	// 超时的提供者在 Complete 返回后仍可能在运行，读取快照以免与 Enrich 及后续阶段的回写并发
+	snapshot := providerSnapshot(bidCtx)
+	index := make(map[string]int, len(providers))
+	runs := make([]*providerRun, len(providers))
+	startTime := time.Now()
+	for i, provider := range providers {
+		index[provider.Name()] = i
+		runs[i] = &providerRun{done: make(chan struct{})}
+		var deps []*providerRun
+		for _, dep := range providerDependencies(provider) {
+			deps = append(deps, runs[index[dep]])
+		}
+		go func(p FeatureProvider, run *providerRun) {
+			defer close(run.done)
+			input := snapshot
+			if len(deps) > 0 {
+				features, ok := dependencyFeatures(ctx, deps)
+				if !ok {
+					run.result = featureResult{latency: time.Since(startTime), err: ErrFeatureTimeout}
+					return
+				}
+				input = snapshot.withFeatures(features)
+			}
+			out := NewFeatures()
+			begin := time.Now()
+			err := runProvider(ctx, p, input, out)
+			run.result = featureResult{features: out, latency: time.Since(begin), err: err}
+		}(provider, runs[i])
+	}
+
+	succeeded := make([]bool, len(providers))
+	for i, provider := range providers {
+		var result featureResult
+		select {
+		case <-runs[i].done:
+			result = runs[i].result
+		default:
+			// 已完成的结果优先，截止时间到达后不再等待
+			select {
+			case <-runs[i].done:
+				result = runs[i].result
+			case <-ctx.Done():
+				result = featureResult{latency: time.Since(startTime), err: ErrFeatureTimeout}
+			}
+		}
+
+		if r.observer != nil {
+			r.observer.ObserveProvider(provider.Name(), result.latency, result.err)
+		}
+		if result.err != nil {
+			bidCtx.AddProcessingError(fmt.Errorf("feature provider %s: %w", provider.Name(), result.err))
+			continue
+		}
+
+		bidCtx.Features.Merge(result.features)
+		succeeded[i] = true
+	}
+
+	for i, provider := range providers {
+		if enricher, ok := provider.(RequestEnricher); ok && succeeded[i] {
+			enricher.Enrich(bidCtx, bidCtx.Features)
+		}
+	}
+}
+
+// providerSnapshot 复制提供者可读取的请求信息，Request 深拷贝
+func providerSnapshot(bidCtx *BidRequestCtx) *BidRequestCtx {
+	snapshot := bidCtx.withFeatures(NewFeatures())
+	if bidCtx.Request != nil {
+		snapshot.Request = proto.Clone(bidCtx.Request).(*admux_rtb.BidRequest)
+	}
+	return snapshot
+}
+
+// dependencyFeatures 等待依赖的提供者结束并合并成功者的产出，截止时间先到时返回false
+func dependencyFeatures(ctx context.Context, deps []*providerRun) (*Features, bool) {
+	features := NewFeatures()
+	for _, dep := range deps {
+		select {
+		case <-dep.done:
+		case <-ctx.Done():
+			return nil, false
+		}
+		if dep.result.err == nil {
+			features.Merge(dep.result.features)
+		}
+	}
+	return features, true
+}
+
+// withFeatures 返回携带指定特征的上下文浅拷贝，Request 与原上下文共享
+func (bidCtx *BidRequestCtx) withFeatures(features *Features) *BidRequestCtx {
+	return &BidRequestCtx{
+		Context:      bidCtx.Context,
+		SSPID:        bidCtx.SSPID,
+		SSPConfig:    bidCtx.SSPConfig,
+		Request:      bidCtx.Request,
+		Features:     features,
+		BidStartTime: bidCtx.BidStartTime,
+		Debug:        bidCtx.Debug,
+	}
+}
+
+// runProvider 执行单个提供者，提供者panic时转换为错误
+func runProvider(ctx context.Context, p FeatureProvider, bidCtx *BidRequestCtx, out *Features) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("feature provider %s panic: %v", p.Name(), r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return p.Provide(ctx, bidCtx, out)
+}
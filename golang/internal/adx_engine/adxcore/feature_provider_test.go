@@ -0,0 +1,187 @@
+package adxcore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+var testFeature = FeatureKey[string]("test_feature")
+
+type funcProvider struct {
+	name    string
+	provide func(ctx context.Context, bidCtx *BidRequestCtx, out *Features) error
+	enrich  func(bidCtx *BidRequestCtx, features *Features)
+}
+
+func (p *funcProvider) Name() string { return p.name }
+
+func (p *funcProvider) Provide(ctx context.Context, bidCtx *BidRequestCtx, out *Features) error {
+	return p.provide(ctx, bidCtx, out)
+}
+
+type enrichProvider struct {
+	*funcProvider
+}
+
+func (p *enrichProvider) Enrich(bidCtx *BidRequestCtx, features *Features) {
+	p.enrich(bidCtx, features)
+}
+
+type dependentProvider struct {
+	*funcProvider
+	deps []string
+}
+
+func (p *dependentProvider) DependsOn() []string { return p.deps }
+
+type recordObserver struct {
+	errs map[string]error
+}
+
+func (o *recordObserver) ObserveProvider(provider string, _ time.Duration, err error) {
+	o.errs[provider] = err
+}
+
+func TestFeatureRegistry_Complete(t *testing.T) {
+	observer := &recordObserver{errs: make(map[string]error)}
+	registry := NewFeatureRegistry(50*time.Millisecond, observer)
+
+	enriched := false
+	require.NoError(t, registry.Register(&enrichProvider{&funcProvider{
+		name: "os",
+		provide: func(_ context.Context, _ *BidRequestCtx, out *Features) error {
+			SetFeature(out, FeatureUserOS, []string{"ios"})
+			return nil
+		},
+		enrich: func(_ *BidRequestCtx, features *Features) {
+			_, enriched = GetFeature(features, FeatureUserOS)
+		},
+	}}))
+	require.NoError(t, registry.Register(&funcProvider{
+		name: "failing",
+		provide: func(_ context.Context, _ *BidRequestCtx, out *Features) error {
+			SetFeature(out, testFeature, "dropped")
+			return errors.New("backend unavailable")
+		},
+	}))
+	require.NoError(t, registry.Register(&funcProvider{
+		name: "slow",
+		provide: func(ctx context.Context, _ *BidRequestCtx, out *Features) error {
+			<-ctx.Done()
+			SetFeature(out, FeatureUserGeo, []string{"BJ"})
+			return nil
+		},
+	}))
+	require.NoError(t, registry.Register(&funcProvider{
+		name: "panic",
+		provide: func(context.Context, *BidRequestCtx, *Features) error {
+			panic("boom")
+		},
+	}))
+	assert.Error(t, registry.Register(&funcProvider{name: "os"}))
+
+	bidCtx := NewBidRequestCtx(context.Background(), nil)
+	registry.Complete(bidCtx)
+
+	// 成功的提供者结果被合并，失败和超时的提供者结果被丢弃
+	assert.Equal(t, map[string][]string{TargetingFieldUserOS: {"ios"}}, bidCtx.Features.TargetingAssignments())
+	_, ok := GetFeature(bidCtx.Features, testFeature)
+	assert.False(t, ok)
+	assert.True(t, enriched)
+	assert.Len(t, bidCtx.ProcessingErrors, 3)
+
+	assert.NoError(t, observer.errs["os"])
+	assert.Error(t, observer.errs["failing"])
+	assert.ErrorIs(t, observer.errs["slow"], ErrFeatureTimeout)
+	assert.Error(t, observer.errs["panic"])
+}
+
+func TestFeatureRegistry_TimedOutProviderReadsSnapshot(t *testing.T) {
+	registry := NewFeatureRegistry(10*time.Millisecond, nil)
+
+	release := make(chan struct{})
+	done := make(chan string, 1)
+	require.NoError(t, registry.Register(&funcProvider{
+		name: "slow",
+		provide: func(ctx context.Context, bidCtx *BidRequestCtx, _ *Features) error {
+			<-ctx.Done()
+			// 超时后继续读取请求，配合 -race 检查与回写是否并发
+			<-release
+			done <- bidCtx.Request.GetDevice().GetGeo().GetCountry()
+			return nil
+		},
+	}))
+	require.NoError(t, registry.Register(&enrichProvider{&funcProvider{
+		name: "geo",
+		provide: func(context.Context, *BidRequestCtx, *Features) error {
+			return nil
+		},
+		enrich: func(bidCtx *BidRequestCtx, _ *Features) {
+			bidCtx.Request.Device.Geo = &admux_rtb.BidRequest_Geo{Country: proto.String("CHN")}
+		},
+	}}))
+
+	bidCtx := NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id:     proto.String("r1"),
+		Device: &admux_rtb.BidRequest_Device{},
+	})
+	registry.Complete(bidCtx)
+	assert.Equal(t, "CHN", bidCtx.Request.GetDevice().GetGeo().GetCountry())
+
+	bidCtx.Request.Device.Geo.Country = proto.String("USA")
+	close(release)
+	// 超时的提供者读到的是补全前的快照
+	assert.Empty(t, <-done)
+}
+
+func TestFeatureRegistry_Dependencies(t *testing.T) {
+	registry := NewFeatureRegistry(50*time.Millisecond, nil)
+	readOS := func(_ context.Context, bidCtx *BidRequestCtx, out *Features) error {
+		os, _ := GetFeature(bidCtx.Features, FeatureUserOS)
+		SetFeature(out, testFeature, strings.Join(os, ","))
+		return nil
+	}
+
+	// 依赖必须先注册
+	assert.Error(t, registry.Register(&dependentProvider{&funcProvider{name: "dependent", provide: readOS}, []string{"os"}}))
+	require.NoError(t, registry.Register(&funcProvider{
+		name: "os",
+		provide: func(_ context.Context, _ *BidRequestCtx, out *Features) error {
+			time.Sleep(5 * time.Millisecond)
+			SetFeature(out, FeatureUserOS, []string{"ios"})
+			return nil
+		},
+	}))
+	require.NoError(t, registry.Register(&dependentProvider{&funcProvider{name: "dependent", provide: readOS}, []string{"os"}}))
+
+	bidCtx := NewBidRequestCtx(context.Background(), nil)
+	registry.Complete(bidCtx)
+
+	// 依赖结束后才执行，并能读到依赖的产出
+	value, _ := GetFeature(bidCtx.Features, testFeature)
+	assert.Equal(t, "ios", value)
+	assert.Empty(t, bidCtx.ProcessingErrors)
+}
+
+func TestFeatures_TypedAccess(t *testing.T) {
+	features := NewFeatures()
+	SetFeature(features, testFeature, "value")
+
+	value, ok := GetFeature(features, testFeature)
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+
+	// 同名键类型不匹配时读取失败
+	_, ok = GetFeature(features, FeatureKey[int]("test_feature"))
+	assert.False(t, ok)
+	assert.Equal(t, []string{"test_feature"}, features.Keys())
+}
@@ -1,6 +1,9 @@
 package adxcore
 
-import ()
+import (
+	"errors"
+	"net/http"
+)
 
 type (
 	// Supply Side Adapter interface
@@ -8,6 +11,27 @@ type (
 		ToInternalBidRequest(ctx *BidRequestCtx, data []byte) error
 
 		PackSSPResponse(ctx *BidRequestCtx) ([]byte, error)
+
+		// ContentType is the content type of the responses packed by PackSSPResponse
+		ContentType() string
+
+		// The outcomes below are encoded in the SSP's protocol, data is the raw request
+		// which may not have been parsed
+
+		// PackNoBid encodes the reply to a request answered without bids
+		PackNoBid(data []byte) SSPReply
+		// PackInvalidRequest encodes the reply to a request rejected before the auction,
+		// see InvalidRequestStatus for the HTTP status of err
+		PackInvalidRequest(data []byte, err error) SSPReply
+		// PackInternalError encodes the reply to a request whose auction failed
+		PackInternalError(data []byte) SSPReply
+	}
+
+	// SSPReply is a complete HTTP reply to an SSP, a nil Body is sent without content
+	SSPReply struct {
+		StatusCode  int
+		ContentType string
+		Body        []byte
 	}
 
 	// PipelineStage interface for bid request processing pipeline
@@ -15,3 +39,16 @@ type (
 		Process(ctx *BidRequestCtx) error
 	}
 )
+
+// InvalidRequestStatus returns the HTTP status of a request rejected with err: 403 for
+// sources not allowed, 401 for failed authentication and 400 otherwise
+func InvalidRequestStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrSSPForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrSSPUnauthorized):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,67 @@
+package adxcore
+
+import (
+	"sort"
+	"sync"
+)
+
+// 定向字段，DSP定向配置(indexingdoc)中的 field 必须是已注册的字段
+const (
+	TargetingFieldUserGeo         = "USER_GEO"
+	TargetingFieldUserOS          = "USER_OS"
+	TargetingFieldDeviceType      = "DEVICE_TYPE"
+	TargetingFieldConnectionType  = "CONNECTION_TYPE"
+	TargetingFieldContentCategory = "CONTENT_CATEGORY"
+)
+
+var (
+	targetingFieldsMu sync.RWMutex
+	targetingFields   = map[string]struct{}{
+		TargetingFieldUserGeo:         {},
+		TargetingFieldUserOS:          {},
+		TargetingFieldDeviceType:      {},
+		TargetingFieldConnectionType:  {},
+		TargetingFieldContentCategory: {},
+	}
+)
+
+// RegisterTargetingField 注册可定向字段，特征补全模块产出新的可定向特征时调用
+func RegisterTargetingField(fields ...string) {
+	targetingFieldsMu.Lock()
+	defer targetingFieldsMu.Unlock()
+
+	for _, field := range fields {
+		targetingFields[field] = struct{}{}
+	}
+}
+
+// IsKnownTargetingField 判断字段是否为已注册的定向字段
+func IsKnownTargetingField(field string) bool {
+	targetingFieldsMu.RLock()
+	defer targetingFieldsMu.RUnlock()
+
+	_, exists := targetingFields[field]
+	return exists
+}
+
+// KnownTargetingFields 返回所有已注册的定向字段(已排序)
+func KnownTargetingFields() []string {
+	targetingFieldsMu.RLock()
+	defer targetingFieldsMu.RUnlock()
+
+	fields := make([]string, 0, len(targetingFields))
+	for field := range targetingFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// 定向特征键，取值为定向字段的值列表，由特征补全阶段写入 BidRequestCtx.Features
+var (
+	FeatureUserGeo         = FeatureKey[[]string](TargetingFieldUserGeo)
+	FeatureUserOS          = FeatureKey[[]string](TargetingFieldUserOS)
+	FeatureDeviceType      = FeatureKey[[]string](TargetingFieldDeviceType)
+	FeatureConnectionType  = FeatureKey[[]string](TargetingFieldConnectionType)
+	FeatureContentCategory = FeatureKey[[]string](TargetingFieldContentCategory)
+)
@@ -0,0 +1,124 @@
+package adxserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminActorKey is the gin context key of the authenticated admin caller
+const adminActorKey = "admin_actor"
+
+// adminToken is a caller allowed to use the admin API
+type adminToken struct {
+	name  string
+	token []byte
+}
+
+// AdminHandler serves the authenticated /admin route group: SSP and DSP status,
+// pause and resume, DSP config rescan, index stats and sample rates. Every change is
+// written to the audit log.
+type AdminHandler struct {
+	adx    *AdxServer
+	tokens []adminToken
+	audit  *AuditLog
+
+	dsp *DSPAdminHandler
+	ssp *SSPAdminHandler
+}
+
+// NewAdminHandler creates the admin handler from appCtx.Config.Admin, the admin API
+// rejects every request when no token is configured
+func NewAdminHandler(appCtx *AdxServerContext, adx *AdxServer, reloader *SSPReloader) (*AdminHandler, error) {
+	audit, err := NewAuditLog(appCtx.Config.Admin.AuditLog, appCtx.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &AdminHandler{
+		adx:   adx,
+		audit: audit,
+		dsp:   NewDSPAdminHandler(appCtx, adx, audit),
+		ssp:   NewSSPAdminHandler(appCtx, reloader, audit),
+	}
+	for _, token := range appCtx.Config.Admin.Tokens {
+		if token.Token == "" {
+			continue
+		}
+		h.tokens = append(h.tokens, adminToken{name: token.Name, token: []byte(token.Token)})
+	}
+	if len(h.tokens) == 0 {
+		appCtx.Logger.Warn("no admin token configured, admin API disabled")
+	}
+	return h, nil
+}
+
+// Register mounts the admin endpoints under /admin behind Authenticate
+func (h *AdminHandler) Register(r gin.IRouter) {
+	admin := r.Group("/admin", h.Authenticate)
+
+	admin.GET("/ssp", h.ssp.HandleListSSPs)
+	admin.POST("/ssp/reload", h.ssp.HandleReloadSSPs)
+	admin.POST("/ssp/:id/pause", h.ssp.HandlePauseSSP)
+	admin.POST("/ssp/:id/resume", h.ssp.HandleResumeSSP)
+
+	admin.GET("/dsp", h.dsp.HandleListDSPs)
+	admin.GET("/dsp/quarantine", h.dsp.HandleQuarantinedDSPs)
+	admin.GET("/dsp/index", h.dsp.HandleIndexStats)
+	admin.POST("/dsp/rescan", h.dsp.HandleRescanDSPs)
+	admin.POST("/dsp/:id/pause", h.dsp.HandlePauseDSP)
+	admin.POST("/dsp/:id/resume", h.dsp.HandleResumeDSP)
+
+	admin.GET("/sampling", h.HandleGetSampling)
+	admin.PUT("/sampling", h.HandleSetSampling)
+}
+
+// Authenticate accepts requests carrying a configured token as "Authorization: Bearer {token}"
+func (h *AdminHandler) Authenticate(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if ok && token != "" {
+		for _, allowed := range h.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), allowed.token) == 1 {
+				c.Set(adminActorKey, allowed.name)
+				c.Next()
+				return
+			}
+		}
+	}
+
+	c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+}
+
+// HandleGetSampling returns the sample rates of the bid log and tracing, null when disabled
+func (h *AdminHandler) HandleGetSampling(c *gin.Context) {
+	c.JSON(http.StatusOK, h.adx.SampleRates())
+}
+
+// HandleSetSampling replaces the sample rates of the components present in the body
+func (h *AdminHandler) HandleSetSampling(c *gin.Context) {
+	var rates SampleRates
+	if err := c.ShouldBindJSON(&rates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sample rates", "details": err.Error()})
+		return
+	}
+
+	err := h.adx.SetSampleRates(rates)
+	h.audit.Record(c, "set_sampling", "", rates, err)
+	switch {
+	case errors.Is(err, ErrSamplingDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, h.adx.SampleRates())
+	}
+}
+
+// Close closes the audit log
+func (h *AdminHandler) Close() error {
+	return h.audit.Close()
+}
@@ -0,0 +1,233 @@
+package adxserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/dspbidder"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+	"github.com/echoface/admux/pkg/logger"
+)
+
+const testAdminToken = "admin-token"
+
+type adminTestServer struct {
+	appCtx *AdxServerContext
+	adx    *AdxServer
+	router *gin.Engine
+	audit  string
+}
+
+func newAdminTestServer(t *testing.T, cfg *config.AdxServerConfig) *adminTestServer {
+	t.Helper()
+	if cfg.Admin.AuditLog == "" {
+		cfg.Admin.AuditLog = filepath.Join(t.TempDir(), "audit.log")
+	}
+	cfg.SSPs = append(cfg.SSPs, config.SSPConfig{ID: "kuaishou", Protocol: sspadapter.ProtocolKuaishou, Timeout: time.Second, Enabled: true})
+
+	appCtx := &AdxServerContext{Config: cfg, MetricsRegistry: prometheus.NewRegistry(), Logger: logger.Default}
+	reloader, err := NewSSPReloader(appCtx, "", nil)
+	require.NoError(t, err)
+	adx := NewAdxServer(appCtx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/bid/kuaishou", NewBidHandler(adx, appCtx).HandleKuaishouBid)
+	admin, err := NewAdminHandler(appCtx, adx, reloader)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+	admin.Register(r)
+
+	return &adminTestServer{appCtx: appCtx, adx: adx, router: r, audit: cfg.Admin.AuditLog}
+}
+
+func (s *adminTestServer) do(method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *adminTestServer) auditEntries(t *testing.T) []AuditEntry {
+	t.Helper()
+	file, err := os.Open(s.audit)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func adminConfig() *config.AdxServerConfig {
+	return &config.AdxServerConfig{Admin: config.AdminConfig{
+		Tokens: []config.AdminTokenConfig{{Name: "ops", Token: testAdminToken}},
+	}}
+}
+
+func TestAdminHandler_Authenticate(t *testing.T) {
+	// 未配置令牌时拒绝所有请求
+	s := newAdminTestServer(t, &config.AdxServerConfig{})
+	assert.Equal(t, http.StatusUnauthorized, s.do(http.MethodGet, "/admin/ssp", testAdminToken, "").Code)
+
+	s = newAdminTestServer(t, adminConfig())
+	assert.Equal(t, http.StatusUnauthorized, s.do(http.MethodGet, "/admin/ssp", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, s.do(http.MethodGet, "/admin/ssp", "wrong", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, s.do(http.MethodPost, "/admin/ssp/kuaishou/pause", "wrong", "").Code)
+	assert.False(t, s.appCtx.GetSSPFactory().IsPaused("kuaishou"))
+	assert.Equal(t, http.StatusOK, s.do(http.MethodGet, "/admin/ssp", testAdminToken, "").Code)
+}
+
+func TestAdminHandler_PauseSSP(t *testing.T) {
+	s := newAdminTestServer(t, adminConfig())
+	bid := func() int {
+		return s.do(http.MethodPost, "/bid/kuaishou?sspid=kuaishou", "", `{"request_id":"r1","imp":[{"imp_id":"1"}]}`).Code
+	}
+	require.Equal(t, http.StatusOK, bid())
+
+	w := s.do(http.MethodPost, "/admin/ssp/kuaishou/pause", testAdminToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"kuaishou","paused":true}`, w.Body.String())
+	assert.Contains(t, s.do(http.MethodGet, "/admin/ssp", testAdminToken, "").Body.String(), `"paused":true`)
+	// 暂停期间直接返回按快手协议编码的不出价，不进入竞价
+	w = s.do(http.MethodPost, "/bid/kuaishou?sspid=kuaishou", "", `{"request_id":"r1","imp":[{"imp_id":"1"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"request_id":"r1","status":1}`, w.Body.String())
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.adx.metrics.Requests.WithLabelValues("kuaishou")))
+
+	require.Equal(t, http.StatusOK, s.do(http.MethodPost, "/admin/ssp/kuaishou/resume", testAdminToken, "").Code)
+	assert.Equal(t, http.StatusOK, bid())
+	assert.Equal(t, http.StatusNotFound, s.do(http.MethodPost, "/admin/ssp/unknown/pause", testAdminToken, "").Code)
+
+	entries := s.auditEntries(t)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "ops", entries[0].Actor)
+	assert.Equal(t, "pause_ssp", entries[0].Action)
+	assert.Equal(t, "kuaishou", entries[0].Target)
+	assert.Equal(t, AuditOK, entries[0].Result)
+	assert.Equal(t, "resume_ssp", entries[1].Action)
+	assert.Equal(t, AuditFailed, entries[2].Result)
+	assert.NotEmpty(t, entries[2].Error)
+}
+
+func TestAdminHandler_DSP(t *testing.T) {
+	// 索引文件写到当前目录
+	t.Chdir(t.TempDir())
+	dir := t.TempDir()
+	for _, id := range []string{"admin_dsp_1", "admin_dsp_2"} {
+		content := fmt.Sprintf(`{"dsp_id":%q,"dsp_name":"test","status":"active","endpoint":"http://127.0.0.1:1/bid","qps_limit":100,"budget_daily":500}`, id)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, id+".json"), []byte(content), 0o644))
+		defer adxcore.GetGlobalBidderFactory().UnregisterBidder(id)
+	}
+
+	cfg := adminConfig()
+	cfg.DSPSource = config.DSPSourceConfig{Type: "local", ScanInterval: time.Hour, Local: config.LocalDSPSourceConfig{Dir: dir}}
+	s := newAdminTestServer(t, cfg)
+	mgr, err := dspbidder.NewBidderIndexManager(cfg, s.appCtx.MetricsRegistry)
+	require.NoError(t, err)
+	s.appCtx.SetBidderIndexManager(mgr)
+
+	w := s.do(http.MethodPost, "/admin/dsp/rescan", testAdminToken, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"dsp_count":2,"quarantined_count":0}`, w.Body.String())
+
+	w = s.do(http.MethodPost, "/admin/dsp/admin_dsp_1/pause", testAdminToken, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusNotFound, s.do(http.MethodPost, "/admin/dsp/unknown/pause", testAdminToken, "").Code)
+
+	var list struct {
+		Count   int             `json:"count"`
+		Breaker string          `json:"breaker"`
+		DSPs    []dspStatusView `json:"dsps"`
+	}
+	w = s.do(http.MethodGet, "/admin/dsp", testAdminToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, 2, list.Count)
+	assert.Equal(t, "closed", list.Breaker)
+	assert.Equal(t, "admin_dsp_1", list.DSPs[0].ID)
+	assert.True(t, list.DSPs[0].Registered)
+	assert.True(t, list.DSPs[0].Paused)
+	assert.True(t, list.DSPs[0].Health.Healthy)
+	assert.Equal(t, 100, list.DSPs[0].QPS.Limit)
+	require.NotNil(t, list.DSPs[0].Budget)
+	assert.Equal(t, 500.0, list.DSPs[0].Budget.DailyBudget)
+	assert.False(t, list.DSPs[1].Paused)
+
+	// 暂停的DSP定向命中也不参与竞价
+	bidCtx := adxcore.NewBidRequestCtx(t.Context(), nil)
+	bidCtx.Debug = true
+	bidders, err := s.adx.targetingBidders(bidCtx)
+	require.NoError(t, err)
+	for _, bidder := range bidders {
+		assert.NotEqual(t, "admin_dsp_1", bidder.GetInfo().ID)
+	}
+	assert.Contains(t, bidCtx.Targeting, adxcore.TargetingDecision{BidderID: "admin_dsp_1", Reason: adxcore.TargetingPaused})
+
+	w = s.do(http.MethodGet, "/admin/dsp/index", testAdminToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats struct {
+		Index   map[string]any `json:"index"`
+		Manager map[string]any `json:"manager"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, true, stats.Index["compiled"])
+	assert.EqualValues(t, 2, stats.Manager["registered_dsp_count"])
+
+	actions := make([]string, 0, 3)
+	for _, entry := range s.auditEntries(t) {
+		actions = append(actions, entry.Action+":"+entry.Result)
+	}
+	assert.Equal(t, []string{"rescan_dsp:ok", "pause_dsp:ok", "pause_dsp:failed"}, actions)
+}
+
+func TestAdminHandler_Sampling(t *testing.T) {
+	cfg := adminConfig()
+	cfg.BidLog = config.BidLogConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "bid.log"), SampleRate: 0.01}
+	s := newAdminTestServer(t, cfg)
+	defer s.adx.Close(t.Context())
+
+	w := s.do(http.MethodGet, "/admin/sampling", testAdminToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"bid_log":{"sample_rate":0.01,"ssp_sample_rates":{},"dsp_sample_rates":{}},"tracing":null}`, w.Body.String())
+
+	w = s.do(http.MethodPut, "/admin/sampling", testAdminToken, `{"bid_log":{"sample_rate":1.5}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = s.do(http.MethodPut, "/admin/sampling", testAdminToken, `{"tracing":{"sample_rate":0.5}}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = s.do(http.MethodPut, "/admin/sampling", testAdminToken, `{"bid_log":{"sample_rate":0,"ssp_sample_rates":{"kuaishou":1}}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"bid_log":{"sample_rate":0,"ssp_sample_rates":{"kuaishou":1},"dsp_sample_rates":null},"tracing":null}`, w.Body.String())
+	assert.True(t, s.adx.bidLog.Sampler().Sample(&adxcore.BidRequestCtx{SSPID: "kuaishou"}, func() float64 { return 0.5 }))
+
+	entries := s.auditEntries(t)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{AuditFailed, AuditFailed, AuditOK}, []string{entries[0].Result, entries[1].Result, entries[2].Result})
+	assert.Equal(t, "set_sampling", entries[2].Action)
+	assert.NotNil(t, entries[2].Params)
+}
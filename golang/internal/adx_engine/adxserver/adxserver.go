@@ -1,82 +1,301 @@
 package adxserver
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"time"
 
+	"github.com/bytedance/gg/gmap"
 	"github.com/bytedance/gg/gslice"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
 	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/feature"
+	"github.com/echoface/admux/internal/adx_engine/health"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+	"github.com/echoface/admux/pkg/notifier"
+	"github.com/echoface/admux/pkg/tracing"
+	"github.com/echoface/admux/pkg/tracking"
 )
 
+// defaultFeatureTimeout is the shared deadline of all feature providers when not configured
+const defaultFeatureTimeout = 10 * time.Millisecond
+
 type AdxServer struct {
 	appCtx *AdxServerContext
+
+	features    *adxcore.FeatureRegistry
+	broadcaster *BroadcastManager
+	responses   *ResponseBuilder
+	notifier    *notifier.Notifier
+	bidLog      *bidlog.Logger
+	tracing     *tracing.Provider
+	sampler     *tracing.KeyedSampler
+	tracer      trace.Tracer
+	debug       *DebugAuth
+	metrics     *metrics.AuctionMetrics
+	shedder     *LoadShedder
 }
 
 func NewAdxServer(appCtx *AdxServerContext) *AdxServer {
-	return &AdxServer{
-		appCtx: appCtx,
+	featureTimeout := defaultFeatureTimeout
+	if appCtx.Config != nil && appCtx.Config.Features.Timeout > 0 {
+		featureTimeout = appCtx.Config.Features.Timeout
+	}
+
+	reg := appCtx.registerer()
+
+	features := adxcore.NewFeatureRegistry(featureTimeout, metrics.NewFeatureMetrics("adx", "feature", reg))
+	registerFeatureProviders(appCtx, features)
+
+	var trackingCfg config.TrackingConfig
+	if appCtx.Config != nil {
+		trackingCfg = appCtx.Config.Tracking
+	}
+	responses, err := NewResponseBuilder(trackingCfg)
+	if err != nil {
+		// serve without ADX tracking rather than failing every response
+		appCtx.Logger.Error("create response builder failed, tracking urls disabled", "error", err)
+		responses, _ = NewResponseBuilder(config.TrackingConfig{Currency: trackingCfg.Currency})
+	}
+
+	var notifierCfg notifier.Config
+	if appCtx.Config != nil {
+		notifierCfg = appCtx.Config.Notifier
+	}
+	var notices *notifier.Notifier
+	if !notifierCfg.Disabled {
+		if notices, err = notifier.New(notifierCfg, notifier.NewMetrics("adx", "notifier", reg)); err != nil {
+			// the dead letter file is optional, keep delivering notices without it
+			appCtx.Logger.Error("create dsp notifier failed, dead letter disabled", "error", err)
+			notifierCfg.DeadLetter.Path = ""
+			notices, _ = notifier.New(notifierCfg, notifier.NewMetrics("adx", "notifier", reg))
+		}
+	}
+
+	var bidLog *bidlog.Logger
+	if appCtx.Config != nil {
+		if bidLog, err = bidlog.New(appCtx.Config.BidLog, metrics.NewBidLogMetrics("adx", "bidlog", reg)); err != nil {
+			appCtx.Logger.Error("create bid log failed, sampling disabled", "error", err)
+		}
+	}
+
+	var debug *DebugAuth
+	if appCtx.Config != nil {
+		if debug, err = NewDebugAuth(appCtx.Config.Debug); err != nil {
+			appCtx.Logger.Error("create debug authorizer failed, debug mode disabled", "error", err)
+		}
+	}
+
+	var metricsCfg config.MetricsConfig
+	if appCtx.Config != nil {
+		metricsCfg = appCtx.Config.Metrics
+	}
+	auctionMetrics := metrics.NewAuctionMetrics("adx", "auction", reg, metricsCfg.MaxSSPLabels, metricsCfg.MaxDSPLabels)
+
+	var overloadCfg config.OverloadConfig
+	if appCtx.Config != nil {
+		overloadCfg = appCtx.Config.Overload
+	}
+
+	traceProvider, traceSampler := newTracing(appCtx)
+	server := &AdxServer{
+		appCtx:      appCtx,
+		features:    features,
+		broadcaster: NewBroadcastManager(appCtx),
+		responses:   responses,
+		notifier:    notices,
+		bidLog:      bidLog,
+		tracing:     traceProvider,
+		sampler:     traceSampler,
+		debug:       debug,
+		metrics:     auctionMetrics,
+		shedder:     NewLoadShedder(overloadCfg),
+	}
+	server.setTracer(server.tracing.Tracer(tracerName))
+	if appCtx.Config != nil {
+		server.AllowSSPLabels(appCtx.Config.SSPs)
+	}
+	return server
+}
+
+// AllowSSPLabels keeps the SSPs' own metrics label values regardless of the label limit,
+// registered with SSPReloader.OnReload so that SSPs added later are kept as well
+func (s *AdxServer) AllowSSPLabels(ssps []config.SSPConfig) {
+	s.metrics.AllowSSPs(gslice.Map(ssps, func(ssp config.SSPConfig) string { return ssp.ID })...)
+}
+
+// Close flushes the pending DSP notices, bid log records and spans, the remaining notices
+// are dead-lettered when ctx expires
+func (s *AdxServer) Close(ctx context.Context) error {
+	err := s.notifier.Close(ctx)
+	if logErr := s.bidLog.Close(); logErr != nil && err == nil {
+		err = logErr
+	}
+	if traceErr := s.tracing.Shutdown(ctx); traceErr != nil && err == nil {
+		err = traceErr
+	}
+	return err
+}
+
+// RecordBid submits the processed request to the sampled bid log, it never blocks
+func (s *AdxServer) RecordBid(bidCtx *adxcore.BidRequestCtx, sspRequest, sspResponse []byte, err error) {
+	s.bidLog.Record(bidCtx, sspRequest, sspResponse, err)
+}
+
+// AllowDebug reports whether the request asks for the auction trace and the caller is trusted
+func (s *AdxServer) AllowDebug(header http.Header, clientIP, sspID string) bool {
+	return s.debug.Allowed(header, clientIP, sspID)
+}
+
+// registerFeatureProviders registers the built-in providers enabled by config,
+// providers that depend on external resources (e.g. the ip geo db) are registered by main
+func registerFeatureProviders(appCtx *AdxServerContext, features *adxcore.FeatureRegistry) {
+	providers := []adxcore.FeatureProvider{feature.NewRequestProvider(), feature.NewDeviceIDProvider()}
+
+	if cfg := appCtx.Config; cfg != nil {
+		if cfg.Features.UA.Enabled {
+			providers = append(providers, feature.NewUAProvider(cfg.Features.UA.CacheSize))
+		}
+
+		if profileCfg := cfg.Features.UserProfile; profileCfg.Enabled {
+			if appCtx.RedisClient == nil {
+				appCtx.Logger.Error("user profile feature provider requires redis, skipped")
+			} else {
+				providers = append(providers, feature.NewUserProfileProvider(appCtx.RedisClient, profileCfg.KeyPrefix, profileCfg.Timeout))
+			}
+		}
 	}
+
+	for _, provider := range providers {
+		if err := features.Register(provider); err != nil {
+			appCtx.Logger.Error("register feature provider failed", "provider", provider.Name(), "error", err)
+		}
+	}
+}
+
+// FeatureRegistry returns the registry used by the feature completion stage,
+// extra providers should be registered before serving traffic
+func (s *AdxServer) FeatureRegistry() *adxcore.FeatureRegistry {
+	return s.features
 }
 
 func (s *AdxServer) ProcessBid(bixCtx *adxcore.BidRequestCtx) (err error) {
-	// Create bid request context
+	// Every stage runs in its own span, see traceStage
+	defer func() { s.metrics.ObserveAuction(bixCtx, err) }()
 
 	// 1. 特征补全
-	if err := s.completeFeatures(bixCtx); err != nil {
+	if err := s.traceStage(bixCtx, "complete_features", s.completeFeatures); err != nil {
 		return fmt.Errorf("completeFeatures fail:%w", err)
 	}
 
 	// 2. DSP targeting 定向，找出本次流量需要请求哪些DSP需要竞价调用
 	var bidders []adxcore.Bidder
-	if bidders, err = s.targetingBidders(bixCtx); err != nil {
+	err = s.traceStage(bixCtx, "targeting", func(ctx *adxcore.BidRequestCtx) (err error) {
+		bidders, err = s.targetingBidders(ctx)
+		return err
+	})
+	if err != nil {
 		return fmt.Errorf("targetingBidders fail:%w", err)
 	}
 
 	// 3. DSP竞价广播
-	if err := s.broadcast(bixCtx, bidders); err != nil {
+	err = s.traceStage(bixCtx, "broadcast", func(ctx *adxcore.BidRequestCtx) error {
+		return s.broadcast(ctx, bidders)
+	})
+	if err != nil {
 		return fmt.Errorf("broadcast fail:%w", err)
 	}
 
 	// 4. 素材Collector
-	if err := s.broadcast(bixCtx, bidders); err != nil {
-		return fmt.Errorf("broadcast fail:%w", err)
-	}
+	// TODO: 素材审核与收集
 
 	// 5. 候选Filters
 	// 串并过滤支持；编排后执行
-	if err := s.filterCanidates(bixCtx); err != nil {
+	if err := s.traceStage(bixCtx, "filter", s.filterCanidates); err != nil {
 		return fmt.Errorf("filterCanidates fail:%w", err)
 	}
 
 	// 6. Ranking: 找出ECPM最高的竞价（最有可能获得最大利润）的候选广告
 	// ecpm * winrate
-	if err := s.rankingCandidates(bixCtx); err != nil {
+	if err := s.traceStage(bixCtx, "ranking", s.rankingCandidates); err != nil {
 		return fmt.Errorf("rankingCandidates fail:%s", err)
 	}
 
-	// Return processing results
-	_ = s.buildResponse(bixCtx)
+	// 7. 构建响应：宏替换、注入签名监测链接
+	if err := s.traceStage(bixCtx, "build_response", s.buildResponse); err != nil {
+		return fmt.Errorf("buildResponse fail:%w", err)
+	}
 	return nil
 }
 
+// completeFeatures 通过各 feature provider 补全特征数据，单个provider失败不影响竞价
 func (s *AdxServer) completeFeatures(ctx *adxcore.BidRequestCtx) error {
-	// 这里通过各种feature provider 补全特征数据
-
+	s.features.Complete(ctx)
+	ctx.AddProcessingStage("complete_features")
 	return nil
 }
 
 // targetingBidders 根据各bidder 和 ssp的要求，得到最终需要广播的竞价方
 func (s *AdxServer) targetingBidders(ctx *adxcore.BidRequestCtx) ([]adxcore.Bidder, error) {
-	return make([]adxcore.Bidder, 0), nil
+	defer ctx.AddProcessingStage("targeting")
+
+	factory := adxcore.GetGlobalBidderFactory()
+
+	// 未启用DSP索引时广播给所有已注册的bidder
+	indexMgr := s.appCtx.GetBidderIndexManager()
+	if indexMgr == nil {
+		bidders := gmap.Values(factory.GetAllBidders())
+		for _, bidder := range bidders {
+			ctx.RecordTargeting(bidder.GetInfo().ID, true, adxcore.TargetingNoIndex)
+		}
+		return bidders, nil
+	}
+
+	matched := indexMgr.MatchDSPs(ctx.Features.TargetingAssignments())
+	bidders := make([]adxcore.Bidder, 0, len(matched))
+	for _, dspInfo := range matched {
+		if indexMgr.IsDSPPaused(dspInfo.DSPID) {
+			ctx.RecordTargeting(dspInfo.DSPID, false, adxcore.TargetingPaused)
+			continue
+		}
+		// 只有处于活跃状态的DSP会注册bidder
+		if bidder, err := factory.GetBidder(dspInfo.DSPID); err == nil {
+			bidders = append(bidders, bidder)
+			ctx.RecordTargeting(dspInfo.DSPID, true, adxcore.TargetingMatched)
+		} else {
+			ctx.RecordTargeting(dspInfo.DSPID, false, adxcore.TargetingInactive)
+		}
+	}
+	if ctx.Debug {
+		recordNotMatched(ctx, factory.GetAllBidders(), bidders)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("targeting.matched", len(matched)))
+	return bidders, nil
+}
+
+// recordNotMatched records the registered bidders left out by targeting
+func recordNotMatched(ctx *adxcore.BidRequestCtx, all map[string]adxcore.Bidder, selected []adxcore.Bidder) {
+	for _, bidder := range selected {
+		delete(all, bidder.GetInfo().ID)
+	}
+	for _, id := range gmap.OrderedKeys(all) {
+		ctx.RecordTargeting(id, false, adxcore.TargetingNotMatched)
+	}
 }
 
 func (s *AdxServer) broadcast(ctx *adxcore.BidRequestCtx, bidders []adxcore.Bidder) error {
-	// 创建广播管理器
-	broadcastManager := NewBroadcastManager(s.appCtx)
+	defer ctx.AddProcessingStage("broadcast")
 
 	// 向定向的bidder广播竞价请求
-	candidates, err := broadcastManager.BroadcastWithBidders(ctx, bidders)
+	candidates, err := s.broadcaster.BroadcastWithBidders(ctx, bidders)
 	if err != nil {
 		return fmt.Errorf("broadcast to bidders failed: %w", err)
 	}
@@ -89,16 +308,35 @@ func (s *AdxServer) broadcast(ctx *adxcore.BidRequestCtx, bidders []adxcore.Bidd
 	return nil
 }
 
+// filterCanidates 过滤无效出价
 func (s *AdxServer) filterCanidates(ctx *adxcore.BidRequestCtx) error {
+	defer ctx.AddProcessingStage("filter")
+
 	canidates := ctx.GetCandidates()
-	ctx.Candidates = make([]*adxcore.BidCandidate, 0, len(canidates)/2)
+	filtered := make([]*adxcore.BidCandidate, 0, len(canidates))
+	for _, candidate := range canidates {
+		if candidate == nil {
+			continue
+		}
+		if candidate.CPMPrice <= 0 {
+			ctx.RejectCandidate(candidate, "invalid_price", tracking.LossInvalidResponse)
+			continue
+		}
+		filtered = append(filtered, candidate)
+	}
 
+	ctx.FilteredCandidates = filtered
+	ctx.SetCandidates(filtered)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("filter.rejected", len(canidates)-len(filtered)))
 	return nil
 }
 
+// rankingCandidates 按出价从高到低排序
 func (s *AdxServer) rankingCandidates(ctx *adxcore.BidRequestCtx) error {
+	defer ctx.AddProcessingStage("ranking")
+
 	gslice.SortBy(ctx.GetCandidates(), func(l, r *adxcore.BidCandidate) bool {
-		return l.CPMPrice < r.CPMPrice
+		return l.CPMPrice > r.CPMPrice
 	})
 	return nil
 }
@@ -126,12 +364,80 @@ func (s *AdxServer) GetSSPAdapter(sspID string) (adxcore.ISSPAdapter, *config.SS
 	return adapter, sspConfig, nil
 }
 
-// buildResponse constructs the final response from processed bid context
-func (s *AdxServer) buildResponse(bidCtx *adxcore.BidRequestCtx) map[string]any {
-	// TODO: Implement response building logic
-	// This should create the OpenRTB response from the winning bid
-	return map[string]any{
-		"status":           "processed",
-		"candidates_count": len(bidCtx.GetCandidates()),
+// Authenticate checks that a bid request comes from the SSP it claims, rejections are
+// counted by reason
+func (s *AdxServer) Authenticate(sspID string, req *sspadapter.AuthRequest) error {
+	factory := s.appCtx.GetSSPFactory()
+	if factory == nil {
+		return nil
+	}
+	err := factory.Authenticate(sspID, req)
+	if err != nil {
+		reason := sspadapter.AuthMisconfigured
+		var authErr *sspadapter.AuthError
+		if errors.As(err, &authErr) {
+			reason = authErr.Reason
+		}
+		s.metrics.ObserveAuthRejection(sspID, reason)
+	}
+	return err
+}
+
+// Admit applies the SSP's QPS limit and the global load shedding before the auction,
+// release must be called once an admitted request is answered. Shed requests are counted
+// by reason and should get a fast no-bid.
+func (s *AdxServer) Admit(sspConfig *config.SSPConfig) (release func(), admitted bool) {
+	done, reason := s.shedder.Admit(sspConfig)
+	s.metrics.ObserveLoad(s.shedder.InFlight(), s.shedder.Limit())
+	if reason != "" {
+		s.metrics.ObserveShed(sspConfig.ID, reason)
+		return nil, false
+	}
+	return func() {
+		done()
+		s.metrics.ObserveLoad(s.shedder.InFlight(), s.shedder.Limit())
+	}, true
+}
+
+// SSPPaused reports whether bidding is paused for the SSP through the admin API
+func (s *AdxServer) SSPPaused(sspID string) bool {
+	factory := s.appCtx.GetSSPFactory()
+	return factory != nil && factory.IsPaused(sspID)
+}
+
+// BidderHealth returns the health of a DSP as seen by the broadcaster
+func (s *AdxServer) BidderHealth(bidderID string) *health.HealthStatus {
+	return s.broadcaster.healthChecker.GetHealthStatus(bidderID)
+}
+
+// BreakerState returns the state of the broadcast circuit breaker
+func (s *AdxServer) BreakerState() health.CircuitState {
+	return s.broadcaster.circuitBreaker.State()
+}
+
+// buildResponse constructs the final response from the ranked candidates
+func (s *AdxServer) buildResponse(bidCtx *adxcore.BidRequestCtx) error {
+	defer bidCtx.AddProcessingStage("build_response")
+
+	bidCtx.SetResponse(s.responses.Build(bidCtx))
+	s.notifyLosers(bidCtx)
+	return nil
+}
+
+// notifyLosers fires the lurl of the auction losers and of the bids rejected by the filter
+// stage asynchronously. The winners' nurl/burl/lurl are fired by the SSP directly or
+// forwarded by the tracking server on the win/billing/loss events
+func (s *AdxServer) notifyLosers(bidCtx *adxcore.BidRequestCtx) {
+	for _, candidate := range slices.Concat(bidCtx.GetCandidates(), bidCtx.RejectedCandidates) {
+		if candidate.Won || candidate.Notice.Loss == "" {
+			continue
+		}
+		s.notifier.Notify(&notifier.Notice{
+			Kind:       notifier.KindLoss,
+			DSPID:      candidate.BidderID,
+			URL:        candidate.Notice.Loss,
+			RequestID:  bidCtx.Request.GetId(),
+			LossReason: candidate.LossReason,
+		})
 	}
 }
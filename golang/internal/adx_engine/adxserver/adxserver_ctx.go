@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/dspbidder"
 	"github.com/echoface/admux/internal/adx_engine/sspadapter"
 	"github.com/echoface/admux/pkg/jsonx"
 	"github.com/echoface/admux/pkg/logger"
@@ -25,8 +28,8 @@ type AdxServerContext struct {
 	HTTPServer *http.Server
 	Router     *gin.Engine
 
-	// Cache and storage
-	// RedisClient *redis.Client // TODO: Add Redis client when implemented
+	// Cache and storage, nil when redis is not configured
+	RedisClient redis.UniversalClient
 
 	// Metrics
 	MetricsRegistry *prometheus.Registry
@@ -37,6 +40,9 @@ type AdxServerContext struct {
 	// SSP factory for new adapter architecture
 	SSPFactory *sspadapter.SSPAdapterFactory
 
+	// DSP index manager, owns DSP config loading and bidder registration
+	BidderIndexManager *dspbidder.BidderIndexManager
+
 	// HTTP client for external API calls
 	HTTPClient *http.Client
 
@@ -71,12 +77,17 @@ func NewAppContext(cfg *config.AdxServerConfig) *AdxServerContext {
 	if cfg == nil {
 		cfg = config.DefaultAdxConfig()
 	}
-	fmt.Println("use config:", jsonx.Pretty(cfg))
+	fmt.Println("use config:", jsonx.Pretty(cfg.Redacted()))
 	// Create context for graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
 
-	// Initialize router
+	// Initialize router, X-Forwarded-For is only honored from the trusted proxies so that
+	// SSP ip allowlists cannot be bypassed with a forged header
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Default.Error("invalid trusted_proxies, forwarded headers ignored", "error", err)
+		router.SetTrustedProxies(nil)
+	}
 
 	// Create HTTP client with reasonable timeouts
 	httpClient := &http.Client{
@@ -109,9 +120,48 @@ func NewAppContext(cfg *config.AdxServerConfig) *AdxServerContext {
 		IsHealthy:       true,
 	}
 
+	if cfg.Redis.Addr != "" {
+		redisClient, err := newRedisClient(cfg.Redis)
+		if err != nil {
+			appCtx.Logger.Error("create redis client failed", "addr", cfg.Redis.Addr, "error", err)
+		} else {
+			appCtx.RedisClient = redisClient
+		}
+	}
+
 	return appCtx
 }
 
+// newRedisClient creates a redis client, addr can be host:port or a redis:// URL
+func newRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
+	opts := &redis.Options{Addr: cfg.Addr}
+	if strings.HasPrefix(cfg.Addr, "redis://") || strings.HasPrefix(cfg.Addr, "rediss://") {
+		parsed, err := redis.ParseURL(cfg.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis url: %w", err)
+		}
+		opts = parsed
+	}
+
+	if cfg.Password != "" {
+		opts.Password = cfg.Password
+	}
+	if cfg.DB != 0 {
+		opts.DB = cfg.DB
+	}
+	opts.PoolSize = cfg.PoolSize
+	opts.MinIdleConns = cfg.MinIdleConns
+	opts.DialTimeout = cfg.DialTimeout
+	opts.ReadTimeout = cfg.ReadTimeout
+	opts.WriteTimeout = cfg.WriteTimeout
+	opts.ConnMaxIdleTime = cfg.IdleTimeout
+	opts.MaxRetries = cfg.MaxRetries
+	// per-request deadlines are set by callers, e.g. the user profile provider
+	opts.ContextTimeoutEnabled = true
+
+	return redis.NewClient(opts), nil
+}
+
 // RegisterSSPAdapter registers a new SSP adapter
 func (ac *AdxServerContext) RegisterSSPAdapter(adapter SSPAdapter) {
 	ac.mu.Lock()
@@ -158,6 +208,12 @@ func (ac *AdxServerContext) Shutdown() {
 		ac.HTTPClient.CloseIdleConnections()
 	}
 
+	if ac.RedisClient != nil {
+		if err := ac.RedisClient.Close(); err != nil {
+			ac.Logger.Error("close redis client failed", "error", err)
+		}
+	}
+
 	ac.Logger.Info("Graceful shutdown completed")
 }
 
@@ -166,6 +222,15 @@ func (ac *AdxServerContext) GetMetricsRegistry() *prometheus.Registry {
 	return ac.MetricsRegistry
 }
 
+// registerer returns the metrics registry as a Registerer, nil when there is no registry
+// so that a typed nil *prometheus.Registry is never passed as a non-nil Registerer
+func (ac *AdxServerContext) registerer() prometheus.Registerer {
+	if ac.MetricsRegistry == nil {
+		return nil
+	}
+	return ac.MetricsRegistry
+}
+
 // GetHTTPClient returns the HTTP client for external API calls
 func (ac *AdxServerContext) GetHTTPClient() *http.Client {
 	return ac.HTTPClient
@@ -191,3 +256,19 @@ func (ac *AdxServerContext) SetSSPFactory(factory *sspadapter.SSPAdapterFactory)
 	defer ac.mu.Unlock()
 	ac.SSPFactory = factory
 }
+
+// GetBidderIndexManager returns the DSP index manager
+// 获取DSP索引管理器
+func (ac *AdxServerContext) GetBidderIndexManager() *dspbidder.BidderIndexManager {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	return ac.BidderIndexManager
+}
+
+// SetBidderIndexManager sets the DSP index manager
+// 设置DSP索引管理器
+func (ac *AdxServerContext) SetBidderIndexManager(mgr *dspbidder.BidderIndexManager) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.BidderIndexManager = mgr
+}
@@ -0,0 +1,72 @@
+package adxserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	pkgconfig "github.com/echoface/admux/pkg/config"
+	"github.com/echoface/admux/pkg/logger"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// priceBidder 返回固定出价的bidder
+type priceBidder struct {
+	id    string
+	price int64
+}
+
+func (b *priceBidder) GetInfo() *adxcore.BidderInfo {
+	return &adxcore.BidderInfo{ID: b.id}
+}
+
+func (b *priceBidder) SendBidRequest(*adxcore.BidRequestCtx) ([]*adxcore.BidCandidate, error) {
+	return []*adxcore.BidCandidate{{CPMPrice: b.price}}, nil
+}
+
+func TestAdxServer_ProcessBid(t *testing.T) {
+	appCtx := &AdxServerContext{
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{MaxConnections: 10},
+		},
+		Logger: logger.Default,
+	}
+	server := NewAdxServer(appCtx)
+
+	factory := adxcore.GetGlobalBidderFactory()
+	for _, bidder := range []*priceBidder{
+		{id: "process-bid-low", price: 100},
+		{id: "process-bid-high", price: 300},
+		{id: "process-bid-zero", price: 0},
+	} {
+		_, err := factory.ReplaceBidder(bidder)
+		require.NoError(t, err)
+		defer factory.UnregisterBidder(bidder.id)
+	}
+
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id:     proto.String("process-bid"),
+		Device: &admux_rtb.BidRequest_Device{Os: proto.String("Android")},
+	})
+	bidCtx.SetSSPInfo("test", &config.SSPConfig{ID: "test", Timeout: time.Second})
+
+	require.NoError(t, server.ProcessBid(bidCtx))
+
+	// 特征补全结果可被后续阶段读取
+	os, ok := adxcore.GetFeature(bidCtx.Features, adxcore.FeatureUserOS)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"android"}, os)
+
+	// 无效出价被过滤，剩余候选按出价从高到低排序
+	candidates := bidCtx.GetCandidates()
+	require.Len(t, candidates, 2)
+	assert.Equal(t, int64(300), candidates[0].CPMPrice)
+	assert.Equal(t, int64(100), candidates[1].CPMPrice)
+	assert.Equal(t, []string{"complete_features", "targeting", "broadcast", "filter", "ranking", "build_response"}, bidCtx.ProcessingStages)
+}
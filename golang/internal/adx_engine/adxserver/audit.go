@@ -0,0 +1,107 @@
+package adxserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/echoface/admux/pkg/logger"
+)
+
+// Audit results
+const (
+	AuditOK     = "ok"
+	AuditFailed = "failed"
+)
+
+// AuditEntry is one change made through the admin API
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	ClientIP string    `json:"client_ip"`
+	Action   string    `json:"action"`
+	Target   string    `json:"target,omitempty"`
+	Params   any       `json:"params,omitempty"`
+	Result   string    `json:"result"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// AuditLog appends admin changes to a JSON Lines file and the application log,
+// the file is optional. A nil AuditLog drops the entries.
+type AuditLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	logger logger.Logger
+	now    func() time.Time
+}
+
+// NewAuditLog opens the audit log file for appending, entries only go to log when path is empty
+func NewAuditLog(path string, log logger.Logger) (*AuditLog, error) {
+	audit := &AuditLog{logger: log, now: time.Now}
+	if path == "" {
+		return audit, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create audit log dir: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	audit.file = file
+	return audit, nil
+}
+
+// Record writes the outcome of an admin change made by the authenticated caller of c
+func (a *AuditLog) Record(c *gin.Context, action, target string, params any, err error) {
+	if a == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:     a.now(),
+		Actor:    c.GetString(adminActorKey),
+		ClientIP: c.ClientIP(),
+		Action:   action,
+		Target:   target,
+		Params:   params,
+		Result:   AuditOK,
+	}
+	if err != nil {
+		entry.Result = AuditFailed
+		entry.Error = err.Error()
+	}
+	a.logger.Info("admin audit", "actor", entry.Actor, "client_ip", entry.ClientIP,
+		"action", action, "target", target, "result", entry.Result, "error", entry.Error)
+
+	if a.file == nil {
+		return
+	}
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		a.logger.Error("marshal audit entry failed", "action", action, "error", marshalErr)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, writeErr := a.file.Write(append(line, '\n')); writeErr != nil {
+		a.logger.Error("write audit log failed", "action", action, "error", writeErr)
+	}
+}
+
+// Close closes the audit log file
+func (a *AuditLog) Close() error {
+	if a == nil || a.file == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
@@ -4,20 +4,30 @@ import (
 	"context"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/echoface/admux/internal/adx_engine/adxcore"
 	"github.com/echoface/admux/internal/adx_engine/health"
 	"github.com/echoface/admux/internal/adx_engine/metrics"
 	"github.com/echoface/admux/pkg/concurrent"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
 	"github.com/echoface/admux/pkg/retry"
+	"github.com/echoface/admux/pkg/tracing"
 )
 
 // BidResponse 竞价响应结构
 type BidResponse struct {
 	BidderID   string
+	Request    *admux_rtb.BidRequest // 下发给该DSP的请求
 	Candidates []*adxcore.BidCandidate
 
 	Error   error
 	Latency time.Duration
+
+	// RawStatus/RawResponse DSP原始响应，仅调试请求记录
+	RawStatus   int
+	RawResponse []byte
 }
 
 // BroadcastManager 广播管理器
@@ -26,6 +36,7 @@ type BroadcastManager struct {
 	healthChecker  *health.HealthChecker
 	circuitBreaker *health.CircuitBreaker
 	metrics        *metrics.BroadcastMetrics
+	tracer         trace.Tracer
 }
 
 // NewBroadcastManager 创建广播管理器
@@ -34,7 +45,8 @@ func NewBroadcastManager(appCtx *AdxServerContext) *BroadcastManager {
 		appCtx:         appCtx,
 		healthChecker:  health.NewHealthChecker(30*time.Second, 5, 3),
 		circuitBreaker: health.NewCircuitBreaker(),
-		metrics:        metrics.NewBroadcastMetrics("adx", "broadcast"),
+		metrics:        metrics.NewBroadcastMetrics("adx", "broadcast", appCtx.registerer()),
+		tracer:         (*tracing.Provider)(nil).Tracer(tracerName),
 	}
 }
 
@@ -45,6 +57,9 @@ func (bm *BroadcastManager) BroadcastToBidders(bidRequest *adxcore.BidRequestCtx
 
 	// 过滤健康的bidder
 	healthyBidders := bm.filterHealthyBidders(bidders)
+	if bidRequest.Debug && len(healthyBidders) < len(bidders) {
+		recordUnhealthy(bidRequest, bidders, healthyBidders)
+	}
 	if len(healthyBidders) == 0 {
 		return nil, nil
 	}
@@ -97,6 +112,16 @@ func (bm *BroadcastManager) BroadcastWithBidders(ctx *adxcore.BidRequestCtx, bid
 		success := response.Error == nil
 		latencySeconds := response.Latency.Seconds()
 		bm.metrics.RecordResponse(success, latencySeconds)
+		ctx.BidderCalls = append(ctx.BidderCalls, &adxcore.BidderCall{
+			BidderID:   response.BidderID,
+			Request:    response.Request,
+			Candidates: response.Candidates,
+			Latency:    response.Latency,
+			Err:        response.Error,
+
+			RawStatus:   response.RawStatus,
+			RawResponse: response.RawResponse,
+		})
 
 		if success {
 			successCount++
@@ -128,6 +153,19 @@ func (bm *BroadcastManager) filterHealthyBidders(bidders []adxcore.Bidder) []adx
 	return healthyBidders
 }
 
+// recordUnhealthy 记录被健康检查或熔断跳过的bidder
+func recordUnhealthy(bidRequest *adxcore.BidRequestCtx, bidders, healthy []adxcore.Bidder) {
+	called := make(map[string]struct{}, len(healthy))
+	for _, bidder := range healthy {
+		called[bidder.GetInfo().ID] = struct{}{}
+	}
+	for _, bidder := range bidders {
+		if _, ok := called[bidder.GetInfo().ID]; !ok {
+			bidRequest.RecordTargeting(bidder.GetInfo().ID, false, adxcore.TargetingUnhealthy)
+		}
+	}
+}
+
 // executeBidderRequest 执行单个bidder请求
 func (bm *BroadcastManager) executeBidderRequest(
 	ctx context.Context,
@@ -140,11 +178,27 @@ func (bm *BroadcastManager) executeBidderRequest(
 
 	startTime := time.Now()
 
-	// 创建带超时的上下文
+	// 创建带超时的上下文，由任务上下文派生，SSP超时或取消时DSP调用随之结束
 	bidderTimeout := bm.getBidderTimeout(bidder)
 	bidderCtx, cancel := context.WithTimeout(ctx, bidderTimeout)
 	defer cancel()
 
+	// 每次DSP调用一个span，bidder 通过请求的上下文透传给DSP
+	bidderCtx, span := bm.tracer.Start(bidderCtx, "dsp.bid",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrDSPID.String(response.BidderID)))
+	defer span.End()
+
+	// 调试请求记录DSP原始响应
+	var capture *adxcore.ResponseCapture
+	if bidRequest.Debug {
+		bidderCtx, capture = adxcore.WithResponseCapture(bidderCtx)
+	}
+
+	// 只下发bidder允许接收的设备ID
+	bidRequest = bidRequest.ForBidder(bidder.GetInfo())
+	response.Request = bidRequest.Request
+
 	// 使用重试机制执行请求
 	retryConfig := &retry.RetryConfig{
 		MaxRetries:        2,
@@ -154,15 +208,23 @@ func (bm *BroadcastManager) executeBidderRequest(
 	}
 
 	candidates, err := retry.Retry(bidderCtx, func(ctx context.Context) ([]*adxcore.BidCandidate, error) {
-		return bidder.SendBidRequest(bidRequest)
+		return bidder.SendBidRequest(bidRequest.WithContext(ctx))
 	}, retryConfig)
 
 	response.Latency = time.Since(startTime)
+	response.RawStatus, response.RawResponse = capture.Get()
+	span.SetAttributes(attribute.Int("dsp.candidates", len(candidates)))
+	recordError(span, err)
 
 	if err != nil {
 		response.Error = err
 		bm.circuitBreaker.RecordFailure()
 	} else {
+		for _, candidate := range candidates {
+			if candidate != nil && candidate.BidderID == "" {
+				candidate.BidderID = response.BidderID
+			}
+		}
 		response.Candidates = candidates
 		bm.circuitBreaker.RecordSuccess()
 	}
@@ -8,7 +8,9 @@ import (
 	"github.com/echoface/admux/internal/adx_engine/adxcore"
 	"github.com/echoface/admux/internal/adx_engine/config"
 	"github.com/echoface/admux/internal/adx_engine/dspbidder"
+	pkgconfig "github.com/echoface/admux/pkg/config"
 	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"google.golang.org/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,8 +21,10 @@ func TestBroadcastIntegration(t *testing.T) {
 
 	// 创建模拟服务器上下文
 	appCtx := &AdxServerContext{
-		Config: &config.ServerConfig{
-			MaxConnections: 10,
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{
+				MaxConnections: 10,
+			},
 		},
 	}
 
@@ -36,7 +40,7 @@ func TestBroadcastIntegration(t *testing.T) {
 
 	// 创建竞价请求上下文
 	bidRequest := adxcore.NewBidRequestCtx(ctx, &admux_rtb.BidRequest{
-		Id: "integration-test",
+		Id: proto.String("integration-test"),
 	})
 
 	// 执行广播
@@ -77,8 +81,10 @@ func TestBroadcastConcurrent(t *testing.T) {
 
 	// 创建模拟服务器上下文
 	appCtx := &AdxServerContext{
-		Config: &config.ServerConfig{
-			MaxConnections: 5, // 限制并发数
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{
+				MaxConnections: 5, // 限制并发数
+			},
 		},
 	}
 
@@ -99,7 +105,7 @@ func TestBroadcastConcurrent(t *testing.T) {
 
 	// 创建竞价请求上下文
 	bidRequest := adxcore.NewBidRequestCtx(ctx, &admux_rtb.BidRequest{
-		Id: "concurrent-test",
+		Id: proto.String("concurrent-test"),
 	})
 
 	// 执行广播
@@ -125,8 +131,10 @@ func TestBroadcastHealthCheck(t *testing.T) {
 
 	// 创建模拟服务器上下文
 	appCtx := &AdxServerContext{
-		Config: &config.ServerConfig{
-			MaxConnections: 10,
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{
+				MaxConnections: 10,
+			},
 		},
 	}
 
@@ -140,7 +148,7 @@ func TestBroadcastHealthCheck(t *testing.T) {
 
 	// 创建竞价请求上下文
 	bidRequest := adxcore.NewBidRequestCtx(ctx, &admux_rtb.BidRequest{
-		Id: "health-check-test",
+		Id: proto.String("health-check-test"),
 	})
 
 	// 多次执行广播来测试健康检查
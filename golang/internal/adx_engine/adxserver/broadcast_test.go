@@ -7,7 +7,10 @@ import (
 
 	"github.com/echoface/admux/internal/adx_engine/adxcore"
 	"github.com/echoface/admux/internal/adx_engine/config"
+	pkgconfig "github.com/echoface/admux/pkg/config"
 	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"github.com/echoface/admux/pkg/retry"
+	"google.golang.org/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,7 +53,7 @@ func (m *MockBidder) SendBidRequest(bidRequest *adxcore.BidRequestCtx) ([]*adxco
 	// 返回模拟竞价候选
 	candidate := &adxcore.BidCandidate{
 		Response: &admux_rtb.BidResponse{
-			Id: "mock-bid",
+			Id: proto.String("mock-bid"),
 		},
 		CPMPrice: 500,
 	}
@@ -64,8 +67,10 @@ func TestBroadcastManager_BroadcastToBidders(t *testing.T) {
 
 	// 创建模拟服务器上下文
 	appCtx := &AdxServerContext{
-		Config: &config.ServerConfig{
-			MaxConnections: 10,
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{
+				MaxConnections: 10,
+			},
 		},
 	}
 
@@ -80,7 +85,7 @@ func TestBroadcastManager_BroadcastToBidders(t *testing.T) {
 
 	// 创建竞价请求上下文
 	bidRequest := adxcore.NewBidRequestCtx(ctx, &admux_rtb.BidRequest{
-		Id: "test-request",
+		Id: proto.String("test-request"),
 	})
 
 	// 执行广播
@@ -114,8 +119,10 @@ func TestBroadcastManager_BroadcastToBidders(t *testing.T) {
 func TestBroadcastManager_FilterHealthyBidders(t *testing.T) {
 	// 创建测试上下文
 	appCtx := &AdxServerContext{
-		Config: &config.ServerConfig{
-			MaxConnections: 10,
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{
+				MaxConnections: 10,
+			},
 		},
 	}
 
@@ -151,8 +158,10 @@ func TestBroadcastManager_TimeoutHandling(t *testing.T) {
 
 	// 创建模拟服务器上下文
 	appCtx := &AdxServerContext{
-		Config: &config.ServerConfig{
-			MaxConnections: 10,
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{
+				MaxConnections: 10,
+			},
 		},
 	}
 
@@ -166,7 +175,7 @@ func TestBroadcastManager_TimeoutHandling(t *testing.T) {
 
 	// 创建竞价请求上下文
 	bidRequest := adxcore.NewBidRequestCtx(ctx, &admux_rtb.BidRequest{
-		Id: "test-timeout",
+		Id: proto.String("test-timeout"),
 	})
 
 	// 执行广播（应该超时）
@@ -0,0 +1,257 @@
+package adxserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/pkg/netx"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+const (
+	// HeaderDebug requests the auction trace, honored for trusted callers only
+	HeaderDebug = "X-Adx-Debug"
+	// HeaderDebugSignature authenticates callers outside the ip allowlist, see SignDebug
+	HeaderDebugSignature = "X-Adx-Debug-Signature"
+
+	defaultDebugMaxAge = 5 * time.Minute
+)
+
+// DebugAuth decides whether a caller may receive the auction trace: the caller ip is
+// in the allowlist or the request carries a fresh signature for the SSP
+type DebugAuth struct {
+	nets   netx.IPAllowlist
+	secret []byte
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// NewDebugAuth creates the debug authorizer, nil when debug mode is disabled
+func NewDebugAuth(cfg config.DebugConfig) (*DebugAuth, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	auth := &DebugAuth{secret: []byte(cfg.Secret), maxAge: cfg.MaxAge, now: time.Now}
+	if auth.maxAge <= 0 {
+		auth.maxAge = defaultDebugMaxAge
+	}
+	nets, err := netx.ParseIPAllowlist(cfg.AllowIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid debug allow_ips: %w", err)
+	}
+	auth.nets = nets
+	return auth, nil
+}
+
+// Allowed reports whether the request asks for debug and the caller is trusted,
+// untrusted debug requests are served as normal requests
+func (a *DebugAuth) Allowed(header http.Header, clientIP, sspID string) bool {
+	if a == nil || !isDebugFlag(header.Get(HeaderDebug)) {
+		return false
+	}
+
+	if a.nets.Contains(clientIP) {
+		return true
+	}
+	return a.verify(header.Get(HeaderDebugSignature), sspID)
+}
+
+// verify checks a "{unix seconds}.{signature}" header value
+func (a *DebugAuth) verify(value, sspID string) bool {
+	if len(a.secret) == 0 || value == "" {
+		return false
+	}
+	tsValue, _, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	ts, err := strconv.ParseInt(tsValue, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := a.now().Sub(time.Unix(ts, 0))
+	if age > a.maxAge || age < -a.maxAge {
+		return false
+	}
+	return hmac.Equal([]byte(value), []byte(SignDebug(a.secret, sspID, time.Unix(ts, 0))))
+}
+
+// SignDebug returns the X-Adx-Debug-Signature value authorizing debug requests of an SSP
+func SignDebug(secret []byte, sspID string, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "." + sspID))
+	return ts + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isDebugFlag(value string) bool {
+	enabled, err := strconv.ParseBool(value)
+	return err == nil && enabled
+}
+
+// DebugTrace is the auction trace returned to trusted callers
+type DebugTrace struct {
+	RequestID string  `json:"request_id"`
+	SSPID     string  `json:"ssp_id"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+
+	// Request is the normalized internal request
+	Request json.RawMessage `json:"request,omitempty"`
+	Stages  []string        `json:"stages,omitempty"`
+	// ProcessingErrors are the non-fatal errors, e.g. failed feature providers
+	ProcessingErrors []string `json:"processing_errors,omitempty"`
+
+	Targeting  []adxcore.TargetingDecision `json:"targeting"`
+	DSPs       []DebugDSPCall              `json:"dsps"`
+	Rejections []DebugBid                  `json:"rejections"`
+	Auctions   []DebugAuction              `json:"auctions"`
+}
+
+// DebugDSPCall is a single DSP call with its raw response
+type DebugDSPCall struct {
+	DSPID     string  `json:"dsp_id"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	// Status and Response are the raw http response, Response is a JSON value for
+	// JSON bodies and a base64 string otherwise
+	Status   int             `json:"status,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Bids     int             `json:"bids"`
+}
+
+// DebugBid is a bid in the auction
+type DebugBid struct {
+	DSPID      string `json:"dsp_id"`
+	BidID      string `json:"bid_id,omitempty"`
+	ImpID      string `json:"imp_id,omitempty"`
+	CPMPrice   int64  `json:"cpm_price"`
+	Won        bool   `json:"won,omitempty"`
+	LossReason int    `json:"loss_reason,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// DebugAuction is the ranking of one imp, ClearingPrice is the price charged to the
+// winner (first price), in cents
+type DebugAuction struct {
+	ImpID         string     `json:"imp_id"`
+	Ranking       []DebugBid `json:"ranking"`
+	ClearingPrice int64      `json:"clearing_price"`
+	// ClearingPriceCPM is the clearing price as a CPM value, e.g. "12.5"
+	ClearingPriceCPM string `json:"clearing_price_cpm,omitempty"`
+}
+
+// NewDebugTrace collects the auction trace of a processed request
+func NewDebugTrace(bidCtx *adxcore.BidRequestCtx, err error) *DebugTrace {
+	trace := &DebugTrace{
+		RequestID:  bidCtx.Request.GetId(),
+		SSPID:      bidCtx.SSPID,
+		LatencyMS:  float64(time.Since(bidCtx.BidStartTime).Microseconds()) / 1000,
+		Request:    debugProto(bidCtx.Request),
+		Stages:     bidCtx.ProcessingStages,
+		Targeting:  bidCtx.Targeting,
+		DSPs:       []DebugDSPCall{},
+		Rejections: []DebugBid{},
+		Auctions:   []DebugAuction{},
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	for _, processErr := range bidCtx.ProcessingErrors {
+		trace.ProcessingErrors = append(trace.ProcessingErrors, processErr.Error())
+	}
+
+	for _, call := range bidCtx.BidderCalls {
+		dsp := DebugDSPCall{
+			DSPID:     call.BidderID,
+			LatencyMS: float64(call.Latency.Microseconds()) / 1000,
+			Status:    call.RawStatus,
+			Response:  debugBody(call.RawResponse),
+			Bids:      len(call.Candidates),
+		}
+		if call.Err != nil {
+			dsp.Error = call.Err.Error()
+		}
+		trace.DSPs = append(trace.DSPs, dsp)
+	}
+
+	for _, candidate := range bidCtx.RejectedCandidates {
+		bid := debugBid(candidate)
+		bid.Reason = candidate.FilterReason
+		trace.Rejections = append(trace.Rejections, bid)
+	}
+
+	// candidates are in ranking order, group them by imp keeping the order
+	auctions := make(map[string]int)
+	for _, candidate := range bidCtx.GetCandidates() {
+		impID := candidate.Bid.GetImpid()
+		idx, ok := auctions[impID]
+		if !ok {
+			idx = len(trace.Auctions)
+			auctions[impID] = idx
+			trace.Auctions = append(trace.Auctions, DebugAuction{ImpID: impID})
+		}
+		auction := &trace.Auctions[idx]
+		auction.Ranking = append(auction.Ranking, debugBid(candidate))
+		if candidate.Won {
+			auction.ClearingPrice = candidate.CPMPrice
+			auction.ClearingPriceCPM = tracking.FormatPrice(candidate.CPMPrice)
+		}
+	}
+	return trace
+}
+
+func debugBid(candidate *adxcore.BidCandidate) DebugBid {
+	return DebugBid{
+		DSPID:      candidate.BidderID,
+		BidID:      candidate.Bid.GetId(),
+		ImpID:      candidate.Bid.GetImpid(),
+		CPMPrice:   candidate.CPMPrice,
+		Won:        candidate.Won,
+		LossReason: int(candidate.LossReason),
+	}
+}
+
+// debugEnvelope wraps the SSP response with the auction trace
+func debugEnvelope(sspResponse []byte, trace *DebugTrace) ([]byte, error) {
+	return json.Marshal(struct {
+		Response json.RawMessage `json:"response"`
+		Debug    *DebugTrace     `json:"debug"`
+	}{Response: debugBody(sspResponse), Debug: trace})
+}
+
+// debugBody keeps JSON bodies as they are and encodes others (e.g. protobuf) as a base64 string
+func debugBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	encoded, _ := json.Marshal(body)
+	return encoded
+}
+
+func debugProto(msg proto.Message) json.RawMessage {
+	if msg == nil || !msg.ProtoReflect().IsValid() {
+		return nil
+	}
+	data, err := protojson.MarshalOptions{AllowPartial: true}.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,165 @@
+package adxserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/dspbidder"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+	pkgconfig "github.com/echoface/admux/pkg/config"
+	"github.com/echoface/admux/pkg/logger"
+)
+
+func TestDebugAuth(t *testing.T) {
+	auth, err := NewDebugAuth(config.DebugConfig{
+		Enabled:  true,
+		AllowIPs: []string{"10.0.0.0/8", "192.0.2.7", "::1"},
+		Secret:   "secret",
+	})
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	auth.now = func() time.Time { return now }
+
+	debugHeader := func(signature string) http.Header {
+		header := http.Header{}
+		header.Set(HeaderDebug, "1")
+		if signature != "" {
+			header.Set(HeaderDebugSignature, signature)
+		}
+		return header
+	}
+
+	assert.True(t, auth.Allowed(debugHeader(""), "10.1.2.3", "ssp"))
+	assert.True(t, auth.Allowed(debugHeader(""), "192.0.2.7", "ssp"))
+	assert.True(t, auth.Allowed(debugHeader(""), "::1", "ssp"))
+	assert.False(t, auth.Allowed(debugHeader(""), "192.0.2.8", "ssp"))
+	// 白名单内但未请求调试
+	assert.False(t, auth.Allowed(http.Header{}, "10.1.2.3", "ssp"))
+
+	secret := []byte("secret")
+	assert.True(t, auth.Allowed(debugHeader(SignDebug(secret, "ssp", now.Add(-time.Minute))), "192.0.2.8", "ssp"))
+	assert.False(t, auth.Allowed(debugHeader(SignDebug(secret, "ssp", now.Add(-time.Hour))), "192.0.2.8", "ssp"))
+	assert.False(t, auth.Allowed(debugHeader(SignDebug(secret, "other", now)), "192.0.2.8", "ssp"))
+	assert.False(t, auth.Allowed(debugHeader(SignDebug([]byte("wrong"), "ssp", now)), "192.0.2.8", "ssp"))
+	assert.False(t, auth.Allowed(debugHeader("garbage"), "192.0.2.8", "ssp"))
+
+	disabled, err := NewDebugAuth(config.DebugConfig{AllowIPs: []string{"10.0.0.0/8"}})
+	require.NoError(t, err)
+	assert.False(t, disabled.Allowed(debugHeader(""), "10.1.2.3", "ssp"))
+
+	_, err = NewDebugAuth(config.DebugConfig{Enabled: true, AllowIPs: []string{"not-an-ip"}})
+	assert.Error(t, err)
+}
+
+func TestBidHandler_Debug(t *testing.T) {
+	dsp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", dspbidder.ContentTypeJSON)
+		switch r.URL.Path {
+		case "/high":
+			w.Write([]byte(`{"id":"r","seatbid":[{"bid":[{"id":"h1","impid":"1","price":3,"adm":"<a>"},{"id":"h0","impid":"1","price":0,"adm":"<a>"}]}]}`))
+		case "/low":
+			w.Write([]byte(`{"id":"r","seatbid":[{"bid":[{"id":"l1","impid":"1","price":2,"adm":"<b>"}]}]}`))
+		}
+	}))
+	defer dsp.Close()
+
+	factory := adxcore.GetGlobalBidderFactory()
+	for _, id := range []string{"high", "low"} {
+		_, err := factory.ReplaceBidder(dspbidder.NewHTTPBidder("debug-"+id, dsp.URL+"/"+id, 0, time.Second, dsp.Client()))
+		require.NoError(t, err)
+		defer factory.UnregisterBidder("debug-" + id)
+	}
+
+	sspConfigs := []config.SSPConfig{{ID: "debug_ssp", Protocol: "kuaishou", Enabled: true, Timeout: time.Second}}
+	appCtx := &AdxServerContext{
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{MaxConnections: 10},
+			SSPs:       sspConfigs,
+			Debug:      config.DebugConfig{Enabled: true, AllowIPs: []string{"10.0.0.0/8"}, Secret: "secret"},
+		},
+		Logger: logger.Default,
+	}
+	appCtx.SetSSPFactory(sspadapter.NewSSPAdapterFactory(sspConfigs))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/bid/kuaishou", NewBidHandler(NewAdxServer(appCtx), appCtx).HandleKuaishouBid)
+
+	send := func(remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/bid/kuaishou?sspid=debug_ssp",
+			strings.NewReader(`{"request_id":"debug-req","imp":[{"imp_id":"1"}]}`))
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w
+	}
+	debugHeader := http.Header{HeaderDebug: []string{"true"}}
+
+	// 不可信调用方的调试标记被忽略，返回正常的SSP响应
+	w := send("192.0.2.1:1234", debugHeader)
+	assert.Empty(t, w.Header().Get(HeaderDebug))
+	var sspResp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sspResp))
+	assert.Equal(t, "debug-req", sspResp["request_id"])
+	assert.NotContains(t, sspResp, "debug")
+
+	signed := http.Header{
+		HeaderDebug:          []string{"1"},
+		HeaderDebugSignature: []string{SignDebug([]byte("secret"), "debug_ssp", time.Now())},
+	}
+	for _, c := range []struct {
+		remoteAddr string
+		header     http.Header
+	}{{"10.0.0.1:1234", debugHeader}, {"192.0.2.1:1234", signed}} {
+		w = send(c.remoteAddr, c.header)
+		assert.Equal(t, "1", w.Header().Get(HeaderDebug))
+
+		var envelope struct {
+			Response map[string]any `json:"response"`
+			Debug    DebugTrace     `json:"debug"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+		assert.Equal(t, "debug-req", envelope.Response["request_id"])
+
+		trace := envelope.Debug
+		assert.Equal(t, "debug-req", trace.RequestID)
+		assert.JSONEq(t, `{"id":"debug-req"}`, string(trace.Request))
+		assert.Contains(t, trace.Targeting, adxcore.TargetingDecision{BidderID: "debug-high", Selected: true, Reason: adxcore.TargetingNoIndex})
+
+		dsps := make(map[string]DebugDSPCall)
+		for _, call := range trace.DSPs {
+			dsps[call.DSPID] = call
+		}
+		require.Contains(t, dsps, "debug-high")
+		assert.Equal(t, http.StatusOK, dsps["debug-high"].Status)
+		assert.Contains(t, string(dsps["debug-high"].Response), `"id":"h0"`)
+		assert.Equal(t, 2, dsps["debug-high"].Bids)
+
+		require.Len(t, trace.Rejections, 1)
+		assert.Equal(t, DebugBid{DSPID: "debug-high", BidID: "h0", ImpID: "1", Reason: "invalid_price", LossReason: trace.Rejections[0].LossReason}, trace.Rejections[0])
+
+		require.Len(t, trace.Auctions, 1)
+		auction := trace.Auctions[0]
+		assert.Equal(t, int64(300), auction.ClearingPrice)
+		assert.Equal(t, "3", auction.ClearingPriceCPM)
+		require.Len(t, auction.Ranking, 2)
+		assert.Equal(t, "h1", auction.Ranking[0].BidID)
+		assert.True(t, auction.Ranking[0].Won)
+		assert.Equal(t, "l1", auction.Ranking[1].BidID)
+		assert.False(t, auction.Ranking[1].Won)
+	}
+}
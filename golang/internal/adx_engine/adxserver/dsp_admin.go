@@ -0,0 +1,185 @@
+package adxserver
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bytedance/gg/gmap"
+	"github.com/gin-gonic/gin"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/dspbidder"
+)
+
+// DSPAdminHandler serves operational endpoints for DSP configs
+type DSPAdminHandler struct {
+	appCtx *AdxServerContext
+	adx    *AdxServer
+	audit  *AuditLog
+}
+
+// NewDSPAdminHandler creates a new DSP admin handler, changes are recorded to audit
+func NewDSPAdminHandler(appCtx *AdxServerContext, adx *AdxServer, audit *AuditLog) *DSPAdminHandler {
+	return &DSPAdminHandler{appCtx: appCtx, adx: adx, audit: audit}
+}
+
+// dspStatusView is the live status of a DSP shown by the admin API
+type dspStatusView struct {
+	ID         string               `json:"id"`
+	Name       string               `json:"name"`
+	Status     string               `json:"status"`
+	Registered bool                 `json:"registered"`
+	Paused     bool                 `json:"paused"`
+	Health     dspHealthView        `json:"health"`
+	QPS        dspQPSView           `json:"qps"`
+	Budget     *dspbidder.DSPBudget `json:"budget,omitempty"`
+	Override   *dspbidder.DSPStatus `json:"status_override,omitempty"`
+	Endpoint   string               `json:"endpoint"`
+	Timeout    string               `json:"timeout"`
+}
+
+type dspHealthView struct {
+	Healthy      bool      `json:"healthy"`
+	FailureCount int       `json:"failure_count"`
+	SuccessCount int       `json:"success_count"`
+	LastCheck    time.Time `json:"last_check"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+type dspQPSView struct {
+	Limit   int `json:"limit"`
+	Current int `json:"current"`
+}
+
+// HandleListDSPs lists the indexed DSPs with their health, QPS usage and budget,
+// the broadcast circuit breaker is shared by all DSPs
+func (h *DSPAdminHandler) HandleListDSPs(c *gin.Context) {
+	mgr := h.appCtx.GetBidderIndexManager()
+	if mgr == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "DSP index manager not initialized"})
+		return
+	}
+
+	registered := adxcore.GetGlobalBidderFactory().GetAllBidders()
+	dsps := mgr.GetAllDSPs()
+	views := make([]dspStatusView, 0, len(dsps))
+	for _, id := range gmap.OrderedKeys(dsps) {
+		info := dsps[id]
+		status := h.adx.BidderHealth(id)
+		view := dspStatusView{
+			ID:         id,
+			Name:       info.DSPName,
+			Status:     info.Status,
+			Registered: registered[id] != nil,
+			Paused:     mgr.IsDSPPaused(id),
+			Health: dspHealthView{
+				Healthy:      status.Healthy,
+				FailureCount: status.FailureCount,
+				SuccessCount: status.SuccessCount,
+				LastCheck:    status.LastCheck,
+			},
+			QPS:      dspQPSView{Limit: info.QPSLimit},
+			Endpoint: info.Endpoint,
+			Timeout:  info.Timeout.String(),
+		}
+		if status.LastError != nil {
+			view.Health.LastError = status.LastError.Error()
+		}
+		view.QPS.Current, _ = mgr.GetDSPQPS(id)
+		if budget, ok := mgr.GetDSPBudget(id); ok {
+			view.Budget = budget
+		} else if info.BudgetDaily > 0 {
+			view.Budget = &dspbidder.DSPBudget{DSPID: id, DailyBudget: info.BudgetDaily, Remaining: info.BudgetDaily}
+		}
+		if override, ok := mgr.GetDSPStatus(id); ok {
+			view.Override = override
+		}
+		views = append(views, view)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":   len(views),
+		"breaker": h.adx.BreakerState().String(),
+		"dsps":    views,
+	})
+}
+
+// HandleQuarantinedDSPs lists DSP config files rejected by validation in the latest scan
+func (h *DSPAdminHandler) HandleQuarantinedDSPs(c *gin.Context) {
+	mgr := h.appCtx.GetBidderIndexManager()
+	if mgr == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "DSP index manager not initialized"})
+		return
+	}
+
+	quarantined := mgr.GetQuarantinedDSPs()
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(quarantined),
+		"files": quarantined,
+	})
+}
+
+// HandleIndexStats dumps the DSP index stats and the index manager counters
+func (h *DSPAdminHandler) HandleIndexStats(c *gin.Context) {
+	mgr := h.appCtx.GetBidderIndexManager()
+	if mgr == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "DSP index manager not initialized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"index":   mgr.GetIndexStats(),
+		"manager": mgr.GetMetrics(),
+	})
+}
+
+// HandleRescanDSPs reads the DSP configs from the source now and rebuilds the index
+func (h *DSPAdminHandler) HandleRescanDSPs(c *gin.Context) {
+	mgr := h.appCtx.GetBidderIndexManager()
+	if mgr == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "DSP index manager not initialized"})
+		return
+	}
+
+	result, err := mgr.Rescan()
+	h.audit.Record(c, "rescan_dsp", "", nil, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"dsp_count":         len(result.DSPs),
+		"quarantined_count": len(result.Quarantined),
+	})
+}
+
+// HandlePauseDSP stops calling the DSP until it is resumed
+func (h *DSPAdminHandler) HandlePauseDSP(c *gin.Context) {
+	h.setPaused(c, "pause_dsp", (*dspbidder.BidderIndexManager).PauseDSP)
+}
+
+// HandleResumeDSP resumes calling a paused DSP
+func (h *DSPAdminHandler) HandleResumeDSP(c *gin.Context) {
+	h.setPaused(c, "resume_dsp", (*dspbidder.BidderIndexManager).ResumeDSP)
+}
+
+func (h *DSPAdminHandler) setPaused(c *gin.Context, action string, apply func(*dspbidder.BidderIndexManager, string) error) {
+	mgr := h.appCtx.GetBidderIndexManager()
+	if mgr == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "DSP index manager not initialized"})
+		return
+	}
+
+	dspID := c.Param("id")
+	err := apply(mgr, dspID)
+	h.audit.Record(c, action, dspID, nil, err)
+	switch {
+	case errors.Is(err, dspbidder.ErrDSPNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"id": dspID, "paused": mgr.IsDSPPaused(dspID)})
+	}
+}
@@ -1,12 +1,12 @@
 package adxserver
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,7 +26,7 @@ func (h *BidHandler) HandleAdMuxBid(c *gin.Context) {
 	// Extract request context with SSP ID
 	info, err := extractRequestContext(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, newErrResponse(err, "invalid query params"))
+		h.rejectUnknownSSP(c, err)
 		return
 	}
 
@@ -37,65 +37,121 @@ func (h *BidHandler) HandleKuaishouBid(c *gin.Context) {
 	// Extract request context with SSP ID
 	info, err := extractRequestContext(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, newErrResponse(err, "invalid query params"))
+		h.rejectUnknownSSP(c, err)
 		return
 	}
 
 	h.handleBidRequest(c, info)
 }
 
+// handleBidRequest runs the auction for an SSP request, every outcome is encoded by the
+// SSP's adapter so the SSP never gets a body in a format it cannot parse
 func (h *BidHandler) handleBidRequest(c *gin.Context, info *reqInfo) {
 	// Get SSP adapter and configuration based on SSP ID
 	sspAdapter, sspConfig, err := h.adxServer.GetSSPAdapter(info.SSPID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, newErrResponse(err, "Invalid SSP configuration"))
+		h.adxServer.metrics.ObserveAuthRejection("", sspadapter.AuthUnknownSSP)
+		h.rejectUnknownSSP(c, err)
 		return
 	}
 
 	bodyData, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Failed to read request body",
-			"details": err.Error(),
-		})
+		writeReply(c, sspAdapter.PackInvalidRequest(nil, err))
 		return
 	}
 	defer c.Request.Body.Close()
 
-	// Create bid request context
-	bidCtx := adxcore.NewBidRequestCtx(context.Background(), nil)
+	// The SSP ID comes from the caller, the SSP's auth schemes prove it
+	if err = h.adxServer.Authenticate(sspConfig.ID, &sspadapter.AuthRequest{
+		Header:   c.Request.Header,
+		ClientIP: info.ClientIP,
+		Body:     bodyData,
+	}); err != nil {
+		writeReply(c, sspAdapter.PackInvalidRequest(bodyData, err))
+		return
+	}
+
+	// Paused SSPs get a no-bid without touching the pipeline
+	if h.adxServer.SSPPaused(sspConfig.ID) {
+		writeReply(c, sspAdapter.PackNoBid(bodyData))
+		return
+	}
+
+	// Over the SSP's QPS limit or overloaded: a fast no-bid instead of competing for the DSP fan-out
+	release, admitted := h.adxServer.Admit(sspConfig)
+	if !admitted {
+		writeReply(c, sspAdapter.PackNoBid(bodyData))
+		return
+	}
+	defer release()
+
+	// Create bid request context under the root span of the request, sampled per SSP
+	traceCtx, span := h.adxServer.StartTrace(sspConfig.ID)
+	bidCtx := adxcore.NewBidRequestCtx(traceCtx, nil)
 	bidCtx.SetSSPInfo(sspConfig.ID, sspConfig)
+	bidCtx.Debug = h.adxServer.AllowDebug(c.Request.Header, info.ClientIP, sspConfig.ID)
+	defer func() { h.adxServer.EndTrace(span, bidCtx, err) }()
 
 	// Convert SSP-specific request to internal format using adapter
-	if err := sspAdapter.ToInternalBidRequest(bidCtx, bodyData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Failed to parse bid request",
-			"details": err.Error(),
-		})
+	if err = sspAdapter.ToInternalBidRequest(bidCtx, bodyData); err != nil {
+		writeReply(c, sspAdapter.PackInvalidRequest(bodyData, err))
 		return
 	}
 
-	// Process the bid request through the pipeline
+	// Process the bid request through the pipeline, failures are answered as the SSP's
+	// internal error no-bid rather than an HTTP 500
 	if err = h.adxServer.ProcessBid(bidCtx); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to process bid request",
-			"details": err.Error(),
-		})
+		h.adxServer.RecordBid(bidCtx, bodyData, nil, err)
+		writeAuctionReply(c, bidCtx, sspAdapter.PackInternalError(bodyData), err)
 		return
 	}
 
 	// Convert internal response to SSP-specific format
 	sspResponse, err := sspAdapter.PackSSPResponse(bidCtx)
+	h.adxServer.RecordBid(bidCtx, bodyData, sspResponse, err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to format response",
-			"details": err.Error(),
-		})
+		writeAuctionReply(c, bidCtx, sspAdapter.PackInternalError(bodyData), err)
 		return
 	}
 
 	// Return SSP-specific response
-	c.Data(http.StatusOK, "application/json", sspResponse)
+	writeAuctionReply(c, bidCtx, adxcore.SSPReply{
+		StatusCode:  http.StatusOK,
+		ContentType: sspAdapter.ContentType(),
+		Body:        sspResponse,
+	}, nil)
+}
+
+// rejectUnknownSSP answers requests without a known SSP, there is no protocol to encode
+// a body in so only the status is sent
+func (h *BidHandler) rejectUnknownSSP(c *gin.Context, err error) {
+	h.appCtx.Logger.Debug("reject bid request of unknown ssp", "error", err, "client_ip", c.ClientIP())
+	c.Status(http.StatusBadRequest)
+}
+
+// writeAuctionReply sends the reply of an auctioned request, trusted debug requests get
+// it wrapped with the auction trace
+func writeAuctionReply(c *gin.Context, bidCtx *adxcore.BidRequestCtx, reply adxcore.SSPReply, err error) {
+	if bidCtx.Debug {
+		if envelope, debugErr := debugEnvelope(reply.Body, NewDebugTrace(bidCtx, err)); debugErr == nil {
+			c.Header(HeaderDebug, "1")
+			if reply.StatusCode == http.StatusNoContent {
+				reply.StatusCode = http.StatusOK
+			}
+			reply.ContentType, reply.Body = "application/json", envelope
+		}
+	}
+	writeReply(c, reply)
+}
+
+// writeReply sends a reply encoded by the SSP adapter
+func writeReply(c *gin.Context, reply adxcore.SSPReply) {
+	if reply.Body == nil {
+		c.Status(reply.StatusCode)
+		return
+	}
+	c.Data(reply.StatusCode, reply.ContentType, reply.Body)
 }
 
 type reqInfo struct {
@@ -122,10 +178,3 @@ func extractRequestContext(c *gin.Context) (*reqInfo, error) {
 	info.ClientIP = c.ClientIP()
 	return info, nil
 }
-
-func newErrResponse(err error, msg string) gin.H {
-	return gin.H{
-		"error":   msg,
-		"details": err.Error(),
-	}
-}
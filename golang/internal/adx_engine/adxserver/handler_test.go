@@ -0,0 +1,71 @@
+package adxserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+	"github.com/echoface/admux/pkg/logger"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+func TestBidHandler_ProtocolReplies(t *testing.T) {
+	appCtx := &AdxServerContext{
+		Config: &config.AdxServerConfig{SSPs: []config.SSPConfig{
+			{ID: "kuaishou", Protocol: sspadapter.ProtocolKuaishou, Timeout: time.Second, Enabled: true},
+			{ID: "xiaomi", Protocol: sspadapter.ProtocolOpenRTB, Timeout: time.Second, Enabled: true},
+		}},
+		MetricsRegistry: prometheus.NewRegistry(),
+		Logger:          logger.Default,
+	}
+	_, err := NewSSPReloader(appCtx, "", nil)
+	require.NoError(t, err)
+	adx := NewAdxServer(appCtx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := NewBidHandler(adx, appCtx)
+	r.POST("/bid/kuaishou", handler.HandleKuaishouBid)
+	r.POST("/bid/admux", handler.HandleAdMuxBid)
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	// 无法解析的请求按各自协议编码
+	w := send("/bid/kuaishou?sspid=kuaishou", `{"request_id":"r1","imp":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"request_id":"r1","status":1,"noBidReason":2}`, w.Body.String())
+
+	w = send("/bid/admux?sspid=xiaomi", "not protobuf")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	var resp admux_rtb.BidResponse
+	require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, admux_rtb.NoBidReason_INVALID_REQUEST, resp.GetNbr())
+
+	// 正常响应使用适配器的编码
+	w = send("/bid/kuaishou?sspid=kuaishou", `{"request_id":"r1","imp":[{"imp_id":"1"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	// 未知SSP无法确定协议，只返回状态码
+	for _, path := range []string{"/bid/kuaishou?sspid=unknown", "/bid/admux"} {
+		w = send(path, "{}")
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Empty(t, w.Body.Bytes(), path)
+	}
+}
@@ -159,6 +159,7 @@ func (hh *HealthHandler) ReadinessProbe(c *gin.Context) {
 		"status":    responseStatus,
 		"timestamp": time.Now(),
 		"checks": map[string]bool{
+			"serving":       hh.checkServing(),
 			"ssps_ready":    hh.checkSSPsReady(),
 			"bidders_ready": hh.checkBiddersReady(),
 			"config_loaded": hh.checkConfigLoaded(),
@@ -265,7 +266,15 @@ func (hh *HealthHandler) checkMemory() bool {
 
 func (hh *HealthHandler) checkReadiness() bool {
 	// Check if the application is ready to serve traffic
-	return hh.checkSSPsReady() && hh.checkBiddersReady() && hh.checkConfigLoaded()
+	return hh.checkServing() && hh.checkSSPsReady() && hh.checkBiddersReady() && hh.checkConfigLoaded()
+}
+
+// checkServing fails once the application starts shutting down, see Serve
+func (hh *HealthHandler) checkServing() bool {
+	if appCtx, ok := hh.appCtx.(*AdxServerContext); ok {
+		return appCtx.IsApplicationHealthy()
+	}
+	return true
 }
 
 func (hh *HealthHandler) checkSSPsReady() bool {
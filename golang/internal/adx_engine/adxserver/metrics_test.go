@@ -0,0 +1,103 @@
+package adxserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bytedance/gg/gmap"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	pkgconfig "github.com/echoface/admux/pkg/config"
+	"github.com/echoface/admux/pkg/logger"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// impBidder 对imp "1" 出价，err 不为空时返回错误
+type impBidder struct {
+	id    string
+	price int64
+	err   error
+}
+
+func (b *impBidder) GetInfo() *adxcore.BidderInfo {
+	return &adxcore.BidderInfo{ID: b.id}
+}
+
+func (b *impBidder) SendBidRequest(*adxcore.BidRequestCtx) ([]*adxcore.BidCandidate, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	bid := &admux_rtb.BidResponse_SeatBid_Bid{Id: proto.String(b.id), Impid: proto.String("1")}
+	return []*adxcore.BidCandidate{{CPMPrice: b.price, Bid: bid}}, nil
+}
+
+func TestAdxServer_AuctionMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	server := NewAdxServer(&AdxServerContext{
+		Config: &config.AdxServerConfig{
+			BaseConfig: pkgconfig.BaseConfig{MaxConnections: 10},
+			SSPs:       []config.SSPConfig{{ID: "metrics_ssp"}},
+		},
+		MetricsRegistry: registry,
+		Logger:          logger.Default,
+	})
+
+	factory := adxcore.GetGlobalBidderFactory()
+	for _, bidder := range []adxcore.Bidder{
+		&impBidder{id: "metrics-high", price: 350},
+		&impBidder{id: "metrics-low", price: 100},
+		&impBidder{id: "metrics-zero", price: 0},
+		&impBidder{id: "metrics-timeout", err: fmt.Errorf("dsp call: %w", context.DeadlineExceeded)},
+		&impBidder{id: "metrics-error", err: errors.New("connection refused")},
+	} {
+		_, err := factory.ReplaceBidder(bidder)
+		require.NoError(t, err)
+		defer factory.UnregisterBidder(bidder.GetInfo().ID)
+	}
+
+	process := func() {
+		bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+			Id:     proto.String("metrics"),
+			Device: &admux_rtb.BidRequest_Device{Os: proto.String("iOS")},
+		})
+		bidCtx.SetSSPInfo("metrics_ssp", &config.SSPConfig{ID: "metrics_ssp", Timeout: 2 * time.Second})
+		require.NoError(t, server.ProcessBid(bidCtx))
+	}
+	process()
+
+	m := server.metrics
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Requests.WithLabelValues("metrics_ssp")))
+	for _, dsp := range []string{"metrics-high", "metrics-low", "metrics-zero", "metrics-timeout", "metrics-error"} {
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.DSPRequests.WithLabelValues("metrics_ssp", dsp)), dsp)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DSPBids.WithLabelValues("metrics_ssp", "metrics-high")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DSPWins.WithLabelValues("metrics_ssp", "metrics-high")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.DSPWins.WithLabelValues("metrics_ssp", "metrics-low")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DSPTimeouts.WithLabelValues("metrics_ssp", "metrics-timeout")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DSPErrors.WithLabelValues("metrics_ssp", "metrics-error")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.DSPErrors.WithLabelValues("metrics_ssp", "metrics-timeout")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DSPNoBids.WithLabelValues("metrics_ssp", "metrics-zero", "filtered")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.FilterRejections.WithLabelValues("metrics_ssp", "metrics-zero", "invalid_price")))
+	assert.Equal(t, 0, testutil.CollectAndCount(m.NoBids))
+
+	// 指标注册在服务的 MetricsRegistry 上
+	count, err := testutil.GatherAndCount(registry, "adx_auction_bid_price_cpm", "adx_auction_dsp_latency_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 3+5, count)
+
+	// 没有可调用的DSP时按 no_dsp 计入不出价
+	for _, bidder := range gmap.Keys(factory.GetAllBidders()) {
+		factory.UnregisterBidder(bidder)
+	}
+	process()
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.NoBids.WithLabelValues("metrics_ssp", "no_dsp")))
+}
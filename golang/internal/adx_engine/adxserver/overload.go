@@ -0,0 +1,153 @@
+package adxserver
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+)
+
+// Shed reasons, used as metrics labels
+const (
+	ShedQPSLimit = "qps_limit" // over the SSP's qps_limit
+	ShedOverload = "overload"  // over the global in-flight limit
+)
+
+// latencyEWMAShift weights every latency sample by 1/8 in the moving average
+const latencyEWMAShift = 3
+
+// LoadShedder admits bid requests within the per-SSP QPS limits and the global in-flight
+// limit. While the recent latency exceeds the target the in-flight limit shrinks in
+// proportion, down to the configured minimum.
+type LoadShedder struct {
+	maxInFlight   int64
+	minInFlight   int64
+	targetLatency time.Duration
+
+	inFlight atomic.Int64
+	latency  atomic.Int64 // EWMA of the request latency in nanoseconds
+
+	mu      sync.RWMutex
+	buckets map[string]*tokenBucket // SSP ID -> bucket, rebuilt when the qps_limit changes
+
+	now func() time.Time
+}
+
+// NewLoadShedder creates a load shedder, a zero config only enforces the per-SSP QPS limits
+func NewLoadShedder(cfg config.OverloadConfig) *LoadShedder {
+	minInFlight := int64(cfg.MinInFlight)
+	if minInFlight <= 0 {
+		minInFlight = max(int64(cfg.MaxInFlight)/10, 1)
+	}
+	return &LoadShedder{
+		maxInFlight:   int64(cfg.MaxInFlight),
+		minInFlight:   minInFlight,
+		targetLatency: cfg.TargetLatency,
+		buckets:       make(map[string]*tokenBucket),
+		now:           time.Now,
+	}
+}
+
+// Admit reserves a slot for a request of the SSP, release must be called once the request
+// is answered. A non-empty reason means the request is shed and holds no slot. The overload
+// check comes first so that requests shed for overload don't consume the SSP's QPS quota.
+func (l *LoadShedder) Admit(ssp *config.SSPConfig) (release func(), reason string) {
+	start := l.now()
+	if inFlight := l.inFlight.Add(1); l.maxInFlight > 0 && inFlight > l.Limit() {
+		l.inFlight.Add(-1)
+		return nil, ShedOverload
+	}
+
+	if ssp.QPSLimit > 0 && !l.bucket(ssp).take(start) {
+		l.inFlight.Add(-1)
+		return nil, ShedQPSLimit
+	}
+	return func() {
+		l.inFlight.Add(-1)
+		l.observe(l.now().Sub(start))
+	}, ""
+}
+
+// Limit returns the in-flight limit in effect, 0 when unlimited
+func (l *LoadShedder) Limit() int64 {
+	if l.maxInFlight <= 0 {
+		return 0
+	}
+	latency := l.latency.Load()
+	if l.targetLatency <= 0 || latency <= int64(l.targetLatency) {
+		return l.maxInFlight
+	}
+	return max(l.maxInFlight*int64(l.targetLatency)/latency, l.minInFlight)
+}
+
+// InFlight returns the number of admitted requests not yet answered
+func (l *LoadShedder) InFlight() int64 {
+	return l.inFlight.Load()
+}
+
+// Latency returns the moving average of the latency of recent requests
+func (l *LoadShedder) Latency() time.Duration {
+	return time.Duration(l.latency.Load())
+}
+
+// observe folds a request latency into the moving average
+func (l *LoadShedder) observe(latency time.Duration) {
+	for {
+		prev := l.latency.Load()
+		next := int64(latency)
+		if prev > 0 {
+			next = prev + (next-prev)>>latencyEWMAShift
+		}
+		if l.latency.CompareAndSwap(prev, next) {
+			return
+		}
+	}
+}
+
+// bucket returns the token bucket of the SSP, a reloaded qps_limit starts a new bucket
+func (l *LoadShedder) bucket(ssp *config.SSPConfig) *tokenBucket {
+	l.mu.RLock()
+	bucket := l.buckets[ssp.ID]
+	l.mu.RUnlock()
+	if bucket != nil && bucket.rate == ssp.QPSLimit {
+		return bucket
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bucket = l.buckets[ssp.ID]; bucket == nil || bucket.rate != ssp.QPSLimit {
+		bucket = newTokenBucket(ssp.QPSLimit, l.now())
+		l.buckets[ssp.ID] = bucket
+	}
+	return bucket
+}
+
+// tokenBucket refills rate tokens per second and holds at most one second of them
+type tokenBucket struct {
+	rate int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: float64(rate), last: now}
+}
+
+// take consumes a token, false when the bucket is empty
+func (b *tokenBucket) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*float64(b.rate), float64(b.rate))
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,159 @@
+package adxserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+	"github.com/echoface/admux/pkg/logger"
+)
+
+func TestLoadShedder_QPSLimit(t *testing.T) {
+	now := time.Now()
+	shedder := NewLoadShedder(config.OverloadConfig{})
+	shedder.now = func() time.Time { return now }
+
+	admit := func(ssp *config.SSPConfig) string {
+		release, reason := shedder.Admit(ssp)
+		if release != nil {
+			release()
+		}
+		return reason
+	}
+
+	ssp := &config.SSPConfig{ID: "kuaishou", QPSLimit: 2}
+	assert.Empty(t, admit(ssp))
+	assert.Empty(t, admit(ssp))
+	assert.Equal(t, ShedQPSLimit, admit(ssp))
+	// 不限制的SSP不受影响
+	assert.Empty(t, admit(&config.SSPConfig{ID: "xiaomi"}))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.Empty(t, admit(ssp))
+	assert.Equal(t, ShedQPSLimit, admit(ssp))
+
+	// 重载修改 qps_limit 后按新限制重新计数
+	ssp = &config.SSPConfig{ID: "kuaishou", QPSLimit: 3}
+	for range 3 {
+		assert.Empty(t, admit(ssp))
+	}
+	assert.Equal(t, ShedQPSLimit, admit(ssp))
+}
+
+func TestLoadShedder_OverloadKeepsQPSQuota(t *testing.T) {
+	now := time.Now()
+	shedder := NewLoadShedder(config.OverloadConfig{MaxInFlight: 1})
+	shedder.now = func() time.Time { return now }
+	ssp := &config.SSPConfig{ID: "kuaishou", QPSLimit: 2}
+
+	hold, reason := shedder.Admit(ssp)
+	require.Empty(t, reason)
+	// 过载丢弃的请求不消耗QPS配额
+	for range 3 {
+		_, reason = shedder.Admit(ssp)
+		assert.Equal(t, ShedOverload, reason)
+	}
+	hold()
+
+	release, reason := shedder.Admit(ssp)
+	require.Empty(t, reason)
+	release()
+	_, reason = shedder.Admit(ssp)
+	assert.Equal(t, ShedQPSLimit, reason)
+	// QPS丢弃的请求不占用并发名额
+	assert.Zero(t, shedder.InFlight())
+}
+
+func TestLoadShedder_Adaptive(t *testing.T) {
+	now := time.Now()
+	shedder := NewLoadShedder(config.OverloadConfig{MaxInFlight: 4, TargetLatency: 10 * time.Millisecond})
+	shedder.now = func() time.Time { return now }
+	ssp := &config.SSPConfig{ID: "kuaishou"}
+
+	releases := make([]func(), 0, 4)
+	for range 4 {
+		release, reason := shedder.Admit(ssp)
+		require.Empty(t, reason)
+		releases = append(releases, release)
+	}
+	_, reason := shedder.Admit(ssp)
+	assert.Equal(t, ShedOverload, reason)
+	assert.EqualValues(t, 4, shedder.InFlight())
+
+	// 耗时为目标的4倍，并发上限收紧到 4*10/40=1
+	now = now.Add(40 * time.Millisecond)
+	for _, release := range releases {
+		release()
+	}
+	assert.Equal(t, 40*time.Millisecond, shedder.Latency())
+	assert.EqualValues(t, 1, shedder.Limit())
+
+	release, reason := shedder.Admit(ssp)
+	require.Empty(t, reason)
+	_, reason = shedder.Admit(ssp)
+	assert.Equal(t, ShedOverload, reason)
+	release()
+
+	// 耗时恢复后上限随之恢复
+	for range 20 {
+		release, reason := shedder.Admit(ssp)
+		require.Empty(t, reason)
+		release()
+	}
+	assert.EqualValues(t, 4, shedder.Limit())
+	assert.EqualValues(t, 0, shedder.InFlight())
+}
+
+func TestBidHandler_Shed(t *testing.T) {
+	appCtx := &AdxServerContext{
+		Config: &config.AdxServerConfig{SSPs: []config.SSPConfig{
+			{ID: "kuaishou", Protocol: sspadapter.ProtocolKuaishou, QPSLimit: 1, Timeout: time.Second, Enabled: true},
+			{ID: "xiaomi", Protocol: sspadapter.ProtocolOpenRTB, QPSLimit: 1, Timeout: time.Second, Enabled: true},
+		}},
+		MetricsRegistry: prometheus.NewRegistry(),
+		Logger:          logger.Default,
+	}
+	_, err := NewSSPReloader(appCtx, "", nil)
+	require.NoError(t, err)
+	adx := NewAdxServer(appCtx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := NewBidHandler(adx, appCtx)
+	r.POST("/bid/kuaishou", handler.HandleKuaishouBid)
+	r.POST("/bid/admux", handler.HandleAdMuxBid)
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	body := `{"request_id":"r1","imp":[{"imp_id":"1"}]}`
+	require.Equal(t, http.StatusOK, send("/bid/kuaishou?sspid=kuaishou", body).Code)
+	// 超过QPS限制：快手协议以 status=1 表示不出价
+	w := send("/bid/kuaishou?sspid=kuaishou", body)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"request_id":"r1","status":1}`, w.Body.String())
+
+	send("/bid/admux?sspid=xiaomi", "")
+	w = send("/bid/admux?sspid=xiaomi", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(adx.metrics.Shed.WithLabelValues("kuaishou", ShedQPSLimit)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(adx.metrics.Shed.WithLabelValues("xiaomi", ShedQPSLimit)))
+	// 被限流的请求不进入竞价
+	assert.Equal(t, 1.0, testutil.ToFloat64(adx.metrics.Requests.WithLabelValues("kuaishou")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(adx.metrics.InFlight))
+}
@@ -0,0 +1,250 @@
+package adxserver
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/pkg/pricecrypto"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+const defaultCurrency = "CNY"
+
+// ResponseBuilder builds the SSP facing response from the ranked candidates:
+// DSP macros are expanded and the DSP notice urls are replaced by signed ADX tracking urls,
+// the DSP notice urls are kept on the candidates for the notifier
+type ResponseBuilder struct {
+	baseURL  string
+	currency string
+	keys     *tracking.KeyRing
+
+	// priceCodec returns the price macro codec required by a DSP, nil for plain prices
+	priceCodec func(bidderID string) pricecrypto.Codec
+	now        func() time.Time
+}
+
+// NewResponseBuilder creates a response builder, tracking urls are not injected when
+// the tracking base url is empty
+func NewResponseBuilder(cfg config.TrackingConfig) (*ResponseBuilder, error) {
+	builder := &ResponseBuilder{
+		baseURL:  cfg.BaseURL,
+		currency: cfg.Currency,
+		now:      time.Now,
+
+		priceCodec: bidderPriceCodec,
+	}
+	if builder.currency == "" {
+		builder.currency = defaultCurrency
+	}
+	if cfg.BaseURL == "" {
+		return builder, nil
+	}
+	if cfg.ActiveKeyID == "" {
+		return nil, fmt.Errorf("tracking active_key_id is required")
+	}
+
+	keys := make([]tracking.Key, 0, len(cfg.SigningKeys))
+	for _, key := range cfg.SigningKeys {
+		keys = append(keys, tracking.Key{ID: key.ID, Secret: []byte(key.Secret)})
+	}
+	ring, err := tracking.NewKeyRing(cfg.ActiveKeyID, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracking signing keys: %w", err)
+	}
+	builder.keys = ring
+	return builder, nil
+}
+
+// KeyRing returns the signing keys, nil when tracking is disabled. Keys can be rotated
+// at runtime through KeyRing.Update
+func (b *ResponseBuilder) KeyRing() *tracking.KeyRing {
+	return b.keys
+}
+
+// Build picks the highest ranked candidate of each imp as the winner (first price),
+// marks the others as lost and returns the response, which carries no seatbid when
+// there is no winner
+func (b *ResponseBuilder) Build(bidCtx *adxcore.BidRequestCtx) *admux_rtb.BidResponse {
+	req := bidCtx.Request
+	resp := &admux_rtb.BidResponse{
+		Id:  proto.String(req.GetId()),
+		Cur: proto.String(b.currency),
+	}
+
+	winners := make(map[string]*adxcore.BidCandidate)
+	seats := make(map[string]*admux_rtb.BidResponse_SeatBid)
+	for _, candidate := range bidCtx.GetCandidates() {
+		if candidate.Bid == nil {
+			continue
+		}
+		impID := candidate.Bid.GetImpid()
+		if winner, ok := winners[impID]; ok {
+			b.markLost(bidCtx, candidate, winner)
+			continue
+		}
+		winners[impID] = candidate
+
+		seat, ok := seats[candidate.BidderID]
+		if !ok {
+			seat = &admux_rtb.BidResponse_SeatBid{Seat: proto.String(candidate.BidderID)}
+			seats[candidate.BidderID] = seat
+			resp.Seatbid = append(resp.Seatbid, seat)
+		}
+		seat.Bid = append(seat.Bid, b.markWon(bidCtx, candidate))
+	}
+
+	for _, candidate := range bidCtx.RejectedCandidates {
+		if candidate.Bid != nil {
+			b.setLossNotice(bidCtx, candidate, winners[candidate.Bid.GetImpid()])
+		}
+	}
+	return resp
+}
+
+// markWon expands the winner's macros and returns the bid sent to the SSP
+func (b *ResponseBuilder) markWon(bidCtx *adxcore.BidRequestCtx, candidate *adxcore.BidCandidate) *admux_rtb.BidResponse_SeatBid_Bid {
+	candidate.Won = true
+	candidate.LossReason = tracking.LossWon
+
+	macros := b.macroValues(bidCtx, candidate)
+	macros.Price = b.formatPrice(candidate.BidderID, candidate.CPMPrice)
+	if b.keys != nil {
+		// the price macro in adm is left for the SSP to fill with the clearing price, the same
+		// price the tracking urls report, instead of the DSP's own bid
+		macros.Price = tracking.MacroAuctionPrice
+	}
+
+	bid := proto.Clone(candidate.Bid).(*admux_rtb.BidResponse_SeatBid_Bid)
+	bid.Price = proto.Float64(float64(candidate.CPMPrice) / 100)
+	if adm, ok := bid.AdmOneof.(*admux_rtb.BidResponse_SeatBid_Bid_Adm); ok {
+		adm.Adm = tracking.ExpandMacros(adm.Adm, macros)
+	}
+
+	if b.keys == nil {
+		// without ADX tracking the SSP calls the DSP notice urls directly
+		candidate.Notice = adxcore.NoticeURLs{
+			Win:     tracking.ExpandMacros(bid.GetNurl(), macros),
+			Billing: tracking.ExpandMacros(bid.GetBurl(), macros),
+		}
+		setURL(&bid.Nurl, candidate.Notice.Win)
+		setURL(&bid.Burl, candidate.Notice.Billing)
+		bid.Lurl = nil
+		return bid
+	}
+
+	// the DSP is notified of the clearing price reported by the SSP, the price macro is
+	// left for the tracking server which encrypts it with the DSP's price codec
+	// the loss reason of a bid lost at the SSP is only known to the tracking server
+	lossMacros := b.macroValues(bidCtx, candidate)
+	candidate.Notice = adxcore.NoticeURLs{
+		Win:     tracking.ExpandMacros(bid.GetNurl(), macros),
+		Billing: tracking.ExpandMacros(bid.GetBurl(), macros),
+		Loss:    tracking.ExpandMacrosKeeping(bid.GetLurl(), lossMacros, tracking.MacroAuctionLoss),
+	}
+	candidate.Tracking = b.trackingURLs(bidCtx, candidate)
+	bid.Nurl = proto.String(candidate.Tracking.Win)
+	bid.Burl = proto.String(candidate.Tracking.Billing)
+	bid.Lurl = proto.String(candidate.Tracking.Loss)
+	return bid
+}
+
+// markLost records the loss reason and expands the loser's lurl
+func (b *ResponseBuilder) markLost(bidCtx *adxcore.BidRequestCtx, candidate, winner *adxcore.BidCandidate) {
+	candidate.Won = false
+	candidate.LossReason = tracking.LossLostToHigherBid
+	b.setLossNotice(bidCtx, candidate, winner)
+}
+
+// setLossNotice expands the lurl of a lost or rejected candidate with its loss reason,
+// the winning price of the imp is reported as the minimum to win when there is a winner
+func (b *ResponseBuilder) setLossNotice(bidCtx *adxcore.BidRequestCtx, candidate, winner *adxcore.BidCandidate) {
+	macros := b.macroValues(bidCtx, candidate)
+	macros.Loss = candidate.LossReason
+	if winner != nil {
+		macros.MinToWin = b.formatPrice(candidate.BidderID, winner.CPMPrice)
+	}
+	candidate.Notice = adxcore.NoticeURLs{Loss: tracking.ExpandMacros(candidate.Bid.GetLurl(), macros)}
+}
+
+// formatPrice formats a CPM price (in cents) for the DSP price macros, encrypted with
+// the DSP's price codec when configured
+func (b *ResponseBuilder) formatPrice(bidderID string, cpm int64) string {
+	codec := b.priceCodec(bidderID)
+	if codec == nil {
+		return tracking.FormatPrice(cpm)
+	}
+	price, err := codec.Encrypt(float64(cpm) / 100)
+	if err != nil {
+		// never leak the plain price to a DSP expecting an encrypted one
+		return ""
+	}
+	return url.QueryEscape(price)
+}
+
+// bidderPriceCodec looks up the price codec of a registered bidder
+func bidderPriceCodec(bidderID string) pricecrypto.Codec {
+	bidder, err := adxcore.GetGlobalBidderFactory().GetBidder(bidderID)
+	if err != nil {
+		return nil
+	}
+	return bidder.GetInfo().PriceCodec
+}
+
+func (b *ResponseBuilder) macroValues(bidCtx *adxcore.BidRequestCtx, candidate *adxcore.BidCandidate) *tracking.MacroValues {
+	seat := candidate.Seat
+	if seat == "" {
+		seat = candidate.BidderID
+	}
+	return &tracking.MacroValues{
+		AuctionID: bidCtx.Request.GetId(),
+		BidID:     candidate.Bid.GetId(),
+		ImpID:     candidate.Bid.GetImpid(),
+		SeatID:    seat,
+		AdID:      candidate.Bid.GetAdid(),
+		Currency:  b.currency,
+	}
+}
+
+// trackingURLs signs the ADX tracking urls of a winning bid, the clearing price and
+// loss reason are left as macros for the SSP. The DSP nurl/burl are carried in the
+// signed win/billing/loss urls and forwarded by the tracking server with the clearing
+// price or the loss reason reported by the SSP
+func (b *ResponseBuilder) trackingURLs(bidCtx *adxcore.BidRequestCtx, candidate *adxcore.BidCandidate) adxcore.TrackingURLs {
+	signer := b.keys.Signer()
+	event := func(eventType tracking.EventType, noticeURL string) string {
+		return signer.URL(b.baseURL, &tracking.Event{
+			Type:      eventType,
+			RequestID: bidCtx.Request.GetId(),
+			ImpID:     candidate.Bid.GetImpid(),
+			BidID:     candidate.Bid.GetId(),
+			DSPID:     candidate.BidderID,
+			SSPID:     bidCtx.SSPID,
+			BidPrice:  candidate.CPMPrice,
+			Timestamp: b.now().Unix(),
+			NoticeURL: noticeURL,
+		})
+	}
+
+	pricePart := "&" + tracking.ParamClearPrice + "=" + tracking.MacroAuctionPrice
+	return adxcore.TrackingURLs{
+		Win:     event(tracking.EventWin, candidate.Notice.Win) + pricePart,
+		Billing: event(tracking.EventBilling, candidate.Notice.Billing) + pricePart,
+		Loss:    event(tracking.EventLoss, candidate.Notice.Loss) + "&" + tracking.ParamLossReason + "=" + tracking.MacroAuctionLoss,
+		Imp:     event(tracking.EventImp, ""),
+		Click:   event(tracking.EventClick, ""),
+	}
+}
+
+func setURL(field **string, value string) {
+	if value == "" {
+		*field = nil
+		return
+	}
+	*field = proto.String(value)
+}
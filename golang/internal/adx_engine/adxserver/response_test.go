@@ -0,0 +1,258 @@
+package adxserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	trackingconfig "github.com/echoface/admux/internal/event_tracking/config"
+	"github.com/echoface/admux/internal/event_tracking/tracker"
+	"github.com/echoface/admux/pkg/notifier"
+	"github.com/echoface/admux/pkg/pricecrypto"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+func newTestCandidate(bidderID, bidID, impID string, price int64) *adxcore.BidCandidate {
+	return &adxcore.BidCandidate{
+		BidderID: bidderID,
+		CPMPrice: price,
+		Bid: &admux_rtb.BidResponse_SeatBid_Bid{
+			Id:       proto.String(bidID),
+			Impid:    proto.String(impID),
+			Price:    proto.Float64(float64(price) / 100),
+			Adid:     proto.String("ad-" + bidID),
+			Nurl:     proto.String("https://" + bidderID + ".example.com/win?p=${AUCTION_PRICE}&id=${AUCTION_ID}"),
+			Burl:     proto.String("https://" + bidderID + ".example.com/bill?p=${AUCTION_PRICE}"),
+			Lurl:     proto.String("https://" + bidderID + ".example.com/loss?r=${AUCTION_LOSS}&m=${AUCTION_MIN_TO_WIN}"),
+			AdmOneof: &admux_rtb.BidResponse_SeatBid_Bid_Adm{Adm: `<img src="https://` + bidderID + `.example.com/imp?b=${AUCTION_BID_ID}&p=${AUCTION_PRICE}">`},
+		},
+	}
+}
+
+func newTestBidCtx(candidates ...*adxcore.BidCandidate) *adxcore.BidRequestCtx {
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{Id: proto.String("req-1")})
+	bidCtx.SetSSPInfo("ssp_a", &config.SSPConfig{ID: "ssp_a"})
+	for _, candidate := range candidates {
+		bidCtx.AddCandidate(candidate)
+	}
+	return bidCtx
+}
+
+func TestResponseBuilder_Tracking(t *testing.T) {
+	builder, err := NewResponseBuilder(config.TrackingConfig{
+		BaseURL:     "https://t.admux.com",
+		ActiveKeyID: "k1",
+		SigningKeys: []config.SigningKeyConfig{{ID: "k1", Secret: "secret"}},
+	})
+	require.NoError(t, err)
+
+	// 候选已按出价排序
+	high := newTestCandidate("dsp_a", "bid-a", "imp-1", 1250)
+	low := newTestCandidate("dsp_b", "bid-b", "imp-1", 1000)
+	mock := &adxcore.BidCandidate{BidderID: "dsp_c", CPMPrice: 900}
+	bidCtx := newTestBidCtx(high, low, mock)
+
+	resp := builder.Build(bidCtx)
+	assert.Equal(t, "req-1", resp.GetId())
+	assert.Equal(t, "CNY", resp.GetCur())
+	require.Len(t, resp.GetSeatbid(), 1)
+	assert.Equal(t, "dsp_a", resp.GetSeatbid()[0].GetSeat())
+	require.Len(t, resp.GetSeatbid()[0].GetBid(), 1)
+	bid := resp.GetSeatbid()[0].GetBid()[0]
+	assert.Equal(t, 12.5, bid.GetPrice())
+	// adm 中的价格宏与监测链接一致，由SSP按成交价替换，而不是DSP自己的出价
+	assert.Equal(t, `<img src="https://dsp_a.example.com/imp?b=bid-a&p=${AUCTION_PRICE}">`, bid.GetAdm())
+
+	// 胜出者的DSP通知地址保留价格宏，由监测服务按成交价替换，下发给SSP的是签名监测地址
+	assert.True(t, high.Won)
+	assert.Equal(t, []*adxcore.BidCandidate{high}, bidCtx.Winners())
+	assert.Equal(t, "https://dsp_a.example.com/win?p=${AUCTION_PRICE}&id=req-1", high.Notice.Win)
+	assert.Equal(t, "https://dsp_a.example.com/bill?p=${AUCTION_PRICE}", high.Notice.Billing)
+	assert.Equal(t, high.Tracking.Win, bid.GetNurl())
+	assert.True(t, strings.HasSuffix(bid.GetNurl(), "&cp=${AUCTION_PRICE}"))
+
+	u, err := url.Parse(strings.Replace(bid.GetNurl(), "${AUCTION_PRICE}", "11.8", 1))
+	require.NoError(t, err)
+	assert.Equal(t, "/track/win", u.Path)
+	event, err := tracking.NewVerifier(builder.KeyRing(), time.Hour).Verify(tracking.EventWin, u.Query(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "dsp_a", event.DSPID)
+	assert.Equal(t, "ssp_a", event.SSPID)
+	assert.Equal(t, int64(1250), event.BidPrice)
+	assert.Equal(t, "11.8", event.ClearPrice)
+	assert.Equal(t, high.Notice.Win, event.NoticeURL)
+
+	// 胜出者的 lurl 随签名的 loss 监测链接下发，失败原因由SSP回传
+	assert.Equal(t, "https://dsp_a.example.com/loss?r=${AUCTION_LOSS}&m=", high.Notice.Loss)
+	u, err = url.Parse(strings.Replace(bid.GetLurl(), "${AUCTION_LOSS}", "103", 1))
+	require.NoError(t, err)
+	event, err = tracking.NewVerifier(builder.KeyRing(), time.Hour).Verify(tracking.EventLoss, u.Query(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "103", event.LossReason)
+	assert.Equal(t, high.Notice.Loss, event.NoticeURL)
+
+	// 失败者只保留 lurl
+	assert.False(t, low.Won)
+	assert.Equal(t, tracking.LossLostToHigherBid, low.LossReason)
+	assert.Equal(t, adxcore.NoticeURLs{Loss: "https://dsp_b.example.com/loss?r=102&m=12.5"}, low.Notice)
+	assert.False(t, mock.Won)
+}
+
+func TestAdxServer_NotifyLosers(t *testing.T) {
+	lurls := make(chan string, 4)
+	dsp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lurls <- r.URL.String()
+	}))
+	defer dsp.Close()
+
+	builder, err := NewResponseBuilder(config.TrackingConfig{})
+	require.NoError(t, err)
+	notices, err := notifier.New(notifier.Config{Workers: 1}, nil)
+	require.NoError(t, err)
+	server := &AdxServer{responses: builder, notifier: notices}
+
+	winner := newTestCandidate("dsp_a", "bid-a", "imp-1", 1250)
+	loser := newTestCandidate("dsp_b", "bid-b", "imp-1", 1000)
+	loser.Bid.Lurl = proto.String(dsp.URL + "/loss?r=${AUCTION_LOSS}&m=${AUCTION_MIN_TO_WIN}")
+	rejected := newTestCandidate("dsp_c", "bid-c", "imp-1", 0)
+	rejected.Bid.Lurl = proto.String(dsp.URL + "/rejected?r=${AUCTION_LOSS}&m=${AUCTION_MIN_TO_WIN}")
+	bidCtx := newTestBidCtx(winner, loser)
+	bidCtx.RejectCandidate(rejected, "invalid_price", tracking.LossInvalidResponse)
+	require.NoError(t, server.buildResponse(bidCtx))
+	require.NoError(t, server.Close(context.Background()))
+
+	// 只通知失败者和被过滤的出价
+	require.Len(t, lurls, 2)
+	assert.Equal(t, "/loss?r=102&m=12.5", <-lurls)
+	assert.Equal(t, "/rejected?r=3&m=12.5", <-lurls)
+}
+
+func TestWinNotice_ClearPriceToDSP(t *testing.T) {
+	sspPrice := pricecrypto.Config{Scheme: pricecrypto.SchemeAESECBBase64URL, EncryptionKey: "0123456789abcdef"}
+	dspPrice := pricecrypto.Config{Scheme: pricecrypto.SchemeAESECB, EncryptionKey: "fedcba9876543210"}
+	sspCodec, err := pricecrypto.New(sspPrice)
+	require.NoError(t, err)
+	dspCodec, err := pricecrypto.New(dspPrice)
+	require.NoError(t, err)
+
+	prices := make(chan string, 4)
+	dsp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prices <- r.URL.Path + " " + r.URL.Query().Get("p")
+	}))
+	defer dsp.Close()
+
+	builder, err := NewResponseBuilder(config.TrackingConfig{
+		BaseURL:     "https://t.admux.com",
+		ActiveKeyID: "k1",
+		SigningKeys: []config.SigningKeyConfig{{ID: "k1", Secret: "secret"}},
+	})
+	require.NoError(t, err)
+	builder.priceCodec = func(string) pricecrypto.Codec { return dspCodec }
+
+	eventLog, err := tracker.NewEventLog(trackingconfig.EventLogConfig{Path: filepath.Join(t.TempDir(), "events.log")})
+	require.NoError(t, err)
+	defer eventLog.Close()
+	notices, err := notifier.New(notifier.Config{Workers: 1}, nil)
+	require.NoError(t, err)
+	server, err := tracker.NewServer(&trackingconfig.TrackingConfig{
+		SigningKeys:    []trackingconfig.SigningKey{{ID: "k1", Secret: "secret"}},
+		SSPPriceCrypto: []trackingconfig.SSPPriceCrypto{{SSPID: "ssp_a", Config: sspPrice}},
+		DSPPriceCrypto: []trackingconfig.DSPPriceCrypto{{DSPID: "dsp_a", Config: dspPrice}},
+	}, eventLog, notices, "admux", "tracking", prometheus.NewRegistry())
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	server.RegisterRoutes(r)
+
+	winner := newTestCandidate("dsp_a", "bid-a", "imp-1", 1250)
+	winner.Bid.Nurl = proto.String(dsp.URL + "/win?p=${AUCTION_PRICE}")
+	winner.Bid.Burl = proto.String(dsp.URL + "/bill?p=${AUCTION_PRICE}")
+	resp := builder.Build(newTestBidCtx(winner))
+	bid := resp.GetSeatbid()[0].GetBid()[0]
+
+	// SSP按自己的方案加密成交价替换宏后回调监测服务
+	cp, err := sspCodec.Encrypt(11.8)
+	require.NoError(t, err)
+	for _, notice := range []string{bid.GetNurl(), bid.GetBurl()} {
+		target := strings.TrimPrefix(strings.ReplaceAll(notice, tracking.MacroAuctionPrice, cp), "https://t.admux.com")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusNoContent, w.Code)
+	}
+	require.NoError(t, notices.Close(context.Background()))
+
+	// DSP收到的是按DSP方案加密的成交价，而不是自己的出价
+	require.Len(t, prices, 2)
+	for range 2 {
+		path, encrypted, _ := strings.Cut(<-prices, " ")
+		price, err := dspCodec.Decrypt(encrypted)
+		require.NoError(t, err, path)
+		assert.Equal(t, 11.8, price, path)
+	}
+}
+
+func TestResponseBuilder_EncryptedPriceMacros(t *testing.T) {
+	builder, err := NewResponseBuilder(config.TrackingConfig{})
+	require.NoError(t, err)
+	codec, err := pricecrypto.New(pricecrypto.Config{Scheme: pricecrypto.SchemeAESECB, EncryptionKey: "0123456789abcdef"})
+	require.NoError(t, err)
+	builder.priceCodec = func(bidderID string) pricecrypto.Codec {
+		if bidderID == "dsp_a" {
+			return codec
+		}
+		return nil
+	}
+
+	winner := newTestCandidate("dsp_a", "bid-a", "imp-1", 1250)
+	loser := newTestCandidate("dsp_b", "bid-b", "imp-1", 1000)
+	resp := builder.Build(newTestBidCtx(winner, loser))
+	require.Len(t, resp.GetSeatbid(), 1)
+	assert.Equal(t, 12.5, resp.GetSeatbid()[0].GetBid()[0].GetPrice())
+
+	// 加密价格经过URL编码后替换宏
+	u, err := url.Parse(winner.Notice.Win)
+	require.NoError(t, err)
+	price, err := codec.Decrypt(u.Query().Get("p"))
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, price)
+
+	// 未配置加密的DSP仍下发明文
+	assert.Equal(t, "https://dsp_b.example.com/loss?r=102&m=12.5", loser.Notice.Loss)
+}
+
+func TestResponseBuilder_WithoutTracking(t *testing.T) {
+	builder, err := NewResponseBuilder(config.TrackingConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, builder.KeyRing())
+
+	winner := newTestCandidate("dsp_a", "bid-a", "imp-1", 800)
+	other := newTestCandidate("dsp_b", "bid-b", "imp-2", 500)
+	resp := builder.Build(newTestBidCtx(winner, other))
+
+	require.Len(t, resp.GetSeatbid(), 2)
+	bid := resp.GetSeatbid()[0].GetBid()[0]
+	assert.Equal(t, "https://dsp_a.example.com/win?p=8&id=req-1", bid.GetNurl())
+	assert.Equal(t, `<img src="https://dsp_a.example.com/imp?b=bid-a&p=8">`, bid.GetAdm())
+	assert.Empty(t, bid.GetLurl())
+	assert.True(t, other.Won)
+
+	empty := builder.Build(newTestBidCtx())
+	assert.Empty(t, empty.GetSeatbid())
+
+	_, err = NewResponseBuilder(config.TrackingConfig{BaseURL: "https://t.admux.com"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package adxserver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
+)
+
+// ErrSamplingDisabled is returned when adjusting the sample rates of a disabled component
+var ErrSamplingDisabled = errors.New("sampling disabled")
+
+// SampleRates are the runtime sample rates, a component is nil when it is disabled,
+// or when it is left untouched by an update
+type SampleRates struct {
+	BidLog  *bidlog.SampleRates `json:"bid_log"`
+	Tracing *TraceSampleRates   `json:"tracing"`
+}
+
+// TraceSampleRates are the root span sample rates, SSP overrides the default per SSP
+type TraceSampleRates struct {
+	Default float64            `json:"sample_rate"`
+	SSP     map[string]float64 `json:"ssp_sample_rates"`
+}
+
+// SampleRates returns the current sample rates of the bid log and tracing
+func (s *AdxServer) SampleRates() SampleRates {
+	var rates SampleRates
+	if sampler := s.bidLog.Sampler(); sampler != nil {
+		current := sampler.Rates()
+		rates.BidLog = &current
+	}
+	if s.sampler != nil {
+		defaultRate, sspRates := s.sampler.Rates()
+		rates.Tracing = &TraceSampleRates{Default: defaultRate, SSP: sspRates}
+	}
+	return rates
+}
+
+// SetSampleRates replaces the sample rates of the non-nil components, nothing is changed
+// when any rate is out of [0,1] or a component is disabled
+func (s *AdxServer) SetSampleRates(rates SampleRates) error {
+	if rates.BidLog != nil {
+		if s.bidLog.Sampler() == nil {
+			return fmt.Errorf("bid log: %w", ErrSamplingDisabled)
+		}
+		if err := validateRates("bid_log", rates.BidLog.Default, rates.BidLog.SSP, rates.BidLog.DSP); err != nil {
+			return err
+		}
+	}
+	if rates.Tracing != nil {
+		if s.sampler == nil {
+			return fmt.Errorf("tracing: %w", ErrSamplingDisabled)
+		}
+		if err := validateRates("tracing", rates.Tracing.Default, rates.Tracing.SSP); err != nil {
+			return err
+		}
+	}
+
+	if rates.BidLog != nil {
+		s.bidLog.Sampler().SetRates(*rates.BidLog)
+	}
+	if rates.Tracing != nil {
+		s.sampler.SetRates(rates.Tracing.Default, rates.Tracing.SSP)
+	}
+	return nil
+}
+
+func validateRates(component string, defaultRate float64, overrides ...map[string]float64) error {
+	if defaultRate < 0 || defaultRate > 1 {
+		return fmt.Errorf("%s: sample_rate %v out of [0,1]", component, defaultRate)
+	}
+	for _, rates := range overrides {
+		for id, rate := range rates {
+			if rate < 0 || rate > 1 {
+				return fmt.Errorf("%s: sample rate %v of %s out of [0,1]", component, rate, id)
+			}
+		}
+	}
+	return nil
+}
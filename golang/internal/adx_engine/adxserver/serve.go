@@ -0,0 +1,97 @@
+package adxserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout bounds draining and flushing when shutdown_timeout is not configured
+const defaultShutdownTimeout = 30 * time.Second
+
+// Serve serves HTTP on ln until ctx is done or SIGINT/SIGTERM is received, then shuts down gracefully:
+//  1. readiness fails, new connections are still accepted for DrainDelay so that load
+//     balancers stop routing to the instance without refused connections
+//  2. the listener is closed and the in-flight bids are drained within ShutdownTimeout
+//  3. the pending DSP notices, bid log records and spans are flushed within ShutdownTimeout
+//  4. the DSP index manager and the application context are stopped
+//
+// The returned error is nil only when the server stopped on request and every step completed.
+func Serve(ctx context.Context, appCtx *AdxServerContext, adx *AdxServer, ln net.Listener) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- appCtx.HTTPServer.Serve(ln)
+	}()
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		// the server failed on its own, still release everything below
+		errs = append(errs, fmt.Errorf("serve: %w", err))
+	case <-ctx.Done():
+		appCtx.Logger.Info("shutdown requested, failing readiness", "cause", context.Cause(ctx))
+		appCtx.SetHealthStatus(false)
+		if delay := appCtx.Config.DrainDelay; delay > 0 {
+			time.Sleep(delay)
+		}
+		if err := drain(appCtx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, closeComponents(appCtx, adx)...)
+	if err := errors.Join(errs...); err != nil {
+		appCtx.Logger.Error("shutdown completed with errors", "error", err)
+		return err
+	}
+	appCtx.Logger.Info("shutdown completed")
+	return nil
+}
+
+// drain stops accepting connections and waits for the in-flight requests, the remaining
+// connections are closed when ShutdownTimeout expires
+func drain(appCtx *AdxServerContext) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout(appCtx))
+	defer cancel()
+
+	err := appCtx.HTTPServer.Shutdown(ctx)
+	if err == nil {
+		return nil
+	}
+	appCtx.HTTPServer.Close()
+	return fmt.Errorf("drain in-flight requests: %w", err)
+}
+
+// closeComponents flushes the ADX server queues and stops the background components
+func closeComponents(appCtx *AdxServerContext, adx *AdxServer) []error {
+	var errs []error
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout(appCtx))
+	defer cancel()
+	if err := adx.Close(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("flush adx server: %w", err))
+	}
+
+	if indexMgr := appCtx.GetBidderIndexManager(); indexMgr != nil {
+		if err := indexMgr.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("stop dsp index manager: %w", err))
+		}
+	}
+
+	appCtx.Shutdown()
+	return errs
+}
+
+func shutdownTimeout(appCtx *AdxServerContext) time.Duration {
+	if appCtx.Config.ShutdownTimeout > 0 {
+		return appCtx.Config.ShutdownTimeout
+	}
+	return defaultShutdownTimeout
+}
@@ -0,0 +1,141 @@
+package adxserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+)
+
+// startServer serves appCtx.Router in the background, /slow blocks until release is closed
+func startServer(t *testing.T, cfg *config.AdxServerConfig) (appCtx *AdxServerContext, addr string, started <-chan struct{}, release chan struct{}, done <-chan error) {
+	gin.SetMode(gin.TestMode)
+	appCtx = NewAppContext(cfg)
+	adx := NewAdxServer(appCtx)
+
+	slowStarted := make(chan struct{}, 1)
+	release = make(chan struct{})
+	appCtx.Router.GET("/slow", func(c *gin.Context) {
+		slowStarted <- struct{}{}
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+	health := NewHealthHandler(appCtx, prometheus.NewRegistry())
+	appCtx.Router.GET("/health/ready", health.ReadinessProbe)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- Serve(context.Background(), appCtx, adx, ln)
+	}()
+	return appCtx, "http://" + ln.Addr().String(), slowStarted, release, result
+}
+
+func shutdownConfig(drainDelay, timeout time.Duration) *config.AdxServerConfig {
+	cfg := config.DefaultAdxConfig()
+	cfg.DrainDelay = drainDelay
+	cfg.ShutdownTimeout = timeout
+	cfg.WriteTimeout = 10 * time.Second
+	return cfg
+}
+
+func TestServe_SIGTERMDrainsInFlightBids(t *testing.T) {
+	appCtx, addr, started, release, done := startServer(t, shutdownConfig(300*time.Millisecond, 5*time.Second))
+
+	ready, err := http.Get(addr + "/health/ready")
+	require.NoError(t, err)
+	ready.Body.Close()
+	assert.Equal(t, http.StatusOK, ready.StatusCode)
+
+	// 在途请求
+	slow := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(addr + "/slow")
+		assert.NoError(t, err)
+		slow <- resp
+	}()
+	<-started
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	// 就绪检查先失败，drain_delay 内仍接受新连接
+	require.Eventually(t, func() bool { return !appCtx.IsApplicationHealthy() }, time.Second, 5*time.Millisecond)
+	notReady, err := http.Get(addr + "/health/ready")
+	require.NoError(t, err)
+	notReady.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, notReady.StatusCode)
+
+	// 在途请求完成前不退出
+	time.Sleep(400 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("server stopped before the in-flight request completed: %v", err)
+	default:
+	}
+
+	close(release)
+	resp := <-slow
+	require.NotNil(t, resp)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+	assert.Error(t, appCtx.ShutdownCtx.Err())
+
+	// 停机后不再接受连接
+	_, err = http.Get(addr + "/health/ready")
+	assert.Error(t, err)
+}
+
+func TestServe_DrainTimeout(t *testing.T) {
+	_, addr, started, release, done := startServer(t, shutdownConfig(0, 100*time.Millisecond))
+	defer close(release)
+
+	go func() {
+		if resp, err := http.Get(addr + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "drain in-flight requests")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestServe_ContextCanceled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appCtx := NewAppContext(shutdownConfig(0, time.Second))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, appCtx, NewAdxServer(appCtx), ln)
+	}()
+	cancel()
+	assert.NoError(t, <-done)
+	assert.False(t, appCtx.IsApplicationHealthy())
+}
@@ -0,0 +1,116 @@
+package adxserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+)
+
+// SSPAdminHandler serves operational endpoints for SSP configs
+type SSPAdminHandler struct {
+	appCtx   *AdxServerContext
+	reloader *SSPReloader
+	audit    *AuditLog
+}
+
+// NewSSPAdminHandler creates a new SSP admin handler, changes are recorded to audit
+func NewSSPAdminHandler(appCtx *AdxServerContext, reloader *SSPReloader, audit *AuditLog) *SSPAdminHandler {
+	return &SSPAdminHandler{appCtx: appCtx, reloader: reloader, audit: audit}
+}
+
+// sspConfigView is the SSP config shown by the admin API, price crypto keys are left out
+type sspConfigView struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Endpoint    string `json:"endpoint"`
+	Protocol    string `json:"protocol"`
+	QPSLimit    int    `json:"qps_limit"`
+	Timeout     string `json:"timeout"`
+	PriceScheme string `json:"price_scheme,omitempty"`
+	Paused      bool   `json:"paused"`
+	// Authenticated is false for SSPs accepting requests from anyone claiming their ID
+	Authenticated bool `json:"authenticated"`
+}
+
+// HandleListSSPs lists the enabled SSPs currently serving
+func (h *SSPAdminHandler) HandleListSSPs(c *gin.Context) {
+	factory := h.appCtx.GetSSPFactory()
+	if factory == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SSP factory not initialized"})
+		return
+	}
+
+	configs := factory.Configs()
+	views := make([]sspConfigView, 0, len(configs))
+	for _, cfg := range configs {
+		views = append(views, sspConfigView{
+			ID:            cfg.ID,
+			Name:          cfg.Name,
+			Endpoint:      cfg.Endpoint,
+			Protocol:      cfg.Protocol,
+			QPSLimit:      cfg.QPSLimit,
+			Timeout:       cfg.Timeout.String(),
+			PriceScheme:   cfg.PriceCrypto.Scheme,
+			Paused:        factory.IsPaused(cfg.ID),
+			Authenticated: factory.AuthConfigured(cfg.ID),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(views),
+		"ssps":  views,
+	})
+}
+
+// HandleReloadSSPs reloads the SSPs from the config file, invalid configs are reported
+// field by field and leave the serving SSPs untouched
+func (h *SSPAdminHandler) HandleReloadSSPs(c *gin.Context) {
+	if h.reloader == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SSP reloader not initialized"})
+		return
+	}
+
+	result, err := h.reloader.Reload(SSPReloadAdmin)
+	h.audit.Record(c, "reload_ssp", "", result, err)
+	var configErrs sspadapter.ConfigErrors
+	switch {
+	case errors.As(err, &configErrs):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid ssp configs", "errors": configErrs})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// HandlePauseSSP answers the SSP's requests with a no-bid until it is resumed
+func (h *SSPAdminHandler) HandlePauseSSP(c *gin.Context) {
+	h.setPaused(c, "pause_ssp", (*sspadapter.SSPAdapterFactory).Pause)
+}
+
+// HandleResumeSSP resumes bidding for a paused SSP
+func (h *SSPAdminHandler) HandleResumeSSP(c *gin.Context) {
+	h.setPaused(c, "resume_ssp", (*sspadapter.SSPAdapterFactory).Resume)
+}
+
+func (h *SSPAdminHandler) setPaused(c *gin.Context, action string, apply func(*sspadapter.SSPAdapterFactory, string) error) {
+	factory := h.appCtx.GetSSPFactory()
+	if factory == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SSP factory not initialized"})
+		return
+	}
+
+	sspID := c.Param("id")
+	err := apply(factory, sspID)
+	h.audit.Record(c, action, sspID, nil, err)
+	switch {
+	case errors.Is(err, sspadapter.ErrSSPNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"id": sspID, "paused": factory.IsPaused(sspID)})
+	}
+}
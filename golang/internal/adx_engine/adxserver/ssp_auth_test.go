@@ -0,0 +1,65 @@
+package adxserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+	"github.com/echoface/admux/pkg/logger"
+)
+
+func TestBidHandler_SSPAuth(t *testing.T) {
+	secret := []byte("ssp-secret")
+	appCtx := &AdxServerContext{
+		Config: &config.AdxServerConfig{SSPs: []config.SSPConfig{{
+			ID: "kuaishou", Protocol: sspadapter.ProtocolKuaishou, Timeout: time.Second, Enabled: true,
+			Auth: config.SSPAuthConfig{AllowIPs: []string{"10.0.0.0/8"}, Token: "ssp-token", HMACSecret: string(secret)},
+		}}},
+		MetricsRegistry: prometheus.NewRegistry(),
+		Logger:          logger.Default,
+	}
+	_, err := NewSSPReloader(appCtx, "", nil)
+	require.NoError(t, err)
+	adx := NewAdxServer(appCtx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/bid/kuaishou", NewBidHandler(adx, appCtx).HandleKuaishouBid)
+
+	body := `{"request_id":"r1","imp":[{"imp_id":"1"}]}`
+	send := func(sspID, remoteAddr, token, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/bid/kuaishou?sspid="+sspID, strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(sspadapter.HeaderSSPSignature, signature)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	signature := sspadapter.SignBody(secret, []byte(body), time.Now())
+
+	assert.Equal(t, http.StatusOK, send("kuaishou", "10.0.0.1:1234", "ssp-token", signature))
+	assert.Equal(t, http.StatusForbidden, send("kuaishou", "192.0.2.1:1234", "ssp-token", signature))
+	assert.Equal(t, http.StatusUnauthorized, send("kuaishou", "10.0.0.1:1234", "forged", signature))
+	assert.Equal(t, http.StatusUnauthorized, send("kuaishou", "10.0.0.1:1234", "ssp-token", sspadapter.SignBody([]byte("forged"), []byte(body), time.Now())))
+	assert.Equal(t, http.StatusBadRequest, send("unknown", "10.0.0.1:1234", "ssp-token", signature))
+
+	rejections := adx.metrics.AuthRejections
+	assert.Equal(t, 1.0, testutil.ToFloat64(rejections.WithLabelValues("kuaishou", sspadapter.AuthIPNotAllowed)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(rejections.WithLabelValues("kuaishou", sspadapter.AuthInvalidToken)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(rejections.WithLabelValues("kuaishou", sspadapter.AuthInvalidSignature)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(rejections.WithLabelValues(metrics.LabelUnknown, sspadapter.AuthUnknownSSP)))
+	// 被拒绝的请求不进入竞价
+	assert.Equal(t, 1.0, testutil.ToFloat64(adx.metrics.Requests.WithLabelValues("kuaishou")))
+}
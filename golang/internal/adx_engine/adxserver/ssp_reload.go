@@ -0,0 +1,186 @@
+package adxserver
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+)
+
+// sspReloadDebounce merges the burst of events written by editors and config map updates
+const sspReloadDebounce = 200 * time.Millisecond
+
+// SSP config reload triggers
+const (
+	SSPReloadFile  = "file"
+	SSPReloadAdmin = "admin"
+)
+
+// SSPConfigLoader reads the current SSP list from the config source, e.g. the config file
+type SSPConfigLoader func() ([]config.SSPConfig, error)
+
+// SSPReloader owns the SSP adapter factory of the application context: the SSP list is
+// validated at startup, and reloaded when the config file changes or on demand through
+// the admin API. Invalid lists are rejected as a whole, the serving adapters are kept.
+type SSPReloader struct {
+	appCtx  *AdxServerContext
+	factory *sspadapter.SSPAdapterFactory
+	load    SSPConfigLoader
+	path    string
+	metrics *metrics.SSPConfigMetrics
+
+	// mu serializes reloads from the file watcher and the admin API
+	mu        sync.Mutex
+	listeners []func([]config.SSPConfig)
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewSSPReloader validates the configured SSPs and installs the SSP adapter factory on appCtx.
+// path is the config file watched by Watch, load reads the SSP list on every reload.
+func NewSSPReloader(appCtx *AdxServerContext, path string, load SSPConfigLoader) (*SSPReloader, error) {
+	var initial []config.SSPConfig
+	if appCtx.Config != nil {
+		initial = appCtx.Config.SSPs
+	}
+
+	factory := sspadapter.NewSSPAdapterFactory(nil)
+	if _, err := factory.Reload(initial); err != nil {
+		return nil, err
+	}
+
+	r := &SSPReloader{
+		appCtx:  appCtx,
+		factory: factory,
+		load:    load,
+		path:    path,
+		metrics: metrics.NewSSPConfigMetrics("adx", "ssp_config", appCtx.registerer()),
+		done:    make(chan struct{}),
+	}
+	r.metrics.LoadedSSPs.Set(float64(len(factory.Configs())))
+	r.warnUnauthenticated()
+	appCtx.SetSSPFactory(factory)
+	return r, nil
+}
+
+// warnUnauthenticated logs the SSPs serving without auth, anyone can send requests as them
+func (r *SSPReloader) warnUnauthenticated() {
+	var ids []string
+	for _, cfg := range r.factory.Configs() {
+		if !r.factory.AuthConfigured(cfg.ID) {
+			ids = append(ids, cfg.ID)
+		}
+	}
+	if len(ids) > 0 {
+		r.appCtx.Logger.Warn("ssps without auth accept requests from any caller", "ssps", ids)
+	}
+}
+
+// Reload reads the SSP list and swaps the adapters, trigger is reported in logs and metrics
+func (r *SSPReloader) Reload(trigger string) (sspadapter.ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configs, err := r.load()
+	if err != nil {
+		r.metrics.Reloads.WithLabelValues(trigger, "error").Inc()
+		r.appCtx.Logger.Error("load ssp configs failed", "trigger", trigger, "error", err)
+		return sspadapter.ReloadResult{}, fmt.Errorf("load ssp configs: %w", err)
+	}
+
+	result, err := r.factory.Reload(configs)
+	if err != nil {
+		r.metrics.Reloads.WithLabelValues(trigger, "invalid").Inc()
+		r.appCtx.Logger.Error("ssp configs rejected, keep serving the current ones", "trigger", trigger, "error", err)
+		return result, err
+	}
+
+	r.metrics.Reloads.WithLabelValues(trigger, "success").Inc()
+	r.metrics.LoadedSSPs.Set(float64(len(r.factory.Configs())))
+	r.warnUnauthenticated()
+	for _, fn := range r.listeners {
+		fn(r.factory.Configs())
+	}
+	if len(result.Added)+len(result.Removed)+len(result.Updated) > 0 {
+		r.appCtx.Logger.Info("ssp configs reloaded", "trigger", trigger,
+			"added", result.Added, "removed", result.Removed, "updated", result.Updated)
+	}
+	return result, nil
+}
+
+// OnReload registers fn to be called with the serving SSP list after every successful reload
+func (r *SSPReloader) OnReload(fn func([]config.SSPConfig)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// Watch reloads the SSPs when the config file changes. The directory is watched rather
+// than the file so that editors replacing the file and config map symlink swaps are seen.
+func (r *SSPReloader) Watch() error {
+	if r.path == "" {
+		return errors.New("ssp config file not set")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create config watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(r.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watch %s: %w", filepath.Dir(r.path), err)
+	}
+	r.watcher = watcher
+
+	r.wg.Add(1)
+	go r.watchLoop()
+	return nil
+}
+
+func (r *SSPReloader) watchLoop() {
+	defer r.wg.Done()
+
+	debounce := time.NewTimer(sspReloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case _, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			debounce.Reset(sspReloadDebounce)
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			r.appCtx.Logger.Error("ssp config watcher error", "path", r.path, "error", err)
+		case <-debounce.C:
+			r.Reload(SSPReloadFile)
+		}
+	}
+}
+
+// Close stops watching the config file, the installed adapters keep serving
+func (r *SSPReloader) Close() error {
+	if r.watcher == nil {
+		return nil
+	}
+	close(r.done)
+	err := r.watcher.Close()
+	r.wg.Wait()
+	r.watcher = nil
+	return err
+}
@@ -0,0 +1,164 @@
+package adxserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+	"github.com/echoface/admux/pkg/logger"
+	"github.com/echoface/admux/pkg/pricecrypto"
+)
+
+// sspSource 测试用的SSP配置源
+type sspSource struct {
+	mu   sync.Mutex
+	ssps []config.SSPConfig
+	err  error
+}
+
+func (s *sspSource) set(ssps []config.SSPConfig, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ssps, s.err = ssps, err
+}
+
+func (s *sspSource) load() ([]config.SSPConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ssps, s.err
+}
+
+func newReloadContext(ssps ...config.SSPConfig) *AdxServerContext {
+	return &AdxServerContext{
+		Config:          &config.AdxServerConfig{SSPs: ssps},
+		MetricsRegistry: prometheus.NewRegistry(),
+		Logger:          logger.Default,
+	}
+}
+
+func TestSSPReloader_Startup(t *testing.T) {
+	_, err := NewSSPReloader(newReloadContext(config.SSPConfig{ID: "kuaishou", Protocol: "custom", Enabled: true}), "", nil)
+	var configErrs sspadapter.ConfigErrors
+	require.ErrorAs(t, err, &configErrs)
+	assert.Equal(t, "protocol", configErrs[0].Field)
+
+	appCtx := newReloadContext(config.SSPConfig{ID: "kuaishou", Protocol: sspadapter.ProtocolKuaishou, Enabled: true})
+	_, err = NewSSPReloader(appCtx, "", nil)
+	require.NoError(t, err)
+	_, _, err = NewAdxServer(appCtx).GetSSPAdapter("kuaishou")
+	assert.NoError(t, err)
+}
+
+func TestSSPReloader_WatchFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0o644))
+
+	initial := config.SSPConfig{ID: "xiaomi", Protocol: sspadapter.ProtocolOpenRTB, Timeout: 50 * time.Millisecond, Enabled: true}
+	source := &sspSource{}
+	appCtx := newReloadContext(initial)
+	reloader, err := NewSSPReloader(appCtx, path, source.load)
+	require.NoError(t, err)
+	require.NoError(t, reloader.Watch())
+	defer reloader.Close()
+
+	_, inflight, err := appCtx.GetSSPFactory().GetAdapter("xiaomi")
+	require.NoError(t, err)
+
+	updated := initial
+	updated.Timeout = 80 * time.Millisecond
+	source.set([]config.SSPConfig{updated, {ID: "maoyan", Protocol: sspadapter.ProtocolOpenRTB, Enabled: true}}, nil)
+	require.NoError(t, os.WriteFile(path, []byte("v2"), 0o644))
+
+	require.Eventually(t, func() bool {
+		_, _, err := appCtx.GetSSPFactory().GetAdapter("maoyan")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_, cfg, err := appCtx.GetSSPFactory().GetAdapter("xiaomi")
+	require.NoError(t, err)
+	assert.Equal(t, 80*time.Millisecond, cfg.Timeout)
+	// 处理中的请求保持开始时的配置
+	assert.Equal(t, 50*time.Millisecond, inflight.Timeout)
+
+	// 配置文件出错时保持当前SSP
+	source.set(nil, errors.New("yaml: line 3: mapping values are not allowed"))
+	require.NoError(t, os.WriteFile(path, []byte("v3"), 0o644))
+	time.Sleep(3 * sspReloadDebounce)
+	assert.Len(t, appCtx.GetSSPFactory().Configs(), 2)
+}
+
+func TestSSPAdminHandler(t *testing.T) {
+	source := &sspSource{}
+	appCtx := newReloadContext(config.SSPConfig{
+		ID: "kuaishou", Protocol: sspadapter.ProtocolKuaishou, Timeout: 50 * time.Millisecond, Enabled: true,
+		PriceCrypto: pricecrypto.Config{Scheme: pricecrypto.SchemeAESECBBase64URL, EncryptionKey: "0123456789abcdef"},
+	})
+	reloader, err := NewSSPReloader(appCtx, "", source.load)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := NewSSPAdminHandler(appCtx, reloader, nil)
+	r.GET("/admin/ssp", handler.HandleListSSPs)
+	r.POST("/admin/ssp/reload", handler.HandleReloadSSPs)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do(http.MethodGet, "/admin/ssp")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"count":1,"ssps":[{"id":"kuaishou","name":"","endpoint":"","protocol":"kuaishou","qps_limit":0,"timeout":"50ms","price_scheme":"aes_ecb_base64url","paused":false,"authenticated":false}]}`, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "0123456789abcdef")
+
+	source.set([]config.SSPConfig{{ID: "kuaishou", Protocol: "custom", Enabled: true}}, nil)
+	w = do(http.MethodPost, "/admin/ssp/reload")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"protocol"`)
+
+	source.set([]config.SSPConfig{{ID: "xiaomi", Protocol: sspadapter.ProtocolOpenRTB, Enabled: true}}, nil)
+	w = do(http.MethodPost, "/admin/ssp/reload")
+	require.Equal(t, http.StatusOK, w.Code)
+	var result sspadapter.ReloadResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, sspadapter.ReloadResult{Added: []string{"xiaomi"}, Removed: []string{"kuaishou"}}, result)
+}
+
+func TestSSPReloader_KeepsMetricsLabels(t *testing.T) {
+	kuaishou := config.SSPConfig{ID: "kuaishou", Protocol: sspadapter.ProtocolKuaishou, Enabled: true}
+	appCtx := newReloadContext(kuaishou)
+	appCtx.Config.Metrics.MaxSSPLabels = 1
+	source := &sspSource{ssps: []config.SSPConfig{kuaishou}}
+	reloader, err := NewSSPReloader(appCtx, "", source.load)
+	require.NoError(t, err)
+	adx := NewAdxServer(appCtx)
+	reloader.OnReload(adx.AllowSSPLabels)
+
+	// 标签名额已被占满
+	adx.metrics.ObserveShed("rogue", ShedQPSLimit)
+
+	// 重载新增的SSP保留自己的标签取值，不归入 other
+	source.set([]config.SSPConfig{kuaishou, {ID: "xiaomi", Protocol: sspadapter.ProtocolOpenRTB, Enabled: true}}, nil)
+	_, err = reloader.Reload(SSPReloadAdmin)
+	require.NoError(t, err)
+	adx.metrics.ObserveShed("xiaomi", ShedQPSLimit)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(adx.metrics.Shed.WithLabelValues("xiaomi", ShedQPSLimit)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(adx.metrics.Shed.WithLabelValues(metrics.LabelOther, ShedQPSLimit)))
+}
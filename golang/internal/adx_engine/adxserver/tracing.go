@@ -0,0 +1,104 @@
+package adxserver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/pkg/tracing"
+)
+
+const tracerName = "github.com/echoface/admux/internal/adx_engine"
+
+// span attributes
+const (
+	attrSSPID      = attribute.Key("ssp.id")
+	attrDSPID      = attribute.Key("dsp.id")
+	attrRequestID  = attribute.Key("bid.request_id")
+	attrStages     = attribute.Key("bid.stages")
+	attrCandidates = attribute.Key("bid.candidates")
+	attrWinners    = attribute.Key("bid.winners")
+)
+
+// newTracing creates the tracing provider, root spans are sampled per SSP and the
+// stage and DSP call spans follow the root span. The sampler is nil when tracing is disabled.
+func newTracing(appCtx *AdxServerContext) (*tracing.Provider, *tracing.KeyedSampler) {
+	if appCtx.Config == nil {
+		return nil, nil
+	}
+	cfg := appCtx.Config.Tracing
+
+	rates := make(map[string]float64, len(cfg.SSPSampleRates))
+	for _, rate := range cfg.SSPSampleRates {
+		rates[rate.ID] = rate.Rate
+	}
+	sampler := tracing.NewKeyedSampler(attrSSPID, cfg.SampleRate, rates)
+	provider, err := tracing.New(cfg.Config, sampler)
+	if err != nil {
+		appCtx.Logger.Error("create tracing provider failed, tracing disabled", "error", err)
+		return nil, nil
+	}
+	if provider == nil {
+		return nil, nil
+	}
+	return provider, sampler
+}
+
+// setTracer replaces the tracer of the pipeline and the DSP calls
+func (s *AdxServer) setTracer(tracer trace.Tracer) {
+	s.tracer = tracer
+	s.broadcaster.tracer = tracer
+}
+
+// StartTrace starts the root span of an SSP bid request, the SSP sampling decision is made here.
+// The returned context should be the parent of the bid request context.
+func (s *AdxServer) StartTrace(sspID string) (context.Context, trace.Span) {
+	return s.tracer.Start(context.Background(), "adx.bid",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrSSPID.String(sspID)))
+}
+
+// EndTrace reports the processed stages, the non-fatal processing errors and the
+// auction result of the request on the root span and ends it
+func (s *AdxServer) EndTrace(span trace.Span, bidCtx *adxcore.BidRequestCtx, err error) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(
+		attrRequestID.String(bidCtx.Request.GetId()),
+		attrStages.StringSlice(bidCtx.ProcessingStages),
+		attrCandidates.Int(len(bidCtx.GetCandidates())),
+		attrWinners.Int(len(bidCtx.Winners())),
+	)
+	for _, processErr := range bidCtx.ProcessingErrors {
+		span.AddEvent("processing_error", trace.WithAttributes(attribute.String("error", processErr.Error())))
+	}
+	recordError(span, err)
+}
+
+// traceStage runs a pipeline stage in its own span, the stage sees the span through bidCtx
+func (s *AdxServer) traceStage(bidCtx *adxcore.BidRequestCtx, name string, stage func(*adxcore.BidRequestCtx) error) error {
+	parent := bidCtx.Context
+	ctx, span := s.tracer.Start(parent, name)
+	defer span.End()
+
+	bidCtx.Context = ctx
+	err := stage(bidCtx)
+	bidCtx.Context = parent
+
+	recordError(span, err)
+	return err
+}
+
+// recordError marks the span failed
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,106 @@
+package adxserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	pkgconfig "github.com/echoface/admux/pkg/config"
+	"github.com/echoface/admux/pkg/logger"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"github.com/echoface/admux/pkg/tracing"
+)
+
+// headerBidder 记录下发给DSP的 traceparent 和请求上下文的截止时间
+type headerBidder struct {
+	priceBidder
+	header      http.Header
+	hasDeadline bool
+}
+
+func (b *headerBidder) SendBidRequest(bidCtx *adxcore.BidRequestCtx) ([]*adxcore.BidCandidate, error) {
+	b.header = http.Header{}
+	tracing.Inject(bidCtx, b.header)
+	_, b.hasDeadline = bidCtx.Deadline()
+	return b.priceBidder.SendBidRequest(bidCtx)
+}
+
+func TestAdxServer_Tracing(t *testing.T) {
+	server := NewAdxServer(&AdxServerContext{
+		Config: &config.AdxServerConfig{BaseConfig: pkgconfig.BaseConfig{MaxConnections: 10}},
+		Logger: logger.Default,
+	})
+	exporter := tracetest.NewInMemoryExporter()
+	sampler := tracing.NewKeyedSampler(attrSSPID, 1, map[string]float64{"muted": 0})
+	server.setTracer(tracing.NewWithExporter("test", exporter, sampler).Tracer(tracerName))
+
+	bidder := &headerBidder{priceBidder: priceBidder{id: "tracing-dsp", price: 200}}
+	factory := adxcore.GetGlobalBidderFactory()
+	_, err := factory.ReplaceBidder(bidder)
+	require.NoError(t, err)
+	defer factory.UnregisterBidder(bidder.id)
+
+	process := func(sspID string) {
+		ctx, span := server.StartTrace(sspID)
+		bidCtx := adxcore.NewBidRequestCtx(ctx, &admux_rtb.BidRequest{Id: proto.String("trace-" + sspID)})
+		bidCtx.SetSSPInfo(sspID, &config.SSPConfig{ID: sspID, Timeout: time.Second})
+		server.EndTrace(span, bidCtx, server.ProcessBid(bidCtx))
+	}
+
+	// 采样率为0的SSP不产生span，下发给DSP的 traceparent 标记为未采样
+	process("muted")
+	assert.Empty(t, exporter.GetSpans())
+	assert.Regexp(t, "-00$", bidder.header.Get("traceparent"))
+
+	process("kuaishou")
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub, len(spans))
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+	for _, name := range []string{"adx.bid", "complete_features", "targeting", "broadcast", "filter", "ranking", "build_response", "dsp.bid"} {
+		require.Contains(t, byName, name)
+	}
+
+	root := byName["adx.bid"]
+	for name, span := range byName {
+		assert.Equal(t, root.SpanContext.TraceID(), span.SpanContext.TraceID(), name)
+	}
+	assert.Equal(t, root.SpanContext.SpanID(), byName["broadcast"].Parent.SpanID())
+
+	dspSpan := byName["dsp.bid"]
+	assert.Equal(t, byName["broadcast"].SpanContext.SpanID(), dspSpan.Parent.SpanID())
+	assert.Contains(t, dspSpan.Attributes, attrDSPID.String("tracing-dsp"))
+	assert.Equal(t, "00-"+dspSpan.SpanContext.TraceID().String()+"-"+dspSpan.SpanContext.SpanID().String()+"-01",
+		bidder.header.Get("traceparent"))
+	// DSP调用受SSP和bidder超时约束
+	assert.True(t, bidder.hasDeadline)
+
+	assert.Contains(t, root.Attributes, attrRequestID.String("trace-kuaishou"))
+	assert.Contains(t, root.Attributes, attrStages.StringSlice([]string{
+		"complete_features", "targeting", "broadcast", "filter", "ranking", "build_response",
+	}))
+}
+
+func TestAdxServer_TracingDisabled(t *testing.T) {
+	server := NewAdxServer(&AdxServerContext{
+		Config: &config.AdxServerConfig{BaseConfig: pkgconfig.BaseConfig{MaxConnections: 10}},
+		Logger: logger.Default,
+	})
+	assert.Nil(t, server.tracing)
+
+	ctx, span := server.StartTrace("kuaishou")
+	assert.False(t, span.IsRecording())
+	header := http.Header{}
+	tracing.Inject(ctx, header)
+	assert.Empty(t, header.Get("traceparent"))
+	assert.NoError(t, server.Close(context.Background()))
+}
@@ -0,0 +1,176 @@
+package bidlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+)
+
+const (
+	defaultQueueSize = 4096
+	defaultMaxSizeMB = 512
+	flushInterval    = time.Second
+)
+
+// 写入结果，用作指标标签
+const (
+	resultWritten = "written"
+	resultDropped = "dropped"
+	resultError   = "error"
+)
+
+// Logger 竞价采样日志：热路径只做采样判断并投递引用，序列化和写文件由单独的goroutine完成，
+// 队列满时丢弃，不阻塞竞价
+type Logger struct {
+	sampler *Sampler
+	metrics *metrics.BidLogMetrics
+
+	writer *lumberjack.Logger
+	buf    *bufio.Writer
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *entry
+	done   chan struct{}
+
+	rand func() float64
+}
+
+// New 创建并启动竞价日志，未开启时返回nil，nil Logger 的方法均为空操作
+func New(cfg config.BidLogConfig, m *metrics.BidLogMetrics) (*Logger, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("bid log path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create bid log dir: %w", err)
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = defaultMaxSizeMB
+	}
+
+	writer := &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeDays,
+		Compress:   cfg.Compress,
+	}
+	l := &Logger{
+		sampler: NewSampler(cfg),
+		metrics: m,
+		writer:  writer,
+		buf:     bufio.NewWriterSize(writer, 64*1024),
+		queue:   make(chan *entry, cfg.QueueSize),
+		done:    make(chan struct{}),
+		rand:    rand.Float64,
+	}
+	go l.run()
+	return l, nil
+}
+
+// Record 按采样率提交一次竞价的记录，err为处理失败原因；调用后bidCtx不应再被修改
+func (l *Logger) Record(bidCtx *adxcore.BidRequestCtx, sspRequest, sspResponse []byte, err error) {
+	if l == nil || !l.sampler.Sample(bidCtx, l.rand) {
+		return
+	}
+	e := &entry{at: time.Now(), bidCtx: bidCtx, sspRequest: sspRequest, sspResponse: sspResponse, err: err}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if !l.closed {
+		select {
+		case l.queue <- e:
+			return
+		default:
+		}
+	}
+	l.observe(resultDropped)
+}
+
+// Sampler 获取采样器，未开启竞价日志时返回nil
+func (l *Logger) Sampler() *Sampler {
+	if l == nil {
+		return nil
+	}
+	return l.sampler
+}
+
+// Close 写完队列中的记录后关闭文件
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.queue)
+	l.mu.Unlock()
+
+	<-l.done
+	return l.writer.Close()
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-l.queue:
+			if !ok {
+				l.flush()
+				return
+			}
+			l.write(e)
+		case <-ticker.C:
+			l.flush()
+		}
+	}
+}
+
+func (l *Logger) write(e *entry) {
+	data, err := json.Marshal(newRecord(e))
+	if err == nil {
+		_, err = l.buf.Write(append(data, '\n'))
+	}
+	if err != nil {
+		log.Printf("write bid log failed, request:%s err:%v", e.bidCtx.Request.GetId(), err)
+		l.observe(resultError)
+		return
+	}
+	l.observe(resultWritten)
+}
+
+func (l *Logger) flush() {
+	if err := l.buf.Flush(); err != nil {
+		log.Printf("flush bid log failed, err:%v", err)
+	}
+}
+
+func (l *Logger) observe(result string) {
+	if l.metrics != nil {
+		l.metrics.Records.WithLabelValues(result).Inc()
+	}
+}
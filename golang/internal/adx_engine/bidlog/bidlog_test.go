@@ -0,0 +1,144 @@
+package bidlog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+func newTestBidCtx(sspID string) *adxcore.BidRequestCtx {
+	req := &admux_rtb.BidRequest{Id: proto.String("req-1")}
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), req)
+	bidCtx.SetSSPInfo(sspID, &config.SSPConfig{ID: sspID})
+
+	dspResp := &admux_rtb.BidResponse{Id: proto.String("req-1"), Bidid: proto.String("resp-a")}
+	won := &adxcore.BidCandidate{
+		BidderID: "dsp_a",
+		Response: dspResp,
+		Bid:      &admux_rtb.BidResponse_SeatBid_Bid{Id: proto.String("bid-a"), Impid: proto.String("imp-1")},
+		CPMPrice: 1200,
+		Won:      true,
+	}
+	invalid := &adxcore.BidCandidate{BidderID: "dsp_a", Response: dspResp, Bid: &admux_rtb.BidResponse_SeatBid_Bid{Id: proto.String("bid-b")}}
+	bidCtx.BidderCalls = []*adxcore.BidderCall{
+		{BidderID: "dsp_a", Request: req, Candidates: []*adxcore.BidCandidate{won, invalid}, Latency: 12 * time.Millisecond},
+		{BidderID: "dsp_b", Request: req, Latency: 30 * time.Millisecond, Err: errors.New("timeout")},
+	}
+	bidCtx.AddCandidate(won)
+	bidCtx.RejectCandidate(invalid, "invalid_price", tracking.LossInvalidResponse)
+	bidCtx.SetResponse(&admux_rtb.BidResponse{Id: proto.String("req-1")})
+	return bidCtx
+}
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []Record
+	require.NoError(t, ReadRecords(file, func(record *Record) error {
+		records = append(records, *record)
+		return nil
+	}))
+	return records
+}
+
+func TestLogger_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bid.log")
+	m := metrics.NewBidLogMetrics("adx", "bidlog", prometheus.NewRegistry())
+	logger, err := New(config.BidLogConfig{Enabled: true, Path: path, SampleRate: 1}, m)
+	require.NoError(t, err)
+
+	logger.Record(newTestBidCtx("ssp_a"), []byte(`{"id":"req-1"}`), []byte(`{"status":0}`), nil)
+	require.NoError(t, logger.Close())
+	// 关闭后提交的记录直接丢弃
+	logger.Record(newTestBidCtx("ssp_a"), nil, nil, nil)
+
+	records := readRecords(t, path)
+	require.Len(t, records, 1)
+	record := records[0]
+	assert.Equal(t, "ssp_a", record.SSPID)
+	assert.Equal(t, "req-1", record.RequestID)
+	assert.Equal(t, `{"id":"req-1"}`, string(record.SSPRequest))
+	assert.Equal(t, `{"status":0}`, string(record.SSPResponse))
+	assert.JSONEq(t, `{"id":"req-1"}`, string(record.Request))
+	assert.JSONEq(t, `{"id":"req-1"}`, string(record.Response))
+
+	require.Len(t, record.Bidders, 2)
+	assert.Equal(t, 12.0, record.Bidders[0].LatencyMS)
+	// 两个出价来自同一个DSP响应
+	require.Len(t, record.Bidders[0].Responses, 1)
+	assert.JSONEq(t, `{"id":"req-1","bidid":"resp-a"}`, string(record.Bidders[0].Responses[0]))
+	assert.Equal(t, "timeout", record.Bidders[1].Error)
+
+	assert.Equal(t, []AuctionRecord{
+		{BidderID: "dsp_a", BidID: "bid-a", ImpID: "imp-1", CPMPrice: 1200, Won: true},
+		{BidderID: "dsp_a", BidID: "bid-b", LossReason: int(tracking.LossInvalidResponse), FilterReason: "invalid_price"},
+	}, record.Auction)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Records.WithLabelValues(resultWritten)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Records.WithLabelValues(resultDropped)))
+}
+
+func TestLogger_Disabled(t *testing.T) {
+	logger, err := New(config.BidLogConfig{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, logger)
+	// nil Logger 为空操作
+	logger.Record(newTestBidCtx("ssp_a"), nil, nil, nil)
+	assert.NoError(t, logger.Close())
+
+	_, err = New(config.BidLogConfig{Enabled: true}, nil)
+	assert.Error(t, err)
+}
+
+func TestSampler_Sample(t *testing.T) {
+	sampler := NewSampler(config.BidLogConfig{
+		SampleRate:     0.01,
+		SSPSampleRates: []config.SampleRateConfig{{ID: "ssp_full", Rate: 1}, {ID: "ssp_off", Rate: 0}},
+		DSPSampleRates: []config.SampleRateConfig{{ID: "dsp_b", Rate: 0.5}},
+	})
+	roll := func(v float64) func() float64 { return func() float64 { return v } }
+
+	assert.True(t, sampler.Sample(newTestBidCtx("ssp_full"), roll(0.99)))
+	assert.True(t, sampler.Sample(newTestBidCtx("ssp_other"), roll(0.005)))
+	// 未命中SSP采样时，参与竞价的DSP按各自的采样率采样
+	assert.True(t, sampler.Sample(newTestBidCtx("ssp_off"), roll(0.3)))
+	assert.False(t, sampler.Sample(newTestBidCtx("ssp_off"), roll(0.6)))
+
+	noDSP := newTestBidCtx("ssp_off")
+	noDSP.BidderCalls = noDSP.BidderCalls[:1]
+	assert.False(t, sampler.Sample(noDSP, roll(0)))
+}
+
+// BenchmarkLogger_Record 热路径开销：采样判断 + 入队
+func BenchmarkLogger_Record(b *testing.B) {
+	logger, err := New(config.BidLogConfig{
+		Enabled: true, Path: filepath.Join(b.TempDir(), "bid.log"), SampleRate: 1, QueueSize: 1024,
+	}, nil)
+	require.NoError(b, err)
+	defer logger.Close()
+
+	bidCtx := newTestBidCtx("ssp_a")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Record(bidCtx, nil, nil, nil)
+	}
+}
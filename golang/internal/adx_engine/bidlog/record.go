@@ -0,0 +1,166 @@
+package bidlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+)
+
+// Record 一次竞价的采样记录，JSONL 中的一行；proto 消息以 protojson 编码
+type Record struct {
+	Time      time.Time `json:"time"`
+	SSPID     string    `json:"ssp_id"`
+	RequestID string    `json:"request_id,omitempty"`
+	LatencyMS float64   `json:"latency_ms"`
+
+	// SSPRequest/SSPResponse SSP协议的原始请求和最终响应(base64)
+	SSPRequest  []byte `json:"ssp_request,omitempty"`
+	SSPResponse []byte `json:"ssp_response,omitempty"`
+
+	// Request/Response 归一化后的内部请求和内部响应
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+
+	Stages  []string        `json:"stages,omitempty"`
+	Bidders []BidderRecord  `json:"bidders,omitempty"`
+	Auction []AuctionRecord `json:"auction,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// BidderRecord 单个DSP的调用记录
+type BidderRecord struct {
+	BidderID  string            `json:"bidder_id"`
+	LatencyMS float64           `json:"latency_ms"`
+	Error     string            `json:"error,omitempty"`
+	Request   json.RawMessage   `json:"request,omitempty"`
+	Responses []json.RawMessage `json:"responses,omitempty"`
+}
+
+// AuctionRecord 单个出价的竞价结果，包括被过滤掉的出价
+type AuctionRecord struct {
+	BidderID     string `json:"bidder_id"`
+	BidID        string `json:"bid_id,omitempty"`
+	ImpID        string `json:"imp_id,omitempty"`
+	CPMPrice     int64  `json:"cpm_price"`
+	Won          bool   `json:"won"`
+	LossReason   int    `json:"loss_reason"`
+	FilterReason string `json:"filter_reason,omitempty"`
+}
+
+// entry 热路径提交的待写入数据，只持有引用，序列化在写入goroutine中进行
+type entry struct {
+	at          time.Time
+	bidCtx      *adxcore.BidRequestCtx
+	sspRequest  []byte
+	sspResponse []byte
+	err         error
+}
+
+// newRecord 由竞价上下文构建记录
+func newRecord(e *entry) *Record {
+	bidCtx := e.bidCtx
+	record := &Record{
+		Time:        e.at,
+		SSPID:       bidCtx.SSPID,
+		RequestID:   bidCtx.Request.GetId(),
+		LatencyMS:   milliseconds(e.at.Sub(bidCtx.BidStartTime)),
+		SSPRequest:  e.sspRequest,
+		SSPResponse: e.sspResponse,
+		Request:     marshalProto(bidCtx.Request),
+		Response:    marshalProto(bidCtx.Response),
+		Stages:      bidCtx.ProcessingStages,
+	}
+	if e.err != nil {
+		record.Error = e.err.Error()
+	}
+
+	for _, call := range bidCtx.BidderCalls {
+		bidder := BidderRecord{
+			BidderID:  call.BidderID,
+			LatencyMS: milliseconds(call.Latency),
+			Request:   marshalProto(call.Request),
+		}
+		if call.Err != nil {
+			bidder.Error = call.Err.Error()
+		}
+		// 同一DSP响应中的多个出价共享一个响应
+		seen := make(map[proto.Message]struct{})
+		for _, candidate := range call.Candidates {
+			if candidate == nil || candidate.Response == nil {
+				continue
+			}
+			if _, ok := seen[candidate.Response]; ok {
+				continue
+			}
+			seen[candidate.Response] = struct{}{}
+			bidder.Responses = append(bidder.Responses, marshalProto(candidate.Response))
+		}
+		record.Bidders = append(record.Bidders, bidder)
+	}
+
+	for _, candidates := range [][]*adxcore.BidCandidate{bidCtx.GetCandidates(), bidCtx.RejectedCandidates} {
+		for _, candidate := range candidates {
+			record.Auction = append(record.Auction, AuctionRecord{
+				BidderID:     candidate.BidderID,
+				BidID:        candidate.Bid.GetId(),
+				ImpID:        candidate.Bid.GetImpid(),
+				CPMPrice:     candidate.CPMPrice,
+				Won:          candidate.Won,
+				LossReason:   int(candidate.LossReason),
+				FilterReason: candidate.FilterReason,
+			})
+		}
+	}
+	return record
+}
+
+func marshalProto(msg proto.Message) json.RawMessage {
+	if msg == nil || !msg.ProtoReflect().IsValid() {
+		return nil
+	}
+	data, err := protojson.MarshalOptions{AllowPartial: true}.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Snapshot 立即由竞价上下文构建记录(不采样、不写文件)，用于回放等离线场景
+func Snapshot(bidCtx *adxcore.BidRequestCtx, sspRequest, sspResponse []byte, err error) *Record {
+	return newRecord(&entry{at: time.Now(), bidCtx: bidCtx, sspRequest: sspRequest, sspResponse: sspResponse, err: err})
+}
+
+// ReadRecords 逐行读取 JSONL 竞价日志，fn 返回错误时停止
+func ReadRecords(r io.Reader, fn func(*Record) error) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var record Record
+			if jsonErr := json.Unmarshal(data, &record); jsonErr != nil {
+				return fmt.Errorf("bid log line %d: %w", line, jsonErr)
+			}
+			if fnErr := fn(&record); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package bidlog
+
+import (
+	"maps"
+	"sync/atomic"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+)
+
+// Sampler 按SSP/DSP采样率决定是否记录一次竞价，采样率可在运行时整体替换
+type Sampler struct {
+	rates atomic.Pointer[SampleRates]
+}
+
+// SampleRates 采样率，SSP/DSP 为按ID覆盖的采样率
+type SampleRates struct {
+	Default float64            `json:"sample_rate"`
+	SSP     map[string]float64 `json:"ssp_sample_rates"`
+	DSP     map[string]float64 `json:"dsp_sample_rates"`
+}
+
+// NewSampler 创建采样器，未单独配置的SSP使用默认采样率；DSP只有单独配置时才参与采样
+func NewSampler(cfg config.BidLogConfig) *Sampler {
+	rates := SampleRates{
+		Default: cfg.SampleRate,
+		SSP:     make(map[string]float64, len(cfg.SSPSampleRates)),
+		DSP:     make(map[string]float64, len(cfg.DSPSampleRates)),
+	}
+	for _, item := range cfg.SSPSampleRates {
+		rates.SSP[item.ID] = item.Rate
+	}
+	for _, item := range cfg.DSPSampleRates {
+		rates.DSP[item.ID] = item.Rate
+	}
+
+	s := &Sampler{}
+	s.SetRates(rates)
+	return s
+}
+
+// Rates 获取当前采样率的副本
+func (s *Sampler) Rates() SampleRates {
+	rates := *s.rates.Load()
+	rates.SSP = maps.Clone(rates.SSP)
+	rates.DSP = maps.Clone(rates.DSP)
+	return rates
+}
+
+// SetRates 替换采样率，之后的请求按新采样率采样
+func (s *Sampler) SetRates(rates SampleRates) {
+	rates.SSP = maps.Clone(rates.SSP)
+	rates.DSP = maps.Clone(rates.DSP)
+	s.rates.Store(&rates)
+}
+
+// Sample 命中SSP采样或任一参与竞价的DSP的采样时返回true，rand返回[0,1)的随机数
+func (s *Sampler) Sample(bidCtx *adxcore.BidRequestCtx, rand func() float64) bool {
+	rates := s.rates.Load()
+
+	rate, ok := rates.SSP[bidCtx.SSPID]
+	if !ok {
+		rate = rates.Default
+	}
+	if hit(rate, rand) {
+		return true
+	}
+
+	if len(rates.DSP) == 0 {
+		return false
+	}
+	for _, call := range bidCtx.BidderCalls {
+		if rate, ok := rates.DSP[call.BidderID]; ok && hit(rate, rand) {
+			return true
+		}
+	}
+	return false
+}
+
+func hit(rate float64, rand func() float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	return rate >= 1 || rand() < rate
+}
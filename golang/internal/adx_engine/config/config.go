@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/echoface/admux/pkg/config"
+	"github.com/echoface/admux/pkg/notifier"
+	"github.com/echoface/admux/pkg/pricecrypto"
+	"github.com/echoface/admux/pkg/tracing"
 )
 
 // ============================================================================
@@ -16,11 +19,28 @@ type AdxServerConfig struct {
 	// 基础配置（嵌入或复制通用配置）
 	config.BaseConfig `yaml:",inline"`
 
+	// TrustedProxies 可信的反向代理IP或网段，只有来自这些地址的 X-Forwarded-For 才用于解析来源IP；
+	// 为空时来源IP取TCP连接的对端地址
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	// DrainDelay 停机时就绪检查失败后、停止接收新连接前的等待时间，留给负载均衡摘除实例
+	DrainDelay time.Duration `yaml:"drain_delay"`
+
 	// ADX Engine 特定配置
-	Redis    RedisConfig    `yaml:"redis"`
-	SSPs     []SSPConfig    `yaml:"ssps"`
-	Bidders  []BidderConfig `yaml:"bidders"`
-	S3       S3Config       `yaml:"s3"`
+	Redis     RedisConfig     `yaml:"redis"`
+	SSPs      []SSPConfig     `yaml:"ssps"`
+	Bidders   []BidderConfig  `yaml:"bidders"`
+	S3        S3Config        `yaml:"s3"`
+	DSPSource DSPSourceConfig `yaml:"dsp_source"`
+	Features  FeatureConfig   `yaml:"features"`
+	Tracking  TrackingConfig  `yaml:"tracking"`
+	Notifier  notifier.Config `yaml:"notifier"`
+	BidLog    BidLogConfig    `yaml:"bid_log"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Debug     DebugConfig     `yaml:"debug"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Admin     AdminConfig     `yaml:"admin"`
+	Overload  OverloadConfig  `yaml:"overload"`
 }
 
 // RedisConfig Redis配置
@@ -46,6 +66,24 @@ type SSPConfig struct {
 	QPSLimit int           `yaml:"qps_limit"`
 	Timeout  time.Duration `yaml:"timeout"`
 	Enabled  bool          `yaml:"enabled"`
+
+	// PriceCrypto 媒体成交价宏的加密方式，监测服务按此解密成交价
+	PriceCrypto pricecrypto.Config `yaml:"price_crypto"`
+
+	// Auth 竞价请求鉴权，未配置任何方式时不鉴权
+	Auth SSPAuthConfig `yaml:"auth"`
+}
+
+// SSPAuthConfig SSP竞价请求鉴权，配置了的方式需全部通过
+type SSPAuthConfig struct {
+	// AllowIPs 允许的来源IP或网段(CIDR)，来源IP按 trusted_proxies 解析
+	AllowIPs []string `yaml:"allow_ips"`
+	// Token 请求头 Authorization: Bearer {token}
+	Token string `yaml:"token"`
+	// HMACSecret 请求体签名密钥，签名头 X-SSP-Signature: {unix秒}.{base64url(HMAC-SHA256("{unix秒}.{body}"))}
+	HMACSecret string `yaml:"hmac_secret"`
+	// MaxAge 签名有效期，默认5分钟
+	MaxAge time.Duration `yaml:"max_age"`
 }
 
 // BidderConfig Bidder配置
@@ -73,6 +111,167 @@ type S3Config struct {
 	Region          string        `yaml:"region"`
 }
 
+// DSPSourceConfig DSP配置源配置
+type DSPSourceConfig struct {
+	// Type 配置源类型: s3 | local | http | composite，为空时默认使用s3
+	Type string `yaml:"type"`
+	// ScanInterval 全量重新扫描间隔，为空时回退到 S3.ScanInterval
+	ScanInterval time.Duration `yaml:"scan_interval"`
+
+	Local LocalDSPSourceConfig `yaml:"local"`
+	HTTP  HTTPDSPSourceConfig  `yaml:"http"`
+
+	// Composite 组合源的子源类型列表，按优先级从高到低排列；
+	// 同一个DSP ID出现在多个子源时，以排在前面的子源为准
+	Composite []string `yaml:"composite"`
+}
+
+// LocalDSPSourceConfig 本地目录配置源
+type LocalDSPSourceConfig struct {
+	Dir string `yaml:"dir"`
+}
+
+// HTTPDSPSourceConfig HTTP配置源
+type HTTPDSPSourceConfig struct {
+	URL     string            `yaml:"url"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// FeatureConfig 特征补全配置
+type FeatureConfig struct {
+	// Timeout 所有特征提供者共享的截止时间，为空时使用默认值
+	Timeout time.Duration `yaml:"timeout"`
+
+	// IPGeo 离线IP地理库配置
+	IPGeo IPGeoConfig `yaml:"ip_geo"`
+
+	// UA User-Agent 解析配置
+	UA UAConfig `yaml:"ua"`
+
+	// UserProfile Redis用户画像配置，使用 redis 配置的连接
+	UserProfile UserProfileConfig `yaml:"user_profile"`
+}
+
+// UserProfileConfig 用户画像配置
+type UserProfileConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// KeyPrefix 画像键前缀，完整键为 {key_prefix}{id_type}:{id}
+	KeyPrefix string `yaml:"key_prefix"`
+	// Timeout 单次画像查询超时，应小于 features.timeout
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// UAConfig User-Agent 解析配置
+type UAConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// CacheSize 解析结果LRU缓存容量，为空时使用默认值
+	CacheSize int `yaml:"cache_size"`
+}
+
+// IPGeoConfig 离线IP地理库配置
+type IPGeoConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// DBPath CIDR CSV文件路径，每行格式: cidr,country,region,city,isp
+	DBPath string `yaml:"db_path"`
+	// ReloadInterval 文件变化轮询间隔，作为文件监听的兜底
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// TrackingConfig 监测链接配置
+type TrackingConfig struct {
+	// BaseURL 监测服务地址，为空时不替换DSP的通知地址
+	BaseURL string `yaml:"base_url"`
+	// ActiveKeyID 签名使用的密钥ID
+	ActiveKeyID string `yaml:"active_key_id"`
+	// SigningKeys 签名密钥，需与监测服务的 tracking.signing_keys 一致
+	SigningKeys []SigningKeyConfig `yaml:"signing_keys"`
+	// Currency 响应币种，为空时使用 CNY
+	Currency string `yaml:"currency"`
+}
+
+// SigningKeyConfig 签名密钥
+type SigningKeyConfig struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+}
+
+// BidLogConfig 竞价采样日志配置
+type BidLogConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Path 日志文件路径，每行一条JSON记录
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+	MaxAgeDays int    `yaml:"max_age_days"`
+	Compress   bool   `yaml:"compress"`
+	// QueueSize 待写入队列长度，队列满时丢弃记录，不阻塞竞价
+	QueueSize int `yaml:"queue_size"`
+
+	// SampleRate 默认采样率 [0,1]
+	SampleRate float64 `yaml:"sample_rate"`
+	// SSPSampleRates/DSPSampleRates 按SSP/DSP覆盖采样率，请求命中SSP或任一参与竞价的DSP的采样即记录
+	SSPSampleRates []SampleRateConfig `yaml:"ssp_sample_rates"`
+	DSPSampleRates []SampleRateConfig `yaml:"dsp_sample_rates"`
+}
+
+// SampleRateConfig 单个SSP/DSP的采样率
+type SampleRateConfig struct {
+	ID   string  `yaml:"id"`
+	Rate float64 `yaml:"rate"`
+}
+
+// TracingConfig 链路追踪配置，sample_rate 为默认SSP采样率
+type TracingConfig struct {
+	tracing.Config `yaml:",inline"`
+
+	// SSPSampleRates 按SSP覆盖采样率
+	SSPSampleRates []SampleRateConfig `yaml:"ssp_sample_rates"`
+}
+
+// DebugConfig 调试模式配置：可信调用方带 X-Adx-Debug 头时，响应中附带完整的竞价过程
+type DebugConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// AllowIPs 无需签名的调用方IP或网段(CIDR)
+	AllowIPs []string `yaml:"allow_ips"`
+	// Secret 签名密钥，签名头 X-Adx-Debug-Signature: {unix秒}.{base64url(HMAC-SHA256("{unix秒}.{ssp_id}"))}
+	Secret string `yaml:"secret"`
+	// MaxAge 签名有效期，默认5分钟
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// MetricsConfig 竞价指标配置，限制 ssp/dsp 标签的取值个数，超出的ID归入 "other"
+type MetricsConfig struct {
+	// MaxSSPLabels ssp标签最多取值数，默认100
+	MaxSSPLabels int `yaml:"max_ssp_labels"`
+	// MaxDSPLabels dsp标签最多取值数，默认200
+	MaxDSPLabels int `yaml:"max_dsp_labels"`
+}
+
+// OverloadConfig 过载保护：超过SSP的 qps_limit 或全局并发上限的请求直接返回不出价，不再广播给DSP
+type OverloadConfig struct {
+	// MaxInFlight 同时处理的竞价请求上限，0 表示不限制
+	MaxInFlight int `yaml:"max_in_flight"`
+	// TargetLatency 竞价耗时目标，最近耗时(EWMA)超过目标时按比例收紧并发上限，0 表示不收紧
+	TargetLatency time.Duration `yaml:"target_latency"`
+	// MinInFlight 收紧后的并发上限下限，默认为 MaxInFlight 的 1/10
+	MinInFlight int `yaml:"min_in_flight"`
+}
+
+// AdminConfig 管理接口配置，未配置令牌时 /admin 接口拒绝所有请求
+type AdminConfig struct {
+	// Tokens 调用方令牌，请求头 Authorization: Bearer {token}，Name 记入审计日志
+	Tokens []AdminTokenConfig `yaml:"tokens"`
+	// AuditLog 审计日志文件(JSON Lines)，为空时审计记录只写应用日志
+	AuditLog string `yaml:"audit_log"`
+}
+
+// AdminTokenConfig 管理接口调用方
+type AdminTokenConfig struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+}
+
 // ============================================================================
 // 配置加载器
 // ============================================================================
@@ -89,6 +288,11 @@ func LoadAdxConfig() (*AdxServerConfig, error) {
 	return &cfg, nil
 }
 
+// AdxConfigFile 返回ADX服务器配置文件路径，用于监听配置变化
+func AdxConfigFile() (string, error) {
+	return config.NewLoader("adxserver").ConfigFile()
+}
+
 // DefaultAdxConfig 获取默认ADX配置
 func DefaultAdxConfig() *AdxServerConfig {
 	return &AdxServerConfig{
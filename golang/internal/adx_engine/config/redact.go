@@ -0,0 +1,54 @@
+package config
+
+import "maps"
+
+// redactedValue 日志输出时替换密钥、令牌的占位符
+const redactedValue = "******"
+
+// Redacted 返回隐去密钥、令牌的配置副本，用于日志输出
+func (c *AdxServerConfig) Redacted() *AdxServerConfig {
+	out := *c
+	out.Redis.Password = redact(c.Redis.Password)
+	out.S3.SecretAccessKey = redact(c.S3.SecretAccessKey)
+	out.Debug.Secret = redact(c.Debug.Secret)
+
+	out.SSPs = make([]SSPConfig, len(c.SSPs))
+	for i, ssp := range c.SSPs {
+		ssp.PriceCrypto = ssp.PriceCrypto.Redacted()
+		ssp.Auth.Token = redact(ssp.Auth.Token)
+		ssp.Auth.HMACSecret = redact(ssp.Auth.HMACSecret)
+		out.SSPs[i] = ssp
+	}
+
+	out.Bidders = make([]BidderConfig, len(c.Bidders))
+	for i, bidder := range c.Bidders {
+		bidder.AuthToken = redact(bidder.AuthToken)
+		out.Bidders[i] = bidder
+	}
+
+	// 请求头可能携带配置源的访问令牌
+	out.DSPSource.HTTP.Headers = maps.Clone(c.DSPSource.HTTP.Headers)
+	for name, value := range out.DSPSource.HTTP.Headers {
+		out.DSPSource.HTTP.Headers[name] = redact(value)
+	}
+
+	out.Tracking.SigningKeys = make([]SigningKeyConfig, len(c.Tracking.SigningKeys))
+	for i, key := range c.Tracking.SigningKeys {
+		key.Secret = redact(key.Secret)
+		out.Tracking.SigningKeys[i] = key
+	}
+
+	out.Admin.Tokens = make([]AdminTokenConfig, len(c.Admin.Tokens))
+	for i, token := range c.Admin.Tokens {
+		token.Token = redact(token.Token)
+		out.Admin.Tokens[i] = token
+	}
+	return &out
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/echoface/admux/pkg/jsonx"
+	"github.com/echoface/admux/pkg/pricecrypto"
+)
+
+func TestAdxServerConfig_Redacted(t *testing.T) {
+	cfg := &AdxServerConfig{
+		Redis: RedisConfig{Addr: "redis:6379", Password: "redis-pass"},
+		SSPs: []SSPConfig{{
+			ID:          "kuaishou",
+			PriceCrypto: pricecrypto.Config{Scheme: pricecrypto.SchemeAESECB, EncryptionKey: "price-key"},
+			Auth:        SSPAuthConfig{AllowIPs: []string{"10.0.0.0/8"}, Token: "ssp-token", HMACSecret: "ssp-hmac"},
+		}},
+		Bidders:   []BidderConfig{{ID: "dsp_001", AuthToken: "bidder-token"}},
+		S3:        S3Config{AccessKeyID: "ak", SecretAccessKey: "s3-secret"},
+		DSPSource: DSPSourceConfig{HTTP: HTTPDSPSourceConfig{Headers: map[string]string{"X-Token": "source-token"}}},
+		Tracking:  TrackingConfig{ActiveKeyID: "k1", SigningKeys: []SigningKeyConfig{{ID: "k1", Secret: "signing-secret"}}},
+		Debug:     DebugConfig{Secret: "debug-secret"},
+		Admin:     AdminConfig{Tokens: []AdminTokenConfig{{Name: "ops", Token: "admin-token"}}},
+	}
+
+	dump := jsonx.Pretty(cfg.Redacted())
+	for _, secret := range []string{"redis-pass", "price-key", "ssp-token", "ssp-hmac", "bidder-token",
+		"s3-secret", "source-token", "signing-secret", "debug-secret", "admin-token"} {
+		assert.NotContains(t, dump, secret)
+	}
+	// 非敏感字段保持原样
+	assert.Contains(t, dump, "10.0.0.0/8")
+	assert.Contains(t, dump, "ops")
+
+	// 原配置不受影响
+	assert.Equal(t, "ssp-token", cfg.SSPs[0].Auth.Token)
+	assert.Equal(t, "source-token", cfg.DSPSource.HTTP.Headers["X-Token"])
+	assert.Equal(t, "admin-token", cfg.Admin.Tokens[0].Token)
+}
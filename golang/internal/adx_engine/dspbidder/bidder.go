@@ -1,12 +1,18 @@
 package dspbidder
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"time"
 
 	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/pkg/pricecrypto"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"github.com/echoface/admux/pkg/tracing"
 )
 
 // BaseBidder 基础DSP bidder实现
@@ -16,6 +22,11 @@ type BaseBidder struct {
 	QPSLimit int
 	Healthy  bool
 	Timeout  time.Duration
+
+	// AllowedDeviceIDs 允许下发的设备ID形式
+	AllowedDeviceIDs []string
+	// PriceCodec 价格宏加密，为空时明文
+	PriceCodec pricecrypto.Codec
 }
 
 // NewBaseBidder 创建基础bidder
@@ -35,6 +46,9 @@ func (b *BaseBidder) GetInfo() *adxcore.BidderInfo {
 		ID:       b.BidderID,
 		QPS:      b.QPSLimit,
 		Endpoint: b.Endpoint,
+
+		AllowedDeviceIDs: b.AllowedDeviceIDs,
+		PriceCodec:       b.PriceCodec,
 	}
 }
 
@@ -76,9 +90,17 @@ func (b *BaseBidder) SetHealthStatus(healthy bool) {
 	b.Healthy = healthy
 }
 
-// HTTPBidder HTTP实现的DSP bidder
+// maxResponseSize DSP响应体大小上限
+const maxResponseSize = 1 << 20
+
+// HTTPBidder HTTP实现的DSP bidder，按 Encoding 以JSON或protobuf发送OpenRTB请求
 type HTTPBidder struct {
 	BaseBidder
+	// Encoding 请求编码，为空时使用JSON；响应按其 Content-Type 解析
+	Encoding string
+	// AuthToken 非空时以 Bearer token 下发
+	AuthToken string
+
 	client HTTPClient
 }
 
@@ -101,22 +123,37 @@ func NewHTTPBidder(bidderID, endpoint string, qpsLimit int, timeout time.Duratio
 	}
 }
 
-// SendBidRequest 发送HTTP竞价请求
+// SendBidRequest 发送HTTP竞价请求；204或空响应体表示不出价
 func (h *HTTPBidder) SendBidRequest(bidRequest *adxcore.BidRequestCtx) ([]*adxcore.BidCandidate, error) {
 	// 检查上下文是否已取消
 	if bidRequest.Context.Err() != nil {
 		return nil, bidRequest.Context.Err()
 	}
 
+	body, err := Marshal(h.Encoding, bidRequest.Request)
+	if err != nil {
+		return nil, fmt.Errorf("marshal bid request: %w", err)
+	}
+
+	ctx := bidRequest.Context
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
 	// 创建HTTP请求
-	req, err := http.NewRequestWithContext(bidRequest.Context, "POST", h.Endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Endpoint, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	// 这里可以添加认证头等
+	req.Header.Set("Content-Type", ContentType(h.Encoding))
+	req.Header.Set("Accept", ContentType(h.Encoding))
+	if h.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.AuthToken)
+	}
+	// 透传本次DSP调用的 traceparent
+	tracing.Inject(ctx, req.Header)
 
 	// 发送请求
 	resp, err := h.client.Do(req)
@@ -126,17 +163,38 @@ func (h *HTTPBidder) SendBidRequest(bidRequest *adxcore.BidRequestCtx) ([]*adxco
 	defer resp.Body.Close()
 
 	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		adxcore.CaptureResponse(ctx, resp.StatusCode, nil)
+		return nil, nil
+	default:
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		adxcore.CaptureResponse(ctx, resp.StatusCode, data)
 		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
 	}
 
-	// 解析响应
-	// 这里需要根据具体的DSP响应格式进行解析
-	// 目前返回模拟数据
-	candidate := &adxcore.BidCandidate{
-		Response: nil, // TODO: 解析真实的DSP响应
-		CPMPrice: int64(rand.Intn(1000)),
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read bid response: %w", err)
+	}
+	// 调试请求记录原始响应
+	adxcore.CaptureResponse(ctx, resp.StatusCode, data)
+	if len(data) > maxResponseSize {
+		return nil, fmt.Errorf("bid response exceeds %d bytes", maxResponseSize)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
 	}
 
-	return []*adxcore.BidCandidate{candidate}, nil
+	// 解析响应，未声明 Content-Type 时按请求编码解析
+	encoding := h.Encoding
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		encoding = EncodingOf(contentType)
+	}
+	bidResp := &admux_rtb.BidResponse{}
+	if err := Unmarshal(encoding, data, bidResp); err != nil {
+		return nil, fmt.Errorf("parse bid response: %w", err)
+	}
+	return CandidatesFromResponse(h.BidderID, bidResp), nil
 }
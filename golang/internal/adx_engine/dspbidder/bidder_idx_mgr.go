@@ -2,23 +2,38 @@ package dspbidder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/echoface/admux/internal/adx_engine/adxcore"
 	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+	"github.com/echoface/admux/pkg/pricecrypto"
 )
 
+// fieldIndexPattern 去掉字段路径中的数组下标，避免指标标签基数膨胀
+var fieldIndexPattern = regexp.MustCompile(`\[\d+\]`)
+
 // BidderIndexManager DSP索引管理器
 type BidderIndexManager struct {
 	config       *config.AdxServerConfig
-	configLoader *ConfigLoader
+	source       DSPConfigSource
 	indexBuilder *IndexBuilder
 	dynamicCache *DSPDynamicCache
 	factory      *adxcore.BidderFactory
 	indexPath    string
+	// httpClient 所有HTTP bidder共享的连接池
+	httpClient *http.Client
 
 	// 运行时状态
 	ctx    context.Context
@@ -26,32 +41,42 @@ type BidderIndexManager struct {
 	mu     sync.RWMutex
 	wg     sync.WaitGroup
 
-	// 监控
-	lastScanTime time.Time
-	scanCount    int64
-	errorCount   int64
+	// 监控，扫描计数在配置推送、手动重扫和指标查询间并发读写
+	lastScanTime atomic.Int64 // UnixNano
+	scanCount    atomic.Int64
+	errorCount   atomic.Int64
+	metrics      *metrics.DSPConfigMetrics
+
+	// 当前已注册Bidder对应的DSP配置，用于判断配置是否变化
+	bidderMu      sync.Mutex
+	bidderConfigs map[string]*DSPInfo
+
+	// 最近一次扫描被隔离的配置文件
+	quarantineMu sync.RWMutex
+	quarantined  []*QuarantinedDSPFile
+
+	// scanMu 串行化配置源推送和手动触发的重新扫描
+	scanMu sync.Mutex
+	// lastGood 最近一次生效的DSP配置，配置文件被隔离时沿用其中的上次有效配置，由 scanMu 保护
+	lastGood map[string]*DSPInfo
+
+	// 运维暂停的DSP，索引重建不影响暂停状态
+	pausedMu sync.RWMutex
+	paused   map[string]bool
 }
 
-// NewBidderIndexManager 创建DSP索引管理器
-func NewBidderIndexManager(cfg *config.AdxServerConfig) (*BidderIndexManager, error) {
-	ctx, cancel := context.WithCancel(context.Background())
+// ErrDSPNotFound DSP不存在
+var ErrDSPNotFound = errors.New("dsp not found")
 
-	// 创建DSP配置加载器
-	s3Config := &S3Config{
-		Endpoint:        cfg.S3.Endpoint,
-		AccessKeyID:     cfg.S3.AccessKeyID,
-		SecretAccessKey: cfg.S3.SecretAccessKey,
-		BucketName:      cfg.S3.BucketName,
-		Prefix:          cfg.S3.Prefix,
-		UseSSL:          cfg.S3.UseSSL,
-		ScanInterval:    cfg.S3.ScanInterval,
-		Timeout:         10 * time.Second,
-	}
+// NewBidderIndexManager 创建DSP索引管理器，reg为nil时指标注册到默认Registry
+func NewBidderIndexManager(cfg *config.AdxServerConfig, reg prometheus.Registerer) (*BidderIndexManager, error) {
+	ctx, cancel := context.WithCancel(context.Background())
 
-	configLoader, err := NewConfigLoader(s3Config)
+	// 根据配置选择DSP配置源
+	source, err := NewDSPConfigSource(cfg)
 	if err != nil {
 		cancel()
-		return nil, fmt.Errorf("failed to create ConfigLoader: %w", err)
+		return nil, fmt.Errorf("failed to create DSP config source: %w", err)
 	}
 
 	// 创建索引构建器
@@ -64,14 +89,18 @@ func NewBidderIndexManager(cfg *config.AdxServerConfig) (*BidderIndexManager, er
 	factory := adxcore.GetGlobalBidderFactory()
 
 	mgr := &BidderIndexManager{
-		config:       cfg,
-		configLoader: configLoader,
-		indexBuilder: indexBuilder,
-		dynamicCache: dynamicCache,
-		factory:      factory,
-		indexPath:    "dsp_index.dat",
-		ctx:          ctx,
-		cancel:       cancel,
+		config:        cfg,
+		source:        source,
+		indexBuilder:  indexBuilder,
+		dynamicCache:  dynamicCache,
+		factory:       factory,
+		indexPath:     "dsp_index.dat",
+		httpClient:    newBidderHTTPClient(),
+		bidderConfigs: make(map[string]*DSPInfo),
+		paused:        make(map[string]bool),
+		metrics:       metrics.NewDSPConfigMetrics("adx", "dsp_config", reg),
+		ctx:           ctx,
+		cancel:        cancel,
 	}
 
 	return mgr, nil
@@ -103,6 +132,9 @@ func (m *BidderIndexManager) Stop() error {
 	log.Println("Stopping BidderIndexManager...")
 
 	m.cancel()
+	if err := m.source.Close(); err != nil {
+		log.Printf("Failed to close DSP source %s: %v", m.source.Name(), err)
+	}
 
 	done := make(chan struct{})
 	go func() {
@@ -126,30 +158,38 @@ func (m *BidderIndexManager) initialLoad() error {
 	// 尝试加载现有索引
 	if m.indexBuilder.LoadIndex(m.indexPath) == nil {
 		log.Println("Loaded existing index from disk")
+		m.lastGood = m.indexBuilder.GetAllDSPs()
+		if err := m.updateBidderRegistrations(m.lastGood); err != nil {
+			return fmt.Errorf("failed to register bidders: %w", err)
+		}
 		log.Println("Initial DSP load completed")
 		return nil
 	}
 
-	// 如果加载失败，从S3构建新索引
-	log.Println("No existing index, building from S3...")
-	return m.buildIndexFromS3()
+	// 如果加载失败，从配置源构建新索引
+	log.Printf("No existing index, building from %s...", m.source.Name())
+	return m.buildIndexFromSource()
 }
 
-// buildIndexFromS3 从S3构建索引
-func (m *BidderIndexManager) buildIndexFromS3() error {
-	log.Println("Loading DSPs from S3...")
+// buildIndexFromSource 从DSP配置源构建索引
+func (m *BidderIndexManager) buildIndexFromSource() error {
+	log.Printf("Loading DSPs from %s...", m.source.Name())
 
-	dspMap, err := m.configLoader.ReadAllDSPs()
+	result, err := m.source.ReadAllDSPs()
 	if err != nil {
 		return fmt.Errorf("failed to read DSPs: %w", err)
 	}
+	m.recordQuarantine(result.Quarantined)
 
+	dspMap := result.DSPs
+	m.lastGood = dspMap
+	m.metrics.LoadedDSPs.Set(float64(len(dspMap)))
 	if len(dspMap) == 0 {
-		log.Println("No DSPs found in S3")
+		log.Printf("No DSPs found in %s", m.source.Name())
 		return nil
 	}
 
-	log.Printf("Found %d DSPs from S3", len(dspMap))
+	log.Printf("Found %d DSPs from %s", len(dspMap), m.source.Name())
 
 	// 构建索引
 	if err := m.indexBuilder.BuildDSPIndex(dspMap); err != nil {
@@ -162,66 +202,62 @@ func (m *BidderIndexManager) buildIndexFromS3() error {
 	}
 
 	// 注册Bidder
-	if err := m.registerBidders(dspMap); err != nil {
+	if err := m.updateBidderRegistrations(dspMap); err != nil {
 		return fmt.Errorf("failed to register bidders: %w", err)
 	}
 
-	m.lastScanTime = time.Now()
-	m.scanCount++
+	m.markScanned()
 
 	log.Println("Initial DSP load completed")
 	return nil
 }
 
-// registerBidders 注册DSP Bidder到工厂
-func (m *BidderIndexManager) registerBidders(dspMap map[string]*DSPInfo) error {
-	for dspID, dspInfo := range dspMap {
-		if dspInfo.Status != "active" {
-			continue
-		}
-
-		// 创建Bidder实例
-		bidder, err := m.createBidder(dspInfo)
-		if err != nil {
-			log.Printf("Failed to create bidder for %s: %v", dspID, err)
-			continue
-		}
-
-		// 注册到工厂
-		if err := m.factory.RegisterBidder(bidder); err != nil {
-			log.Printf("Failed to register bidder %s: %v", dspID, err)
-		} else {
-			log.Printf("Registered bidder: %s (%s)", dspID, dspInfo.DSPName)
-		}
-	}
-
-	return nil
-}
-
 // createBidder 创建Bidder实例
 func (m *BidderIndexManager) createBidder(dspInfo *DSPInfo) (adxcore.Bidder, error) {
-	// 创建BaseBidder（使用现有实现）
-	bidder := NewBaseBidder(
+	bidder := NewHTTPBidder(
 		dspInfo.DSPID,
 		dspInfo.Endpoint,
 		dspInfo.QPSLimit,
 		dspInfo.Timeout,
+		m.httpClient,
 	)
+	bidder.Encoding = dspInfo.Encoding
+	bidder.AuthToken = dspInfo.AuthToken
+	bidder.AllowedDeviceIDs = dspInfo.AllowedDeviceIDs
+	if dspInfo.PriceCrypto != nil {
+		codec, err := pricecrypto.New(*dspInfo.PriceCrypto)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price_crypto: %w", err)
+		}
+		bidder.PriceCodec = codec
+	}
 
 	return bidder, nil
 }
 
-// scanLoop 定时扫描循环
+// newBidderHTTPClient DSP请求的HTTP客户端，超时由各bidder按DSP配置控制
+func newBidderHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConns:        1000,
+			MaxIdleConnsPerHost: 100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// scanLoop 监听配置源变化循环
 func (m *BidderIndexManager) scanLoop() {
 	defer m.wg.Done()
 
-	ticker := time.NewTicker(m.config.S3.ScanInterval)
-	defer ticker.Stop()
-
+	changeCh := m.source.WatchDSPChanges()
 	for {
 		select {
-		case <-ticker.C:
-			m.scanAndUpdate()
+		case result, ok := <-changeCh:
+			if !ok {
+				return
+			}
+			m.applyScanResult(result)
 		case <-m.ctx.Done():
 			return
 		}
@@ -230,20 +266,122 @@ func (m *BidderIndexManager) scanLoop() {
 
 // scanAndUpdate 扫描并更新DSP索引
 func (m *BidderIndexManager) scanAndUpdate() {
-	log.Println("Scanning DSPs from S3...")
+	if _, err := m.Rescan(); err != nil {
+		log.Printf("Failed to scan DSPs: %v", err)
+	}
+}
+
+// Rescan 立即从配置源全量读取DSP配置并重建索引，返回本次扫描结果
+func (m *BidderIndexManager) Rescan() (*DSPScanResult, error) {
+	log.Printf("Scanning DSPs from %s...", m.source.Name())
 
-	dspMap, err := m.configLoader.ReadAllDSPs()
+	result, err := m.source.ReadAllDSPs()
 	if err != nil {
-		log.Printf("Failed to scan DSPs: %v", err)
-		m.errorCount++
-		return
+		m.errorCount.Add(1)
+		return nil, fmt.Errorf("failed to read DSPs: %w", err)
+	}
+
+	m.applyScanResult(result)
+	return result, nil
+}
+
+// applyScanResult 应用一次扫描结果，隔离文件只记录不参与索引构建
+func (m *BidderIndexManager) applyScanResult(result *DSPScanResult) {
+	m.scanMu.Lock()
+	defer m.scanMu.Unlock()
+
+	dspMap, quarantined := m.retainLastGood(result)
+	m.recordQuarantine(quarantined)
+	m.metrics.LoadedDSPs.Set(float64(len(dspMap)))
+	m.applyDSPs(dspMap)
+	m.lastGood = dspMap
+}
+
+// retainLastGood 此前有效的配置文件被隔离时，该DSP沿用上次有效配置而不是被下线；
+// 返回生效的DSP配置和标记了沿用状态的隔离列表，不修改扫描结果本身
+func (m *BidderIndexManager) retainLastGood(result *DSPScanResult) (map[string]*DSPInfo, []*QuarantinedDSPFile) {
+	dspMap, quarantined := result.DSPs, result.Quarantined
+
+	fileDSPs := make(map[string]string, len(m.lastGood))
+	for dspID, dspInfo := range m.lastGood {
+		if dspInfo.SourceFile != "" {
+			fileDSPs[dspInfo.SourceFile] = dspID
+		}
 	}
 
+	cloned := false
+	for i, file := range result.Quarantined {
+		dspID, ok := fileDSPs[sourceFileKey(file.Source, file.Key)]
+		if !ok {
+			continue
+		}
+		if _, exists := dspMap[dspID]; exists {
+			continue
+		}
+		if !cloned {
+			dspMap, quarantined = maps.Clone(dspMap), slices.Clone(quarantined)
+			cloned = true
+		}
+		dspMap[dspID] = m.lastGood[dspID]
+		retained := *file
+		retained.Retained = true
+		quarantined[i] = &retained
+		log.Printf("DSP %s keeps its last valid config while %s is quarantined", dspID, file.Key)
+	}
+	return dspMap, quarantined
+}
+
+// recordQuarantine 记录隔离文件并更新指标；校验失败只在文件新被隔离或内容变化时计数
+func (m *BidderIndexManager) recordQuarantine(quarantined []*QuarantinedDSPFile) {
+	m.quarantineMu.Lock()
+	previous := m.quarantined
+	m.quarantined = quarantined
+	m.quarantineMu.Unlock()
+
+	// 重置上一轮涉及的配置源，已修复的配置源归零
+	seen := make(map[string]string, len(previous))
+	for _, file := range previous {
+		m.metrics.QuarantinedFiles.WithLabelValues(file.Source).Set(0)
+		seen[sourceFileKey(file.Source, file.Key)] = file.digest
+	}
+
+	counts := make(map[string]int)
+	for _, file := range quarantined {
+		counts[file.Source]++
+		if digest, ok := seen[sourceFileKey(file.Source, file.Key)]; ok && digest == file.digest {
+			continue
+		}
+		for _, fieldErr := range file.Errors {
+			field := fieldIndexPattern.ReplaceAllString(fieldErr.Field, "")
+			m.metrics.ValidationFailures.WithLabelValues(file.Source, field).Inc()
+		}
+	}
+	for source, count := range counts {
+		m.metrics.QuarantinedFiles.WithLabelValues(source).Set(float64(count))
+	}
+
+	if len(quarantined) > 0 {
+		log.Printf("%d DSP config files quarantined", len(quarantined))
+	}
+}
+
+// GetQuarantinedDSPs 获取最近一次扫描被隔离的配置文件
+func (m *BidderIndexManager) GetQuarantinedDSPs() []*QuarantinedDSPFile {
+	m.quarantineMu.RLock()
+	defer m.quarantineMu.RUnlock()
+
+	result := make([]*QuarantinedDSPFile, len(m.quarantined))
+	copy(result, m.quarantined)
+	return result
+}
+
+// applyDSPs 使用全量DSP配置重建索引并更新Bidder注册
+func (m *BidderIndexManager) applyDSPs(dspMap map[string]*DSPInfo) {
 	// 构建新索引
 	log.Println("Building new index...")
 	if err := m.indexBuilder.BuildDSPIndex(dspMap); err != nil {
 		log.Printf("Failed to build new index: %v", err)
-		m.errorCount++
+		m.errorCount.Add(1)
 		return
 	}
 
@@ -253,49 +391,85 @@ func (m *BidderIndexManager) scanAndUpdate() {
 	}
 
 	// 更新Bidder注册
-	m.updateBidderRegistrations(dspMap)
+	if err := m.updateBidderRegistrations(dspMap); err != nil {
+		log.Printf("Failed to update bidder registrations: %v", err)
+		m.errorCount.Add(1)
+		return
+	}
 
 	log.Printf("DSP index updated successfully, total DSPs: %d", len(dspMap))
 
-	m.lastScanTime = time.Now()
-	m.scanCount++
+	m.markScanned()
 }
 
-// updateBidderRegistrations 更新Bidder注册
-func (m *BidderIndexManager) updateBidderRegistrations(dspMap map[string]*DSPInfo) {
-	// 记录已注册的DSP
-	registeredDSPs := make(map[string]bool)
+// updateBidderRegistrations 按全量DSP配置整体替换Bidder注册
+// 配置未变化的DSP复用原Bidder实例，变化的重新创建，已删除或非活跃的DSP被移除；
+// 新注册集合一次性切换，进行中的请求继续使用已获取的旧实例
+func (m *BidderIndexManager) updateBidderRegistrations(dspMap map[string]*DSPInfo) error {
+	m.bidderMu.Lock()
+	defer m.bidderMu.Unlock()
+
+	bidders := make([]adxcore.Bidder, 0, len(dspMap))
+	configs := make(map[string]*DSPInfo, len(dspMap))
 
-	// 添加/更新DSP
 	for dspID, dspInfo := range dspMap {
-		if dspInfo.Status != "active" {
-			// 如果状态变为非活跃，取消注册
-			if m.factory.HasBidder(dspID) {
-				if err := m.factory.UnregisterBidder(dspID); err != nil {
-					log.Printf("Failed to unregister bidder %s: %v", dspID, err)
-				} else {
-					log.Printf("Unregistered bidder: %s", dspID)
-				}
-			}
+		if dspInfo.Status != DSPStatusActive {
 			continue
 		}
 
-		// 创建或更新Bidder
+		// 配置未变化时复用现有Bidder，保留其运行时状态
+		if prev, exists := m.bidderConfigs[dspID]; exists && !bidderConfigChanged(prev, dspInfo) {
+			if bidder, err := m.factory.GetBidder(dspID); err == nil {
+				bidders = append(bidders, bidder)
+				configs[dspID] = prev
+				continue
+			}
+		}
+
 		bidder, err := m.createBidder(dspInfo)
 		if err != nil {
 			log.Printf("Failed to create bidder for %s: %v", dspID, err)
 			continue
 		}
+		bidders = append(bidders, bidder)
+		configs[dspID] = dspInfo
 
-		// 注册Bidder
-		if err := m.factory.RegisterBidder(bidder); err != nil {
-			// 如果注册失败，可能已存在，尝试更新
-			log.Printf("Failed to register bidder %s: %v", dspID, err)
+		if _, exists := m.bidderConfigs[dspID]; exists {
+			log.Printf("Updated bidder: %s (%s)", dspID, dspInfo.DSPName)
 		} else {
 			log.Printf("Registered bidder: %s (%s)", dspID, dspInfo.DSPName)
-			registeredDSPs[dspID] = true
 		}
 	}
+
+	if err := m.factory.SwapBidders(bidders); err != nil {
+		return err
+	}
+
+	for dspID := range m.bidderConfigs {
+		if _, exists := configs[dspID]; !exists {
+			log.Printf("Unregistered bidder: %s", dspID)
+		}
+	}
+	m.bidderConfigs = configs
+	return nil
+}
+
+// bidderConfigChanged 判断影响Bidder实例的配置项是否变化
+func bidderConfigChanged(prev, next *DSPInfo) bool {
+	return prev.Endpoint != next.Endpoint ||
+		prev.QPSLimit != next.QPSLimit ||
+		prev.Timeout != next.Timeout ||
+		prev.AuthToken != next.AuthToken ||
+		prev.Encoding != next.Encoding ||
+		!slices.Equal(prev.AllowedDeviceIDs, next.AllowedDeviceIDs) ||
+		!priceCryptoEqual(prev.PriceCrypto, next.PriceCrypto)
+}
+
+func priceCryptoEqual(prev, next *pricecrypto.Config) bool {
+	if prev == nil || next == nil {
+		return prev == next
+	}
+	return *prev == *next
 }
 
 // qpsResetLoop QPS重置循环
@@ -343,7 +517,12 @@ func (m *BidderIndexManager) GetAllDSPs() map[string]*DSPInfo {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// 使用be_indexer获取所有DSP信息
+	return m.allDSPsLocked()
+}
+
+// allDSPsLocked 使用be_indexer获取所有DSP信息，调用方需持有 m.mu 读锁；
+// 读锁不可重入，持锁时不能再调用 GetAllDSPs，否则与等待中的写锁互相阻塞
+func (m *BidderIndexManager) allDSPsLocked() map[string]*DSPInfo {
 	if m.indexBuilder != nil {
 		return m.indexBuilder.GetAllDSPs()
 	}
@@ -357,7 +536,7 @@ func (m *BidderIndexManager) GetAllActiveDSPs() map[string]*DSPInfo {
 	defer m.mu.RUnlock()
 
 	// 使用be_indexer获取所有DSP信息，然后过滤活跃状态
-	dspMap := m.GetAllDSPs()
+	dspMap := m.allDSPsLocked()
 	if dspMap == nil {
 		return nil
 	}
@@ -409,18 +588,64 @@ func (m *BidderIndexManager) GetDSPBudget(dspID string) (*DSPBudget, bool) {
 	return m.dynamicCache.GetDSPBudget(dspID)
 }
 
+// GetIndexStats 获取DSP索引统计
+func (m *BidderIndexManager) GetIndexStats() map[string]interface{} {
+	return m.indexBuilder.GetIndexStats()
+}
+
+// PauseDSP 暂停DSP竞价直到ResumeDSP，暂停期间定向命中也不会被调用
+func (m *BidderIndexManager) PauseDSP(dspID string) error {
+	return m.setPaused(dspID, true)
+}
+
+// ResumeDSP 恢复已暂停DSP的竞价
+func (m *BidderIndexManager) ResumeDSP(dspID string) error {
+	return m.setPaused(dspID, false)
+}
+
+func (m *BidderIndexManager) setPaused(dspID string, paused bool) error {
+	if _, ok := m.GetDSP(dspID); !ok {
+		return fmt.Errorf("%w: %s", ErrDSPNotFound, dspID)
+	}
+
+	m.pausedMu.Lock()
+	defer m.pausedMu.Unlock()
+	if paused {
+		m.paused[dspID] = true
+	} else {
+		delete(m.paused, dspID)
+	}
+	return nil
+}
+
+// IsDSPPaused DSP是否已暂停
+func (m *BidderIndexManager) IsDSPPaused(dspID string) bool {
+	m.pausedMu.RLock()
+	defer m.pausedMu.RUnlock()
+	return m.paused[dspID]
+}
+
+// markScanned 记录一次成功扫描
+func (m *BidderIndexManager) markScanned() {
+	m.lastScanTime.Store(time.Now().UnixNano())
+	m.scanCount.Add(1)
+}
+
 // GetMetrics 获取指标
 func (m *BidderIndexManager) GetMetrics() *BidderIndexManagerMetrics {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	var lastScanTime time.Time
+	if nanos := m.lastScanTime.Load(); nanos > 0 {
+		lastScanTime = time.Unix(0, nanos)
+	}
 
 	return &BidderIndexManagerMetrics{
-		LastScanTime:   m.lastScanTime,
-		ScanCount:      m.scanCount,
-		ErrorCount:     m.errorCount,
+		LastScanTime:   lastScanTime,
+		ScanCount:      m.scanCount.Load(),
+		ErrorCount:     m.errorCount.Load(),
 		CurrentDSPs:    m.indexBuilder.GetDSPCount(),
 		ActiveDSPs:     len(m.GetAllActiveDSPs()),
 		RegisteredDSPs: m.factory.BidderCount(),
+		Quarantined:    len(m.GetQuarantinedDSPs()),
 		CacheMetrics:   m.dynamicCache.GetMetrics(),
 	}
 }
@@ -433,6 +658,7 @@ type BidderIndexManagerMetrics struct {
 	CurrentDSPs    int                  `json:"current_dsp_count"`
 	ActiveDSPs     int                  `json:"active_dsp_count"`
 	RegisteredDSPs int                  `json:"registered_dsp_count"`
+	Quarantined    int                  `json:"quarantined_file_count"`
 	CacheMetrics   *DynamicCacheMetrics `json:"cache_metrics"`
 }
 
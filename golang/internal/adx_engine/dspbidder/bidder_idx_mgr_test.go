@@ -0,0 +1,176 @@
+package dspbidder
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+)
+
+func TestBidderIndexManager_UpdateBidderRegistrations(t *testing.T) {
+	mgr := &BidderIndexManager{
+		factory:       adxcore.NewBidderFactory(),
+		bidderConfigs: make(map[string]*DSPInfo),
+	}
+
+	require.NoError(t, mgr.updateBidderRegistrations(map[string]*DSPInfo{
+		"dsp_001": {DSPID: "dsp_001", Status: DSPStatusActive, Endpoint: "http://v1", Timeout: 100 * time.Millisecond},
+		"dsp_002": {DSPID: "dsp_002", Status: DSPStatusActive, Endpoint: "http://v1", QPSLimit: 10},
+		"dsp_003": {DSPID: "dsp_003", Status: DSPStatusActive, Endpoint: "http://v1"},
+	}))
+	assert.Equal(t, 3, mgr.factory.BidderCount())
+	unchanged, err := mgr.factory.GetBidder("dsp_002")
+	require.NoError(t, err)
+
+	// dsp_001 修改endpoint，dsp_002 仅修改名称和未被Bidder使用的重试配置，dsp_003 被删除，dsp_004 暂停
+	require.NoError(t, mgr.updateBidderRegistrations(map[string]*DSPInfo{
+		"dsp_001": {DSPID: "dsp_001", Status: DSPStatusActive, Endpoint: "http://v2", Timeout: 100 * time.Millisecond},
+		"dsp_002": {DSPID: "dsp_002", DSPName: "renamed", Status: DSPStatusActive, Endpoint: "http://v1", QPSLimit: 10, RetryCount: 3},
+		"dsp_004": {DSPID: "dsp_004", Status: DSPStatusPaused, Endpoint: "http://v1"},
+	}))
+	assert.Equal(t, 2, mgr.factory.BidderCount())
+	assert.False(t, mgr.factory.HasBidder("dsp_003"))
+	assert.False(t, mgr.factory.HasBidder("dsp_004"))
+
+	updated, err := mgr.factory.GetBidder("dsp_001")
+	require.NoError(t, err)
+	assert.Equal(t, "http://v2", updated.GetInfo().Endpoint)
+
+	reused, err := mgr.factory.GetBidder("dsp_002")
+	require.NoError(t, err)
+	assert.Same(t, unchanged, reused)
+}
+
+func TestBidderIndexManager_ConcurrentScanMetrics(t *testing.T) {
+	mgr := &BidderIndexManager{
+		source:        &staticSource{name: "broken", err: errors.New("unavailable")},
+		indexBuilder:  NewIndexBuilder(),
+		dynamicCache:  NewDSPDynamicCache(16),
+		factory:       adxcore.NewBidderFactory(),
+		indexPath:     filepath.Join(t.TempDir(), "dsp_index.dat"),
+		bidderConfigs: make(map[string]*DSPInfo),
+		paused:        make(map[string]bool),
+		metrics:       metrics.NewDSPConfigMetrics("adx", "dsp_config", prometheus.NewRegistry()),
+	}
+	result := &DSPScanResult{DSPs: map[string]*DSPInfo{
+		"dsp_001": {DSPID: "dsp_001", Status: DSPStatusActive, Endpoint: "http://v1"},
+	}}
+
+	// 配置推送、手动重扫失败和指标查询并发进行，配合 -race 检查计数读写
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			mgr.applyScanResult(result)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := mgr.Rescan()
+			assert.Error(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			mgr.GetMetrics()
+		}()
+	}
+	wg.Wait()
+
+	got := mgr.GetMetrics()
+	assert.EqualValues(t, 4, got.ScanCount)
+	assert.EqualValues(t, 4, got.ErrorCount)
+	assert.False(t, got.LastScanTime.IsZero())
+}
+
+func TestBidderIndexManager_QuarantineKeepsLastGood(t *testing.T) {
+	mgr := &BidderIndexManager{
+		indexBuilder:  NewIndexBuilder(),
+		dynamicCache:  NewDSPDynamicCache(16),
+		factory:       adxcore.NewBidderFactory(),
+		bidderConfigs: make(map[string]*DSPInfo),
+		paused:        make(map[string]bool),
+		metrics:       metrics.NewDSPConfigMetrics("adx", "dsp_config", prometheus.NewRegistry()),
+	}
+	files := map[string]string{
+		"dsp_001.json": `{"dsp_id":"dsp_001","status":"active","endpoint":"http://v1"}`,
+		"dsp_002.json": `{"dsp_id":"dsp_002","status":"active","endpoint":"http://v1"}`,
+	}
+	scan := func() *DSPScanResult {
+		return collectDSPs("local", slices.Sorted(maps.Keys(files)), func(key string) ([]byte, error) {
+			return []byte(files[key]), nil
+		})
+	}
+
+	mgr.applyScanResult(scan())
+	require.True(t, mgr.factory.HasBidder("dsp_001"))
+
+	// dsp_001 被改坏，沿用上次有效配置继续竞价
+	files["dsp_001.json"] = `{"dsp_id":"dsp_001","status":"active","endpoint":"ftp://v2"}`
+	mgr.applyScanResult(scan())
+	assert.True(t, mgr.factory.HasBidder("dsp_001"))
+	assert.Equal(t, "http://v1", mgr.bidderConfigs["dsp_001"].Endpoint)
+	quarantined := mgr.GetQuarantinedDSPs()
+	require.Len(t, quarantined, 1)
+	assert.True(t, quarantined[0].Retained)
+
+	// 再次扫描仍然沿用
+	mgr.applyScanResult(scan())
+	assert.True(t, mgr.factory.HasBidder("dsp_001"))
+
+	// 修复后使用新配置
+	files["dsp_001.json"] = `{"dsp_id":"dsp_001","status":"active","endpoint":"http://v3"}`
+	mgr.applyScanResult(scan())
+	assert.Equal(t, "http://v3", mgr.bidderConfigs["dsp_001"].Endpoint)
+	assert.Empty(t, mgr.GetQuarantinedDSPs())
+
+	// 从未合法过的文件不会被沿用，删除的文件正常下线
+	files["dsp_003.json"] = `{"dsp_id":"dsp_003","status":"active"}`
+	delete(files, "dsp_002.json")
+	mgr.applyScanResult(scan())
+	assert.False(t, mgr.factory.HasBidder("dsp_003"))
+	assert.False(t, mgr.factory.HasBidder("dsp_002"))
+	quarantined = mgr.GetQuarantinedDSPs()
+	require.Len(t, quarantined, 1)
+	assert.False(t, quarantined[0].Retained)
+}
+
+func TestBidderIndexManager_ValidationFailuresCountedOnce(t *testing.T) {
+	mgr := &BidderIndexManager{
+		metrics: metrics.NewDSPConfigMetrics("adx", "dsp_config", prometheus.NewRegistry()),
+	}
+	content := `{"dsp_id":"dsp_001","status":"active","endpoint":"ftp://v1"}`
+	scan := func() []*QuarantinedDSPFile {
+		return collectDSPs("local", []string{"dsp_001.json"}, func(string) ([]byte, error) {
+			return []byte(content), nil
+		}).Quarantined
+	}
+	failures := mgr.metrics.ValidationFailures.WithLabelValues("local", "endpoint")
+
+	// 同一份坏文件重复扫描只计一次
+	for range 3 {
+		mgr.recordQuarantine(scan())
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(failures))
+
+	// 内容变化后重新计数
+	content = `{"dsp_id":"dsp_001","status":"active","endpoint":"ftp://v2"}`
+	mgr.recordQuarantine(scan())
+	assert.Equal(t, 2.0, testutil.ToFloat64(failures))
+
+	// 修复后再次改坏也重新计数
+	mgr.recordQuarantine(nil)
+	mgr.recordQuarantine(scan())
+	assert.Equal(t, 3.0, testutil.ToFloat64(failures))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mgr.metrics.QuarantinedFiles.WithLabelValues("local")))
+}
@@ -3,7 +3,6 @@ package dspbidder
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -11,14 +10,18 @@ import (
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/echoface/admux/pkg/pricecrypto"
 )
 
-// ConfigLoader DSP配置加载器
+// ConfigLoader 基于S3兼容存储的DSP配置源
 type ConfigLoader struct {
-	client     *minio.Client
-	bucketName string
-	prefix     string
-	ctx        context.Context
+	client       *minio.Client
+	bucketName   string
+	prefix       string
+	scanInterval time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
 }
 
 // S3Config S3配置
@@ -35,8 +38,6 @@ type S3Config struct {
 
 // NewConfigLoader 创建DSP配置加载器
 func NewConfigLoader(config *S3Config) (*ConfigLoader, error) {
-	ctx := context.Background()
-
 	client, err := minio.New(config.Endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
 		Secure: config.UseSSL,
@@ -45,30 +46,57 @@ func NewConfigLoader(config *S3Config) (*ConfigLoader, error) {
 		return nil, fmt.Errorf("failed to create S3 client: %w", err)
 	}
 
+	scanInterval := config.ScanInterval
+	if scanInterval <= 0 {
+		scanInterval = defaultDSPScanInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ConfigLoader{
-		client:     client,
-		bucketName: config.BucketName,
-		prefix:     config.Prefix,
-		ctx:        ctx,
+		client:       client,
+		bucketName:   config.BucketName,
+		prefix:       config.Prefix,
+		scanInterval: scanInterval,
+		ctx:          ctx,
+		cancel:       cancel,
 	}, nil
 }
 
+// Name 返回配置源名称
+func (s *ConfigLoader) Name() string {
+	return DSPSourceS3
+}
+
+// Close 停止监控
+func (s *ConfigLoader) Close() error {
+	s.cancel()
+	return nil
+}
+
 // DSPInfo DSP配置信息
 type DSPInfo struct {
-	DSPID       string                 `json:"dsp_id"`
-	DSPName     string                 `json:"dsp_name"`
-	Status      string                 `json:"status"`
-	QPSLimit    int                    `json:"qps_limit"`
-	BudgetDaily float64                `json:"budget_daily"`
-	Endpoint    string                 `json:"endpoint"`
-	AuthToken   string                 `json:"auth_token"`
-	Timeout     time.Duration          `json:"timeout"`
-	RetryCount  int                    `json:"retry_count"`
-	RetryDelay  time.Duration          `json:"retry_delay"`
-	Targeting   *DSPTargeting          `json:"targeting,omitempty"`
+	DSPID       string  `json:"dsp_id"`
+	DSPName     string  `json:"dsp_name"`
+	Status      string  `json:"status"`
+	QPSLimit    int     `json:"qps_limit"`
+	BudgetDaily float64 `json:"budget_daily"`
+	Endpoint    string  `json:"endpoint"`
+	AuthToken   string  `json:"auth_token"`
+	// Encoding 竞价请求编码 json(默认)/protobuf
+	Encoding   string        `json:"encoding,omitempty"`
+	Timeout    time.Duration `json:"timeout"`
+	RetryCount int           `json:"retry_count"`
+	RetryDelay time.Duration `json:"retry_delay"`
+	Targeting  *DSPTargeting `json:"targeting,omitempty"`
+	// AllowedDeviceIDs 允许下发的设备ID形式(如 imei_md5、oaid)，为空时使用 adxcore.DefaultAllowedDeviceIDs
+	AllowedDeviceIDs []string `json:"allowed_device_ids,omitempty"`
+	// PriceCrypto DSP要求的价格宏加密方式，为空时价格明文下发
+	PriceCrypto *pricecrypto.Config    `json:"price_crypto,omitempty"`
 	Filters     map[string]interface{} `json:"filters,omitempty"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 	Version     string                 `json:"version,omitempty"`
+	// SourceFile 定义该DSP的配置文件(配置源:key)，文件被隔离时据此沿用上次有效配置
+	SourceFile string `json:"-"`
 }
 
 // DSPTargeting DSP定向配置
@@ -118,6 +146,15 @@ func (s *ConfigLoader) ListDSPFiles() ([]string, error) {
 
 // ReadDSPFile 读取单个DSP配置文件
 func (s *ConfigLoader) ReadDSPFile(objectKey string) (*DSPInfo, error) {
+	data, err := s.readObject(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDSPInfo(objectKey, data)
+}
+
+// readObject 读取对象原始内容
+func (s *ConfigLoader) readObject(objectKey string) ([]byte, error) {
 	obj, err := s.client.GetObject(s.ctx, s.bucketName, objectKey, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object %s: %w", objectKey, err)
@@ -129,67 +166,41 @@ func (s *ConfigLoader) ReadDSPFile(objectKey string) (*DSPInfo, error) {
 		return nil, fmt.Errorf("failed to read object %s: %w", objectKey, err)
 	}
 
-	dspInfo := &DSPInfo{}
-	if err := json.Unmarshal(buf.Bytes(), dspInfo); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal DSP info from %s: %w", objectKey, err)
-	}
-
-	dspInfo.UpdatedAt = time.Now()
-	return dspInfo, nil
+	return buf.Bytes(), nil
 }
 
 // ReadAllDSPs 读取所有DSP配置
-func (s *ConfigLoader) ReadAllDSPs() (map[string]*DSPInfo, error) {
+func (s *ConfigLoader) ReadAllDSPs() (*DSPScanResult, error) {
 	objectKeys, err := s.ListDSPFiles()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list DSP files: %w", err)
 	}
 
-	dspMap := make(map[string]*DSPInfo)
-	for _, objectKey := range objectKeys {
-		dspInfo, err := s.ReadDSPFile(objectKey)
-		if err != nil {
-			// 记录错误但继续处理其他文件
-			fmt.Printf("failed to read DSP file %s: %v\n", objectKey, err)
-			continue
-		}
-
-		if dspInfo.DSPID == "" {
-			return nil, fmt.Errorf("DSP ID is empty in file %s", objectKey)
-		}
-
-		dspMap[dspInfo.DSPID] = dspInfo
-	}
-
-	return dspMap, nil
+	return collectDSPs(s.Name(), objectKeys, s.readObject), nil
 }
 
 // WatchDSPChanges 监控DSP配置变化
-func (s *ConfigLoader) WatchDSPChanges() <-chan map[string]*DSPInfo {
-	changeCh := make(chan map[string]*DSPInfo, 1)
+func (s *ConfigLoader) WatchDSPChanges() <-chan *DSPScanResult {
+	changeCh := make(chan *DSPScanResult, 1)
 
 	go func() {
-		ticker := time.NewTicker(time.Second * 30)
+		defer close(changeCh)
+
+		ticker := time.NewTicker(s.scanInterval)
 		defer ticker.Stop()
 
 		// 初始加载
-		dspMap, err := s.ReadAllDSPs()
+		result, err := s.ReadAllDSPs()
 		if err == nil {
-			select {
-			case changeCh <- dspMap:
-			default:
-			}
+			publishDSPSnapshot(changeCh, result)
 		}
 
 		for {
 			select {
 			case <-ticker.C:
-				newDspMap, err := s.ReadAllDSPs()
+				newResult, err := s.ReadAllDSPs()
 				if err == nil {
-					select {
-					case changeCh <- newDspMap:
-					default:
-					}
+					publishDSPSnapshot(changeCh, newResult)
 				}
 			case <-s.ctx.Done():
 				return
@@ -0,0 +1,233 @@
+package dspbidder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+)
+
+// DSP配置源类型
+const (
+	DSPSourceS3        = "s3"
+	DSPSourceLocal     = "local"
+	DSPSourceHTTP      = "http"
+	DSPSourceComposite = "composite"
+)
+
+// defaultDSPScanInterval 未配置扫描间隔时的默认值
+const defaultDSPScanInterval = 30 * time.Second
+
+// DSPConfigSource DSP配置源，负责发现并读取全量DSP配置
+type DSPConfigSource interface {
+	// Name 返回配置源名称，用于日志和监控
+	Name() string
+
+	// ReadAllDSPs 读取当前全量DSP配置，校验失败的文件放入隔离列表
+	ReadAllDSPs() (*DSPScanResult, error)
+
+	// WatchDSPChanges 监控配置变化，每次变化推送一份全量快照；
+	// 通道只保留最新一份快照，消费不及时时旧快照会被覆盖
+	WatchDSPChanges() <-chan *DSPScanResult
+
+	// Close 停止监控并释放资源
+	Close() error
+}
+
+// DSPScanResult 一次全量扫描的结果
+type DSPScanResult struct {
+	// DSPs 校验通过的DSP配置，key为DSP ID
+	DSPs map[string]*DSPInfo
+	// Quarantined 校验失败被隔离的配置文件
+	Quarantined []*QuarantinedDSPFile
+}
+
+// QuarantinedDSPFile 被隔离的DSP配置文件，不参与索引构建和Bidder注册
+type QuarantinedDSPFile struct {
+	Source     string        `json:"source"`
+	Key        string        `json:"key"`
+	DSPID      string        `json:"dsp_id,omitempty"`
+	Errors     []*FieldError `json:"errors"`
+	DetectedAt time.Time     `json:"detected_at"`
+	// Retained 该文件此前定义的DSP沿用上次有效配置，直到文件修复或删除
+	Retained bool `json:"retained,omitempty"`
+
+	// digest 文件内容摘要(读取失败时为错误信息)，用于识别重复扫描到的同一份坏文件
+	digest string
+}
+
+// sourceFileKey 配置文件在所有配置源中的唯一标识
+func sourceFileKey(source, key string) string {
+	return source + ":" + key
+}
+
+// NewDSPConfigSource 根据配置创建DSP配置源
+func NewDSPConfigSource(cfg *config.AdxServerConfig) (DSPConfigSource, error) {
+	sourceType := cfg.DSPSource.Type
+	if sourceType == "" {
+		sourceType = DSPSourceS3
+	}
+
+	if sourceType != DSPSourceComposite {
+		return newDSPConfigSource(cfg, sourceType)
+	}
+
+	if len(cfg.DSPSource.Composite) == 0 {
+		return nil, fmt.Errorf("composite DSP source requires at least one sub source")
+	}
+
+	sources := make([]DSPConfigSource, 0, len(cfg.DSPSource.Composite))
+	for _, subType := range cfg.DSPSource.Composite {
+		if subType == DSPSourceComposite {
+			closeDSPSources(sources)
+			return nil, fmt.Errorf("composite DSP source can not be nested")
+		}
+		source, err := newDSPConfigSource(cfg, subType)
+		if err != nil {
+			closeDSPSources(sources)
+			return nil, fmt.Errorf("failed to create sub source %s: %w", subType, err)
+		}
+		sources = append(sources, source)
+	}
+
+	return NewCompositeSource(sources...), nil
+}
+
+// newDSPConfigSource 创建单一类型的DSP配置源
+func newDSPConfigSource(cfg *config.AdxServerConfig, sourceType string) (DSPConfigSource, error) {
+	scanInterval := dspScanInterval(cfg)
+
+	switch sourceType {
+	case DSPSourceS3:
+		return NewConfigLoader(&S3Config{
+			Endpoint:        cfg.S3.Endpoint,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			BucketName:      cfg.S3.BucketName,
+			Prefix:          cfg.S3.Prefix,
+			UseSSL:          cfg.S3.UseSSL,
+			ScanInterval:    scanInterval,
+			Timeout:         10 * time.Second,
+		})
+	case DSPSourceLocal:
+		return NewLocalDirSource(cfg.DSPSource.Local.Dir, scanInterval)
+	case DSPSourceHTTP:
+		return NewHTTPSource(&HTTPSourceConfig{
+			URL:          cfg.DSPSource.HTTP.URL,
+			Headers:      cfg.DSPSource.HTTP.Headers,
+			Timeout:      cfg.DSPSource.HTTP.Timeout,
+			PollInterval: scanInterval,
+		})
+	default:
+		return nil, fmt.Errorf("unknown DSP source type: %s", sourceType)
+	}
+}
+
+// dspScanInterval 获取DSP配置扫描间隔
+func dspScanInterval(cfg *config.AdxServerConfig) time.Duration {
+	if cfg.DSPSource.ScanInterval > 0 {
+		return cfg.DSPSource.ScanInterval
+	}
+	if cfg.S3.ScanInterval > 0 {
+		return cfg.S3.ScanInterval
+	}
+	return defaultDSPScanInterval
+}
+
+// decodeDSPInfo 解析单个DSP配置文件内容
+func decodeDSPInfo(key string, data []byte) (*DSPInfo, error) {
+	dspInfo := &DSPInfo{}
+	if err := json.Unmarshal(data, dspInfo); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DSP info from %s: %w", key, err)
+	}
+
+	dspInfo.UpdatedAt = time.Now()
+	return dspInfo, nil
+}
+
+// collectDSPs 按顺序读取并校验配置文件，汇总为扫描结果；
+// 读取、解析或校验失败的文件被隔离，不影响其他文件。
+// 多个文件声明同一个DSP ID时，按key排序的第一个文件生效，其余文件被隔离
+func collectDSPs(source string, keys []string, read func(key string) ([]byte, error)) *DSPScanResult {
+	validator := NewDSPConfigValidator()
+	result := &DSPScanResult{DSPs: make(map[string]*DSPInfo)}
+	owners := make(map[string]string)
+
+	var digest string
+	quarantine := func(key, dspID string, errs ...*FieldError) {
+		log.Printf("quarantine DSP file %s from %s: %v", key, source, errs)
+		result.Quarantined = append(result.Quarantined, &QuarantinedDSPFile{
+			Source:     source,
+			Key:        key,
+			DSPID:      dspID,
+			Errors:     errs,
+			DetectedAt: time.Now(),
+			digest:     digest,
+		})
+	}
+
+	for _, key := range keys {
+		data, err := read(key)
+		if err != nil {
+			digest = err.Error()
+			quarantine(key, "", &FieldError{Field: "file", Reason: err.Error()})
+			continue
+		}
+		sum := sha256.Sum256(data)
+		digest = hex.EncodeToString(sum[:])
+
+		dspInfo, err := decodeDSPInfo(key, data)
+		if err != nil {
+			quarantine(key, "", &FieldError{Field: "json", Reason: err.Error()})
+			continue
+		}
+
+		if errs := validator.Validate(dspInfo); len(errs) > 0 {
+			quarantine(key, dspInfo.DSPID, errs...)
+			continue
+		}
+
+		if owner, exists := owners[dspInfo.DSPID]; exists {
+			quarantine(key, dspInfo.DSPID, &FieldError{
+				Field:  "dsp_id",
+				Reason: fmt.Sprintf("duplicate dsp_id %s, already defined in %s", dspInfo.DSPID, owner),
+			})
+			continue
+		}
+
+		owners[dspInfo.DSPID] = key
+		dspInfo.SourceFile = sourceFileKey(source, key)
+		result.DSPs[dspInfo.DSPID] = dspInfo
+	}
+
+	return result
+}
+
+// publishDSPSnapshot 推送最新快照，丢弃通道中尚未被消费的旧快照
+func publishDSPSnapshot(ch chan *DSPScanResult, result *DSPScanResult) {
+	for {
+		select {
+		case ch <- result:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+// closeDSPSources 关闭一组配置源
+func closeDSPSources(sources []DSPConfigSource) {
+	for _, source := range sources {
+		if err := source.Close(); err != nil {
+			log.Printf("failed to close DSP source %s: %v", source.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package dspbidder
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSource 固定返回预设DSP配置的测试配置源
+type staticSource struct {
+	name   string
+	dspMap map[string]*DSPInfo
+	err    error
+}
+
+func (s *staticSource) Name() string { return s.name }
+
+func (s *staticSource) ReadAllDSPs() (*DSPScanResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &DSPScanResult{DSPs: s.dspMap}, nil
+}
+
+func (s *staticSource) WatchDSPChanges() <-chan *DSPScanResult {
+	ch := make(chan *DSPScanResult, 1)
+	ch <- &DSPScanResult{DSPs: s.dspMap}
+	close(ch)
+	return ch
+}
+
+func (s *staticSource) Close() error { return nil }
+
+func writeDSPFile(t *testing.T, dir, dspID, endpoint string) {
+	t.Helper()
+	content := fmt.Sprintf(`{"dsp_id":%q,"dsp_name":"test","status":"active","endpoint":%q}`, dspID, endpoint)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, dspID+".json"), []byte(content), 0o644))
+}
+
+func TestLocalDirSource_ReadAllDSPs(t *testing.T) {
+	dir := t.TempDir()
+	writeDSPFile(t, dir, "dsp_001", "http://dsp1.example.com/bid")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	writeDSPFile(t, filepath.Join(dir, "sub"), "dsp_002", "http://dsp2.example.com/bid")
+	// 非json文件和损坏文件不影响其他配置
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644))
+
+	source, err := NewLocalDirSource(dir, time.Minute)
+	require.NoError(t, err)
+	defer source.Close()
+
+	result, err := source.ReadAllDSPs()
+	require.NoError(t, err)
+	assert.Len(t, result.DSPs, 2)
+	assert.Equal(t, "http://dsp2.example.com/bid", result.DSPs["dsp_002"].Endpoint)
+	require.Len(t, result.Quarantined, 1)
+	assert.Equal(t, filepath.Join(dir, "broken.json"), result.Quarantined[0].Key)
+}
+
+func TestLocalDirSource_WatchDSPChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeDSPFile(t, dir, "dsp_001", "http://dsp1.example.com/bid")
+
+	source, err := NewLocalDirSource(dir, time.Minute)
+	require.NoError(t, err)
+	defer source.Close()
+
+	changeCh := source.WatchDSPChanges()
+
+	// 初始快照
+	select {
+	case result := <-changeCh:
+		assert.Len(t, result.DSPs, 1)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for initial snapshot")
+	}
+
+	// 新增文件触发重载
+	writeDSPFile(t, dir, "dsp_002", "http://dsp2.example.com/bid")
+	select {
+	case result := <-changeCh:
+		assert.Len(t, result.DSPs, 2)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for change snapshot")
+	}
+}
+
+func TestLocalDirSource_RewatchStopsPrevious(t *testing.T) {
+	dir := t.TempDir()
+	writeDSPFile(t, dir, "dsp_001", "http://dsp1.example.com/bid")
+
+	source, err := NewLocalDirSource(dir, time.Minute)
+	require.NoError(t, err)
+	defer source.Close()
+
+	first := source.WatchDSPChanges()
+	second := source.WatchDSPChanges()
+
+	// 重新监控后旧的通道被关闭
+	deadline := time.After(2 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-first:
+			closed = !ok
+		case <-deadline:
+			t.Fatal("previous watch not stopped")
+		}
+	}
+
+	select {
+	case result := <-second:
+		assert.Len(t, result.DSPs, 1)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for initial snapshot")
+	}
+	writeDSPFile(t, dir, "dsp_002", "http://dsp2.example.com/bid")
+	select {
+	case result := <-second:
+		assert.Len(t, result.DSPs, 2)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for change snapshot")
+	}
+}
+
+func TestHTTPSource_ReadAllDSPs(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`[{"dsp_id":"dsp_001","status":"active","endpoint":"http://dsp1.example.com/bid"},{"dsp_id":"dsp_002","status":"inactive"}]`))
+	}))
+	defer server.Close()
+
+	source, err := NewHTTPSource(&HTTPSourceConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+	})
+	require.NoError(t, err)
+	defer source.Close()
+
+	result, err := source.ReadAllDSPs()
+	require.NoError(t, err)
+	assert.Len(t, result.DSPs, 2)
+
+	// 未变化时复用上一次结果
+	result, err = source.ReadAllDSPs()
+	require.NoError(t, err)
+	assert.Len(t, result.DSPs, 2)
+	assert.Equal(t, 2, requests)
+
+	// 304 不视为变化，轮询不推送
+	_, modified, err := source.poll()
+	require.NoError(t, err)
+	assert.False(t, modified)
+}
+
+func TestHTTPSource_WatchOnlyChanges(t *testing.T) {
+	var mu sync.Mutex
+	body := `[{"dsp_id":"dsp_001","status":"active","endpoint":"http://dsp1.example.com/bid"}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// 不带ETag，按内容哈希判断变化
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	source, err := NewHTTPSource(&HTTPSourceConfig{URL: server.URL, PollInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer source.Close()
+
+	changeCh := source.WatchDSPChanges()
+	select {
+	case result := <-changeCh:
+		assert.Len(t, result.DSPs, 1)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for initial snapshot")
+	}
+
+	// 远端未变化时不推送
+	select {
+	case <-changeCh:
+		t.Fatal("unchanged DSPs published")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	mu.Lock()
+	body = `[{"dsp_id":"dsp_001","status":"active","endpoint":"http://dsp1.example.com/bid"},{"dsp_id":"dsp_002","status":"active","endpoint":"http://dsp2.example.com/bid"}]`
+	mu.Unlock()
+	select {
+	case result := <-changeCh:
+		assert.Len(t, result.DSPs, 2)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for change snapshot")
+	}
+}
+
+func TestCompositeSource_Precedence(t *testing.T) {
+	primary := &staticSource{name: "primary", dspMap: map[string]*DSPInfo{
+		"dsp_001": {DSPID: "dsp_001", Endpoint: "http://primary"},
+	}}
+	fallback := &staticSource{name: "fallback", dspMap: map[string]*DSPInfo{
+		"dsp_001": {DSPID: "dsp_001", Endpoint: "http://fallback"},
+		"dsp_002": {DSPID: "dsp_002", Endpoint: "http://fallback"},
+	}}
+
+	source := NewCompositeSource(primary, fallback)
+	result, err := source.ReadAllDSPs()
+	require.NoError(t, err)
+	assert.Len(t, result.DSPs, 2)
+	assert.Equal(t, "http://primary", result.DSPs["dsp_001"].Endpoint)
+	assert.Equal(t, "http://fallback", result.DSPs["dsp_002"].Endpoint)
+
+	// 子源失败时沿用最近一次成功的快照
+	primary.err = fmt.Errorf("unavailable")
+	primary.dspMap = nil
+	result, err = source.ReadAllDSPs()
+	require.NoError(t, err)
+	assert.Equal(t, "http://primary", result.DSPs["dsp_001"].Endpoint)
+
+	// 全部失败时返回错误
+	fallback.err = fmt.Errorf("unavailable")
+	_, err = source.ReadAllDSPs()
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+package dspbidder
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/pkg/pricecrypto"
+)
+
+// DSP状态
+const (
+	DSPStatusActive   = "active"
+	DSPStatusInactive = "inactive"
+	DSPStatusPaused   = "paused"
+)
+
+// 定向条件操作符
+const (
+	ConditionOpEQ    = "EQ"
+	ConditionOpIN    = "IN"
+	ConditionOpNotIN = "NOT_IN"
+)
+
+var dspIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_\-.]{0,63}$`)
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// DSPConfigValidator DSP配置校验器
+type DSPConfigValidator struct {
+	MinTimeout    time.Duration
+	MaxTimeout    time.Duration
+	MaxRetryCount int
+	MaxRetryDelay time.Duration
+}
+
+// NewDSPConfigValidator 创建默认规则的DSP配置校验器
+func NewDSPConfigValidator() *DSPConfigValidator {
+	return &DSPConfigValidator{
+		MinTimeout:    5 * time.Millisecond,
+		MaxTimeout:    2 * time.Second,
+		MaxRetryCount: 5,
+		MaxRetryDelay: time.Second,
+	}
+}
+
+// Validate 校验单个DSP配置，返回所有不合法的字段
+func (v *DSPConfigValidator) Validate(info *DSPInfo) []*FieldError {
+	var errs []*FieldError
+	addErr := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	// 基础字段
+	if info.DSPID == "" {
+		addErr("dsp_id", "is required")
+	} else if !dspIDPattern.MatchString(info.DSPID) {
+		addErr("dsp_id", "must match %s", dspIDPattern.String())
+	}
+
+	switch info.Status {
+	case DSPStatusActive, DSPStatusInactive, DSPStatusPaused:
+	default:
+		addErr("status", "must be one of [%s %s %s], got %q",
+			DSPStatusActive, DSPStatusInactive, DSPStatusPaused, info.Status)
+	}
+
+	// 非活跃DSP不会发起请求，endpoint允许为空
+	if info.Endpoint != "" || info.Status == DSPStatusActive {
+		if reason := validateEndpoint(info.Endpoint); reason != "" {
+			addErr("endpoint", "%s", reason)
+		}
+	}
+
+	// 数值范围
+	if info.Timeout != 0 && (info.Timeout < v.MinTimeout || info.Timeout > v.MaxTimeout) {
+		addErr("timeout", "must be within [%s, %s], got %s", v.MinTimeout, v.MaxTimeout, info.Timeout)
+	}
+	if info.QPSLimit < 0 {
+		addErr("qps_limit", "must not be negative")
+	}
+	if info.BudgetDaily < 0 {
+		addErr("budget_daily", "must not be negative")
+	}
+	if info.RetryCount < 0 || info.RetryCount > v.MaxRetryCount {
+		addErr("retry_count", "must be within [0, %d], got %d", v.MaxRetryCount, info.RetryCount)
+	}
+	if info.RetryDelay < 0 || info.RetryDelay > v.MaxRetryDelay {
+		addErr("retry_delay", "must be within [0, %s], got %s", v.MaxRetryDelay, info.RetryDelay)
+	}
+
+	if !IsValidEncoding(info.Encoding) {
+		addErr("encoding", "must be one of [%s %s], got %q", EncodingJSON, EncodingProtobuf, info.Encoding)
+	}
+
+	for i, idType := range info.AllowedDeviceIDs {
+		if !adxcore.IsKnownDeviceIDType(idType) {
+			addErr(fmt.Sprintf("allowed_device_ids[%d]", i), "unknown device id type %q", idType)
+		}
+	}
+
+	if info.PriceCrypto != nil {
+		if _, err := pricecrypto.New(*info.PriceCrypto); err != nil {
+			addErr("price_crypto", "%v", err)
+		}
+	}
+
+	// 定向条件
+	if info.Targeting != nil {
+		errs = append(errs, v.validateTargeting(info.Targeting)...)
+	}
+
+	return errs
+}
+
+// validateTargeting 校验定向配置
+func (v *DSPConfigValidator) validateTargeting(targeting *DSPTargeting) []*FieldError {
+	var errs []*FieldError
+
+	clauseIDs := make(map[string]bool)
+	for i, clause := range targeting.IndexingDoc {
+		prefix := fmt.Sprintf("targeting.indexingdoc[%d]", i)
+
+		if clause.ClauseID != "" {
+			if clauseIDs[clause.ClauseID] {
+				errs = append(errs, &FieldError{Field: prefix + ".clause_id", Reason: "duplicate clause_id " + clause.ClauseID})
+			}
+			clauseIDs[clause.ClauseID] = true
+		}
+		if len(clause.Conditions) == 0 {
+			errs = append(errs, &FieldError{Field: prefix + ".conditions", Reason: "must not be empty"})
+		}
+
+		for j, cond := range clause.Conditions {
+			field := fmt.Sprintf("%s.conditions[%d]", prefix, j)
+
+			if !adxcore.IsKnownTargetingField(cond.Field) {
+				errs = append(errs, &FieldError{
+					Field:  field + ".field",
+					Reason: fmt.Sprintf("unknown targeting field %q, known fields: %v", cond.Field, adxcore.KnownTargetingFields()),
+				})
+			}
+
+			switch cond.Operator {
+			case ConditionOpEQ, ConditionOpIN, ConditionOpNotIN:
+			default:
+				errs = append(errs, &FieldError{
+					Field:  field + ".operator",
+					Reason: fmt.Sprintf("must be one of [%s %s %s], got %q", ConditionOpEQ, ConditionOpIN, ConditionOpNotIN, cond.Operator),
+				})
+			}
+
+			if len(cond.Values) == 0 {
+				errs = append(errs, &FieldError{Field: field + ".values", Reason: "must not be empty"})
+			} else if cond.Operator == ConditionOpEQ && len(cond.Values) != 1 {
+				errs = append(errs, &FieldError{Field: field + ".values", Reason: "EQ requires exactly one value"})
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateEndpoint 校验DSP竞价地址，合法时返回空字符串
+func validateEndpoint(endpoint string) string {
+	if endpoint == "" {
+		return "is required for active DSP"
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Sprintf("invalid url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Sprintf("scheme must be http or https, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "host is required"
+	}
+	return ""
+}
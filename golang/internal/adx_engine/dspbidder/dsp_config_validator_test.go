@@ -0,0 +1,94 @@
+package dspbidder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/pkg/pricecrypto"
+)
+
+func errorFields(errs []*FieldError) []string {
+	fields := make([]string, 0, len(errs))
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestDSPConfigValidator_Validate(t *testing.T) {
+	validator := NewDSPConfigValidator()
+
+	valid := &DSPInfo{
+		DSPID:    "dsp_001",
+		Status:   DSPStatusActive,
+		Endpoint: "https://dsp.example.com/bid",
+		Timeout:  100 * time.Millisecond,
+
+		AllowedDeviceIDs: []string{"imei_md5", "oaid"},
+		PriceCrypto:      &pricecrypto.Config{Scheme: pricecrypto.SchemeAESCBC, EncryptionKey: "0123456789abcdef"},
+		Targeting: &DSPTargeting{IndexingDoc: []IndexingClause{{
+			ClauseID:   "c1",
+			Conditions: []Condition{{Field: "USER_OS", Operator: ConditionOpIN, Values: []string{"ios"}}},
+		}}},
+	}
+	assert.Empty(t, validator.Validate(valid))
+
+	invalid := &DSPInfo{
+		DSPID:      "dsp_002",
+		Status:     "running",
+		Endpoint:   "ftp://dsp.example.com",
+		Timeout:    10 * time.Second,
+		RetryCount: 10,
+		Encoding:   "xml",
+
+		AllowedDeviceIDs: []string{"oaid", "mac"},
+		PriceCrypto:      &pricecrypto.Config{Scheme: pricecrypto.SchemeAESECB, EncryptionKey: "short"},
+		Targeting: &DSPTargeting{IndexingDoc: []IndexingClause{{
+			Conditions: []Condition{{Field: "UNKNOWN", Operator: "LIKE"}},
+		}}},
+	}
+	fields := errorFields(validator.Validate(invalid))
+	assert.ElementsMatch(t, []string{
+		"status",
+		"endpoint",
+		"timeout",
+		"retry_count",
+		"encoding",
+		"allowed_device_ids[1]",
+		"price_crypto",
+		"targeting.indexingdoc[0].conditions[0].field",
+		"targeting.indexingdoc[0].conditions[0].operator",
+		"targeting.indexingdoc[0].conditions[0].values",
+	}, fields)
+
+	// 非活跃DSP允许不配置endpoint
+	assert.Empty(t, validator.Validate(&DSPInfo{DSPID: "dsp_003", Status: DSPStatusInactive}))
+}
+
+func TestCollectDSPs_Quarantine(t *testing.T) {
+	files := map[string]string{
+		"a.json": `{"dsp_id":"dsp_001","status":"active","endpoint":"http://a.example.com/bid"}`,
+		"b.json": `{"dsp_id":"dsp_001","status":"active","endpoint":"http://b.example.com/bid"}`,
+		"c.json": `{"dsp_name":"no id","status":"active","endpoint":"http://c.example.com/bid"}`,
+		"d.json": `{"dsp_id":"dsp_004","status":"active","endpoint":"http://d.example.com/bid"}`,
+	}
+	keys := []string{"a.json", "b.json", "c.json", "d.json"}
+
+	result := collectDSPs("test", keys, func(key string) ([]byte, error) {
+		return []byte(files[key]), nil
+	})
+
+	// 缺少dsp_id和重复ID的文件被隔离，不影响其他合法配置
+	assert.Len(t, result.DSPs, 2)
+	assert.Equal(t, "http://a.example.com/bid", result.DSPs["dsp_001"].Endpoint)
+	assert.Contains(t, result.DSPs, "dsp_004")
+
+	require.Len(t, result.Quarantined, 2)
+	assert.Equal(t, "b.json", result.Quarantined[0].Key)
+	assert.Equal(t, "dsp_id", result.Quarantined[0].Errors[0].Field)
+	assert.Equal(t, "c.json", result.Quarantined[1].Key)
+	assert.Equal(t, "test", result.Quarantined[1].Source)
+}
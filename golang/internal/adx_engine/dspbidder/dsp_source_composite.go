@@ -0,0 +1,109 @@
+package dspbidder
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// CompositeSource 组合多个DSP配置源
+// 子源按优先级从高到低排列，同一个DSP ID以优先级最高的子源为准；
+// 某个子源读取失败时沿用其最近一次成功的快照，全部失败时才返回错误
+type CompositeSource struct {
+	sources []DSPConfigSource
+
+	mu        sync.Mutex
+	snapshots []*DSPScanResult
+}
+
+// NewCompositeSource 创建组合DSP配置源
+func NewCompositeSource(sources ...DSPConfigSource) *CompositeSource {
+	return &CompositeSource{
+		sources:   sources,
+		snapshots: make([]*DSPScanResult, len(sources)),
+	}
+}
+
+// Name 返回配置源名称
+func (s *CompositeSource) Name() string {
+	names := make([]string, 0, len(s.sources))
+	for _, source := range s.sources {
+		names = append(names, source.Name())
+	}
+	return fmt.Sprintf("%s(%s)", DSPSourceComposite, strings.Join(names, ","))
+}
+
+// ReadAllDSPs 读取所有子源并按优先级合并
+func (s *CompositeSource) ReadAllDSPs() (*DSPScanResult, error) {
+	var errs []error
+	for i, source := range s.sources {
+		result, err := source.ReadAllDSPs()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+			continue
+		}
+		s.updateSnapshot(i, result)
+	}
+
+	if len(errs) == len(s.sources) {
+		return nil, fmt.Errorf("all DSP sources failed: %v", errs)
+	}
+	for _, err := range errs {
+		log.Printf("DSP source read failed, using last snapshot: %v", err)
+	}
+
+	return s.merge(), nil
+}
+
+// WatchDSPChanges 汇聚所有子源的变化，任一子源变化时推送合并后的快照
+func (s *CompositeSource) WatchDSPChanges() <-chan *DSPScanResult {
+	changeCh := make(chan *DSPScanResult, 1)
+
+	for i, source := range s.sources {
+		go func(idx int, ch <-chan *DSPScanResult) {
+			for result := range ch {
+				s.updateSnapshot(idx, result)
+				publishDSPSnapshot(changeCh, s.merge())
+			}
+		}(i, source.WatchDSPChanges())
+	}
+
+	return changeCh
+}
+
+// Close 关闭所有子源
+func (s *CompositeSource) Close() error {
+	closeDSPSources(s.sources)
+	return nil
+}
+
+// updateSnapshot 更新子源快照
+func (s *CompositeSource) updateSnapshot(idx int, result *DSPScanResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[idx] = result
+}
+
+// merge 按优先级合并子源快照，先写低优先级再由高优先级覆盖；
+// 各子源的隔离文件全部保留
+func (s *CompositeSource) merge() *DSPScanResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	merged := &DSPScanResult{DSPs: make(map[string]*DSPInfo)}
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		if s.snapshots[i] == nil {
+			continue
+		}
+		for dspID, dspInfo := range s.snapshots[i].DSPs {
+			merged.DSPs[dspID] = dspInfo
+		}
+	}
+	for _, snapshot := range s.snapshots {
+		if snapshot != nil {
+			merged.Quarantined = append(merged.Quarantined, snapshot.Quarantined...)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,183 @@
+package dspbidder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPSourceConfig HTTP配置源配置
+type HTTPSourceConfig struct {
+	URL          string
+	Headers      map[string]string
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// HTTPSource 基于HTTP接口的DSP配置源
+// 接口返回DSP配置的JSON数组，支持ETag；配置未变化(304或内容哈希相同)时不重复解析，轮询也不推送
+type HTTPSource struct {
+	url          string
+	headers      map[string]string
+	pollInterval time.Duration
+	client       *http.Client
+
+	// 缓存最近一次成功拉取的结果
+	mu       sync.Mutex
+	etag     string
+	bodyHash [sha256.Size]byte
+	lastDSPs *DSPScanResult
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewHTTPSource 创建HTTP DSP配置源
+func NewHTTPSource(config *HTTPSourceConfig) (*HTTPSource, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("http DSP source url is empty")
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	pollInterval := config.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultDSPScanInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HTTPSource{
+		url:          config.URL,
+		headers:      config.Headers,
+		pollInterval: pollInterval,
+		client:       &http.Client{Timeout: timeout},
+		ctx:          ctx,
+		cancel:       cancel,
+	}, nil
+}
+
+// Name 返回配置源名称
+func (s *HTTPSource) Name() string {
+	return DSPSourceHTTP
+}
+
+// ReadAllDSPs 拉取所有DSP配置，远端未变化时返回当前快照
+func (s *HTTPSource) ReadAllDSPs() (*DSPScanResult, error) {
+	result, _, err := s.poll()
+	return result, err
+}
+
+// poll 拉取远端配置，modified 表示与上一次成功拉取的结果相比是否有变化
+func (s *HTTPSource) poll() (result *DSPScanResult, modified bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if modified, err = s.fetch(); err != nil {
+		return nil, false, err
+	}
+	return s.lastDSPs, modified, nil
+}
+
+// fetch 请求远端配置并更新缓存，304 或内容哈希与上一次相同时返回未变化
+func (s *HTTPSource) fetch() (bool, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	if s.etag != "" && s.lastDSPs != nil {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch DSPs from %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("fetch DSPs from %s HTTP error: %d", s.url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// 不支持ETag的接口按内容哈希判断是否变化
+	hash := sha256.Sum256(body)
+	if s.lastDSPs != nil && hash == s.bodyHash {
+		s.etag = resp.Header.Get("ETag")
+		return false, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return false, fmt.Errorf("failed to unmarshal DSP list from %s: %w", s.url, err)
+	}
+
+	keys := make([]string, 0, len(items))
+	raws := make(map[string][]byte, len(items))
+	for i, item := range items {
+		key := fmt.Sprintf("%s#%d", s.url, i)
+		keys = append(keys, key)
+		raws[key] = item
+	}
+	result := collectDSPs(s.Name(), keys, func(key string) ([]byte, error) {
+		return raws[key], nil
+	})
+
+	s.etag = resp.Header.Get("ETag")
+	s.bodyHash = hash
+	s.lastDSPs = result
+	return true, nil
+}
+
+// WatchDSPChanges 定时轮询HTTP接口，只在配置变化时推送快照
+func (s *HTTPSource) WatchDSPChanges() <-chan *DSPScanResult {
+	changeCh := make(chan *DSPScanResult, 1)
+
+	go func() {
+		defer close(changeCh)
+
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			result, modified, err := s.poll()
+			if err != nil {
+				log.Printf("failed to poll DSPs from %s: %v", s.url, err)
+			} else if modified {
+				publishDSPSnapshot(changeCh, result)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changeCh
+}
+
+// Close 停止轮询
+func (s *HTTPSource) Close() error {
+	s.cancel()
+	return nil
+}
@@ -0,0 +1,242 @@
+package dspbidder
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// localSourceDebounce 文件变化事件合并窗口，避免编辑器多次写入触发多次重载
+const localSourceDebounce = 200 * time.Millisecond
+
+// LocalDirSource 基于本地目录的DSP配置源，适用于开发和CI环境
+// 目录下(含子目录)的每个 .json 文件对应一个DSP配置，通过fsnotify感知变化
+type LocalDirSource struct {
+	dir          string
+	scanInterval time.Duration
+
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher // 当前监控使用的watcher，重新监控时关闭旧的
+	stop    chan struct{}     // 停止当前监控循环
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewLocalDirSource 创建本地目录DSP配置源
+func NewLocalDirSource(dir string, scanInterval time.Duration) (*LocalDirSource, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("local DSP source dir is empty")
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat DSP config dir %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("DSP config path %s is not a directory", dir)
+	}
+
+	if scanInterval <= 0 {
+		scanInterval = defaultDSPScanInterval
+	}
+
+	return &LocalDirSource{
+		dir:          dir,
+		scanInterval: scanInterval,
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// Name 返回配置源名称
+func (s *LocalDirSource) Name() string {
+	return DSPSourceLocal
+}
+
+// ListDSPFiles 列出目录下所有DSP配置文件
+func (s *LocalDirSource) ListDSPFiles() ([]string, error) {
+	var dspFiles []string
+
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".json") {
+			dspFiles = append(dspFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk dir %s error: %w", s.dir, err)
+	}
+
+	// 按文件路径排序，确保一致性
+	sort.Strings(dspFiles)
+	return dspFiles, nil
+}
+
+// ReadDSPFile 读取单个DSP配置文件
+func (s *LocalDirSource) ReadDSPFile(path string) (*DSPInfo, error) {
+	data, err := s.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDSPInfo(path, data)
+}
+
+// readFile 读取文件原始内容
+func (s *LocalDirSource) readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// ReadAllDSPs 读取所有DSP配置
+func (s *LocalDirSource) ReadAllDSPs() (*DSPScanResult, error) {
+	files, err := s.ListDSPFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DSP files: %w", err)
+	}
+
+	return collectDSPs(s.Name(), files, s.readFile), nil
+}
+
+// WatchDSPChanges 监控目录变化，文件变化或定时全量扫描时推送快照；
+// 重复调用时停止上一次的监控并关闭其通道
+func (s *LocalDirSource) WatchDSPChanges() <-chan *DSPScanResult {
+	changeCh := make(chan *DSPScanResult, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopWatchLocked()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		// 无法使用fsnotify时退化为定时扫描
+		log.Printf("fsnotify unavailable for %s, fallback to polling: %v", s.dir, err)
+		watcher = nil
+	} else {
+		s.addWatchDirs(watcher)
+	}
+	s.watcher = watcher
+	s.stop = make(chan struct{})
+
+	go s.watchLoop(changeCh, watcher, s.stop)
+
+	return changeCh
+}
+
+// stopWatchLocked 停止当前监控循环并关闭watcher，调用方需持有 s.mu
+func (s *LocalDirSource) stopWatchLocked() error {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	if s.watcher == nil {
+		return nil
+	}
+	err := s.watcher.Close()
+	s.watcher = nil
+	return err
+}
+
+// watchLoop 监控循环
+func (s *LocalDirSource) watchLoop(changeCh chan *DSPScanResult, watcher *fsnotify.Watcher, stop <-chan struct{}) {
+	defer close(changeCh)
+
+	ticker := time.NewTicker(s.scanInterval)
+	defer ticker.Stop()
+
+	debounce := time.NewTimer(localSourceDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if watcher != nil {
+		events = watcher.Events
+		errs = watcher.Errors
+	}
+
+	reload := func() {
+		result, err := s.ReadAllDSPs()
+		if err != nil {
+			log.Printf("failed to reload DSPs from %s: %v", s.dir, err)
+			return
+		}
+		publishDSPSnapshot(changeCh, result)
+	}
+
+	// 初始加载
+	reload()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				// 新建子目录需要加入监控
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					s.addWatchDirs(watcher)
+				}
+			}
+			debounce.Reset(localSourceDebounce)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			log.Printf("watch DSP dir %s error: %v", s.dir, err)
+		case <-debounce.C:
+			reload()
+		case <-ticker.C:
+			reload()
+		case <-stop:
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// addWatchDirs 将配置目录及其子目录加入监控
+func (s *LocalDirSource) addWatchDirs(watcher *fsnotify.Watcher) {
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err := watcher.Add(path); err != nil {
+				log.Printf("failed to watch dir %s: %v", path, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to walk DSP dir %s: %v", s.dir, err)
+	}
+}
+
+// Close 停止监控
+func (s *LocalDirSource) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		err = s.stopWatchLocked()
+	})
+	return err
+}
@@ -0,0 +1,65 @@
+package dspbidder
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// DSP竞价协议编码，请求与响应使用同一编码
+const (
+	EncodingJSON     = "json"     // protojson，默认
+	EncodingProtobuf = "protobuf" // proto二进制
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// IsValidEncoding 是否为支持的编码，空值表示默认的JSON
+func IsValidEncoding(encoding string) bool {
+	return encoding == "" || encoding == EncodingJSON || encoding == EncodingProtobuf
+}
+
+// EncodingOf 由 Content-Type 判断编码，无法识别时按JSON处理
+func EncodingOf(contentType string) string {
+	if strings.Contains(contentType, "protobuf") {
+		return EncodingProtobuf
+	}
+	return EncodingJSON
+}
+
+// ContentType 编码对应的 Content-Type
+func ContentType(encoding string) string {
+	if encoding == EncodingProtobuf {
+		return ContentTypeProtobuf
+	}
+	return ContentTypeJSON
+}
+
+// Marshal 按编码序列化OpenRTB消息
+func Marshal(encoding string, msg proto.Message) ([]byte, error) {
+	switch encoding {
+	case EncodingProtobuf:
+		return proto.MarshalOptions{AllowPartial: true}.Marshal(msg)
+	case "", EncodingJSON:
+		return protojson.MarshalOptions{AllowPartial: true}.Marshal(msg)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// Unmarshal 按编码解析OpenRTB消息，忽略未知字段
+func Unmarshal(encoding string, data []byte, msg proto.Message) error {
+	switch encoding {
+	case EncodingProtobuf:
+		return proto.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}.Unmarshal(data, msg)
+	case "", EncodingJSON:
+		return protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}.Unmarshal(data, msg)
+	default:
+		return fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
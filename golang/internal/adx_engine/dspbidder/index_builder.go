@@ -315,17 +315,11 @@ func convertDSPToDocumentJSON(docID be_indexer.DocID, dspInfo *DSPInfo) ([]byte,
 	}
 
 	// 如果没有定向条件，创建一个通配符Conjunction匹配所有请求
-	if doc["cons"] == nil {
+	if cons, _ := doc["cons"].([]map[string]interface{}); len(cons) == 0 {
+		// 不含任何包含条件的Conjunction会被be_indexer作为通配符处理
 		doc["cons"] = []map[string]interface{}{
 			{
-				"exprs": map[string]interface{}{
-					"_Z_": []map[string]interface{}{
-						{
-							"inc":   true,
-							"value": []string{},
-						},
-					},
-				},
+				"exprs": map[string]interface{}{},
 			},
 		}
 	}
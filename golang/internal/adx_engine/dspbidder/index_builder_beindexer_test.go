@@ -162,3 +162,29 @@ func TestIndexBuilder_DocIDMapping(t *testing.T) {
 
 	t.Logf("DocID mapping test passed! DSP: %s -> DocID: %d", dspID, docID)
 }
+
+func TestIndexBuilder_SearchWithoutTargeting(t *testing.T) {
+	builder := NewIndexBuilder()
+	err := builder.BuildDSPIndex(map[string]*DSPInfo{
+		// 无定向条件的DSP匹配所有请求
+		"dsp_all": {DSPID: "dsp_all", Status: "active"},
+		"dsp_ios": {DSPID: "dsp_ios", Status: "active", Targeting: &DSPTargeting{
+			IndexingDoc: []IndexingClause{{Conditions: []Condition{{Field: "USER_OS", Operator: "IN", Values: []string{"ios"}}}}},
+		}},
+	})
+	assert.NoError(t, err)
+
+	matchedIDs := func(query map[string][]string) []string {
+		results, err := builder.SearchDSPs(query)
+		assert.NoError(t, err)
+		ids := make([]string, 0, len(results))
+		for _, dspInfo := range results {
+			ids = append(ids, dspInfo.DSPID)
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []string{"dsp_all", "dsp_ios"}, matchedIDs(map[string][]string{"USER_OS": {"ios"}}))
+	assert.ElementsMatch(t, []string{"dsp_all"}, matchedIDs(map[string][]string{"USER_OS": {"android"}}))
+	assert.ElementsMatch(t, []string{"dsp_all"}, matchedIDs(map[string][]string{}))
+}
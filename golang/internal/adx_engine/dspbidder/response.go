@@ -0,0 +1,29 @@
+package dspbidder
+
+import (
+	"math"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// CandidatesFromResponse 将DSP的OpenRTB响应拆分为候选，每个出价一个候选；
+// 出价(元)转换为CPM分，无出价时返回空
+func CandidatesFromResponse(bidderID string, resp *admux_rtb.BidResponse) []*adxcore.BidCandidate {
+	var candidates []*adxcore.BidCandidate
+	for _, seat := range resp.GetSeatbid() {
+		for _, bid := range seat.GetBid() {
+			if bid == nil {
+				continue
+			}
+			candidates = append(candidates, &adxcore.BidCandidate{
+				Response: resp,
+				BidderID: bidderID,
+				Seat:     seat.GetSeat(),
+				Bid:      bid,
+				CPMPrice: int64(math.Round(bid.GetPrice() * 100)),
+			})
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,114 @@
+package stub
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/echoface/admux/pkg/config"
+)
+
+// 分布类型
+const (
+	DistFixed     = "fixed"     // 固定值 value
+	DistUniform   = "uniform"   // [min, max) 均匀分布
+	DistNormal    = "normal"    // 正态分布 mean/stddev，截断到 [min, max]
+	DistLogNormal = "lognormal" // 对数正态分布，mean/stddev 为结果的均值和标准差，截断到 [min, max]
+)
+
+// SimulatorConfig DSP模拟器配置
+type SimulatorConfig struct {
+	config.BaseConfig `yaml:",inline"`
+
+	Simulator Config `yaml:"simulator"`
+}
+
+// Config 模拟器出价行为配置
+type Config struct {
+	// Seed 随机数种子，非0时多次运行结果可复现
+	Seed int64 `yaml:"seed"`
+	// DefaultScenario 未指定场景时使用的场景，为空时使用第一个
+	DefaultScenario string `yaml:"default_scenario"`
+	// Scenarios 出价场景，请求路径 /bid/{name} 选择场景
+	Scenarios []Scenario `yaml:"scenarios"`
+}
+
+// Scenario 一种出价行为
+type Scenario struct {
+	Name string `yaml:"name"`
+	// BidRate 每个广告位的出价概率
+	BidRate float64 `yaml:"bid_rate"`
+	// Price 出价CPM分布，单位元
+	Price Distribution `yaml:"price"`
+	// RespectBidfloor 低于广告位底价时不出价
+	RespectBidfloor bool `yaml:"respect_bidfloor"`
+	// Latency 响应延迟分布，单位毫秒
+	Latency Distribution `yaml:"latency"`
+
+	// ErrorRate 返回 ErrorStatus(默认500) 的概率
+	ErrorRate   float64 `yaml:"error_rate"`
+	ErrorStatus int     `yaml:"error_status"`
+	// TimeoutRate 挂起请求直到 TimeoutDelay(默认5s) 或调用方断开的概率
+	TimeoutRate  float64       `yaml:"timeout_rate"`
+	TimeoutDelay time.Duration `yaml:"timeout_delay"`
+
+	// Seat 响应中的seat
+	Seat string `yaml:"seat"`
+	// Creatives 素材模板，每次出价随机选择一个
+	Creatives []CreativeTemplate `yaml:"creatives"`
+}
+
+// Distribution 数值分布
+type Distribution struct {
+	Type   string  `yaml:"type"`
+	Value  float64 `yaml:"value"`
+	Min    float64 `yaml:"min"`
+	Max    float64 `yaml:"max"`
+	Mean   float64 `yaml:"mean"`
+	StdDev float64 `yaml:"stddev"`
+}
+
+// CreativeTemplate 素材模板；adm/nurl/burl/lurl 为 text/template，
+// 可用 {{.RequestID}} {{.ImpID}} {{.BidID}} {{.CreativeID}} {{.Price}}；
+// OpenRTB宏可写作 {{macro "AUCTION_PRICE"}}，输出 ${AUCTION_PRICE}
+type CreativeTemplate struct {
+	ID      string   `yaml:"id"`
+	Adm     string   `yaml:"adm"`
+	Adomain []string `yaml:"adomain"`
+	Bundle  string   `yaml:"bundle"`
+	Nurl    string   `yaml:"nurl"`
+	Burl    string   `yaml:"burl"`
+	Lurl    string   `yaml:"lurl"`
+}
+
+// LoadSimulatorConfig 加载DSP模拟器配置
+func LoadSimulatorConfig() (*SimulatorConfig, error) {
+	loader := config.NewLoader("dsp_simulator")
+	var cfg SimulatorConfig
+
+	if err := loader.Load(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to load DSP simulator config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// Validate 检查分布参数
+func (d *Distribution) Validate() error {
+	switch d.Type {
+	case "", DistFixed:
+	case DistUniform:
+		if d.Max < d.Min {
+			return fmt.Errorf("uniform max %v < min %v", d.Max, d.Min)
+		}
+	case DistNormal, DistLogNormal:
+		if d.StdDev < 0 {
+			return fmt.Errorf("%s stddev must not be negative", d.Type)
+		}
+		if d.Type == DistLogNormal && d.Mean <= 0 {
+			return fmt.Errorf("lognormal mean must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown distribution %q", d.Type)
+	}
+	return nil
+}
@@ -1 +1,399 @@
 package stub
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/dspbidder"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// 请求结果，用作指标标签
+const (
+	resultBid        = "bid"
+	resultNoBid      = "nobid"
+	resultError      = "error"
+	resultTimeout    = "timeout"
+	resultBadRequest = "bad_request"
+)
+
+const (
+	defaultErrorStatus  = http.StatusInternalServerError
+	defaultTimeoutDelay = 5 * time.Second
+	maxRequestSize      = 1 << 20
+)
+
+// templateFuncs 素材模板函数；{{macro "AUCTION_PRICE"}} 输出OpenRTB宏 ${AUCTION_PRICE}
+var templateFuncs = template.FuncMap{
+	"macro": func(name string) string { return "${" + name + "}" },
+}
+
+// defaultCreative 未配置素材模板时使用
+var defaultCreative = CreativeTemplate{
+	ID:  "sim-creative",
+	Adm: `<div class="admux-sim" data-bid="{{.BidID}}">{{.CreativeID}}</div>`,
+}
+
+// Simulator DSP模拟器：以admux OpenRTB协议(JSON/protobuf)响应竞价请求，
+// 出价率、出价和延迟分布、错误和超时注入按场景配置
+type Simulator struct {
+	scenarios       map[string]*scenario
+	defaultScenario string
+	metrics         *metrics.DSPSimulatorMetrics
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+type scenario struct {
+	Scenario
+	creatives []*creative
+}
+
+type creative struct {
+	id                    string
+	adomain               []string
+	bundle                string
+	adm, nurl, burl, lurl *template.Template
+}
+
+// creativeData 素材模板可用的变量
+type creativeData struct {
+	RequestID  string
+	ImpID      string
+	BidID      string
+	CreativeID string
+	Price      float64
+}
+
+// NewSimulator 创建模拟器，metrics为nil时不记录指标
+func NewSimulator(cfg Config, m *metrics.DSPSimulatorMetrics) (*Simulator, error) {
+	if len(cfg.Scenarios) == 0 {
+		return nil, fmt.Errorf("at least one scenario is required")
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	s := &Simulator{
+		scenarios:       make(map[string]*scenario, len(cfg.Scenarios)),
+		defaultScenario: cfg.DefaultScenario,
+		metrics:         m,
+		rnd:             rand.New(rand.NewSource(seed)),
+	}
+	for _, sc := range cfg.Scenarios {
+		compiled, err := compileScenario(sc)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %q: %w", sc.Name, err)
+		}
+		if _, ok := s.scenarios[sc.Name]; ok {
+			return nil, fmt.Errorf("duplicate scenario %q", sc.Name)
+		}
+		s.scenarios[sc.Name] = compiled
+	}
+	if s.defaultScenario == "" {
+		s.defaultScenario = cfg.Scenarios[0].Name
+	}
+	if _, ok := s.scenarios[s.defaultScenario]; !ok {
+		return nil, fmt.Errorf("default scenario %q not found", s.defaultScenario)
+	}
+	return s, nil
+}
+
+func compileScenario(sc Scenario) (*scenario, error) {
+	if sc.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	for name, rate := range map[string]float64{"bid_rate": sc.BidRate, "error_rate": sc.ErrorRate, "timeout_rate": sc.TimeoutRate} {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%s must be within [0, 1], got %v", name, rate)
+		}
+	}
+	if err := sc.Price.Validate(); err != nil {
+		return nil, fmt.Errorf("price: %w", err)
+	}
+	if err := sc.Latency.Validate(); err != nil {
+		return nil, fmt.Errorf("latency: %w", err)
+	}
+	if sc.ErrorStatus == 0 {
+		sc.ErrorStatus = defaultErrorStatus
+	}
+	if sc.TimeoutDelay <= 0 {
+		sc.TimeoutDelay = defaultTimeoutDelay
+	}
+
+	templates := sc.Creatives
+	if len(templates) == 0 {
+		templates = []CreativeTemplate{defaultCreative}
+	}
+	compiled := &scenario{Scenario: sc}
+	for i, tpl := range templates {
+		c := &creative{id: tpl.ID, adomain: tpl.Adomain, bundle: tpl.Bundle}
+		if c.id == "" {
+			c.id = fmt.Sprintf("%s-creative-%d", sc.Name, i)
+		}
+		for _, field := range []struct {
+			name string
+			text string
+			dst  **template.Template
+		}{
+			{"adm", tpl.Adm, &c.adm},
+			{"nurl", tpl.Nurl, &c.nurl},
+			{"burl", tpl.Burl, &c.burl},
+			{"lurl", tpl.Lurl, &c.lurl},
+		} {
+			if field.text == "" {
+				continue
+			}
+			parsed, err := template.New(field.name).Funcs(templateFuncs).Option("missingkey=error").Parse(field.text)
+			if err != nil {
+				return nil, fmt.Errorf("creative %s %s: %w", c.id, field.name, err)
+			}
+			*field.dst = parsed
+		}
+		compiled.creatives = append(compiled.creatives, c)
+	}
+	return compiled, nil
+}
+
+// RegisterRoutes 注册竞价接口：POST /bid 使用默认场景，POST /bid/:scenario 指定场景
+func (s *Simulator) RegisterRoutes(r gin.IRoutes) {
+	r.POST("/bid", s.handleBid)
+	r.POST("/bid/:scenario", s.handleBid)
+}
+
+func (s *Simulator) handleBid(c *gin.Context) {
+	name := c.Param("scenario")
+	if name == "" {
+		name = s.defaultScenario
+	}
+	sc, ok := s.scenarios[name]
+	if !ok {
+		c.String(http.StatusNotFound, "unknown scenario %q", name)
+		return
+	}
+
+	encoding := dspbidder.EncodingOf(c.ContentType())
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRequestSize))
+	if err != nil {
+		s.observe(name, resultBadRequest)
+		c.String(http.StatusBadRequest, "read body: %v", err)
+		return
+	}
+	req := &admux_rtb.BidRequest{}
+	if err := dspbidder.Unmarshal(encoding, body, req); err != nil {
+		s.observe(name, resultBadRequest)
+		c.String(http.StatusBadRequest, "parse bid request: %v", err)
+		return
+	}
+
+	// 超时注入：挂起直到 TimeoutDelay 或调用方放弃
+	if s.roll(sc.TimeoutRate) {
+		s.observe(name, resultTimeout)
+		select {
+		case <-time.After(sc.TimeoutDelay):
+			c.Status(http.StatusNoContent)
+		case <-c.Request.Context().Done():
+		}
+		return
+	}
+
+	if latency := s.latency(sc); latency > 0 {
+		if s.metrics != nil {
+			s.metrics.Latency.WithLabelValues(name).Observe(latency.Seconds())
+		}
+		select {
+		case <-time.After(latency):
+		case <-c.Request.Context().Done():
+			s.observe(name, resultTimeout)
+			return
+		}
+	}
+
+	if s.roll(sc.ErrorRate) {
+		s.observe(name, resultError)
+		c.String(sc.ErrorStatus, "simulated error")
+		return
+	}
+
+	resp, err := s.Respond(name, req)
+	if err != nil {
+		s.observe(name, resultError)
+		c.String(http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if len(resp.GetSeatbid()) == 0 {
+		s.observe(name, resultNoBid)
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	data, err := dspbidder.Marshal(encoding, resp)
+	if err != nil {
+		s.observe(name, resultError)
+		c.String(http.StatusInternalServerError, "marshal bid response: %v", err)
+		return
+	}
+	s.observe(name, resultBid)
+	c.Data(http.StatusOK, dspbidder.ContentType(encoding), data)
+}
+
+// Respond 按场景生成竞价响应(不含延迟和错误注入)，不出价时 Seatbid 为空
+func (s *Simulator) Respond(scenarioName string, req *admux_rtb.BidRequest) (*admux_rtb.BidResponse, error) {
+	sc, ok := s.scenarios[scenarioName]
+	if !ok {
+		return nil, fmt.Errorf("unknown scenario %q", scenarioName)
+	}
+
+	seat := &admux_rtb.BidResponse_SeatBid{}
+	if sc.Seat != "" {
+		seat.Seat = proto.String(sc.Seat)
+	}
+	for _, imp := range req.GetImp() {
+		if !s.roll(sc.BidRate) {
+			continue
+		}
+		// 出价精度到分
+		price := math.Round(s.sample(sc.Price)*100) / 100
+		if price <= 0 || (sc.RespectBidfloor && price < imp.GetBidfloor()) {
+			continue
+		}
+
+		c := sc.creatives[s.intn(len(sc.creatives))]
+		data := creativeData{
+			RequestID:  req.GetId(),
+			ImpID:      imp.GetId(),
+			BidID:      req.GetId() + "-" + imp.GetId(),
+			CreativeID: c.id,
+			Price:      price,
+		}
+		bid := &admux_rtb.BidResponse_SeatBid_Bid{
+			Id:      proto.String(data.BidID),
+			Impid:   proto.String(imp.GetId()),
+			Price:   proto.Float64(price),
+			Adid:    proto.String(c.id),
+			Crid:    proto.String(c.id),
+			Adomain: c.adomain,
+		}
+		if c.bundle != "" {
+			bid.Bundle = proto.String(c.bundle)
+		}
+		var err error
+		var adm string
+		if adm, err = render(c.adm, &data); err != nil {
+			return nil, err
+		}
+		if adm != "" {
+			bid.AdmOneof = &admux_rtb.BidResponse_SeatBid_Bid_Adm{Adm: adm}
+		}
+		for _, field := range []struct {
+			tpl *template.Template
+			dst **string
+		}{{c.nurl, &bid.Nurl}, {c.burl, &bid.Burl}, {c.lurl, &bid.Lurl}} {
+			value, err := render(field.tpl, &data)
+			if err != nil {
+				return nil, err
+			}
+			if value != "" {
+				*field.dst = proto.String(value)
+			}
+		}
+
+		seat.Bid = append(seat.Bid, bid)
+		if s.metrics != nil {
+			s.metrics.BidPrice.WithLabelValues(scenarioName).Observe(price)
+		}
+	}
+
+	resp := &admux_rtb.BidResponse{Id: proto.String(req.GetId())}
+	if len(seat.Bid) > 0 {
+		resp.Seatbid = []*admux_rtb.BidResponse_SeatBid{seat}
+	}
+	return resp, nil
+}
+
+func render(tpl *template.Template, data *creativeData) (string, error) {
+	if tpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render %s: %w", tpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+func (s *Simulator) observe(scenarioName, result string) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.Requests.WithLabelValues(scenarioName, result).Inc()
+}
+
+// latency 按分布抽取响应延迟
+func (s *Simulator) latency(sc *scenario) time.Duration {
+	ms := s.sample(sc.Latency)
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// roll 以概率 rate 返回true
+func (s *Simulator) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rnd.Float64() < rate
+}
+
+func (s *Simulator) intn(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rnd.Intn(n)
+}
+
+// sample 按分布抽样，normal/lognormal 在 max > min 时截断到 [min, max]
+func (s *Simulator) sample(d Distribution) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var v float64
+	switch d.Type {
+	case "", DistFixed:
+		return d.Value
+	case DistUniform:
+		return d.Min + s.rnd.Float64()*(d.Max-d.Min)
+	case DistNormal:
+		v = d.Mean + d.StdDev*s.rnd.NormFloat64()
+	case DistLogNormal:
+		// 由结果的均值、标准差换算底层正态分布参数
+		sigma2 := math.Log(1 + (d.StdDev*d.StdDev)/(d.Mean*d.Mean))
+		mu := math.Log(d.Mean) - sigma2/2
+		v = math.Exp(mu + math.Sqrt(sigma2)*s.rnd.NormFloat64())
+	}
+	if d.Max > d.Min {
+		v = math.Min(math.Max(v, d.Min), d.Max)
+	}
+	return v
+}
@@ -0,0 +1,148 @@
+package stub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/dspbidder"
+	"github.com/echoface/admux/internal/adx_engine/metrics"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+func newTestSimulator(t *testing.T) (*httptest.Server, *metrics.DSPSimulatorMetrics) {
+	t.Helper()
+	m := metrics.NewDSPSimulatorMetrics("admux", "dsp_simulator", prometheus.NewRegistry())
+	sim, err := NewSimulator(Config{
+		Seed: 1,
+		Scenarios: []Scenario{
+			{
+				Name:            "always",
+				BidRate:         1,
+				RespectBidfloor: true,
+				Seat:            "sim-seat",
+				Price:           Distribution{Type: DistFixed, Value: 8.5},
+				Creatives: []CreativeTemplate{{
+					ID:      "cr-1",
+					Adm:     `<img src="https://example.com/{{.BidID}}.png">`,
+					Adomain: []string{"example.com"},
+					Nurl:    `https://dsp.example.com/win?b={{.BidID}}&p={{macro "AUCTION_PRICE"}}`,
+				}},
+			},
+			{Name: "nobid"},
+			{Name: "broken", BidRate: 1, Price: Distribution{Value: 1}, ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable},
+			{Name: "hang", BidRate: 1, Price: Distribution{Value: 1}, TimeoutRate: 1, TimeoutDelay: time.Second},
+		},
+	}, m)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	sim.RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, m
+}
+
+func newBidCtx() *adxcore.BidRequestCtx {
+	return adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id: proto.String("req-1"),
+		Imp: []*admux_rtb.BidRequest_Imp{
+			{Id: proto.String("imp-1"), Bidfloor: proto.Float64(1)},
+			{Id: proto.String("imp-2"), Bidfloor: proto.Float64(10)},
+		},
+	})
+}
+
+func TestSimulator_HTTPBidder(t *testing.T) {
+	server, m := newTestSimulator(t)
+
+	for _, encoding := range []string{dspbidder.EncodingJSON, dspbidder.EncodingProtobuf} {
+		t.Run(encoding, func(t *testing.T) {
+			bidder := dspbidder.NewHTTPBidder("dsp_sim", server.URL+"/bid", 0, time.Second, http.DefaultClient)
+			bidder.Encoding = encoding
+
+			candidates, err := bidder.SendBidRequest(newBidCtx())
+			require.NoError(t, err)
+			// imp-2 底价高于出价，不出价
+			require.Len(t, candidates, 1)
+			candidate := candidates[0]
+			assert.Equal(t, "dsp_sim", candidate.BidderID)
+			assert.Equal(t, "sim-seat", candidate.Seat)
+			assert.Equal(t, int64(850), candidate.CPMPrice)
+			assert.Equal(t, "req-1-imp-1", candidate.Bid.GetId())
+			assert.Equal(t, "cr-1", candidate.Bid.GetCrid())
+			assert.Equal(t, `<img src="https://example.com/req-1-imp-1.png">`, candidate.Bid.GetAdm())
+			assert.Equal(t, "https://dsp.example.com/win?b=req-1-imp-1&p=${AUCTION_PRICE}", candidate.Bid.GetNurl())
+		})
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.Requests.WithLabelValues("always", resultBid)))
+}
+
+func TestSimulator_Scenarios(t *testing.T) {
+	server, m := newTestSimulator(t)
+	send := func(scenario string, timeout time.Duration) ([]*adxcore.BidCandidate, error) {
+		bidder := dspbidder.NewHTTPBidder("dsp_sim", server.URL+"/bid/"+scenario, 0, timeout, http.DefaultClient)
+		return bidder.SendBidRequest(newBidCtx())
+	}
+
+	candidates, err := send("nobid", time.Second)
+	assert.NoError(t, err)
+	assert.Empty(t, candidates)
+
+	_, err = send("broken", time.Second)
+	assert.ErrorContains(t, err, "503")
+
+	start := time.Now()
+	_, err = send("hang", 50*time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	_, err = send("unknown", time.Second)
+	assert.ErrorContains(t, err, "404")
+
+	resp, err := http.Post(server.URL+"/bid", dspbidder.ContentTypeJSON, strings.NewReader("{"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Requests.WithLabelValues("nobid", resultNoBid)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Requests.WithLabelValues("broken", resultError)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Requests.WithLabelValues("hang", resultTimeout)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Requests.WithLabelValues("always", resultBadRequest)))
+}
+
+func TestSimulator_Distributions(t *testing.T) {
+	sim, err := NewSimulator(Config{Seed: 7, Scenarios: []Scenario{{Name: "s"}}}, nil)
+	require.NoError(t, err)
+
+	for _, d := range []Distribution{
+		{Type: DistUniform, Min: 1, Max: 3},
+		{Type: DistNormal, Mean: 2, StdDev: 5, Min: 1, Max: 3},
+		{Type: DistLogNormal, Mean: 2, StdDev: 1, Min: 1, Max: 3},
+	} {
+		for i := 0; i < 1000; i++ {
+			v := sim.sample(d)
+			assert.GreaterOrEqual(t, v, 1.0, d.Type)
+			assert.LessOrEqual(t, v, 3.0, d.Type)
+		}
+	}
+
+	_, err = NewSimulator(Config{Scenarios: []Scenario{{Name: "s", BidRate: 2}}}, nil)
+	assert.Error(t, err)
+	_, err = NewSimulator(Config{Scenarios: []Scenario{{Name: "s", Price: Distribution{Type: "zipf"}}}}, nil)
+	assert.Error(t, err)
+	_, err = NewSimulator(Config{DefaultScenario: "x", Scenarios: []Scenario{{Name: "s"}}}, nil)
+	assert.Error(t, err)
+}
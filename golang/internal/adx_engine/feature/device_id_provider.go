@@ -0,0 +1,193 @@
+package feature
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// ProviderDeviceID 设备ID归一化特征提供者名称
+const ProviderDeviceID = "device_id"
+
+var (
+	reIMEI      = regexp.MustCompile(`^[0-9a-fA-F]{14,15}$`)
+	reIDFA      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	reOAID      = regexp.MustCompile(`^[0-9a-zA-Z-]{16,100}$`)
+	reAndroidID = regexp.MustCompile(`^[0-9a-fA-F]{13,16}$`)
+	reMD5       = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+)
+
+// deviceIDForm 设备ID的明文/MD5两种形式
+type deviceIDForm struct {
+	raw, md5 string
+	// normalize 校验并归一化明文，非法时返回false
+	normalize func(string) (string, bool)
+}
+
+// deviceIDForms 支持补全MD5的设备ID，顺序即用户唯一键的优先级
+var deviceIDForms = []deviceIDForm{
+	{adxcore.DeviceIDIDFA, adxcore.DeviceIDIDFAMD5, normalizeWith(reIDFA, strings.ToUpper)},
+	{adxcore.DeviceIDOAID, adxcore.DeviceIDOAIDMD5, normalizeWith(reOAID, nil)},
+	{adxcore.DeviceIDIMEI, adxcore.DeviceIDIMEIMD5, normalizeWith(reIMEI, strings.ToLower)},
+	{adxcore.DeviceIDAndroidID, adxcore.DeviceIDAndroidIDMD5, normalizeWith(reAndroidID, strings.ToLower)},
+}
+
+// adTrackingIDs 受限制广告追踪约束的广告标识符
+var adTrackingIDs = []string{adxcore.DeviceIDIDFA, adxcore.DeviceIDIDFAMD5, adxcore.DeviceIDOAID, adxcore.DeviceIDOAIDMD5}
+
+// zeroMD5s 全零ID的MD5，部分媒体对未授权的全零IDFA直接取MD5上报
+var zeroMD5s = map[string]struct{}{
+	md5Hex("00000000-0000-0000-0000-000000000000"): {},
+	md5Hex("00000000000000"):                       {},
+	md5Hex("000000000000000"):                      {},
+	md5Hex("0000000000000000"):                     {},
+}
+
+// DeviceIDProvider 归一化并校验请求中的设备ID，补全缺失的MD5形式，
+// 标记全零和限制广告追踪的ID，并生成用于画像查询的用户唯一键
+type DeviceIDProvider struct{}
+
+// NewDeviceIDProvider 创建设备ID特征提供者
+func NewDeviceIDProvider() *DeviceIDProvider {
+	return &DeviceIDProvider{}
+}
+
+// Name 返回提供者名称
+func (p *DeviceIDProvider) Name() string {
+	return ProviderDeviceID
+}
+
+// Provide 产出归一化设备ID，请求中没有任何设备ID时不产出
+func (p *DeviceIDProvider) Provide(_ context.Context, bidCtx *adxcore.BidRequestCtx, out *adxcore.Features) error {
+	ids := NormalizeDeviceIDs(bidCtx.Request)
+	if len(ids.Values) == 0 && len(ids.Zeroed) == 0 && len(ids.Invalid) == 0 {
+		return nil
+	}
+	adxcore.SetFeature(out, adxcore.FeatureDeviceIDs, ids)
+	return nil
+}
+
+// NormalizeDeviceIDs 从请求中收集设备ID并归一化：
+// device.ifa 在iOS上为IDFA、其他系统为OAID，didmd5/dpidmd5 为 IMEI/Android ID 的MD5，
+// user.eids 中 source 为设备ID类型的条目(IMEI明文、OAID、CAID等)
+func NormalizeDeviceIDs(req *admux_rtb.BidRequest) *adxcore.DeviceIDs {
+	ids := &adxcore.DeviceIDs{Values: make(map[string]string)}
+
+	collected := make(map[string]string)
+	collect := func(idType, value string) {
+		if value = strings.TrimSpace(value); value != "" && collected[idType] == "" {
+			collected[idType] = value
+		}
+	}
+
+	device := req.GetDevice()
+	if strings.EqualFold(device.GetOs(), OSIOS) {
+		collect(adxcore.DeviceIDIDFA, device.GetIfa())
+	} else {
+		collect(adxcore.DeviceIDOAID, device.GetIfa())
+	}
+	collect(adxcore.DeviceIDIMEIMD5, device.GetDidmd5())
+	collect(adxcore.DeviceIDAndroidIDMD5, device.GetDpidmd5())
+	for _, eid := range req.GetUser().GetEids() {
+		if !adxcore.IsKnownDeviceIDType(eid.GetSource()) || len(eid.GetUids()) == 0 {
+			continue
+		}
+		collect(eid.GetSource(), eid.GetUids()[0].GetId())
+	}
+
+	for _, form := range deviceIDForms {
+		normalizeForm(ids, form, collected[form.raw], collected[form.md5])
+	}
+	if caid := collected[adxcore.DeviceIDCAID]; caid != "" {
+		switch {
+		case isZeroID(caid):
+			ids.Zeroed = append(ids.Zeroed, adxcore.DeviceIDCAID)
+		case reMD5.MatchString(caid):
+			ids.Values[adxcore.DeviceIDCAID] = strings.ToLower(caid)
+		default:
+			ids.Invalid = append(ids.Invalid, adxcore.DeviceIDCAID)
+		}
+	}
+
+	if device.GetLmt() {
+		ids.LimitAdTracking = true
+		for _, idType := range adTrackingIDs {
+			if _, ok := ids.Values[idType]; ok {
+				ids.Limited = append(ids.Limited, idType)
+				delete(ids.Values, idType)
+			}
+		}
+	}
+
+	for _, idType := range []string{
+		adxcore.DeviceIDIDFAMD5, adxcore.DeviceIDOAIDMD5, adxcore.DeviceIDIMEIMD5,
+		adxcore.DeviceIDAndroidIDMD5, adxcore.DeviceIDCAID,
+	} {
+		if value, ok := ids.Get(idType); ok {
+			ids.CanonicalKey = idType + ":" + value
+			break
+		}
+	}
+	return ids
+}
+
+// normalizeForm 归一化一种设备ID的明文和MD5形式，明文存在时由明文计算MD5
+func normalizeForm(ids *adxcore.DeviceIDs, form deviceIDForm, raw, hashed string) {
+	// 部分媒体在MD5字段中上报明文
+	if raw == "" && hashed != "" && !reMD5.MatchString(hashed) {
+		raw, hashed = hashed, ""
+	}
+
+	if raw != "" {
+		switch normalized, ok := form.normalize(raw); {
+		case isZeroID(raw):
+			ids.Zeroed = append(ids.Zeroed, form.raw)
+		case !ok:
+			ids.Invalid = append(ids.Invalid, form.raw)
+		default:
+			ids.Values[form.raw] = normalized
+			ids.Values[form.md5] = md5Hex(normalized)
+			return
+		}
+	}
+
+	if hashed == "" {
+		return
+	}
+	hashed = strings.ToLower(hashed)
+	switch _, zero := zeroMD5s[hashed]; {
+	case zero || isZeroID(hashed):
+		ids.Zeroed = append(ids.Zeroed, form.md5)
+	case !reMD5.MatchString(hashed):
+		ids.Invalid = append(ids.Invalid, form.md5)
+	default:
+		ids.Values[form.md5] = hashed
+	}
+}
+
+func normalizeWith(pattern *regexp.Regexp, transform func(string) string) func(string) (string, bool) {
+	return func(value string) (string, bool) {
+		if !pattern.MatchString(value) {
+			return "", false
+		}
+		if transform != nil {
+			value = transform(value)
+		}
+		return value, true
+	}
+}
+
+// isZeroID 判断是否为全零ID(忽略连字符)
+func isZeroID(value string) bool {
+	return strings.Trim(value, "0-") == "" && strings.Contains(value, "0")
+}
+
+func md5Hex(value string) string {
+	sum := md5.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,112 @@
+package feature
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+func deviceIDEID(source, id string) *admux_rtb.BidRequest_User_EID {
+	return &admux_rtb.BidRequest_User_EID{
+		Source: proto.String(source),
+		Uids:   []*admux_rtb.BidRequest_User_EID_UID{{Id: proto.String(id)}},
+	}
+}
+
+func TestNormalizeDeviceIDs_Android(t *testing.T) {
+	ids := NormalizeDeviceIDs(&admux_rtb.BidRequest{
+		Device: &admux_rtb.BidRequest_Device{
+			Os:      proto.String("android"),
+			Ifa:     proto.String(" 1fd9a2c3-0b5e-4c8d-9e1f-2a3b4c5d6e7f "),
+			Dpidmd5: proto.String("ABCDEF0123456789ABCDEF0123456789"),
+		},
+		User: &admux_rtb.BidRequest_User{Eids: []*admux_rtb.BidRequest_User_EID{
+			deviceIDEID(adxcore.DeviceIDIMEI, "86123456789012A"),
+			deviceIDEID(adxcore.DeviceIDAndroidID, "not-an-android-id"),
+			deviceIDEID("pubcid", "ignored"),
+		}},
+	})
+
+	assert.Equal(t, map[string]string{
+		adxcore.DeviceIDOAID:         "1fd9a2c3-0b5e-4c8d-9e1f-2a3b4c5d6e7f",
+		adxcore.DeviceIDOAIDMD5:      md5Hex("1fd9a2c3-0b5e-4c8d-9e1f-2a3b4c5d6e7f"),
+		adxcore.DeviceIDIMEI:         "86123456789012a",
+		adxcore.DeviceIDIMEIMD5:      md5Hex("86123456789012a"),
+		adxcore.DeviceIDAndroidIDMD5: "abcdef0123456789abcdef0123456789",
+	}, ids.Values)
+	assert.Equal(t, []string{adxcore.DeviceIDAndroidID}, ids.Invalid)
+	assert.Equal(t, "oaid_md5:"+md5Hex("1fd9a2c3-0b5e-4c8d-9e1f-2a3b4c5d6e7f"), ids.CanonicalKey)
+}
+
+func TestNormalizeDeviceIDs_ZeroedAndLimited(t *testing.T) {
+	ids := NormalizeDeviceIDs(&admux_rtb.BidRequest{
+		Device: &admux_rtb.BidRequest_Device{
+			Os:     proto.String("iOS"),
+			Ifa:    proto.String("00000000-0000-0000-0000-000000000000"),
+			Didmd5: proto.String(md5Hex("000000000000000")),
+		},
+		User: &admux_rtb.BidRequest_User{Eids: []*admux_rtb.BidRequest_User_EID{
+			deviceIDEID(adxcore.DeviceIDCAID, "E3B0C44298FC1C149AFBF4C8996FB924"),
+		}},
+	})
+	assert.ElementsMatch(t, []string{adxcore.DeviceIDIDFA, adxcore.DeviceIDIMEIMD5}, ids.Zeroed)
+	assert.Equal(t, map[string]string{adxcore.DeviceIDCAID: "e3b0c44298fc1c149afbf4c8996fb924"}, ids.Values)
+	assert.Equal(t, "caid:e3b0c44298fc1c149afbf4c8996fb924", ids.CanonicalKey)
+
+	// 限制广告追踪时 IDFA 不可用，由 IMEI-MD5 生成用户唯一键
+	limited := NormalizeDeviceIDs(&admux_rtb.BidRequest{
+		Device: &admux_rtb.BidRequest_Device{
+			Os:     proto.String("ios"),
+			Lmt:    proto.Bool(true),
+			Ifa:    proto.String("6d92078a-8246-4ba4-ae5b-76104861e7dc"),
+			Didmd5: proto.String("861234567890123"),
+		},
+	})
+	assert.True(t, limited.LimitAdTracking)
+	assert.ElementsMatch(t, []string{adxcore.DeviceIDIDFA, adxcore.DeviceIDIDFAMD5}, limited.Limited)
+	assert.Equal(t, "imei_md5:"+md5Hex("861234567890123"), limited.CanonicalKey)
+}
+
+func TestDeviceIDProvider_ForBidder(t *testing.T) {
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id: proto.String("req-1"),
+		Device: &admux_rtb.BidRequest_Device{
+			Os:      proto.String("android"),
+			Ifa:     proto.String("1fd9a2c3-0b5e-4c8d-9e1f-2a3b4c5d6e7f"),
+			Didsha1: proto.String("da39a3ee5e6b4b0d3255bfef95601890afd80709"),
+		},
+		User: &admux_rtb.BidRequest_User{Eids: []*admux_rtb.BidRequest_User_EID{
+			deviceIDEID(adxcore.DeviceIDIMEI, "861234567890123"),
+			deviceIDEID("pubcid", "keep"),
+		}},
+	})
+	require.NoError(t, NewDeviceIDProvider().Provide(context.Background(), bidCtx, bidCtx.Features))
+
+	// 默认策略不下发IMEI明文
+	view := bidCtx.ForBidder(&adxcore.BidderInfo{ID: "dsp_a"})
+	device := view.Request.GetDevice()
+	assert.Equal(t, "1fd9a2c3-0b5e-4c8d-9e1f-2a3b4c5d6e7f", device.GetIfa())
+	assert.Equal(t, md5Hex("861234567890123"), device.GetDidmd5())
+	assert.Empty(t, device.GetDidsha1())
+	var sources []string
+	for _, eid := range view.Request.GetUser().GetEids() {
+		sources = append(sources, eid.GetSource())
+	}
+	assert.Equal(t, []string{"pubcid", adxcore.DeviceIDOAIDMD5}, sources)
+
+	// 只允许 imei_md5
+	view = bidCtx.ForBidder(&adxcore.BidderInfo{ID: "dsp_b", AllowedDeviceIDs: []string{adxcore.DeviceIDIMEIMD5}})
+	assert.Empty(t, view.Request.GetDevice().GetIfa())
+	assert.Equal(t, md5Hex("861234567890123"), view.Request.GetDevice().GetDidmd5())
+	assert.Len(t, view.Request.GetUser().GetEids(), 1)
+
+	// 原请求不受影响
+	assert.Equal(t, "da39a3ee5e6b4b0d3255bfef95601890afd80709", bidCtx.Request.GetDevice().GetDidsha1())
+	assert.Len(t, bidCtx.Request.GetUser().GetEids(), 2)
+}
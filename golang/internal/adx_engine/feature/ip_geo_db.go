@@ -0,0 +1,296 @@
+package feature
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ipGeoReloadDebounce 文件变化事件合并窗口
+const ipGeoReloadDebounce = 200 * time.Millisecond
+
+// defaultIPGeoPollInterval 未配置时的文件变化轮询间隔，fsnotify不可用时依赖轮询
+const defaultIPGeoPollInterval = time.Minute
+
+// GeoInfo IP地理信息
+type GeoInfo struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+	City    string `json:"city"`
+	ISP     string `json:"isp"`
+}
+
+// ipRadixNode 二叉前缀树节点，按地址位逐位分支
+type ipRadixNode struct {
+	children [2]*ipRadixNode
+	info     *GeoInfo
+}
+
+// ipRadixTree IPv4/IPv6 最长前缀匹配树，IPv4统一映射为 ::ffff:a.b.c.d 存储
+type ipRadixTree struct {
+	root    ipRadixNode
+	entries int
+}
+
+// insert 插入一个网段
+func (t *ipRadixTree) insert(prefix netip.Prefix, info *GeoInfo) {
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4() {
+		addr, bits = netip.AddrFrom16(addr.As16()), bits+96
+	}
+
+	bytes := addr.As16()
+	node := &t.root
+	for i := 0; i < bits; i++ {
+		bit := (bytes[i/8] >> (7 - uint(i%8))) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &ipRadixNode{}
+		}
+		node = node.children[bit]
+	}
+	node.info = info
+	t.entries++
+}
+
+// lookup 最长前缀匹配
+func (t *ipRadixTree) lookup(addr netip.Addr) (*GeoInfo, bool) {
+	// As16 对IPv4返回 ::ffff:a.b.c.d 形式，与 insert 的存储方式一致
+	bytes := addr.As16()
+
+	var found *GeoInfo
+	node := &t.root
+	for i := 0; i < 128 && node != nil; i++ {
+		if node.info != nil {
+			found = node.info
+		}
+		bit := (bytes[i/8] >> (7 - uint(i%8))) & 1
+		node = node.children[bit]
+	}
+	if node != nil && node.info != nil {
+		found = node.info
+	}
+	return found, found != nil
+}
+
+// parseIPGeoCSV 解析CIDR CSV，每行格式: cidr,country,region,city,isp
+// 空行和 # 开头的注释行被忽略，首行为表头时自动跳过
+func parseIPGeoCSV(r io.Reader) (*ipRadixTree, error) {
+	tree := &ipRadixTree{}
+	scanner := bufio.NewScanner(r)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			if lineNo == 1 {
+				continue // 表头
+			}
+			return nil, fmt.Errorf("line %d: invalid cidr %q: %w", lineNo, fields[0], err)
+		}
+
+		info := &GeoInfo{}
+		for i, dst := range []*string{&info.Country, &info.Region, &info.City, &info.ISP} {
+			if i+1 < len(fields) {
+				*dst = fields[i+1]
+			}
+		}
+		tree.insert(prefix.Masked(), info)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ip geo csv error: %w", err)
+	}
+
+	return tree, nil
+}
+
+// IPGeoDB 本地IP地理库，文件变化时自动重新加载，加载失败时保留旧数据
+type IPGeoDB struct {
+	path         string
+	pollInterval time.Duration
+	tree         atomic.Pointer[ipRadixTree]
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewIPGeoDB 加载IP地理库文件
+func NewIPGeoDB(path string, pollInterval time.Duration) (*IPGeoDB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("ip geo db path is empty")
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultIPGeoPollInterval
+	}
+
+	db := &IPGeoDB{
+		path:         path,
+		pollInterval: pollInterval,
+		done:         make(chan struct{}),
+	}
+	if err := db.Reload(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Lookup 查询IP对应的地理信息
+func (db *IPGeoDB) Lookup(addr netip.Addr) (*GeoInfo, bool) {
+	tree := db.tree.Load()
+	if tree == nil || !addr.IsValid() {
+		return nil, false
+	}
+	return tree.lookup(addr)
+}
+
+// Entries 返回当前加载的网段数
+func (db *IPGeoDB) Entries() int {
+	if tree := db.tree.Load(); tree != nil {
+		return tree.entries
+	}
+	return 0
+}
+
+// Reload 重新加载库文件，成功后原子替换
+func (db *IPGeoDB) Reload() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	file, err := os.Open(db.path)
+	if err != nil {
+		return fmt.Errorf("failed to open ip geo db %s: %w", db.path, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat ip geo db %s: %w", db.path, err)
+	}
+
+	tree, err := parseIPGeoCSV(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse ip geo db %s: %w", db.path, err)
+	}
+
+	db.tree.Store(tree)
+	db.modTime, db.size = stat.ModTime(), stat.Size()
+	log.Printf("ip geo db loaded from %s, entries: %d", db.path, tree.entries)
+	return nil
+}
+
+// Watch 监控库文件变化并自动重新加载
+func (db *IPGeoDB) Watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("fsnotify unavailable for %s, fallback to polling: %v", db.path, err)
+	} else if err := watcher.Add(filepath.Dir(db.path)); err != nil {
+		// 监控所在目录，兼容先写临时文件再rename的更新方式
+		log.Printf("failed to watch %s, fallback to polling: %v", db.path, err)
+		watcher.Close()
+	} else {
+		db.watcher = watcher
+	}
+
+	go db.watchLoop()
+}
+
+// watchLoop 监控循环
+func (db *IPGeoDB) watchLoop() {
+	ticker := time.NewTicker(db.pollInterval)
+	defer ticker.Stop()
+
+	debounce := time.NewTimer(ipGeoReloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if db.watcher != nil {
+		events = db.watcher.Events
+		errs = db.watcher.Errors
+	}
+
+	target := filepath.Clean(db.path)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if filepath.Clean(event.Name) == target {
+				debounce.Reset(ipGeoReloadDebounce)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			log.Printf("watch ip geo db %s error: %v", db.path, err)
+		case <-debounce.C:
+			db.reloadLogged()
+		case <-ticker.C:
+			if db.changed() {
+				db.reloadLogged()
+			}
+		case <-db.done:
+			return
+		}
+	}
+}
+
+// changed 判断文件是否相对上次加载发生变化
+func (db *IPGeoDB) changed() bool {
+	stat, err := os.Stat(db.path)
+	if err != nil {
+		return false
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return !stat.ModTime().Equal(db.modTime) || stat.Size() != db.size
+}
+
+// reloadLogged 重新加载，失败时保留旧数据
+func (db *IPGeoDB) reloadLogged() {
+	if err := db.Reload(); err != nil {
+		log.Printf("reload ip geo db failed, keep previous version: %v", err)
+	}
+}
+
+// Close 停止监控
+func (db *IPGeoDB) Close() error {
+	var err error
+	db.once.Do(func() {
+		close(db.done)
+		if db.watcher != nil {
+			err = db.watcher.Close()
+		}
+	})
+	return err
+}
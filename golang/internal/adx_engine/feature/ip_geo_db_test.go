@@ -0,0 +1,127 @@
+package feature
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+const testIPGeoCSV = `cidr,country,region,city,isp
+# 注释行
+1.2.0.0/16,CN,Beijing,Beijing,China Telecom
+1.2.3.0/24,CN,Shanghai,Shanghai,China Unicom
+2001:db8::/32,US,California,San Jose,Example ISP
+`
+
+func writeIPGeoDB(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "ip_geo.csv")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestParseIPGeoCSV_LongestPrefixMatch(t *testing.T) {
+	tree, err := parseIPGeoCSV(strings.NewReader(testIPGeoCSV))
+	require.NoError(t, err)
+	assert.Equal(t, 3, tree.entries)
+
+	info, ok := tree.lookup(netip.MustParseAddr("1.2.3.4"))
+	require.True(t, ok)
+	assert.Equal(t, "Shanghai", info.City)
+
+	info, ok = tree.lookup(netip.MustParseAddr("1.2.200.1"))
+	require.True(t, ok)
+	assert.Equal(t, "Beijing", info.City)
+	assert.Equal(t, "China Telecom", info.ISP)
+
+	info, ok = tree.lookup(netip.MustParseAddr("2001:db8::1"))
+	require.True(t, ok)
+	assert.Equal(t, "US", info.Country)
+
+	_, ok = tree.lookup(netip.MustParseAddr("8.8.8.8"))
+	assert.False(t, ok)
+}
+
+func TestParseIPGeoCSV_InvalidLine(t *testing.T) {
+	_, err := parseIPGeoCSV(strings.NewReader("1.2.0.0/16,CN\nnot-a-cidr,US\n"))
+	assert.Error(t, err)
+}
+
+func TestIPGeoDB_Reload(t *testing.T) {
+	path := writeIPGeoDB(t, t.TempDir(), testIPGeoCSV)
+
+	db, err := NewIPGeoDB(path, 20*time.Millisecond)
+	require.NoError(t, err)
+	db.Watch()
+	defer db.Close()
+
+	info, ok := db.Lookup(netip.MustParseAddr("1.2.3.4"))
+	require.True(t, ok)
+	assert.Equal(t, "Shanghai", info.City)
+
+	// 无效文件不替换旧数据
+	require.NoError(t, os.WriteFile(path, []byte("1.2.0.0/16,CN\nbroken\n"), 0o644))
+	require.Error(t, db.Reload())
+	assert.Equal(t, 3, db.Entries())
+
+	require.NoError(t, os.WriteFile(path, []byte("1.2.3.0/24,CN,Guangdong,Shenzhen,China Mobile\n"), 0o644))
+	assert.Eventually(t, func() bool {
+		info, ok := db.Lookup(netip.MustParseAddr("1.2.3.4"))
+		return ok && info.City == "Shenzhen"
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 1, db.Entries())
+}
+
+func TestIPGeoProvider_FillDeviceGeo(t *testing.T) {
+	db, err := NewIPGeoDB(writeIPGeoDB(t, t.TempDir(), testIPGeoCSV), 0)
+	require.NoError(t, err)
+
+	registry := adxcore.NewFeatureRegistry(time.Second, nil)
+	require.NoError(t, registry.Register(NewRequestProvider()))
+	require.NoError(t, registry.Register(NewIPGeoProvider(db)))
+
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id:     proto.String("req-1"),
+		Device: &admux_rtb.BidRequest_Device{Ip: proto.String("1.2.3.4")},
+	})
+	registry.Complete(bidCtx)
+
+	geo := bidCtx.Request.GetDevice().GetGeo()
+	assert.Equal(t, "CN", geo.GetCountry())
+	assert.Equal(t, "Shanghai", geo.GetCity())
+	assert.Equal(t, admux_rtb.LocationType_IP, geo.GetType())
+	assert.Equal(t, "China Unicom", bidCtx.Request.GetDevice().GetCarrier())
+	assert.Equal(t, []string{"CN", "SHANGHAI", "SHANGHAI"}, bidCtx.Features.TargetingAssignments()[adxcore.TargetingFieldUserGeo])
+}
+
+func TestIPGeoProvider_KeepExistingGeo(t *testing.T) {
+	db, err := NewIPGeoDB(writeIPGeoDB(t, t.TempDir(), testIPGeoCSV), 0)
+	require.NoError(t, err)
+
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id: proto.String("req-2"),
+		Device: &admux_rtb.BidRequest_Device{
+			Ipv6: proto.String("2001:db8::1"),
+			Geo:  &admux_rtb.BidRequest_Geo{Country: proto.String("JP")},
+		},
+	})
+
+	provider := NewIPGeoProvider(db)
+	out := adxcore.NewFeatures()
+	require.NoError(t, provider.Provide(context.Background(), bidCtx, out))
+	provider.Enrich(bidCtx, out)
+
+	assert.Empty(t, out.Keys())
+	assert.Equal(t, "JP", bidCtx.Request.GetDevice().GetGeo().GetCountry())
+}
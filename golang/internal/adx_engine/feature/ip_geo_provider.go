@@ -0,0 +1,112 @@
+package feature
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProviderIPGeo IP地理特征提供者名称
+const ProviderIPGeo = "ip_geo"
+
+// FeatureIPGeo IP解析得到的地理信息
+const FeatureIPGeo adxcore.FeatureKey[*GeoInfo] = "ip_geo"
+
+// IPGeoProvider 基于本地IP库解析设备IP的地理位置，不依赖网络
+// 请求已携带设备地理位置时不做解析
+type IPGeoProvider struct {
+	db *IPGeoDB
+}
+
+// NewIPGeoProvider 创建IP地理特征提供者
+func NewIPGeoProvider(db *IPGeoDB) *IPGeoProvider {
+	return &IPGeoProvider{db: db}
+}
+
+// Name 返回提供者名称
+func (p *IPGeoProvider) Name() string {
+	return ProviderIPGeo
+}
+
+// Provide 解析设备IP，产出地理信息和 USER_GEO 定向特征
+func (p *IPGeoProvider) Provide(_ context.Context, bidCtx *adxcore.BidRequestCtx, out *adxcore.Features) error {
+	device := bidCtx.Request.GetDevice()
+	if device == nil || hasGeoLocation(device.GetGeo()) {
+		return nil
+	}
+
+	addr, ok := deviceAddr(device)
+	if !ok {
+		return nil
+	}
+
+	info, ok := p.db.Lookup(addr)
+	if !ok {
+		return nil
+	}
+
+	adxcore.SetFeature(out, FeatureIPGeo, info)
+	geo := &admux_rtb.BidRequest_Geo{
+		Country: proto.String(info.Country),
+		Region:  proto.String(info.Region),
+		City:    proto.String(info.City),
+	}
+	if values := GeoTargetingValues(geo); len(values) > 0 {
+		adxcore.SetFeature(out, adxcore.FeatureUserGeo, values)
+	}
+	return nil
+}
+
+// Enrich 设备地理位置为空时回写IP解析结果，运营商为空时回写ISP
+func (p *IPGeoProvider) Enrich(bidCtx *adxcore.BidRequestCtx, features *adxcore.Features) {
+	info, ok := adxcore.GetFeature(features, FeatureIPGeo)
+	if !ok || info == nil {
+		return
+	}
+
+	device := bidCtx.Request.GetDevice()
+	if device == nil {
+		return
+	}
+
+	if !hasGeoLocation(device.GetGeo()) {
+		if device.Geo == nil {
+			device.Geo = &admux_rtb.BidRequest_Geo{}
+		}
+		geo := device.Geo
+		setIfNotEmpty(&geo.Country, info.Country)
+		setIfNotEmpty(&geo.Region, info.Region)
+		setIfNotEmpty(&geo.City, info.City)
+		geo.Type = admux_rtb.LocationType_IP.Enum()
+	}
+	if device.GetCarrier() == "" {
+		setIfNotEmpty(&device.Carrier, info.ISP)
+	}
+}
+
+// hasGeoLocation 判断地理位置是否已包含国家、地区或城市
+func hasGeoLocation(geo *admux_rtb.BidRequest_Geo) bool {
+	return geo.GetCountry() != "" || geo.GetRegion() != "" || geo.GetCity() != ""
+}
+
+// deviceAddr 获取设备IP，IPv4优先
+func deviceAddr(device *admux_rtb.BidRequest_Device) (netip.Addr, bool) {
+	for _, ip := range []string{device.GetIp(), device.GetIpv6()} {
+		if ip == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func setIfNotEmpty(dst **string, value string) {
+	if value != "" {
+		*dst = proto.String(value)
+	}
+}
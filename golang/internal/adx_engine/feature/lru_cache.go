@@ -0,0 +1,70 @@
+package feature
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache 并发安全的定长LRU缓存
+type lruCache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// newLRUCache 创建LRU缓存，capacity<=0 时退化为容量1
+func newLRUCache[K comparable, V any](capacity int) *lruCache[K, V] {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &lruCache[K, V]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[K]*list.Element, capacity),
+	}
+}
+
+// Get 读取缓存并标记为最近使用
+func (c *lruCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		return elem.Value.(*lruEntry[K, V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Add 写入缓存，超出容量时淘汰最久未使用的条目
+func (c *lruCache[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry[K, V]).value = value
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry[K, V]{key: key, value: value})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// Len 返回缓存条目数
+func (c *lruCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
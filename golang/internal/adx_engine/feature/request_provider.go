@@ -0,0 +1,99 @@
+package feature
+
+import (
+	"context"
+	"strings"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// ProviderRequest 请求特征提供者名称
+const ProviderRequest = "request"
+
+// deviceTypeValues OpenRTB 设备类型到定向取值的映射
+var deviceTypeValues = map[admux_rtb.DeviceType]string{
+	admux_rtb.DeviceType_MOBILE:            "mobile",
+	admux_rtb.DeviceType_HIGHEND_PHONE:     "mobile",
+	admux_rtb.DeviceType_TABLET:            "tablet",
+	admux_rtb.DeviceType_PERSONAL_COMPUTER: "desktop",
+	admux_rtb.DeviceType_CONNECTED_TV:      "tv",
+	admux_rtb.DeviceType_SET_TOP_BOX:       "tv",
+	admux_rtb.DeviceType_CONNECTED_DEVICE:  "connected_device",
+	admux_rtb.DeviceType_OOH_DEVICE:        "ooh",
+}
+
+// connectionTypeValues OpenRTB 网络类型到定向取值的映射
+var connectionTypeValues = map[admux_rtb.ConnectionType]string{
+	admux_rtb.ConnectionType_ETHERNET:     "ethernet",
+	admux_rtb.ConnectionType_WIFI:         "wifi",
+	admux_rtb.ConnectionType_CELL_UNKNOWN: "cellular",
+	admux_rtb.ConnectionType_CELL_2G:      "2g",
+	admux_rtb.ConnectionType_CELL_3G:      "3g",
+	admux_rtb.ConnectionType_CELL_4G:      "4g",
+	admux_rtb.ConnectionType_CELL_5G:      "5g",
+}
+
+// RequestProvider 从 OpenRTB 请求本身提取基础定向特征
+// 其他提供者(如IP地理、UA解析)产出的同名特征会覆盖这里的结果
+type RequestProvider struct{}
+
+// NewRequestProvider 创建请求特征提供者
+func NewRequestProvider() *RequestProvider {
+	return &RequestProvider{}
+}
+
+// Name 返回提供者名称
+func (p *RequestProvider) Name() string {
+	return ProviderRequest
+}
+
+// Provide 提取设备、地理、内容类别等定向特征
+func (p *RequestProvider) Provide(_ context.Context, bidCtx *adxcore.BidRequestCtx, out *adxcore.Features) error {
+	req := bidCtx.Request
+	if req == nil {
+		return nil
+	}
+
+	device := req.GetDevice()
+	if os := strings.ToLower(device.GetOs()); os != "" {
+		adxcore.SetFeature(out, adxcore.FeatureUserOS, []string{os})
+	}
+	// devicetype 未设置时 getter 返回 MOBILE，需要判断字段是否存在
+	if value, ok := deviceTypeValues[device.GetDevicetype()]; ok && device.Devicetype != nil {
+		adxcore.SetFeature(out, adxcore.FeatureDeviceType, []string{value})
+	}
+	if value, ok := connectionTypeValues[device.GetConnectiontype()]; ok {
+		adxcore.SetFeature(out, adxcore.FeatureConnectionType, []string{value})
+	}
+
+	// 设备位置优先，其次用户登记的位置
+	geo := device.GetGeo()
+	if geo == nil {
+		geo = req.GetUser().GetGeo()
+	}
+	if values := GeoTargetingValues(geo); len(values) > 0 {
+		adxcore.SetFeature(out, adxcore.FeatureUserGeo, values)
+	}
+
+	categories := req.GetApp().GetCat()
+	if len(categories) == 0 {
+		categories = req.GetSite().GetCat()
+	}
+	if len(categories) > 0 {
+		adxcore.SetFeature(out, adxcore.FeatureContentCategory, categories)
+	}
+
+	return nil
+}
+
+// GeoTargetingValues 将地理位置转换为 USER_GEO 定向取值(国家、地区、城市)
+func GeoTargetingValues(geo *admux_rtb.BidRequest_Geo) []string {
+	var values []string
+	for _, value := range []string{geo.GetCountry(), geo.GetRegion(), geo.GetCity()} {
+		if value != "" {
+			values = append(values, strings.ToUpper(value))
+		}
+	}
+	return values
+}
@@ -0,0 +1,39 @@
+package feature
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+func TestRequestProvider_Provide(t *testing.T) {
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id: proto.String("req-1"),
+		Device: &admux_rtb.BidRequest_Device{
+			Os:             proto.String("iOS"),
+			Devicetype:     admux_rtb.DeviceType_HIGHEND_PHONE.Enum(),
+			Connectiontype: admux_rtb.ConnectionType_CELL_5G.Enum(),
+		},
+		User: &admux_rtb.BidRequest_User{
+			Geo: &admux_rtb.BidRequest_Geo{Country: proto.String("CN"), Region: proto.String("bj")},
+		},
+		DistributionchannelOneof: &admux_rtb.BidRequest_App_{App: &admux_rtb.BidRequest_App{Cat: []string{"news"}}},
+	})
+
+	out := adxcore.NewFeatures()
+	require.NoError(t, NewRequestProvider().Provide(context.Background(), bidCtx, out))
+
+	assert.Equal(t, map[string][]string{
+		adxcore.TargetingFieldUserOS:          {"ios"},
+		adxcore.TargetingFieldDeviceType:      {"mobile"},
+		adxcore.TargetingFieldConnectionType:  {"5g"},
+		adxcore.TargetingFieldUserGeo:         {"CN", "BJ"},
+		adxcore.TargetingFieldContentCategory: {"news"},
+	}, out.TargetingAssignments())
+}
@@ -0,0 +1,252 @@
+package feature
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 归一化后的操作系统
+const (
+	OSAndroid = "android"
+	OSIOS     = "ios"
+	OSWindows = "windows"
+	OSMacOS   = "macos"
+	OSLinux   = "linux"
+	OSHarmony = "harmonyos"
+)
+
+// 归一化后的设备类型，与 RequestProvider 的 DEVICE_TYPE 取值一致
+const (
+	DeviceTypeMobile  = "mobile"
+	DeviceTypeTablet  = "tablet"
+	DeviceTypeDesktop = "desktop"
+	DeviceTypeTV      = "tv"
+)
+
+// UAInfo User-Agent 解析结果
+type UAInfo struct {
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	DeviceType     string `json:"device_type,omitempty"`
+	Make           string `json:"make,omitempty"`
+	Model          string `json:"model,omitempty"`
+	IsBot          bool   `json:"is_bot"`
+}
+
+var (
+	reAndroidVersion = regexp.MustCompile(`Android[ /]?([\d.]+)`)
+	reLocale         = regexp.MustCompile(`^[a-zA-Z]{2}[-_][a-zA-Z]{2}$`)
+	reIOSVersion     = regexp.MustCompile(`(?:iPhone )?OS (\d+[_\d]*) like Mac OS X`)
+	reMacVersion     = regexp.MustCompile(`Mac OS X (\d+[_.\d]*)`)
+	reWindowsVersion = regexp.MustCompile(`Windows NT ([\d.]+)`)
+	reHarmonyVersion = regexp.MustCompile(`HarmonyOS[ /]?([\d.]+)?`)
+)
+
+// windowsVersions Windows NT 内核版本到发行版本的映射
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "vista",
+	"5.1":  "xp",
+}
+
+// browserRules 浏览器识别规则，按顺序匹配，内嵌浏览器和国产浏览器需排在 Chrome/Safari 之前
+var browserRules = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"wechat", regexp.MustCompile(`MicroMessenger/([\d.]+)`)},
+	{"qq", regexp.MustCompile(`\bQQ/([\d.]+)`)},
+	{"weibo", regexp.MustCompile(`Weibo \(`)},
+	{"uc", regexp.MustCompile(`UCBrowser/([\d.]+)`)},
+	{"quark", regexp.MustCompile(`Quark/([\d.]+)`)},
+	{"qqbrowser", regexp.MustCompile(`MQQBrowser/([\d.]+)`)},
+	{"baidu", regexp.MustCompile(`(?:baiduboxapp|BaiduBrowser)/([\d.]+)`)},
+	{"samsung", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+	{"opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+	{"firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+}
+
+// botKeywords 爬虫/自动化工具特征(小写匹配)
+var botKeywords = []string{
+	"bot", "spider", "crawler", "slurp", "curl/", "wget/", "python-requests",
+	"python-urllib", "go-http-client", "okhttp/", "java/", "headlesschrome",
+	"phantomjs", "lighthouse", "facebookexternalhit", "bytespider",
+}
+
+// androidMakes 常见安卓机型前缀到厂商的映射(小写匹配)
+var androidMakes = []struct {
+	prefix string
+	make   string
+}{
+	{"sm-", "samsung"}, {"samsung", "samsung"}, {"galaxy", "samsung"},
+	{"huawei", "huawei"}, {"honor", "honor"}, {"mi ", "xiaomi"}, {"xiaomi", "xiaomi"},
+	{"redmi", "xiaomi"}, {"pixel", "google"}, {"oppo", "oppo"}, {"cph", "oppo"},
+	{"vivo", "vivo"}, {"v2", "vivo"}, {"oneplus", "oneplus"}, {"realme", "realme"},
+	{"rmx", "realme"}, {"meizu", "meizu"}, {"nokia", "nokia"}, {"moto", "motorola"},
+	{"lenovo", "lenovo"}, {"zte", "zte"},
+}
+
+// ParseUserAgent 解析 User-Agent 为归一化的系统、浏览器、设备类型等信息
+// 仅基于规则匹配，无法识别的字段保持为空
+func ParseUserAgent(ua string) *UAInfo {
+	info := &UAInfo{}
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return info
+	}
+
+	lower := strings.ToLower(ua)
+	for _, keyword := range botKeywords {
+		if strings.Contains(lower, keyword) {
+			info.IsBot = true
+			break
+		}
+	}
+
+	parseOS(ua, lower, info)
+	parseDeviceType(lower, info)
+
+	for _, rule := range browserRules {
+		if match := rule.pattern.FindStringSubmatch(ua); match != nil {
+			info.Browser = rule.name
+			if len(match) > 1 {
+				info.BrowserVersion = match[1]
+			}
+			break
+		}
+	}
+
+	return info
+}
+
+// parseOS 识别操作系统、版本及设备厂商型号
+func parseOS(ua, lower string, info *UAInfo) {
+	switch {
+	case strings.Contains(ua, "HarmonyOS") || strings.Contains(ua, "OpenHarmony"):
+		info.OS = OSHarmony
+		if match := reHarmonyVersion.FindStringSubmatch(ua); match != nil {
+			info.OSVersion = match[1]
+		}
+		info.Make = "huawei"
+	case strings.Contains(ua, "Android"):
+		info.OS = OSAndroid
+		if match := reAndroidVersion.FindStringSubmatch(ua); match != nil {
+			info.OSVersion = strings.TrimSuffix(match[1], ".")
+		}
+		if model := androidModel(ua); model != "" {
+			info.Model = model
+			info.Make = androidMake(model)
+		}
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod"):
+		info.OS = OSIOS
+		info.Make = "apple"
+		for _, model := range []string{"iPhone", "iPad", "iPod"} {
+			if strings.Contains(ua, model) {
+				info.Model = model
+				break
+			}
+		}
+		if match := reIOSVersion.FindStringSubmatch(ua); match != nil {
+			info.OSVersion = strings.ReplaceAll(match[1], "_", ".")
+		}
+	case strings.Contains(ua, "Windows"):
+		info.OS = OSWindows
+		if match := reWindowsVersion.FindStringSubmatch(ua); match != nil {
+			if version, ok := windowsVersions[match[1]]; ok {
+				info.OSVersion = version
+			}
+		}
+	case strings.Contains(ua, "Macintosh") || strings.Contains(ua, "Mac OS X"):
+		info.OS = OSMacOS
+		info.Make = "apple"
+		if match := reMacVersion.FindStringSubmatch(ua); match != nil {
+			info.OSVersion = strings.ReplaceAll(match[1], "_", ".")
+		}
+	case strings.Contains(lower, "linux") || strings.Contains(lower, "cros"):
+		info.OS = OSLinux
+	}
+}
+
+// parseDeviceType 识别设备类型
+func parseDeviceType(lower string, info *UAInfo) {
+	switch {
+	case containsAny(lower, "smart-tv", "smarttv", "googletv", "appletv", "crkey", "hbbtv", "tizen", "webos", "roku", "bravia", "aftb", "afts"):
+		info.DeviceType = DeviceTypeTV
+	case strings.Contains(lower, "ipad") || strings.Contains(lower, "tablet") ||
+		(info.OS == OSAndroid && !strings.Contains(lower, "mobile")):
+		info.DeviceType = DeviceTypeTablet
+	case strings.Contains(lower, "mobi") || strings.Contains(lower, "iphone") || strings.Contains(lower, "ipod") ||
+		info.OS == OSAndroid || info.OS == OSHarmony:
+		info.DeviceType = DeviceTypeMobile
+	case info.OS == OSWindows || info.OS == OSMacOS || info.OS == OSLinux:
+		info.DeviceType = DeviceTypeDesktop
+	}
+}
+
+// androidModel 提取UA中 Android 版本之后的机型段，如 (Linux; Android 13; SM-S9180 Build/TP1A; wv)
+// 精简UA中的占位机型 K 视为未知
+func androidModel(ua string) string {
+	start := strings.Index(ua, "Android")
+	if start < 0 {
+		return ""
+	}
+	end := strings.Index(ua[start:], ")")
+	if end < 0 {
+		return ""
+	}
+
+	segments := strings.Split(ua[start:start+end], ";")
+	for _, segment := range segments[1:] {
+		segment = strings.TrimSpace(segment)
+		if idx := strings.Index(segment, "Build/"); idx >= 0 {
+			segment = strings.TrimSpace(segment[:idx])
+		}
+		if segment == "" || segment == "K" || segment == "wv" || segment == "U" || reLocale.MatchString(segment) {
+			continue
+		}
+		return segment
+	}
+	return ""
+}
+
+// androidMake 根据机型推断厂商
+func androidMake(model string) string {
+	lower := strings.ToLower(model)
+	for _, m := range androidMakes {
+		if strings.HasPrefix(lower, m.prefix) {
+			return m.make
+		}
+	}
+	return ""
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// MajorMinorVersion 截取版本号的主版本和次版本，如 17.2.1 -> 17.2
+func MajorMinorVersion(version string) (major, majorMinor string) {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) == 0 || parts[0] == "" {
+		return "", ""
+	}
+	major = parts[0]
+	majorMinor = major
+	if len(parts) > 1 && parts[1] != "" {
+		majorMinor = major + "." + parts[1]
+	}
+	return major, majorMinor
+}
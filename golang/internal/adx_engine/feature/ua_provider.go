@@ -0,0 +1,246 @@
+package feature
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// ProviderUA User-Agent 解析特征提供者名称
+const ProviderUA = "ua"
+
+// defaultUACacheSize 默认UA解析缓存容量
+const defaultUACacheSize = 10000
+
+// UA 解析产出的定向字段
+const (
+	TargetingFieldOSVersion  = "USER_OS_VERSION"
+	TargetingFieldBrowser    = "BROWSER"
+	TargetingFieldDeviceMake = "DEVICE_MAKE"
+	TargetingFieldUABot      = "UA_BOT"
+)
+
+// UA 解析产出的特征
+var (
+	FeatureUAInfo = adxcore.FeatureKey[*UAInfo]("ua_info")
+
+	FeatureOSVersion  = adxcore.FeatureKey[[]string](TargetingFieldOSVersion)
+	FeatureBrowser    = adxcore.FeatureKey[[]string](TargetingFieldBrowser)
+	FeatureDeviceMake = adxcore.FeatureKey[[]string](TargetingFieldDeviceMake)
+	FeatureUABot      = adxcore.FeatureKey[[]string](TargetingFieldUABot)
+)
+
+func init() {
+	// DSP配置校验和索引召回依赖已注册的定向字段
+	adxcore.RegisterTargetingField(TargetingFieldOSVersion, TargetingFieldBrowser, TargetingFieldDeviceMake, TargetingFieldUABot)
+}
+
+// uaDeviceTypes 归一化设备类型到 OpenRTB 设备类型的映射
+var uaDeviceTypes = map[string]admux_rtb.DeviceType{
+	DeviceTypeMobile:  admux_rtb.DeviceType_HIGHEND_PHONE,
+	DeviceTypeTablet:  admux_rtb.DeviceType_TABLET,
+	DeviceTypeDesktop: admux_rtb.DeviceType_PERSONAL_COMPUTER,
+	DeviceTypeTV:      admux_rtb.DeviceType_CONNECTED_TV,
+}
+
+// suaPlatforms Sec-CH-UA-Platform 到归一化系统的映射
+var suaPlatforms = map[string]string{
+	"android":   OSAndroid,
+	"ios":       OSIOS,
+	"windows":   OSWindows,
+	"macos":     OSMacOS,
+	"linux":     OSLinux,
+	"chrome os": OSLinux,
+	"harmonyos": OSHarmony,
+}
+
+// suaBrowsers Sec-CH-UA 品牌到归一化浏览器的映射
+var suaBrowsers = map[string]string{
+	"google chrome":    "chrome",
+	"microsoft edge":   "edge",
+	"opera":            "opera",
+	"samsung internet": "samsung",
+	"yandex":           "yandex",
+	"chromium":         "chrome",
+}
+
+// UAProvider 解析 device.ua 及 device.sua(结构化UA/Client Hints)，
+// 产出归一化的系统、版本、浏览器、设备类型和爬虫标记，解析结果按UA缓存
+type UAProvider struct {
+	cache *lruCache[string, *UAInfo]
+}
+
+// NewUAProvider 创建UA解析特征提供者，cacheSize<=0 时使用默认容量
+func NewUAProvider(cacheSize int) *UAProvider {
+	if cacheSize <= 0 {
+		cacheSize = defaultUACacheSize
+	}
+	return &UAProvider{cache: newLRUCache[string, *UAInfo](cacheSize)}
+}
+
+// Name 返回提供者名称
+func (p *UAProvider) Name() string {
+	return ProviderUA
+}
+
+// Provide 解析UA并产出定向特征，请求已携带的系统和设备类型优先
+func (p *UAProvider) Provide(_ context.Context, bidCtx *adxcore.BidRequestCtx, out *adxcore.Features) error {
+	device := bidCtx.Request.GetDevice()
+	if device == nil || (device.GetUa() == "" && device.GetSua() == nil) {
+		return nil
+	}
+
+	info := p.parse(device.GetUa())
+	if sua := device.GetSua(); sua != nil {
+		info = applyStructuredUA(info, sua)
+	}
+	adxcore.SetFeature(out, FeatureUAInfo, info)
+
+	os := strings.ToLower(device.GetOs())
+	if os == "" && info.OS != "" {
+		os = info.OS
+		adxcore.SetFeature(out, adxcore.FeatureUserOS, []string{os})
+	}
+	if device.Devicetype == nil && info.DeviceType != "" {
+		adxcore.SetFeature(out, adxcore.FeatureDeviceType, []string{info.DeviceType})
+	}
+
+	osv := device.GetOsv()
+	if osv == "" && os == info.OS {
+		osv = info.OSVersion
+	}
+	if major, majorMinor := MajorMinorVersion(osv); major != "" {
+		values := []string{major}
+		if majorMinor != major {
+			values = append(values, majorMinor)
+		}
+		adxcore.SetFeature(out, FeatureOSVersion, values)
+	}
+
+	if info.Browser != "" {
+		adxcore.SetFeature(out, FeatureBrowser, []string{info.Browser})
+	}
+
+	deviceMake := strings.ToLower(device.GetMake())
+	if deviceMake == "" {
+		deviceMake = info.Make
+	}
+	if deviceMake != "" {
+		adxcore.SetFeature(out, FeatureDeviceMake, []string{deviceMake})
+	}
+
+	adxcore.SetFeature(out, FeatureUABot, []string{strconv.FormatBool(info.IsBot)})
+	return nil
+}
+
+// Enrich 回写请求中缺失的 os、osv、make、model、devicetype
+func (p *UAProvider) Enrich(bidCtx *adxcore.BidRequestCtx, features *adxcore.Features) {
+	info, ok := adxcore.GetFeature(features, FeatureUAInfo)
+	if !ok || info == nil {
+		return
+	}
+
+	device := bidCtx.Request.GetDevice()
+	if device == nil {
+		return
+	}
+
+	if device.GetOs() == "" {
+		setIfNotEmpty(&device.Os, info.OS)
+		if device.GetOsv() == "" {
+			setIfNotEmpty(&device.Osv, info.OSVersion)
+		}
+	}
+	if device.GetMake() == "" {
+		setIfNotEmpty(&device.Make, info.Make)
+	}
+	if device.GetModel() == "" {
+		setIfNotEmpty(&device.Model, info.Model)
+	}
+	if device.Devicetype == nil {
+		if deviceType, ok := uaDeviceTypes[info.DeviceType]; ok {
+			device.Devicetype = deviceType.Enum()
+		}
+	}
+}
+
+// parse 解析UA，命中缓存时直接返回；缓存中的结果只读，调用方需要修改时先复制
+func (p *UAProvider) parse(ua string) *UAInfo {
+	if ua == "" {
+		return &UAInfo{}
+	}
+	if info, ok := p.cache.Get(ua); ok {
+		return info
+	}
+
+	info := ParseUserAgent(ua)
+	p.cache.Add(ua, info)
+	return info
+}
+
+// applyStructuredUA 用结构化UA修正解析结果，结构化UA由客户端上报，可信度高于UA字符串
+func applyStructuredUA(parsed *UAInfo, sua *admux_rtb.BidRequest_Device_UserAgent) *UAInfo {
+	info := *parsed
+
+	if platform := sua.GetPlatform(); platform != nil {
+		if os, ok := suaPlatforms[strings.ToLower(platform.GetBrand())]; ok {
+			if os != info.OS {
+				info.Make, info.Model = "", ""
+			}
+			info.OS = os
+			if version := strings.Join(platform.GetVersion(), "."); version != "" {
+				info.OSVersion = suaOSVersion(os, version)
+			}
+		}
+	}
+
+	for _, browser := range sua.GetBrowsers() {
+		if name, ok := suaBrowsers[strings.ToLower(browser.GetBrand())]; ok {
+			info.Browser = name
+			info.BrowserVersion = strings.Join(browser.GetVersion(), ".")
+			// Chromium 为兜底品牌，继续查找更具体的品牌
+			if name != "chrome" || !strings.EqualFold(browser.GetBrand(), "chromium") {
+				break
+			}
+		}
+	}
+
+	if model := sua.GetModel(); model != "" {
+		info.Model = model
+		if info.OS == OSAndroid && info.Make == "" {
+			info.Make = androidMake(model)
+		}
+	}
+	if sua.Mobile != nil {
+		if sua.GetMobile() {
+			if info.DeviceType != DeviceTypeTablet {
+				info.DeviceType = DeviceTypeMobile
+			}
+		} else if info.DeviceType == DeviceTypeMobile || info.DeviceType == "" {
+			info.DeviceType = DeviceTypeDesktop
+			if info.OS == OSAndroid {
+				info.DeviceType = DeviceTypeTablet
+			}
+		}
+	}
+
+	return &info
+}
+
+// suaOSVersion Windows 的平台版本号为内部版本，13及以上为 Windows 11
+func suaOSVersion(os, version string) string {
+	if os != OSWindows {
+		return version
+	}
+	major, _ := MajorMinorVersion(version)
+	if n, err := strconv.Atoi(major); err == nil && n > 0 {
+		if n >= 13 {
+			return "11"
+		}
+		return "10"
+	}
+	return version
+}
@@ -0,0 +1,132 @@
+package feature
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want UAInfo
+	}{
+		{
+			name: "iphone safari",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			want: UAInfo{OS: OSIOS, OSVersion: "17.2.1", Browser: "safari", BrowserVersion: "17.2", DeviceType: DeviceTypeMobile, Make: "apple", Model: "iPhone"},
+		},
+		{
+			name: "android wechat",
+			ua:   "Mozilla/5.0 (Linux; Android 13; SM-S9180 Build/TP1A.220624.014; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/116.0.0.0 Mobile Safari/537.36 MicroMessenger/8.0.43",
+			want: UAInfo{OS: OSAndroid, OSVersion: "13", Browser: "wechat", BrowserVersion: "8.0.43", DeviceType: DeviceTypeMobile, Make: "samsung", Model: "SM-S9180"},
+		},
+		{
+			name: "android tablet",
+			ua:   "Mozilla/5.0 (Linux; Android 12; Pixel Tablet) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: UAInfo{OS: OSAndroid, OSVersion: "12", Browser: "chrome", BrowserVersion: "120.0.0.0", DeviceType: DeviceTypeTablet, Make: "google", Model: "Pixel Tablet"},
+		},
+		{
+			name: "windows edge",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			want: UAInfo{OS: OSWindows, OSVersion: "10", Browser: "edge", BrowserVersion: "120.0.2210.91", DeviceType: DeviceTypeDesktop},
+		},
+		{
+			name: "bot",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: UAInfo{IsBot: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, *ParseUserAgent(tt.ua))
+		})
+	}
+}
+
+func TestUAProvider_ProvideAndEnrich(t *testing.T) {
+	provider := NewUAProvider(16)
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id: proto.String("req-1"),
+		Device: &admux_rtb.BidRequest_Device{
+			Ua: proto.String("Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"),
+			Sua: &admux_rtb.BidRequest_Device_UserAgent{
+				Browsers: []*admux_rtb.BidRequest_Device_UserAgent_BrandVersion{
+					{Brand: proto.String("Not_A Brand"), Version: []string{"8"}},
+					{Brand: proto.String("Chromium"), Version: []string{"120"}},
+					{Brand: proto.String("Google Chrome"), Version: []string{"120", "0", "6099"}},
+				},
+				Platform: &admux_rtb.BidRequest_Device_UserAgent_BrandVersion{Brand: proto.String("Android"), Version: []string{"14", "0", "0"}},
+				Mobile:   proto.Bool(true),
+				Model:    proto.String("Pixel 8"),
+			},
+		},
+	})
+
+	out := adxcore.NewFeatures()
+	require.NoError(t, provider.Provide(context.Background(), bidCtx, out))
+	provider.Enrich(bidCtx, out)
+
+	assert.Equal(t, map[string][]string{
+		adxcore.TargetingFieldUserOS:     {"android"},
+		adxcore.TargetingFieldDeviceType: {"mobile"},
+		TargetingFieldOSVersion:          {"14", "14.0"},
+		TargetingFieldBrowser:            {"chrome"},
+		TargetingFieldDeviceMake:         {"google"},
+		TargetingFieldUABot:              {"false"},
+	}, out.TargetingAssignments())
+
+	device := bidCtx.Request.GetDevice()
+	assert.Equal(t, "android", device.GetOs())
+	assert.Equal(t, "14.0.0", device.GetOsv())
+	assert.Equal(t, "Pixel 8", device.GetModel())
+	assert.Equal(t, admux_rtb.DeviceType_HIGHEND_PHONE, device.GetDevicetype())
+	assert.Equal(t, 1, provider.cache.Len())
+}
+
+func TestUAProvider_KeepRequestFields(t *testing.T) {
+	provider := NewUAProvider(16)
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id: proto.String("req-2"),
+		Device: &admux_rtb.BidRequest_Device{
+			Ua:         proto.String("Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1"),
+			Os:         proto.String("iOS"),
+			Osv:        proto.String("16.6"),
+			Devicetype: admux_rtb.DeviceType_TABLET.Enum(),
+		},
+	})
+
+	out := adxcore.NewFeatures()
+	require.NoError(t, provider.Provide(context.Background(), bidCtx, out))
+	provider.Enrich(bidCtx, out)
+
+	assignments := out.TargetingAssignments()
+	assert.NotContains(t, assignments, adxcore.TargetingFieldUserOS)
+	assert.NotContains(t, assignments, adxcore.TargetingFieldDeviceType)
+	assert.Equal(t, []string{"16", "16.6"}, assignments[TargetingFieldOSVersion])
+	assert.Equal(t, "iOS", bidCtx.Request.GetDevice().GetOs())
+	assert.Equal(t, "iPad", bidCtx.Request.GetDevice().GetModel())
+}
+
+func TestLRUCache_Evict(t *testing.T) {
+	cache := newLRUCache[string, int](2)
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+	_, _ = cache.Get("a")
+	cache.Add("c", 3)
+
+	_, ok := cache.Get("b")
+	assert.False(t, ok)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 2, cache.Len())
+}
@@ -0,0 +1,181 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// ProviderUserProfile 用户画像特征提供者名称
+const ProviderUserProfile = "user_profile"
+
+const (
+	// defaultProfileKeyPrefix 画像键前缀，完整键为 {prefix}{CanonicalKey}，即 {prefix}{id_type}:{id}
+	defaultProfileKeyPrefix = "up:"
+	// defaultProfileTimeout 单次画像查询超时
+	defaultProfileTimeout = 5 * time.Millisecond
+
+	// profileFieldSegments 画像hash中的人群包字段，多个人群包以逗号分隔
+	profileFieldSegments = "segments"
+	// profileFieldFreqPrefix 画像hash中的频次字段前缀，如 fc:dsp_a -> 3
+	profileFieldFreqPrefix = "fc:"
+
+	// ProfileDataID 回写到 User.data 的数据源标识
+	ProfileDataID = "admux_profile"
+)
+
+// TargetingFieldUserSegment 人群包定向字段
+const TargetingFieldUserSegment = "USER_SEGMENT"
+
+// 用户画像特征
+var (
+	FeatureUserProfile = adxcore.FeatureKey[*UserProfile]("user_profile")
+	FeatureUserSegment = adxcore.FeatureKey[[]string](TargetingFieldUserSegment)
+)
+
+func init() {
+	adxcore.RegisterTargetingField(TargetingFieldUserSegment)
+}
+
+// UserProfile 用户画像
+type UserProfile struct {
+	// Key 命中画像的用户唯一键(DeviceIDs.CanonicalKey)
+	Key string `json:"key"`
+	// Segments 人群包(已去重排序)
+	Segments []string `json:"segments"`
+	// Frequency 频次数据
+	Frequency map[string]int64 `json:"frequency,omitempty"`
+}
+
+// UserProfileProvider 按设备ID特征提供者生成的用户唯一键从Redis查询人群包和频次数据，
+// 依赖 device_id 提供者；查询有独立的超时时间，超时或Redis异常时该请求不使用画像
+type UserProfileProvider struct {
+	client    redis.UniversalClient
+	keyPrefix string
+	timeout   time.Duration
+}
+
+// NewUserProfileProvider 创建用户画像特征提供者，keyPrefix 为空或 timeout<=0 时使用默认值
+func NewUserProfileProvider(client redis.UniversalClient, keyPrefix string, timeout time.Duration) *UserProfileProvider {
+	if keyPrefix == "" {
+		keyPrefix = defaultProfileKeyPrefix
+	}
+	if timeout <= 0 {
+		timeout = defaultProfileTimeout
+	}
+	return &UserProfileProvider{
+		client:    client,
+		keyPrefix: keyPrefix,
+		timeout:   timeout,
+	}
+}
+
+// Name 返回提供者名称
+func (p *UserProfileProvider) Name() string {
+	return ProviderUserProfile
+}
+
+// DependsOn 画像查询使用设备ID特征提供者生成的用户唯一键
+func (p *UserProfileProvider) DependsOn() []string {
+	return []string{ProviderDeviceID}
+}
+
+// Provide 查询画像，没有用户唯一键或未命中时不产出特征
+func (p *UserProfileProvider) Provide(ctx context.Context, bidCtx *adxcore.BidRequestCtx, out *adxcore.Features) error {
+	ids, _ := adxcore.GetFeature(bidCtx.Features, adxcore.FeatureDeviceIDs)
+	if ids == nil || ids.CanonicalKey == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	fields, err := p.client.HGetAll(ctx, p.keyPrefix+ids.CanonicalKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		// 连接读写截止时间取自 ctx，可能先于 ctx 本身到期
+		if ctx.Err() != nil || errors.Is(err, os.ErrDeadlineExceeded) {
+			return adxcore.ErrFeatureTimeout
+		}
+		return fmt.Errorf("query user profile: %w", err)
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	profile := &UserProfile{Key: ids.CanonicalKey, Frequency: make(map[string]int64)}
+	segments := make(map[string]struct{})
+	mergeProfileFields(profile, segments, fields)
+
+	for segment := range segments {
+		profile.Segments = append(profile.Segments, segment)
+	}
+	sort.Strings(profile.Segments)
+
+	adxcore.SetFeature(out, FeatureUserProfile, profile)
+	if len(profile.Segments) > 0 {
+		adxcore.SetFeature(out, FeatureUserSegment, profile.Segments)
+	}
+	return nil
+}
+
+// Enrich 将人群包回写到 User.data，供DSP使用
+func (p *UserProfileProvider) Enrich(bidCtx *adxcore.BidRequestCtx, features *adxcore.Features) {
+	profile, ok := adxcore.GetFeature(features, FeatureUserProfile)
+	if !ok || profile == nil || len(profile.Segments) == 0 || bidCtx.Request == nil {
+		return
+	}
+
+	req := bidCtx.Request
+	if req.User == nil {
+		req.User = &admux_rtb.BidRequest_User{}
+	}
+	for _, data := range req.User.GetData() {
+		if data.GetId() == ProfileDataID {
+			return
+		}
+	}
+
+	data := &admux_rtb.BidRequest_Data{
+		Id:   proto.String(ProfileDataID),
+		Name: proto.String(ProfileDataID),
+	}
+	for _, segment := range profile.Segments {
+		data.Segment = append(data.Segment, &admux_rtb.BidRequest_Data_Segment{Id: proto.String(segment)})
+	}
+	req.User.Data = append(req.User.Data, data)
+}
+
+// mergeProfileFields 解析画像hash字段
+func mergeProfileFields(profile *UserProfile, segments map[string]struct{}, fields map[string]string) {
+	for field, value := range fields {
+		switch {
+		case field == profileFieldSegments:
+			for _, segment := range strings.Split(value, ",") {
+				if segment = strings.TrimSpace(segment); segment != "" {
+					segments[segment] = struct{}{}
+				}
+			}
+		case strings.HasPrefix(field, profileFieldFreqPrefix):
+			count, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			key := strings.TrimPrefix(field, profileFieldFreqPrefix)
+			if count > profile.Frequency[key] {
+				profile.Frequency[key] = count
+			}
+		}
+	}
+}
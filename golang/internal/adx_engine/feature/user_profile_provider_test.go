@@ -0,0 +1,146 @@
+package feature
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), ContextTimeoutEnabled: true})
+	t.Cleanup(func() { _ = client.Close() })
+	return server, client
+}
+
+// profileBidCtx 创建已产出设备ID特征的请求上下文，与注册表中 device_id 先于画像执行一致
+func profileBidCtx(t *testing.T, req *admux_rtb.BidRequest) *adxcore.BidRequestCtx {
+	t.Helper()
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), req)
+	require.NoError(t, NewDeviceIDProvider().Provide(context.Background(), bidCtx, bidCtx.Features))
+	return bidCtx
+}
+
+func TestUserProfileProvider_ProvideAndEnrich(t *testing.T) {
+	server, client := newTestRedis(t)
+	// 用户唯一键按 oaid_md5 优先于 imei_md5，只查询唯一键
+	server.HSet("up:oaid_md5:"+md5Hex("ABCD-EF01-2345-6789"), "segments", "s1, s2,s3", "fc:dsp_a", "5", "fc:dsp_b", "1")
+	server.HSet("up:imei_md5:"+md5Hex("861234567890123"), "segments", "s9")
+
+	provider := NewUserProfileProvider(client, "", 100*time.Millisecond)
+	bidCtx := profileBidCtx(t, &admux_rtb.BidRequest{
+		Id: proto.String("req-1"),
+		Device: &admux_rtb.BidRequest_Device{
+			Os:     proto.String("android"),
+			Ifa:    proto.String("ABCD-EF01-2345-6789"),
+			Didmd5: proto.String("861234567890123"), // 明文IMEI
+		},
+	})
+
+	out := adxcore.NewFeatures()
+	require.NoError(t, provider.Provide(context.Background(), bidCtx, out))
+	provider.Enrich(bidCtx, out)
+
+	profile, ok := adxcore.GetFeature(out, FeatureUserProfile)
+	require.True(t, ok)
+	assert.Equal(t, "oaid_md5:"+md5Hex("ABCD-EF01-2345-6789"), profile.Key)
+	assert.Equal(t, []string{"s1", "s2", "s3"}, profile.Segments)
+	assert.Equal(t, map[string]int64{"dsp_a": 5, "dsp_b": 1}, profile.Frequency)
+	assert.Equal(t, []string{"s1", "s2", "s3"}, out.TargetingAssignments()[TargetingFieldUserSegment])
+
+	data := bidCtx.Request.GetUser().GetData()
+	require.Len(t, data, 1)
+	assert.Equal(t, ProfileDataID, data[0].GetId())
+	assert.Len(t, data[0].GetSegment(), 3)
+
+	// 重复回写不追加
+	provider.Enrich(bidCtx, out)
+	assert.Len(t, bidCtx.Request.GetUser().GetData(), 1)
+}
+
+func TestUserProfileProvider_NoMatch(t *testing.T) {
+	_, client := newTestRedis(t)
+	provider := NewUserProfileProvider(client, "", 100*time.Millisecond)
+
+	// 全零IDFA没有用户唯一键，不查询
+	bidCtx := profileBidCtx(t, &admux_rtb.BidRequest{
+		Id:     proto.String("req-2"),
+		Device: &admux_rtb.BidRequest_Device{Os: proto.String("ios"), Ifa: proto.String("00000000-0000-0000-0000-000000000000")},
+	})
+	out := adxcore.NewFeatures()
+	require.NoError(t, provider.Provide(context.Background(), bidCtx, out))
+	assert.Empty(t, out.Keys())
+
+	bidCtx = profileBidCtx(t, &admux_rtb.BidRequest{
+		Id:     proto.String("req-2"),
+		Device: &admux_rtb.BidRequest_Device{Os: proto.String("ios"), Ifa: proto.String("6d92078a-8246-4ba4-ae5b-76104861e7dc")},
+	})
+	require.NoError(t, provider.Provide(context.Background(), bidCtx, out))
+	assert.Empty(t, out.Keys())
+}
+
+func TestUserProfileProvider_DependsOnDeviceID(t *testing.T) {
+	server, client := newTestRedis(t)
+	server.HSet("up:idfa_md5:"+md5Hex("6D92078A-8246-4BA4-AE5B-76104861E7DC"), "segments", "s1")
+
+	registry := adxcore.NewFeatureRegistry(time.Second, nil)
+	profile := NewUserProfileProvider(client, "", 100*time.Millisecond)
+	// 依赖的 device_id 未注册时拒绝注册
+	require.Error(t, registry.Register(profile))
+	require.NoError(t, registry.Register(NewDeviceIDProvider()))
+	require.NoError(t, registry.Register(profile))
+
+	bidCtx := adxcore.NewBidRequestCtx(context.Background(), &admux_rtb.BidRequest{
+		Id:     proto.String("req-4"),
+		Device: &admux_rtb.BidRequest_Device{Os: proto.String("ios"), Ifa: proto.String("6d92078a-8246-4ba4-ae5b-76104861e7dc")},
+	})
+	registry.Complete(bidCtx)
+	assert.Empty(t, bidCtx.ProcessingErrors)
+	assert.Equal(t, []string{"s1"}, bidCtx.Features.TargetingAssignments()[TargetingFieldUserSegment])
+}
+
+func TestUserProfileProvider_Timeout(t *testing.T) {
+	// 只接受连接不响应的Redis，模拟慢查询
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				_ = conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), ContextTimeoutEnabled: true, MaxRetries: -1})
+	defer client.Close()
+
+	provider := NewUserProfileProvider(client, "", 5*time.Millisecond)
+	bidCtx := profileBidCtx(t, &admux_rtb.BidRequest{
+		Id:     proto.String("req-3"),
+		Device: &admux_rtb.BidRequest_Device{Os: proto.String("iOS"), Ifa: proto.String("6d92078a-8246-4ba4-ae5b-76104861e7dc")},
+	})
+
+	out := adxcore.NewFeatures()
+	err = provider.Provide(context.Background(), bidCtx, out)
+	assert.ErrorIs(t, err, adxcore.ErrFeatureTimeout)
+	assert.Empty(t, out.Keys())
+}
@@ -155,6 +155,20 @@ const (
 	StateHalfOpen                     // 半开状态（试探）
 )
 
+// String 返回状态名称
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
 // NewCircuitBreaker 创建熔断器
 func NewCircuitBreaker() *CircuitBreaker {
 	return &CircuitBreaker{
@@ -226,4 +240,11 @@ func (cb *CircuitBreaker) RecordFailure() {
 		cb.state = StateOpen
 		cb.lastStateTime = time.Now()
 	}
-}
\ No newline at end of file
+}
+
+// State 获取当前状态
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.state
+}
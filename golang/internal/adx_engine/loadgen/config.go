@@ -0,0 +1,88 @@
+package loadgen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/echoface/admux/pkg/config"
+)
+
+const (
+	defaultQPS         = 100
+	defaultDuration    = 30 * time.Second
+	defaultTimeout     = time.Second
+	defaultMaxInFlight = 1000
+)
+
+// LoadGenConfig 压测工具配置文件
+type LoadGenConfig struct {
+	LoadGen Config `yaml:"loadgen"`
+}
+
+// Config 压测配置
+type Config struct {
+	// BaseURL ADX地址，如 http://localhost:8080
+	BaseURL string `yaml:"base_url"`
+	// QPS 目标总QPS，按 weight 分配到各目标；开环发送，不等待上一个请求返回
+	QPS float64 `yaml:"qps"`
+	// Duration 压测时长
+	Duration time.Duration `yaml:"duration"`
+	// Timeout 单个请求超时
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxInFlight 最大并发请求数，超出时该次发送记为 dropped
+	MaxInFlight int `yaml:"max_inflight"`
+	// Seed 目标和样本选择的随机数种子，非0时可复现
+	Seed int64 `yaml:"seed"`
+
+	Targets []TargetConfig `yaml:"targets"`
+}
+
+// TargetConfig 单个SSP的流量配置
+type TargetConfig struct {
+	SSPID string `yaml:"ssp_id"`
+	// Protocol SSP协议: rtb(admux OpenRTB protobuf) / kuaishou(JSON)
+	Protocol string `yaml:"protocol"`
+	// Path 竞价接口路径，为空时使用协议默认路径
+	Path string `yaml:"path"`
+	// Weight 流量权重，默认1
+	Weight int `yaml:"weight"`
+	// Templates 请求模板文件(JSON)，rtb 为 protojson 格式的 BidRequest
+	Templates []string `yaml:"templates"`
+	// Samples 竞价日志(JSONL)，使用其中该SSP采集的原始请求
+	Samples string `yaml:"samples"`
+}
+
+// LoadConfig 加载压测配置
+func LoadConfig() (*LoadGenConfig, error) {
+	loader := config.NewLoader("adx_loadgen")
+	var cfg LoadGenConfig
+
+	if err := loader.Load(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to load loadgen config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// withDefaults 填充默认值并校验
+func (c Config) withDefaults() (Config, error) {
+	if c.BaseURL == "" {
+		return c, fmt.Errorf("base_url is required")
+	}
+	if c.QPS <= 0 {
+		c.QPS = defaultQPS
+	}
+	if c.Duration <= 0 {
+		c.Duration = defaultDuration
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.MaxInFlight <= 0 {
+		c.MaxInFlight = defaultMaxInFlight
+	}
+	if len(c.Targets) == 0 {
+		return c, fmt.Errorf("at least one target is required")
+	}
+	return c, nil
+}
@@ -0,0 +1,240 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	kuaishou_rtb "github.com/echoface/admux/pkg/protogen/kuaishou"
+)
+
+const (
+	rtbTemplate      = `{"id":"tpl","imp":[{"id":"imp-1","bidfloor":1}]}`
+	kuaishouTemplate = `{"request_id":"tpl","imp":[{"imp_id":"imp-1","cpm_bid_floor":1}]}`
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// fakeADX 按请求序号轮流返回 出价/不出价/500/非法响应
+func fakeADX(t *testing.T) *httptest.Server {
+	t.Helper()
+	var seq atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		n := seq.Add(1)
+		switch r.URL.Path {
+		case "/bid/rtb/v1":
+			req := &admux_rtb.BidRequest{}
+			require.NoError(t, proto.Unmarshal(body, req))
+			resp := &admux_rtb.BidResponse{Id: req.Id}
+			switch n % 4 {
+			case 0:
+				resp.Seatbid = []*admux_rtb.BidResponse_SeatBid{{Bid: []*admux_rtb.BidResponse_SeatBid_Bid{{
+					Id: proto.String("b"), Impid: proto.String("imp-1"), Price: proto.Float64(2),
+					AdmOneof: &admux_rtb.BidResponse_SeatBid_Bid_Adm{Adm: "<a>"},
+				}}}}
+			case 2:
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			case 3:
+				resp.Id = proto.String("other")
+			}
+			data, _ := proto.Marshal(resp)
+			w.Write(data)
+		case "/bid/kuaishou":
+			req := &kuaishou_rtb.BidRequest{}
+			require.NoError(t, json.Unmarshal(body, req))
+			resp := &kuaishou_rtb.BidResponse{RequestId: req.RequestId, Status: proto.Uint32(1)}
+			if n%2 == 0 {
+				resp.Status = proto.Uint32(0)
+				resp.SeatBid = []*kuaishou_rtb.SeatBid{{Bid: []*kuaishou_rtb.Bid{{
+					BidId: proto.String("b"), ImpId: proto.String("imp-9"), Price: proto.Float64(2), AdId: proto.String("ad"),
+				}}}}
+			}
+			data, _ := json.Marshal(resp)
+			w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunner_Run(t *testing.T) {
+	server := fakeADX(t)
+
+	runner, err := NewRunner(Config{
+		BaseURL:  server.URL,
+		QPS:      400,
+		Duration: 500 * time.Millisecond,
+		Seed:     1,
+		Targets: []TargetConfig{
+			{SSPID: "ssp_rtb", Protocol: ProtocolRTB, Weight: 3, Templates: []string{writeFile(t, "rtb.json", rtbTemplate)}},
+			{SSPID: "ssp_ks", Protocol: ProtocolKuaishou, Templates: []string{writeFile(t, "ks.json", kuaishouTemplate)}},
+		},
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	report := runner.Run(context.Background())
+	// 开环调度：总时长接近 Duration，请求数为 qps*duration
+	assert.GreaterOrEqual(t, time.Since(start), 450*time.Millisecond)
+	require.Len(t, report.Targets, 2)
+
+	rtb, ks := report.Targets[0], report.Targets[1]
+	assert.Equal(t, 200, rtb.Sent+ks.Sent)
+	assert.Zero(t, rtb.Dropped+ks.Dropped)
+	assert.Greater(t, rtb.Sent, ks.Sent)
+
+	assert.Positive(t, rtb.Bid)
+	assert.Positive(t, rtb.NoBid)
+	assert.Equal(t, rtb.Error, rtb.Reasons["error:http_500"])
+	assert.Equal(t, rtb.Invalid, rtb.Reasons["invalid:id_mismatch"])
+	assert.Positive(t, ks.NoBid)
+	assert.Equal(t, ks.Invalid, ks.Reasons["invalid:unknown_impid"])
+	assert.True(t, report.HasFailures())
+
+	var out bytes.Buffer
+	report.WriteText(&out)
+	assert.Contains(t, out.String(), "[ssp_rtb/rtb]")
+	assert.Contains(t, out.String(), "p99.9=")
+	assert.Contains(t, out.String(), "error:http_500")
+}
+
+func TestRunner_TimeoutAndDropped(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	runner, err := NewRunner(Config{
+		BaseURL:     server.URL,
+		QPS:         100,
+		Duration:    200 * time.Millisecond,
+		Timeout:     100 * time.Millisecond,
+		MaxInFlight: 2,
+		Targets:     []TargetConfig{{SSPID: "ssp_rtb", Protocol: ProtocolRTB, Templates: []string{writeFile(t, "rtb.json", rtbTemplate)}}},
+	})
+	require.NoError(t, err)
+
+	report := runner.Run(context.Background())
+	target := report.Targets[0]
+	assert.Positive(t, target.Dropped)
+	assert.Equal(t, target.Error, target.Reasons["error:timeout"])
+	assert.Positive(t, target.Error)
+	assert.GreaterOrEqual(t, target.Max, 100*time.Millisecond)
+}
+
+func TestTarget_Samples(t *testing.T) {
+	sspRequest, err := proto.Marshal(&admux_rtb.BidRequest{Id: proto.String("captured"), Imp: []*admux_rtb.BidRequest_Imp{{Id: proto.String("imp-7")}}})
+	require.NoError(t, err)
+
+	var lines []string
+	for _, record := range []*bidlog.Record{
+		{SSPID: "ssp_rtb", RequestID: "r1", SSPRequest: sspRequest},
+		{SSPID: "other", RequestID: "r2", SSPRequest: []byte("ignored")},
+	} {
+		data, err := json.Marshal(record)
+		require.NoError(t, err)
+		lines = append(lines, string(data))
+	}
+	samples := writeFile(t, "bid.jsonl", strings.Join(lines, "\n"))
+
+	target, err := newTarget("http://adx/", TargetConfig{SSPID: "ssp_rtb", Protocol: ProtocolRTB, Samples: samples})
+	require.NoError(t, err)
+	assert.Equal(t, "http://adx/bid/rtb/v1?sspid=ssp_rtb", target.url)
+	require.Len(t, target.samples, 1)
+
+	body, err := target.protocol.encode(target.sample(), "new-id")
+	require.NoError(t, err)
+	req := &admux_rtb.BidRequest{}
+	require.NoError(t, proto.Unmarshal(body, req))
+	assert.Equal(t, "new-id", req.GetId())
+	assert.Equal(t, "imp-7", req.GetImp()[0].GetId())
+	// 样本本身不被修改
+	assert.Equal(t, "captured", target.samples[0].(*admux_rtb.BidRequest).GetId())
+
+	_, err = newTarget("http://adx", TargetConfig{SSPID: "ssp_rtb", Protocol: "unknown"})
+	assert.Error(t, err)
+	_, err = newTarget("http://adx", TargetConfig{SSPID: "ssp_rtb", Protocol: ProtocolRTB})
+	assert.Error(t, err)
+}
+
+func TestKuaishouValidate(t *testing.T) {
+	p := kuaishouProtocol{}
+	sample, err := p.parseTemplate([]byte(kuaishouTemplate))
+	require.NoError(t, err)
+
+	encode := func(resp *kuaishou_rtb.BidResponse) []byte {
+		data, err := json.Marshal(resp)
+		require.NoError(t, err)
+		return data
+	}
+	bid := func(mutate func(*kuaishou_rtb.Bid)) []byte {
+		b := &kuaishou_rtb.Bid{BidId: proto.String("b"), ImpId: proto.String("imp-1"), Price: proto.Float64(1), AdId: proto.String("ad")}
+		if mutate != nil {
+			mutate(b)
+		}
+		return encode(&kuaishou_rtb.BidResponse{RequestId: proto.String("r"), Status: proto.Uint32(0),
+			SeatBid: []*kuaishou_rtb.SeatBid{{Bid: []*kuaishou_rtb.Bid{b}}}})
+	}
+
+	cases := []struct {
+		body    []byte
+		outcome string
+		reason  string
+	}{
+		{bid(nil), OutcomeBid, ""},
+		{encode(&kuaishou_rtb.BidResponse{RequestId: proto.String("r"), Status: proto.Uint32(1)}), OutcomeNoBid, ""},
+		{encode(&kuaishou_rtb.BidResponse{RequestId: proto.String("r"), Status: proto.Uint32(1),
+			NoBidReason: kuaishou_rtb.BidResponse_TECHNICAL_ERROR.Enum()}), OutcomeError, "technical_error"},
+		{encode(&kuaishou_rtb.BidResponse{RequestId: proto.String("x"), Status: proto.Uint32(1)}), OutcomeInvalid, "id_mismatch"},
+		{encode(&kuaishou_rtb.BidResponse{RequestId: proto.String("r"), Status: proto.Uint32(0)}), OutcomeInvalid, "empty_seat_bid"},
+		{bid(func(b *kuaishou_rtb.Bid) { b.Price = proto.Float64(0) }), OutcomeInvalid, "invalid_price"},
+		{bid(func(b *kuaishou_rtb.Bid) { b.BidId = nil }), OutcomeInvalid, "missing_bid_id"},
+		{bid(func(b *kuaishou_rtb.Bid) { b.AdId = nil }), OutcomeInvalid, "missing_ad_id"},
+		{[]byte("{"), OutcomeInvalid, "unmarshal"},
+	}
+	for _, c := range cases {
+		outcome, reason := p.validate(sample, "r", c.body)
+		assert.Equal(t, c.outcome, outcome, string(c.body))
+		assert.Equal(t, c.reason, reason, string(c.body))
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 1000; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 500*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 990*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, 999*time.Millisecond, percentile(sorted, 99.9))
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+}
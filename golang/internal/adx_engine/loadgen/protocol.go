@@ -0,0 +1,191 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+	kuaishou_rtb "github.com/echoface/admux/pkg/protogen/kuaishou"
+)
+
+// 支持的SSP协议
+const (
+	ProtocolRTB      = "rtb"
+	ProtocolKuaishou = "kuaishou"
+)
+
+// 响应分类
+const (
+	OutcomeBid     = "bid"
+	OutcomeNoBid   = "nobid"
+	OutcomeError   = "error"   // 传输错误、非200状态或ADX返回技术错误
+	OutcomeInvalid = "invalid" // 响应校验失败
+	OutcomeDropped = "dropped" // 并发已满未发送
+)
+
+// protocol SSP协议：由模板或采集样本生成请求，并校验ADX响应
+type protocol interface {
+	name() string
+	defaultPath() string
+	contentType() string
+	// parseTemplate 解析JSON模板
+	parseTemplate(data []byte) (proto.Message, error)
+	// parseSample 解析竞价日志中采集的原始请求
+	parseSample(data []byte) (proto.Message, error)
+	// encode 以新的请求ID编码请求，不修改样本
+	encode(sample proto.Message, requestID string) ([]byte, error)
+	// validate 校验响应，返回分类和校验失败原因
+	validate(sample proto.Message, requestID string, body []byte) (outcome, reason string)
+}
+
+func newProtocol(name string) (protocol, error) {
+	switch name {
+	case ProtocolRTB:
+		return rtbProtocol{}, nil
+	case ProtocolKuaishou:
+		return kuaishouProtocol{}, nil
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", name)
+	}
+}
+
+// rtbProtocol admux OpenRTB，protobuf 编码
+type rtbProtocol struct{}
+
+func (rtbProtocol) name() string        { return ProtocolRTB }
+func (rtbProtocol) defaultPath() string { return "/bid/rtb/v1" }
+func (rtbProtocol) contentType() string { return "application/x-protobuf" }
+
+func (rtbProtocol) parseTemplate(data []byte) (proto.Message, error) {
+	req := &admux_rtb.BidRequest{}
+	if err := (protojson.UnmarshalOptions{AllowPartial: true}).Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (rtbProtocol) parseSample(data []byte) (proto.Message, error) {
+	req := &admux_rtb.BidRequest{}
+	if err := (proto.UnmarshalOptions{AllowPartial: true}).Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (rtbProtocol) encode(sample proto.Message, requestID string) ([]byte, error) {
+	req := proto.Clone(sample).(*admux_rtb.BidRequest)
+	req.Id = proto.String(requestID)
+	return proto.Marshal(req)
+}
+
+func (rtbProtocol) validate(sample proto.Message, requestID string, body []byte) (string, string) {
+	resp := &admux_rtb.BidResponse{}
+	if err := (proto.UnmarshalOptions{AllowPartial: true}).Unmarshal(body, resp); err != nil {
+		return OutcomeInvalid, "unmarshal"
+	}
+	if resp.GetId() != requestID {
+		return OutcomeInvalid, "id_mismatch"
+	}
+	// 竞价失败时ADX以 nbr=TECHNICAL_ERROR 不出价
+	if resp.Nbr != nil && resp.GetNbr() == admux_rtb.NoBidReason_TECHNICAL_ERROR {
+		return OutcomeError, "technical_error"
+	}
+
+	imps := make(map[string]struct{})
+	for _, imp := range sample.(*admux_rtb.BidRequest).GetImp() {
+		imps[imp.GetId()] = struct{}{}
+	}
+	bids := 0
+	for _, seat := range resp.GetSeatbid() {
+		for _, bid := range seat.GetBid() {
+			bids++
+			switch {
+			case bid.GetId() == "":
+				return OutcomeInvalid, "missing_bid_id"
+			case bid.GetPrice() <= 0:
+				return OutcomeInvalid, "invalid_price"
+			case bid.GetAdmOneof() == nil && bid.GetNurl() == "":
+				return OutcomeInvalid, "missing_adm"
+			}
+			if _, ok := imps[bid.GetImpid()]; !ok {
+				return OutcomeInvalid, "unknown_impid"
+			}
+		}
+	}
+	if bids == 0 {
+		return OutcomeNoBid, ""
+	}
+	return OutcomeBid, ""
+}
+
+// kuaishouProtocol 快手协议，JSON 编码(与适配器一致使用 encoding/json)
+type kuaishouProtocol struct{}
+
+func (kuaishouProtocol) name() string        { return ProtocolKuaishou }
+func (kuaishouProtocol) defaultPath() string { return "/bid/kuaishou" }
+func (kuaishouProtocol) contentType() string { return "application/json" }
+
+func (p kuaishouProtocol) parseTemplate(data []byte) (proto.Message, error) {
+	return p.parseSample(data)
+}
+
+func (kuaishouProtocol) parseSample(data []byte) (proto.Message, error) {
+	req := &kuaishou_rtb.BidRequest{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (kuaishouProtocol) encode(sample proto.Message, requestID string) ([]byte, error) {
+	req := proto.Clone(sample).(*kuaishou_rtb.BidRequest)
+	req.RequestId = proto.String(requestID)
+	return json.Marshal(req)
+}
+
+func (kuaishouProtocol) validate(sample proto.Message, requestID string, body []byte) (string, string) {
+	resp := &kuaishou_rtb.BidResponse{}
+	if err := json.Unmarshal(body, resp); err != nil {
+		return OutcomeInvalid, "unmarshal"
+	}
+	if resp.GetRequestId() != requestID {
+		return OutcomeInvalid, "id_mismatch"
+	}
+	if resp.GetStatus() != 0 {
+		// 竞价失败时ADX以 noBidReason=TECHNICAL_ERROR 不出价
+		if resp.GetNoBidReason() == kuaishou_rtb.BidResponse_TECHNICAL_ERROR {
+			return OutcomeError, "technical_error"
+		}
+		return OutcomeNoBid, ""
+	}
+
+	imps := make(map[string]struct{})
+	for _, imp := range sample.(*kuaishou_rtb.BidRequest).GetImp() {
+		imps[imp.GetImpId()] = struct{}{}
+	}
+	bids := 0
+	for _, seat := range resp.GetSeatBid() {
+		for _, bid := range seat.GetBid() {
+			bids++
+			switch {
+			case bid.GetBidId() == "":
+				return OutcomeInvalid, "missing_bid_id"
+			case bid.GetPrice() <= 0:
+				return OutcomeInvalid, "invalid_price"
+			case bid.GetAdId() == "":
+				return OutcomeInvalid, "missing_ad_id"
+			}
+			if _, ok := imps[bid.GetImpId()]; !ok {
+				return OutcomeInvalid, "unknown_impid"
+			}
+		}
+	}
+	if bids == 0 {
+		// status=0 表示有广告返回
+		return OutcomeInvalid, "empty_seat_bid"
+	}
+	return OutcomeBid, ""
+}
@@ -0,0 +1,171 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxResponseSize 响应体读取上限
+const maxResponseSize = 1 << 20
+
+// Runner 开环压测：按固定间隔调度请求，不等待之前的请求返回，
+// 延迟从计划发送时间开始计算，避免 coordinated omission
+type Runner struct {
+	cfg     Config
+	client  *http.Client
+	targets []*target
+	stats   []*targetStats
+	weights int
+
+	// rnd 只在调度协程中使用
+	rnd      *rand.Rand
+	runID    int64
+	seq      atomic.Uint64
+	inflight atomic.Int64
+}
+
+// NewRunner 创建压测任务，加载所有目标的模板和样本
+func NewRunner(cfg Config) (*Runner, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := &Runner{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConns:        cfg.MaxInFlight,
+				MaxIdleConnsPerHost: cfg.MaxInFlight,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		rnd:   rand.New(rand.NewSource(seed)),
+		runID: time.Now().Unix(),
+	}
+	for _, tc := range cfg.Targets {
+		t, err := newTarget(cfg.BaseURL, tc)
+		if err != nil {
+			return nil, err
+		}
+		r.targets = append(r.targets, t)
+		r.stats = append(r.stats, newTargetStats())
+		r.weights += t.weight
+	}
+	return r, nil
+}
+
+// Run 执行压测直到 Duration 结束或 ctx 取消，等待在途请求完成后返回报告
+func (r *Runner) Run(ctx context.Context) *Report {
+	interval := time.Duration(float64(time.Second) / r.cfg.QPS)
+	total := int(r.cfg.Duration.Seconds() * r.cfg.QPS)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	var wg sync.WaitGroup
+	start := time.Now()
+schedule:
+	for i := 0; i < total; i++ {
+		scheduled := start.Add(time.Duration(i) * interval)
+		// 落后于计划时立即发送，不累积漂移
+		if wait := time.Until(scheduled); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				break schedule
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			break
+		}
+
+		idx := r.pick()
+		if r.inflight.Load() >= int64(r.cfg.MaxInFlight) {
+			r.stats[idx].record(OutcomeDropped, "", 0)
+			continue
+		}
+		r.inflight.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer r.inflight.Add(-1)
+			outcome, reason := r.fire(ctx, r.targets[idx])
+			r.stats[idx].record(outcome, reason, time.Since(scheduled))
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{Elapsed: time.Since(start)}
+	for i, t := range r.targets {
+		report.Targets = append(report.Targets, r.stats[i].report(t))
+	}
+	return report
+}
+
+// pick 按权重随机选择目标
+func (r *Runner) pick() int {
+	n := r.rnd.Intn(r.weights)
+	for i, t := range r.targets {
+		if n < t.weight {
+			return i
+		}
+		n -= t.weight
+	}
+	return len(r.targets) - 1
+}
+
+// fire 发送一个请求并分类结果
+func (r *Runner) fire(ctx context.Context, t *target) (string, string) {
+	sample := t.sample()
+	requestID := fmt.Sprintf("loadgen-%d-%d", r.runID, r.seq.Add(1))
+	body, err := t.protocol.encode(sample, requestID)
+	if err != nil {
+		return OutcomeError, "encode"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return OutcomeError, "request"
+	}
+	req.Header.Set("Content-Type", t.protocol.contentType())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return OutcomeError, "timeout"
+		}
+		return OutcomeError, "transport"
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return OutcomeError, "timeout"
+		}
+		return OutcomeError, "read_body"
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return OutcomeNoBid, ""
+	}
+	if resp.StatusCode != http.StatusOK {
+		return OutcomeError, fmt.Sprintf("http_%d", resp.StatusCode)
+	}
+	return t.protocol.validate(sample, requestID, data)
+}
@@ -0,0 +1,155 @@
+package loadgen
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// percentiles 报告中的延迟分位
+var percentiles = []float64{50, 90, 99, 99.9}
+
+// targetStats 单个目标的统计
+type targetStats struct {
+	mu        sync.Mutex
+	outcomes  map[string]int
+	reasons   map[string]int
+	latencies []time.Duration
+}
+
+func newTargetStats() *targetStats {
+	return &targetStats{outcomes: make(map[string]int), reasons: make(map[string]int)}
+}
+
+// record 记录一次请求结果；dropped 的请求没有延迟
+func (s *targetStats) record(outcome, reason string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outcomes[outcome]++
+	if reason != "" {
+		s.reasons[outcome+":"+reason]++
+	}
+	if outcome != OutcomeDropped {
+		s.latencies = append(s.latencies, latency)
+	}
+}
+
+// TargetReport 单个目标的压测结果
+type TargetReport struct {
+	SSPID    string
+	Protocol string
+
+	Sent    int // 实际发出的请求数(不含 dropped)
+	Bid     int
+	NoBid   int
+	Error   int
+	Invalid int
+	Dropped int
+	// Reasons 错误和校验失败原因，key 为 outcome:reason
+	Reasons map[string]int
+
+	// Latencies 延迟分位，与 percentiles 对应
+	Latencies []time.Duration
+	Max       time.Duration
+}
+
+// Rate 结果占已发送请求的比例
+func (r *TargetReport) Rate(n int) float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(n) / float64(r.Sent)
+}
+
+// Report 压测报告
+type Report struct {
+	Elapsed time.Duration
+	Targets []*TargetReport
+}
+
+func (s *targetStats) report(t *target) *TargetReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &TargetReport{
+		SSPID:    t.sspID,
+		Protocol: t.protocol.name(),
+		Bid:      s.outcomes[OutcomeBid],
+		NoBid:    s.outcomes[OutcomeNoBid],
+		Error:    s.outcomes[OutcomeError],
+		Invalid:  s.outcomes[OutcomeInvalid],
+		Dropped:  s.outcomes[OutcomeDropped],
+		Reasons:  make(map[string]int, len(s.reasons)),
+	}
+	r.Sent = r.Bid + r.NoBid + r.Error + r.Invalid
+	for k, v := range s.reasons {
+		r.Reasons[k] = v
+	}
+
+	sorted := append([]time.Duration(nil), s.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, p := range percentiles {
+		r.Latencies = append(r.Latencies, percentile(sorted, p))
+	}
+	if len(sorted) > 0 {
+		r.Max = sorted[len(sorted)-1]
+	}
+	return r
+}
+
+// percentile nearest-rank 分位，sorted 需已升序
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	// 减去误差避免 99.9% 这类分位因浮点误差多取一位
+	rank := int(math.Ceil(p/100*float64(len(sorted)) - 1e-9))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// HasFailures 是否存在错误或校验失败
+func (r *Report) HasFailures() bool {
+	for _, t := range r.Targets {
+		if t.Error > 0 || t.Invalid > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText 输出文本报告
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "elapsed: %s\n", r.Elapsed.Round(time.Millisecond))
+	for _, t := range r.Targets {
+		qps := 0.0
+		if r.Elapsed > 0 {
+			qps = float64(t.Sent) / r.Elapsed.Seconds()
+		}
+		fmt.Fprintf(w, "\n[%s/%s] sent=%d dropped=%d qps=%.1f\n", t.SSPID, t.Protocol, t.Sent, t.Dropped, qps)
+		fmt.Fprintf(w, "  bid=%d (%.2f%%) nobid=%d (%.2f%%) error=%d (%.2f%%) invalid=%d (%.2f%%)\n",
+			t.Bid, t.Rate(t.Bid)*100, t.NoBid, t.Rate(t.NoBid)*100,
+			t.Error, t.Rate(t.Error)*100, t.Invalid, t.Rate(t.Invalid)*100)
+
+		fmt.Fprint(w, "  latency:")
+		for i, p := range percentiles {
+			fmt.Fprintf(w, " p%g=%s", p, t.Latencies[i].Round(time.Microsecond))
+		}
+		fmt.Fprintf(w, " max=%s\n", t.Max.Round(time.Microsecond))
+
+		reasons := make([]string, 0, len(t.Reasons))
+		for k := range t.Reasons {
+			reasons = append(reasons, k)
+		}
+		sort.Strings(reasons)
+		for _, k := range reasons {
+			fmt.Fprintf(w, "  %s: %d\n", k, t.Reasons[k])
+		}
+	}
+}
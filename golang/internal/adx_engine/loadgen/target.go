@@ -0,0 +1,98 @@
+package loadgen
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
+)
+
+// target 一个SSP的流量源
+type target struct {
+	sspID    string
+	protocol protocol
+	url      string
+	weight   int
+	samples  []proto.Message
+	next     atomic.Uint64
+}
+
+func newTarget(baseURL string, cfg TargetConfig) (*target, error) {
+	if cfg.SSPID == "" {
+		return nil, fmt.Errorf("target ssp_id is required")
+	}
+	p, err := newProtocol(cfg.Protocol)
+	if err != nil {
+		return nil, fmt.Errorf("target %s: %w", cfg.SSPID, err)
+	}
+
+	path := cfg.Path
+	if path == "" {
+		path = p.defaultPath()
+	}
+	t := &target{
+		sspID:    cfg.SSPID,
+		protocol: p,
+		url:      strings.TrimRight(baseURL, "/") + path + "?sspid=" + cfg.SSPID,
+		weight:   cfg.Weight,
+	}
+	if t.weight <= 0 {
+		t.weight = 1
+	}
+
+	for _, file := range cfg.Templates {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: %w", cfg.SSPID, err)
+		}
+		msg, err := p.parseTemplate(data)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: parse template %s: %w", cfg.SSPID, file, err)
+		}
+		t.samples = append(t.samples, msg)
+	}
+	if cfg.Samples != "" {
+		if err := t.loadSamples(cfg.Samples); err != nil {
+			return nil, fmt.Errorf("target %s: %w", cfg.SSPID, err)
+		}
+	}
+	if len(t.samples) == 0 {
+		return nil, fmt.Errorf("target %s: no templates or samples", cfg.SSPID)
+	}
+	return t, nil
+}
+
+// loadSamples 从竞价日志中读取该SSP采集的原始请求
+func (t *target) loadSamples(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return bidlog.ReadRecords(f, func(record *bidlog.Record) error {
+		if record.SSPID != t.sspID || len(record.SSPRequest) == 0 {
+			return nil
+		}
+		msg, err := t.protocol.parseSample(record.SSPRequest)
+		if err != nil {
+			return fmt.Errorf("parse sample %s: %w", record.RequestID, err)
+		}
+		t.samples = append(t.samples, msg)
+		return nil
+	})
+}
+
+// sample 轮询选择样本
+func (t *target) sample() proto.Message {
+	return t.samples[(t.next.Add(1)-1)%uint64(len(t.samples))]
+}
+
+// key 报告中的目标标识
+func (t *target) key() string {
+	return t.sspID + "/" + t.protocol.name()
+}
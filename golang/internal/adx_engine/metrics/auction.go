@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+)
+
+// SSP 不出价原因
+const (
+	NoBidError    = "error"    // 竞价流程失败
+	NoBidTimeout  = "timeout"  // 广播超过SSP超时
+	NoBidNoDSP    = "no_dsp"   // 没有定向命中且健康的DSP
+	NoBidEmpty    = "no_bid"   // DSP均未出价
+	NoBidFiltered = "filtered" // 出价全部被过滤
+)
+
+// 默认标签取值上限
+const (
+	defaultMaxSSPLabels = 100
+	defaultMaxDSPLabels = 200
+)
+
+// AuctionMetrics 按 ssp/dsp 维度的竞价指标，ssp/dsp 标签经 LabelGuard 限制取值个数
+type AuctionMetrics struct {
+	// SSP请求数、请求耗时、不出价次数(按原因)
+	Requests       *prometheus.CounterVec
+	RequestLatency *prometheus.HistogramVec
+	NoBids         *prometheus.CounterVec
+
+	// DSP调用数、出价数、胜出数
+	DSPRequests *prometheus.CounterVec
+	DSPBids     *prometheus.CounterVec
+	DSPWins     *prometheus.CounterVec
+	// DSP不出价(按原因: no_bid/filtered)、超时、错误次数
+	DSPNoBids   *prometheus.CounterVec
+	DSPTimeouts *prometheus.CounterVec
+	DSPErrors   *prometheus.CounterVec
+	// DSP调用耗时
+	DSPLatency *prometheus.HistogramVec
+
+	// 出价CPM分布(货币单位)
+	BidPrice *prometheus.HistogramVec
+	// 过滤阶段淘汰的出价(按原因)
+	FilterRejections *prometheus.CounterVec
+
+	// 鉴权失败被拒绝的SSP请求(按原因)
+	AuthRejections *prometheus.CounterVec
+
+	// 超过SSP QPS限制或过载被直接返回不出价的SSP请求(按原因)
+	Shed *prometheus.CounterVec
+	// 处理中的竞价请求数、当前生效的并发上限(0 表示不限制)
+	InFlight      prometheus.Gauge
+	InFlightLimit prometheus.Gauge
+
+	// 超出取值上限被归入 other 的次数(按标签)
+	LabelOverflows *prometheus.CounterVec
+
+	ssps *LabelGuard
+	dsps *LabelGuard
+}
+
+// NewAuctionMetrics 创建竞价指标实例，reg为nil时注册到默认Registry；
+// maxSSPs/maxDSPs 为 ssp/dsp 标签的取值上限，<=0 时使用默认值
+func NewAuctionMetrics(namespace, subsystem string, reg prometheus.Registerer, maxSSPs, maxDSPs int) *AuctionMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if maxSSPs <= 0 {
+		maxSSPs = defaultMaxSSPLabels
+	}
+	if maxDSPs <= 0 {
+		maxDSPs = defaultMaxDSPLabels
+	}
+
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return registerOrReuse(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+		}, labels))
+	}
+	histogram := func(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+		return registerOrReuse(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+			Buckets:   buckets,
+		}, labels))
+	}
+	gauge := func(name, help string) prometheus.Gauge {
+		return registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+		}))
+	}
+	latencyBuckets := []float64{0.005, 0.01, 0.02, 0.05, 0.08, 0.1, 0.15, 0.2, 0.3, 0.5, 1}
+
+	m := &AuctionMetrics{
+		Requests:       counter("requests_total", "Total number of SSP bid requests", "ssp"),
+		RequestLatency: histogram("request_latency_seconds", "SSP bid request processing latency in seconds", latencyBuckets, "ssp"),
+		NoBids:         counter("nobids_total", "Total number of SSP bid requests answered without bids by reason", "ssp", "reason"),
+
+		DSPRequests: counter("dsp_requests_total", "Total number of DSP bid calls", "ssp", "dsp"),
+		DSPBids:     counter("dsp_bids_total", "Total number of bids returned by DSPs", "ssp", "dsp"),
+		DSPWins:     counter("dsp_wins_total", "Total number of auctions won by DSPs", "ssp", "dsp"),
+		DSPNoBids:   counter("dsp_nobids_total", "Total number of DSP calls without valid bids by reason", "ssp", "dsp", "reason"),
+		DSPTimeouts: counter("dsp_timeouts_total", "Total number of timed out DSP calls", "ssp", "dsp"),
+		DSPErrors:   counter("dsp_errors_total", "Total number of failed DSP calls other than timeouts", "ssp", "dsp"),
+		DSPLatency:  histogram("dsp_latency_seconds", "DSP bid call latency in seconds", latencyBuckets, "ssp", "dsp"),
+
+		BidPrice: histogram("bid_price_cpm", "Distribution of DSP bid CPM prices in currency units",
+			[]float64{0.1, 0.5, 1, 2, 5, 10, 20, 50, 100, 200}, "ssp", "dsp"),
+		FilterRejections: counter("filter_rejections_total", "Total number of bids rejected by the filter stage by reason", "ssp", "dsp", "reason"),
+
+		AuthRejections: counter("auth_rejections_total", "Total number of SSP bid requests rejected by authentication by reason", "ssp", "reason"),
+
+		Shed:          counter("shed_total", "Total number of SSP bid requests answered with a fast no-bid by the QPS limit or load shedding by reason", "ssp", "reason"),
+		InFlight:      gauge("in_flight_requests", "Number of SSP bid requests being processed"),
+		InFlightLimit: gauge("in_flight_limit", "In-flight request limit in effect, 0 when unlimited"),
+
+		LabelOverflows: counter("label_overflows_total", "Total number of label values folded into other by the cardinality limit", "label"),
+	}
+	m.ssps = NewLabelGuard(maxSSPs, func() { m.LabelOverflows.WithLabelValues("ssp").Inc() })
+	m.dsps = NewLabelGuard(maxDSPs, func() { m.LabelOverflows.WithLabelValues("dsp").Inc() })
+	return m
+}
+
+// AllowSSPs 保留已配置的SSP标签取值
+func (m *AuctionMetrics) AllowSSPs(ids ...string) {
+	m.ssps.Allow(ids...)
+}
+
+// ObserveAuthRejection 记录一次鉴权失败，sspID为空表示请求的SSP不存在
+func (m *AuctionMetrics) ObserveAuthRejection(sspID, reason string) {
+	ssp := LabelUnknown
+	if sspID != "" {
+		ssp = m.ssps.Value(sspID)
+	}
+	m.AuthRejections.WithLabelValues(ssp, reason).Inc()
+}
+
+// ObserveShed 记录一次因QPS限制或过载直接返回的不出价
+func (m *AuctionMetrics) ObserveShed(sspID, reason string) {
+	m.Shed.WithLabelValues(m.ssps.Value(sspID), reason).Inc()
+}
+
+// ObserveLoad 记录处理中的请求数和当前生效的并发上限
+func (m *AuctionMetrics) ObserveLoad(inFlight, limit int64) {
+	m.InFlight.Set(float64(inFlight))
+	m.InFlightLimit.Set(float64(limit))
+}
+
+// ObserveAuction 记录一次竞价请求的全部指标，err 为竞价流程的错误
+func (m *AuctionMetrics) ObserveAuction(bidCtx *adxcore.BidRequestCtx, err error) {
+	ssp := m.ssps.Value(bidCtx.SSPID)
+	m.Requests.WithLabelValues(ssp).Inc()
+	m.RequestLatency.WithLabelValues(ssp).Observe(time.Since(bidCtx.BidStartTime).Seconds())
+
+	// 按DSP统计被过滤的出价，用于区分DSP不出价和出价全部被过滤
+	rejected := make(map[string]int)
+	for _, candidate := range bidCtx.RejectedCandidates {
+		rejected[candidate.BidderID]++
+		m.FilterRejections.WithLabelValues(ssp, m.dsps.Value(candidate.BidderID), candidate.FilterReason).Inc()
+	}
+
+	for _, call := range bidCtx.BidderCalls {
+		dsp := m.dsps.Value(call.BidderID)
+		m.DSPRequests.WithLabelValues(ssp, dsp).Inc()
+		m.DSPLatency.WithLabelValues(ssp, dsp).Observe(call.Latency.Seconds())
+
+		switch {
+		case call.Err != nil && isTimeout(call.Err):
+			m.DSPTimeouts.WithLabelValues(ssp, dsp).Inc()
+		case call.Err != nil:
+			m.DSPErrors.WithLabelValues(ssp, dsp).Inc()
+		case len(call.Candidates) == 0:
+			m.DSPNoBids.WithLabelValues(ssp, dsp, NoBidEmpty).Inc()
+		default:
+			m.DSPBids.WithLabelValues(ssp, dsp).Add(float64(len(call.Candidates)))
+			for _, candidate := range call.Candidates {
+				m.BidPrice.WithLabelValues(ssp, dsp).Observe(float64(candidate.CPMPrice) / 100)
+			}
+			if rejected[call.BidderID] >= len(call.Candidates) {
+				m.DSPNoBids.WithLabelValues(ssp, dsp, NoBidFiltered).Inc()
+			}
+		}
+	}
+
+	winners := bidCtx.Winners()
+	for _, winner := range winners {
+		m.DSPWins.WithLabelValues(ssp, m.dsps.Value(winner.BidderID)).Inc()
+	}
+	if reason := noBidReason(bidCtx, err, len(winners)); reason != "" {
+		m.NoBids.WithLabelValues(ssp, reason).Inc()
+	}
+}
+
+// noBidReason 返回SSP请求不出价的原因，有胜出出价时为空
+func noBidReason(bidCtx *adxcore.BidRequestCtx, err error, winners int) string {
+	switch {
+	case err != nil && isTimeout(err):
+		return NoBidTimeout
+	case err != nil:
+		return NoBidError
+	case winners > 0:
+		return ""
+	case len(bidCtx.BidderCalls) == 0:
+		return NoBidNoDSP
+	case len(bidCtx.RejectedCandidates) > 0:
+		return NoBidFiltered
+	default:
+		return NoBidEmpty
+	}
+}
+
+// isTimeout 是否为超时错误，包括上下文超时和网络超时
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
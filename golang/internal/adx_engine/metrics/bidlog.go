@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BidLogMetrics 竞价采样日志指标
+type BidLogMetrics struct {
+	// 采样记录数(按结果: written/dropped/error)
+	Records *prometheus.CounterVec
+}
+
+// NewBidLogMetrics 创建竞价日志指标实例，reg为nil时注册到默认Registry
+func NewBidLogMetrics(namespace, subsystem string, reg prometheus.Registerer) *BidLogMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	return &BidLogMetrics{
+		Records: registerOrReuse(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "records_total",
+			Help:      "Total number of sampled bid log records by result",
+		}, []string{"result"})),
+	}
+}
@@ -2,7 +2,6 @@ package metrics
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 // BroadcastMetrics 广播相关指标
@@ -32,82 +31,86 @@ type BroadcastMetrics struct {
 	CircuitBreakerClosed prometheus.Gauge
 }
 
-// NewBroadcastMetrics 创建广播指标实例
-func NewBroadcastMetrics(namespace, subsystem string) *BroadcastMetrics {
+// NewBroadcastMetrics 创建广播指标实例，重复创建时复用已注册的指标，reg为nil时注册到默认Registry
+func NewBroadcastMetrics(namespace, subsystem string, reg prometheus.Registerer) *BroadcastMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
 	return &BroadcastMetrics{
-		TotalRequests: promauto.NewCounter(prometheus.CounterOpts{
+		TotalRequests: registerOrReuse(reg, prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "total_requests",
 			Help:      "Total number of broadcast requests",
-		}),
-		SuccessResponses: promauto.NewCounter(prometheus.CounterOpts{
+		})),
+		SuccessResponses: registerOrReuse(reg, prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "success_responses",
 			Help:      "Number of successful bid responses",
-		}),
-		FailedResponses: promauto.NewCounter(prometheus.CounterOpts{
+		})),
+		FailedResponses: registerOrReuse(reg, prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "failed_responses",
 			Help:      "Number of failed bid responses",
-		}),
-		ResponseLatency: promauto.NewHistogram(prometheus.HistogramOpts{
+		})),
+		ResponseLatency: registerOrReuse(reg, prometheus.NewHistogram(prometheus.HistogramOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "response_latency_seconds",
 			Help:      "Response latency in seconds",
 			Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0, 2.0},
-		}),
-		ActiveBidders: promauto.NewGauge(prometheus.GaugeOpts{
+		})),
+		ActiveBidders: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "active_bidders",
 			Help:      "Number of active bidders",
-		}),
-		ConcurrentRequests: promauto.NewGauge(prometheus.GaugeOpts{
+		})),
+		ConcurrentRequests: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "concurrent_requests",
 			Help:      "Number of concurrent broadcast requests",
-		}),
-		RetryCount: promauto.NewCounter(prometheus.CounterOpts{
+		})),
+		RetryCount: registerOrReuse(reg, prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "retry_count",
 			Help:      "Total number of retries",
-		}),
-		HealthyBidders: promauto.NewGauge(prometheus.GaugeOpts{
+		})),
+		HealthyBidders: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "healthy_bidders",
 			Help:      "Number of healthy bidders",
-		}),
-		UnhealthyBidders: promauto.NewGauge(prometheus.GaugeOpts{
+		})),
+		UnhealthyBidders: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "unhealthy_bidders",
 			Help:      "Number of unhealthy bidders",
-		}),
-		CircuitBreakerOpen: promauto.NewGauge(prometheus.GaugeOpts{
+		})),
+		CircuitBreakerOpen: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "circuit_breaker_open",
 			Help:      "Circuit breaker open state",
-		}),
-		CircuitBreakerHalfOpen: promauto.NewGauge(prometheus.GaugeOpts{
+		})),
+		CircuitBreakerHalfOpen: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "circuit_breaker_half_open",
 			Help:      "Circuit breaker half-open state",
-		}),
-		CircuitBreakerClosed: promauto.NewGauge(prometheus.GaugeOpts{
+		})),
+		CircuitBreakerClosed: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "circuit_breaker_closed",
 			Help:      "Circuit breaker closed state",
-		}),
+		})),
 	}
 }
 
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DSPConfigMetrics DSP配置加载相关指标
+type DSPConfigMetrics struct {
+	// 当前被隔离的配置文件数(按配置源)
+	QuarantinedFiles *prometheus.GaugeVec
+
+	// 校验失败次数(按配置源、字段)，同一文件内容不变时重复扫描不计数
+	ValidationFailures *prometheus.CounterVec
+
+	// 当前生效的DSP数
+	LoadedDSPs prometheus.Gauge
+}
+
+// NewDSPConfigMetrics 创建DSP配置指标实例，reg为nil时注册到默认Registry
+func NewDSPConfigMetrics(namespace, subsystem string, reg prometheus.Registerer) *DSPConfigMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	return &DSPConfigMetrics{
+		QuarantinedFiles: registerOrReuse(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "quarantined_files",
+			Help:      "Number of DSP config files currently quarantined",
+		}, []string{"source"})),
+		ValidationFailures: registerOrReuse(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "validation_failures_total",
+			Help:      "Total number of DSP config validation failures, counted once per quarantined file content",
+		}, []string{"source", "field"})),
+		LoadedDSPs: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "loaded_dsps",
+			Help:      "Number of valid DSP configs currently loaded",
+		})),
+	}
+}
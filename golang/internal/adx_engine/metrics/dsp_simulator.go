@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DSPSimulatorMetrics DSP模拟器指标
+type DSPSimulatorMetrics struct {
+	// 请求数(按场景、结果: bid/nobid/error/timeout/bad_request)
+	Requests *prometheus.CounterVec
+	// 注入的响应延迟(按场景)
+	Latency *prometheus.HistogramVec
+	// 出价CPM(元，按场景)
+	BidPrice *prometheus.HistogramVec
+}
+
+// NewDSPSimulatorMetrics 创建DSP模拟器指标实例，reg为nil时注册到默认Registry
+func NewDSPSimulatorMetrics(namespace, subsystem string, reg prometheus.Registerer) *DSPSimulatorMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	return &DSPSimulatorMetrics{
+		Requests: registerOrReuse(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Total number of simulated bid requests by scenario and result",
+		}, []string{"scenario", "result"})),
+		Latency: registerOrReuse(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "latency_seconds",
+			Help:      "Injected response latency by scenario",
+			Buckets:   []float64{.005, .01, .02, .05, .1, .2, .5, 1, 2, 5},
+		}, []string{"scenario"})),
+		BidPrice: registerOrReuse(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "bid_price",
+			Help:      "Simulated bid CPM price (yuan) by scenario",
+			Buckets:   []float64{.5, 1, 2, 5, 10, 20, 50, 100},
+		}, []string{"scenario"})),
+	}
+}
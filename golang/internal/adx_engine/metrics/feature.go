@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+)
+
+// FeatureMetrics 特征补全相关指标，实现 adxcore.FeatureObserver
+type FeatureMetrics struct {
+	// 提供者耗时(按提供者)
+	ProviderLatency *prometheus.HistogramVec
+
+	// 提供者错误次数(按提供者、原因)
+	ProviderErrors *prometheus.CounterVec
+}
+
+// NewFeatureMetrics 创建特征补全指标实例，reg为nil时注册到默认Registry
+func NewFeatureMetrics(namespace, subsystem string, reg prometheus.Registerer) *FeatureMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	return &FeatureMetrics{
+		ProviderLatency: registerOrReuse(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "provider_latency_seconds",
+			Help:      "Feature provider latency in seconds",
+			Buckets:   []float64{0.0005, 0.001, 0.002, 0.005, 0.01, 0.02, 0.05, 0.1},
+		}, []string{"provider"})),
+		ProviderErrors: registerOrReuse(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "provider_errors_total",
+			Help:      "Total number of feature provider failures",
+		}, []string{"provider", "reason"})),
+	}
+}
+
+// ObserveProvider 记录单个提供者的耗时和错误
+func (m *FeatureMetrics) ObserveProvider(provider string, latency time.Duration, err error) {
+	m.ProviderLatency.WithLabelValues(provider).Observe(latency.Seconds())
+	if err == nil {
+		return
+	}
+
+	reason := "error"
+	if errors.Is(err, adxcore.ErrFeatureTimeout) {
+		reason = "timeout"
+	}
+	m.ProviderErrors.WithLabelValues(provider, reason).Inc()
+}
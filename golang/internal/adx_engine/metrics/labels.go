@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"sync"
+)
+
+const (
+	// LabelOther 超出取值上限的标签值
+	LabelOther = "other"
+	// LabelUnknown 空标签值
+	LabelUnknown = "unknown"
+)
+
+// LabelGuard 限制单个标签的取值个数，防止来自请求的ID导致时间序列无限增长。
+// 先出现的取值被保留，达到上限后新的取值归入 LabelOther；已保留的取值始终不变。
+type LabelGuard struct {
+	mu     sync.RWMutex
+	limit  int
+	values map[string]struct{}
+
+	// onOverflow 取值被归入 LabelOther 时调用
+	onOverflow func()
+}
+
+// NewLabelGuard 创建标签取值限制，limit<=0 时不限制
+func NewLabelGuard(limit int, onOverflow func()) *LabelGuard {
+	return &LabelGuard{limit: limit, values: make(map[string]struct{}), onOverflow: onOverflow}
+}
+
+// Allow 预先保留已知的取值(如配置中的SSP)，不受上限限制
+func (g *LabelGuard) Allow(values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, value := range values {
+		g.values[value] = struct{}{}
+	}
+}
+
+// Value 返回可用作标签的取值
+func (g *LabelGuard) Value(value string) string {
+	if value == "" {
+		return LabelUnknown
+	}
+	if g.limit <= 0 {
+		return value
+	}
+
+	g.mu.RLock()
+	_, ok := g.values[value]
+	g.mu.RUnlock()
+	if ok {
+		return value
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.values[value]; ok {
+		return value
+	}
+	if len(g.values) >= g.limit {
+		if g.onOverflow != nil {
+			g.onOverflow()
+		}
+		return LabelOther
+	}
+	g.values[value] = struct{}{}
+	return value
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelGuard(t *testing.T) {
+	overflows := 0
+	guard := NewLabelGuard(2, func() { overflows++ })
+	guard.Allow("configured")
+
+	assert.Equal(t, "configured", guard.Value("configured"))
+	assert.Equal(t, "a", guard.Value("a"))
+	assert.Equal(t, LabelOther, guard.Value("b"))
+	assert.Equal(t, LabelOther, guard.Value("c"))
+	// 已保留的取值不受上限影响
+	assert.Equal(t, "a", guard.Value("a"))
+	assert.Equal(t, LabelUnknown, guard.Value(""))
+	assert.Equal(t, 2, overflows)
+
+	unlimited := NewLabelGuard(0, nil)
+	assert.Equal(t, "b", unlimited.Value("b"))
+}
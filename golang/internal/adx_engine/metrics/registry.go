@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registerOrReuse 注册指标，同名指标已注册时返回已有实例，
+// 避免组件被多次创建(如测试、配置重载)时重复注册panic
+func registerOrReuse[T prometheus.Collector](reg prometheus.Registerer, collector T) T {
+	if err := reg.Register(collector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return collector
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SSPConfigMetrics SSP配置重载相关指标
+type SSPConfigMetrics struct {
+	// 重载次数(按触发来源: file/admin，结果: success/invalid/error)
+	Reloads *prometheus.CounterVec
+
+	// 当前启用的SSP数
+	LoadedSSPs prometheus.Gauge
+}
+
+// NewSSPConfigMetrics 创建SSP配置指标实例，reg为nil时注册到默认Registry
+func NewSSPConfigMetrics(namespace, subsystem string, reg prometheus.Registerer) *SSPConfigMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	return &SSPConfigMetrics{
+		Reloads: registerOrReuse(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "reloads_total",
+			Help:      "Total number of SSP config reloads by trigger and result",
+		}, []string{"trigger", "result"})),
+		LoadedSSPs: registerOrReuse(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "loaded_ssps",
+			Help:      "Number of enabled SSPs currently loaded",
+		})),
+	}
+}
@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
+	"github.com/echoface/admux/internal/adx_engine/dspbidder"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+// DSP模式
+const (
+	DSPModeCaptured  = "captured"  // 返回竞价日志中记录的DSP响应
+	DSPModeSimulated = "simulated" // 按请求确定性生成出价
+)
+
+type capturedKey struct{}
+
+// capturedResponses 当前回放记录中各DSP的响应，随请求上下文传递，支持并发回放
+type capturedResponses map[string][]*admux_rtb.BidResponse
+
+// withCaptured 解析记录中的DSP响应并放入上下文
+func withCaptured(ctx context.Context, record *bidlog.Record) (context.Context, error) {
+	responses := make(capturedResponses, len(record.Bidders))
+	for _, bidder := range record.Bidders {
+		for _, raw := range bidder.Responses {
+			resp := &admux_rtb.BidResponse{}
+			if err := (protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}).Unmarshal(raw, resp); err != nil {
+				return nil, fmt.Errorf("bidder %s response: %w", bidder.BidderID, err)
+			}
+			responses[bidder.BidderID] = append(responses[bidder.BidderID], resp)
+		}
+	}
+	return context.WithValue(ctx, capturedKey{}, responses), nil
+}
+
+// CapturedBidder 回放记录中的DSP响应，记录中失败或未参与的DSP不出价
+type CapturedBidder struct {
+	id string
+}
+
+// NewCapturedBidder 创建回放bidder
+func NewCapturedBidder(id string) *CapturedBidder {
+	return &CapturedBidder{id: id}
+}
+
+func (b *CapturedBidder) GetInfo() *adxcore.BidderInfo {
+	return &adxcore.BidderInfo{ID: b.id}
+}
+
+func (b *CapturedBidder) SendBidRequest(bidRequest *adxcore.BidRequestCtx) ([]*adxcore.BidCandidate, error) {
+	responses, _ := bidRequest.Value(capturedKey{}).(capturedResponses)
+	var candidates []*adxcore.BidCandidate
+	for _, resp := range responses[b.id] {
+		// 克隆一份，避免响应构建阶段修改记录中的响应
+		candidates = append(candidates, dspbidder.CandidatesFromResponse(b.id, proto.Clone(resp).(*admux_rtb.BidResponse))...)
+	}
+	return candidates, nil
+}
+
+// SimulatedBidder 模拟DSP：对每个广告位出价，出价由 DSP+请求ID+广告位ID 确定，多次回放结果一致
+type SimulatedBidder struct {
+	id                 string
+	minPrice, maxPrice int64 // CPM，单位分
+}
+
+// NewSimulatedBidder 创建模拟bidder，出价范围 [minPrice, maxPrice) 分
+func NewSimulatedBidder(id string, minPrice, maxPrice int64) *SimulatedBidder {
+	if maxPrice <= minPrice {
+		maxPrice = minPrice + 1
+	}
+	return &SimulatedBidder{id: id, minPrice: minPrice, maxPrice: maxPrice}
+}
+
+func (b *SimulatedBidder) GetInfo() *adxcore.BidderInfo {
+	return &adxcore.BidderInfo{ID: b.id}
+}
+
+func (b *SimulatedBidder) SendBidRequest(bidRequest *adxcore.BidRequestCtx) ([]*adxcore.BidCandidate, error) {
+	req := bidRequest.Request
+	resp := &admux_rtb.BidResponse{Id: proto.String(req.GetId())}
+	seat := &admux_rtb.BidResponse_SeatBid{Seat: proto.String(b.id)}
+	for _, imp := range req.GetImp() {
+		h := fnv.New64a()
+		h.Write([]byte(b.id + "|" + req.GetId() + "|" + imp.GetId()))
+		price := b.minPrice + int64(h.Sum64()%uint64(b.maxPrice-b.minPrice))
+
+		bidID := b.id + "-" + imp.GetId()
+		base := "https://" + b.id + ".sim.admux.local"
+		seat.Bid = append(seat.Bid, &admux_rtb.BidResponse_SeatBid_Bid{
+			Id:       proto.String(bidID),
+			Impid:    proto.String(imp.GetId()),
+			Price:    proto.Float64(float64(price) / 100),
+			Adid:     proto.String("ad-" + bidID),
+			Crid:     proto.String("cr-" + bidID),
+			Nurl:     proto.String(base + "/win?p=${AUCTION_PRICE}"),
+			Lurl:     proto.String(base + "/loss?r=${AUCTION_LOSS}"),
+			AdmOneof: &admux_rtb.BidResponse_SeatBid_Bid_Adm{Adm: `<img src="` + base + `/ad/` + bidID + `.png">`},
+		})
+	}
+	if len(seat.Bid) > 0 {
+		resp.Seatbid = append(resp.Seatbid, seat)
+	}
+	return dspbidder.CandidatesFromResponse(b.id, resp), nil
+}
@@ -0,0 +1,169 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// missing 字段缺失时的占位值
+const missing = "<missing>"
+
+// indexPattern 字段路径中的数组下标，汇总时归一化为 []
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// FieldDiff 单个字段的差异
+type FieldDiff struct {
+	Path     string `json:"path"`
+	Baseline string `json:"baseline"`
+	Current  string `json:"current"`
+}
+
+// DiffOptions 字段比较选项
+type DiffOptions struct {
+	// IgnoreFields 忽略的字段路径(数组下标写作 [])，如 seatbid[].bid[].ext
+	IgnoreFields []string
+	// IgnoreURLParams 比较URL类字段时忽略的查询参数，如监测链接中的 ts、sig
+	IgnoreURLParams []string
+}
+
+// NormalizePath 去掉数组下标，用于汇总和忽略匹配
+func NormalizePath(path string) string {
+	return indexPattern.ReplaceAllString(path, "[]")
+}
+
+// DiffJSON 逐字段比较两个JSON文档，返回按路径排序的差异；prefix 为路径前缀
+func DiffJSON(prefix string, baseline, current []byte, opts *DiffOptions) ([]FieldDiff, error) {
+	var b, c any
+	if len(bytes.TrimSpace(baseline)) > 0 {
+		if err := json.Unmarshal(baseline, &b); err != nil {
+			return nil, fmt.Errorf("baseline %s: %w", prefix, err)
+		}
+	}
+	if len(bytes.TrimSpace(current)) > 0 {
+		if err := json.Unmarshal(current, &c); err != nil {
+			return nil, fmt.Errorf("current %s: %w", prefix, err)
+		}
+	}
+
+	ignored := make(map[string]struct{})
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	for _, field := range opts.IgnoreFields {
+		ignored[field] = struct{}{}
+	}
+	d := &differ{opts: opts, ignored: ignored}
+	d.diff(prefix, b, c)
+	sort.Slice(d.diffs, func(i, j int) bool { return d.diffs[i].Path < d.diffs[j].Path })
+	return d.diffs, nil
+}
+
+type differ struct {
+	opts    *DiffOptions
+	ignored map[string]struct{}
+	diffs   []FieldDiff
+}
+
+func (d *differ) diff(path string, b, c any) {
+	if _, ok := d.ignored[NormalizePath(path)]; ok {
+		return
+	}
+
+	switch bv := b.(type) {
+	case map[string]any:
+		if cv, ok := c.(map[string]any); ok {
+			keys := make(map[string]struct{}, len(bv)+len(cv))
+			for k := range bv {
+				keys[k] = struct{}{}
+			}
+			for k := range cv {
+				keys[k] = struct{}{}
+			}
+			for k := range keys {
+				bField, bOK := bv[k]
+				cField, cOK := cv[k]
+				if !bOK {
+					bField = missingValue{}
+				}
+				if !cOK {
+					cField = missingValue{}
+				}
+				d.diff(joinPath(path, k), bField, cField)
+			}
+			return
+		}
+	case []any:
+		if cv, ok := c.([]any); ok {
+			for i := 0; i < max(len(bv), len(cv)); i++ {
+				var bItem, cItem any = missingValue{}, missingValue{}
+				if i < len(bv) {
+					bItem = bv[i]
+				}
+				if i < len(cv) {
+					cItem = cv[i]
+				}
+				d.diff(fmt.Sprintf("%s[%d]", path, i), bItem, cItem)
+			}
+			return
+		}
+	case string:
+		if cv, ok := c.(string); ok && d.sameURL(bv, cv) {
+			return
+		}
+	}
+
+	bs, cs := format(b), format(c)
+	if bs != cs {
+		d.diffs = append(d.diffs, FieldDiff{Path: path, Baseline: bs, Current: cs})
+	}
+}
+
+// sameURL 两个URL去掉忽略的查询参数后是否一致
+func (d *differ) sameURL(b, c string) bool {
+	if b == c {
+		return true
+	}
+	if len(d.opts.IgnoreURLParams) == 0 || !strings.Contains(b, "?") || !strings.Contains(c, "?") {
+		return false
+	}
+	return stripParams(b, d.opts.IgnoreURLParams) == stripParams(c, d.opts.IgnoreURLParams)
+}
+
+func stripParams(raw string, params []string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	query := u.Query()
+	for _, param := range params {
+		query.Del(param)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+type missingValue struct{}
+
+func format(v any) string {
+	switch v := v.(type) {
+	case missingValue:
+		return missing
+	case string:
+		return v
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
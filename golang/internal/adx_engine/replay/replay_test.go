@@ -0,0 +1,172 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+func TestDiffJSON(t *testing.T) {
+	baseline := `{"id":"r1","imp":[{"id":"1","bidfloor":1.5},{"id":"2"}],"ext":{"a":1},
+		"nurl":"https://t.admux.com/win?b=1&ts=100&sig=aaa"}`
+	current := `{"id":"r1","imp":[{"id":"1","bidfloor":2}],"ext":{"a":2},"cur":["CNY"],
+		"nurl":"https://t.admux.com/win?b=1&ts=200&sig=bbb"}`
+
+	diffs, err := DiffJSON("request", []byte(baseline), []byte(current), &DiffOptions{
+		IgnoreFields:    []string{"request.ext"},
+		IgnoreURLParams: []string{"ts", "sig"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDiff{
+		{Path: "request.cur", Baseline: missing, Current: `["CNY"]`},
+		{Path: "request.imp[0].bidfloor", Baseline: "1.5", Current: "2"},
+		{Path: "request.imp[1]", Baseline: `{"id":"2"}`, Current: missing},
+	}, diffs)
+
+	// 不忽略签名参数时URL不同
+	diffs, err = DiffJSON("", []byte(`{"u":"https://a?ts=1"}`), []byte(`{"u":"https://a?ts=2"}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDiff{{Path: "u", Baseline: "https://a?ts=1", Current: "https://a?ts=2"}}, diffs)
+
+	_, err = DiffJSON("request", []byte(`{`), nil, nil)
+	assert.Error(t, err)
+}
+
+func TestReport(t *testing.T) {
+	report := NewReport()
+	report.Add("ssp_b", nil, nil)
+	report.Add("ssp_a", []FieldDiff{{Path: "response.seatbid[0].bid[0].price"}, {Path: "response.seatbid[0].bid[1].price"}}, nil)
+	report.Add("ssp_a", []FieldDiff{{Path: "response.seatbid[0].bid[0].price"}, {Path: "error"}}, nil)
+	report.Add("ssp_a", nil, assert.AnError)
+
+	ssps := report.SSPs()
+	require.Len(t, ssps, 2)
+	assert.Equal(t, &SSPReport{
+		SSPID: "ssp_a", Total: 3, Replayed: 2, Errors: 1, Differing: 2,
+		Fields: map[string]int{"response.seatbid[].bid[].price": 2, "error": 1},
+	}, ssps[0])
+	assert.Equal(t, 0, ssps[1].Differing)
+	assert.True(t, report.HasDiff())
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteText(&out))
+	assert.Contains(t, out.String(), "ssp ssp_a: total=3 replayed=2 errors=1 differing=2")
+	assert.Less(t, bytes.Index(out.Bytes(), []byte("price")), bytes.Index(out.Bytes(), []byte("  error")))
+}
+
+func newTestConfig() *config.AdxServerConfig {
+	cfg := config.DefaultAdxConfig()
+	cfg.SSPs = []config.SSPConfig{{ID: "ssp_std", Protocol: "admux", Enabled: true}}
+	cfg.Tracking = config.TrackingConfig{
+		BaseURL:     "https://t.admux.com",
+		ActiveKeyID: "k1",
+		SigningKeys: []config.SigningKeyConfig{{ID: "k1", Secret: "secret"}},
+	}
+	return cfg
+}
+
+// newCapturedRecord 构造一条线上采集的记录：SSP请求 + dsp_a 的响应
+func newCapturedRecord(t *testing.T, price float64) *bidlog.Record {
+	req := &admux_rtb.BidRequest{
+		Id:  proto.String("req-1"),
+		Imp: []*admux_rtb.BidRequest_Imp{{Id: proto.String("imp-1"), Bidfloor: proto.Float64(1)}},
+	}
+	sspRequest, err := proto.Marshal(req)
+	require.NoError(t, err)
+
+	resp := &admux_rtb.BidResponse{
+		Id: proto.String("req-1"),
+		Seatbid: []*admux_rtb.BidResponse_SeatBid{{
+			Seat: proto.String("seat-a"),
+			Bid: []*admux_rtb.BidResponse_SeatBid_Bid{{
+				Id:       proto.String("bid-a"),
+				Impid:    proto.String("imp-1"),
+				Price:    proto.Float64(price),
+				Nurl:     proto.String("https://dsp-a.example.com/win?p=${AUCTION_PRICE}"),
+				AdmOneof: &admux_rtb.BidResponse_SeatBid_Bid_Adm{Adm: "<img>"},
+			}},
+		}},
+	}
+	dspResponse, err := protojson.Marshal(resp)
+	require.NoError(t, err)
+
+	return &bidlog.Record{
+		SSPID:      "ssp_std",
+		RequestID:  "req-1",
+		SSPRequest: sspRequest,
+		Bidders:    []bidlog.BidderRecord{{BidderID: "dsp_a", Responses: []json.RawMessage{dspResponse}}},
+	}
+}
+
+func TestRunner_ReplayCaptured(t *testing.T) {
+	runner, err := NewRunner(newTestConfig(), Options{})
+	require.NoError(t, err)
+	defer runner.Close()
+
+	baseline, err := runner.Replay(context.Background(), newCapturedRecord(t, 12.5))
+	require.NoError(t, err)
+	assert.Empty(t, baseline.Error)
+	require.Len(t, baseline.Auction, 1)
+	assert.Equal(t, int64(1250), baseline.Auction[0].CPMPrice)
+	assert.True(t, baseline.Auction[0].Won)
+	assert.NotEmpty(t, baseline.SSPResponse)
+
+	// 相同输入重放结果一致(忽略监测链接签名，proto编码的SSP响应中的签名无法忽略)
+	current, err := runner.Replay(context.Background(), newCapturedRecord(t, 12.5))
+	require.NoError(t, err)
+	diffs, err := Compare(baseline, current, &DiffOptions{
+		IgnoreFields: []string{"ssp_response"}, IgnoreURLParams: []string{"ts", "sig"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// DSP出价变化体现为响应字段差异
+	current, err = runner.Replay(context.Background(), newCapturedRecord(t, 15))
+	require.NoError(t, err)
+	diffs, err = Compare(baseline, current, &DiffOptions{IgnoreURLParams: []string{"ts", "sig"}})
+	require.NoError(t, err)
+	paths := make([]string, 0, len(diffs))
+	for _, diff := range diffs {
+		paths = append(paths, diff.Path)
+	}
+	assert.Contains(t, paths, "response.seatbid[0].bid[0].price")
+	assert.Contains(t, paths, "ssp_response")
+
+	// 未配置的SSP无法回放
+	unknown := newCapturedRecord(t, 1)
+	unknown.SSPID = "ssp_unknown"
+	_, err = runner.Replay(context.Background(), unknown)
+	assert.Error(t, err)
+}
+
+func TestRunner_ReplaySimulated(t *testing.T) {
+	_, err := NewRunner(newTestConfig(), Options{DSPMode: DSPModeSimulated})
+	assert.Error(t, err)
+
+	runner, err := NewRunner(newTestConfig(), Options{DSPMode: DSPModeSimulated, DSPs: []string{"dsp_x", "dsp_y"}})
+	require.NoError(t, err)
+	defer runner.Close()
+
+	first, err := runner.Replay(context.Background(), newCapturedRecord(t, 1))
+	require.NoError(t, err)
+	second, err := runner.Replay(context.Background(), newCapturedRecord(t, 1))
+	require.NoError(t, err)
+
+	assert.Len(t, first.Bidders, 2)
+	assert.Len(t, first.Auction, 2)
+	diffs, err := Compare(first, second, &DiffOptions{
+		IgnoreFields: []string{"ssp_response"}, IgnoreURLParams: []string{"ts", "sig"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+}
@@ -0,0 +1,162 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
+)
+
+// Compare 比较基线记录与回放记录的内部请求、内部响应、SSP响应和错误；
+// SSP响应为JSON时逐字段比较，否则按字节比较(其中的监测签名无法忽略，可忽略 ssp_response 只看内部响应)
+func Compare(baseline, current *bidlog.Record, opts *DiffOptions) ([]FieldDiff, error) {
+	var diffs []FieldDiff
+	for _, part := range []struct {
+		name              string
+		baseline, current []byte
+	}{
+		{"request", baseline.Request, current.Request},
+		{"response", baseline.Response, current.Response},
+	} {
+		partDiffs, err := DiffJSON(part.name, part.baseline, part.current, opts)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, partDiffs...)
+	}
+
+	switch {
+	case json.Valid(baseline.SSPResponse) && json.Valid(current.SSPResponse):
+		partDiffs, err := DiffJSON("ssp_response", baseline.SSPResponse, current.SSPResponse, opts)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, partDiffs...)
+	case !ignoredField(opts, "ssp_response") && !bytes.Equal(baseline.SSPResponse, current.SSPResponse):
+		diffs = append(diffs, FieldDiff{
+			Path:     "ssp_response",
+			Baseline: base64.StdEncoding.EncodeToString(baseline.SSPResponse),
+			Current:  base64.StdEncoding.EncodeToString(current.SSPResponse),
+		})
+	}
+
+	if baseline.Error != current.Error && !ignoredField(opts, "error") {
+		diffs = append(diffs, FieldDiff{Path: "error", Baseline: baseline.Error, Current: current.Error})
+	}
+	return diffs, nil
+}
+
+func ignoredField(opts *DiffOptions, path string) bool {
+	if opts == nil {
+		return false
+	}
+	for _, field := range opts.IgnoreFields {
+		if field == path {
+			return true
+		}
+	}
+	return false
+}
+
+// SSPReport 单个SSP的回放汇总
+type SSPReport struct {
+	SSPID string `json:"ssp_id"`
+	// Total 读到的记录数，Replayed 成功回放的记录数，Errors 无法回放的记录数
+	Total    int `json:"total"`
+	Replayed int `json:"replayed"`
+	Errors   int `json:"errors"`
+	// Differing 存在差异的记录数
+	Differing int `json:"differing"`
+	// Fields 各字段(数组下标归一化为 [])出现差异的记录数
+	Fields map[string]int `json:"fields,omitempty"`
+}
+
+// Report 按SSP汇总字段级差异
+type Report struct {
+	ssps map[string]*SSPReport
+}
+
+// NewReport 创建空报告
+func NewReport() *Report {
+	return &Report{ssps: make(map[string]*SSPReport)}
+}
+
+// Add 记录一条回放结果，err 为无法回放的原因
+func (r *Report) Add(sspID string, diffs []FieldDiff, err error) {
+	report, ok := r.ssps[sspID]
+	if !ok {
+		report = &SSPReport{SSPID: sspID, Fields: make(map[string]int)}
+		r.ssps[sspID] = report
+	}
+	report.Total++
+	if err != nil {
+		report.Errors++
+		return
+	}
+	report.Replayed++
+	if len(diffs) == 0 {
+		return
+	}
+	report.Differing++
+	// 同一记录中同一字段的多处差异只计一次
+	seen := make(map[string]struct{}, len(diffs))
+	for _, diff := range diffs {
+		path := NormalizePath(diff.Path)
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		report.Fields[path]++
+	}
+}
+
+// SSPs 按SSP ID排序的汇总
+func (r *Report) SSPs() []*SSPReport {
+	reports := make([]*SSPReport, 0, len(r.ssps))
+	for _, report := range r.ssps {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].SSPID < reports[j].SSPID })
+	return reports
+}
+
+// HasDiff 是否存在差异或无法回放的记录
+func (r *Report) HasDiff() bool {
+	for _, report := range r.ssps {
+		if report.Differing > 0 || report.Errors > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText 输出文本报告，字段按差异记录数降序
+func (r *Report) WriteText(w io.Writer) error {
+	for _, report := range r.SSPs() {
+		if _, err := fmt.Fprintf(w, "ssp %s: total=%d replayed=%d errors=%d differing=%d\n",
+			report.SSPID, report.Total, report.Replayed, report.Errors, report.Differing); err != nil {
+			return err
+		}
+
+		fields := make([]string, 0, len(report.Fields))
+		for field := range report.Fields {
+			fields = append(fields, field)
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			if report.Fields[fields[i]] != report.Fields[fields[j]] {
+				return report.Fields[fields[i]] > report.Fields[fields[j]]
+			}
+			return fields[i] < fields[j]
+		})
+		for _, field := range fields {
+			if _, err := fmt.Fprintf(w, "  %-60s %d\n", field, report.Fields[field]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
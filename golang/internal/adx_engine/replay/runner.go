@@ -0,0 +1,145 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/adxserver"
+	"github.com/echoface/admux/internal/adx_engine/bidlog"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter"
+	"github.com/echoface/admux/pkg/logger"
+)
+
+const (
+	defaultMaxConnections    = 100
+	defaultSimulatedMinPrice = 100  // 1元
+	defaultSimulatedMaxPrice = 2000 // 20元
+)
+
+// Options 回放选项
+type Options struct {
+	// DSPMode DSP响应来源，captured(默认)或simulated
+	DSPMode string
+	// DSPs simulated 模式下参与竞价的DSP
+	DSPs []string
+	// SimulatedMinPrice/SimulatedMaxPrice 模拟出价范围，CPM分
+	SimulatedMinPrice int64
+	SimulatedMaxPrice int64
+}
+
+// Runner 使用当前构建的SSP适配器和竞价流程重放竞价日志；
+// 不发送DSP通知、不写竞价日志，DSP由回放或模拟bidder代替
+type Runner struct {
+	server  *adxserver.AdxServer
+	factory *adxcore.BidderFactory
+	opts    Options
+
+	mu      sync.Mutex
+	bidders map[string]struct{}
+}
+
+// NewRunner 创建回放器，cfg 中的SSP配置决定各SSP使用的适配器
+func NewRunner(cfg *config.AdxServerConfig, opts Options) (*Runner, error) {
+	if opts.DSPMode == "" {
+		opts.DSPMode = DSPModeCaptured
+	}
+	if opts.DSPMode != DSPModeCaptured && opts.DSPMode != DSPModeSimulated {
+		return nil, fmt.Errorf("unknown dsp mode %q", opts.DSPMode)
+	}
+	if opts.DSPMode == DSPModeSimulated && len(opts.DSPs) == 0 {
+		return nil, fmt.Errorf("simulated dsp mode requires at least one dsp")
+	}
+	if opts.SimulatedMinPrice <= 0 {
+		opts.SimulatedMinPrice = defaultSimulatedMinPrice
+	}
+	if opts.SimulatedMaxPrice <= opts.SimulatedMinPrice {
+		opts.SimulatedMaxPrice = max(opts.SimulatedMinPrice+1, defaultSimulatedMaxPrice)
+	}
+
+	// 复制一份配置，关闭回放时不应产生的副作用
+	replayCfg := *cfg
+	replayCfg.Notifier.Disabled = true
+	replayCfg.BidLog.Enabled = false
+	replayCfg.Tracing.Enabled = false
+	if replayCfg.MaxConnections <= 0 {
+		replayCfg.MaxConnections = defaultMaxConnections
+	}
+
+	appCtx := &adxserver.AdxServerContext{Config: &replayCfg, Logger: logger.Default}
+	appCtx.SetSSPFactory(sspadapter.NewSSPAdapterFactory(replayCfg.SSPs))
+
+	r := &Runner{
+		server:  adxserver.NewAdxServer(appCtx),
+		factory: adxcore.GetGlobalBidderFactory(),
+		opts:    opts,
+		bidders: make(map[string]struct{}),
+	}
+	if opts.DSPMode == DSPModeSimulated {
+		for _, id := range opts.DSPs {
+			if err := r.register(NewSimulatedBidder(id, opts.SimulatedMinPrice, opts.SimulatedMaxPrice)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// Replay 重放一条记录，返回当前构建产出的记录；SSP请求解析、竞价或打包失败记录在 Record.Error 中，
+// 只有无法回放(如SSP未配置)时返回错误
+func (r *Runner) Replay(ctx context.Context, record *bidlog.Record) (*bidlog.Record, error) {
+	adapter, sspConfig, err := r.server.GetSSPAdapter(record.SSPID)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.opts.DSPMode == DSPModeCaptured {
+		for _, bidder := range record.Bidders {
+			if err := r.register(NewCapturedBidder(bidder.BidderID)); err != nil {
+				return nil, err
+			}
+		}
+		if ctx, err = withCaptured(ctx, record); err != nil {
+			return nil, err
+		}
+	}
+
+	bidCtx := adxcore.NewBidRequestCtx(ctx, nil)
+	bidCtx.SetSSPInfo(sspConfig.ID, sspConfig)
+	if err := adapter.ToInternalBidRequest(bidCtx, record.SSPRequest); err != nil {
+		return bidlog.Snapshot(bidCtx, record.SSPRequest, nil, err), nil
+	}
+	if err := r.server.ProcessBid(bidCtx); err != nil {
+		return bidlog.Snapshot(bidCtx, record.SSPRequest, nil, err), nil
+	}
+	sspResponse, err := adapter.PackSSPResponse(bidCtx)
+	return bidlog.Snapshot(bidCtx, record.SSPRequest, sspResponse, err), nil
+}
+
+// Close 注销回放注册的bidder
+func (r *Runner) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id := range r.bidders {
+		_ = r.factory.UnregisterBidder(id)
+	}
+	r.bidders = make(map[string]struct{})
+	return r.server.Close(context.Background())
+}
+
+// register 首次出现的DSP注册到全局bidder工厂
+func (r *Runner) register(bidder adxcore.Bidder) error {
+	id := bidder.GetInfo().ID
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.bidders[id]; ok {
+		return nil
+	}
+	if _, err := r.factory.ReplaceBidder(bidder); err != nil {
+		return fmt.Errorf("register bidder %s: %w", id, err)
+	}
+	r.bidders[id] = struct{}{}
+	return nil
+}
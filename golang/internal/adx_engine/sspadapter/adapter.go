@@ -1,7 +1,11 @@
 package sspadapter
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
 	"sync"
 
 	"google.golang.org/protobuf/proto"
@@ -12,32 +16,198 @@ import (
 	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
 )
 
+// ErrSSPNotFound is returned for SSP IDs that are not enabled
+// SSP未启用
+var ErrSSPNotFound = errors.New("ssp not found")
+
 // SSPAdapterFactory manages SSP adapter instances
 // 管理SSP适配器实例的工厂
 type SSPAdapterFactory struct {
 	mu       sync.RWMutex
 	adapters map[string]adxcore.ISSPAdapter // SSP ID -> Adapter mapping
 	configs  map[string]*config.SSPConfig   // SSP ID -> Config mapping
+	paused   map[string]bool                // SSP ID -> paused by operators
+	auths    map[string]Authenticator       // SSP ID -> Authenticator, absent when not configured
 }
 
 // NewSSPAdapterFactory creates a new SSP adapter factory
 // 创建新的SSP适配器工厂
 func NewSSPAdapterFactory(sspConfigs []config.SSPConfig) *SSPAdapterFactory {
-	factory := &SSPAdapterFactory{
-		adapters: make(map[string]adxcore.ISSPAdapter),
-		configs:  make(map[string]*config.SSPConfig),
+	factory := &SSPAdapterFactory{}
+
+	// Register all configured SSPs, SSPs whose authenticator cannot be built reject all requests
+	// 注册所有已配置的SSP，鉴权器创建失败的SSP拒绝所有请求
+	factory.adapters, factory.configs, factory.auths, _ = factory.build(sspConfigs)
+	return factory
+}
+
+// ReloadResult lists the SSPs changed by a reload
+// 重载变化的SSP
+type ReloadResult struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+}
+
+// Reload validates the SSP configs and swaps all adapters and configs at once, the current
+// ones are kept when any config is invalid. Requests already being processed keep the
+// config they started with, new requests see the reloaded ones.
+// 校验后原子替换全部适配器和配置，任一配置不合法时保持当前配置；处理中的请求继续使用开始时的配置
+func (f *SSPAdapterFactory) Reload(sspConfigs []config.SSPConfig) (ReloadResult, error) {
+	if err := ValidateConfigs(sspConfigs); err != nil {
+		return ReloadResult{}, err
+	}
+
+	adapters, configs, auths, err := f.build(sspConfigs)
+	if err != nil {
+		return ReloadResult{}, err
 	}
 
-	// Register all configured SSPs
-	// 注册所有已配置的SSP
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var result ReloadResult
+	for id, cfg := range configs {
+		prev, ok := f.configs[id]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, id)
+		case !reflect.DeepEqual(prev, cfg):
+			result.Updated = append(result.Updated, id)
+		}
+		// 协议未变时沿用已有的适配器实例
+		if ok && prev.Protocol == cfg.Protocol {
+			adapters[id] = f.adapters[id]
+		}
+	}
+	for id := range f.configs {
+		if _, ok := configs[id]; !ok {
+			result.Removed = append(result.Removed, id)
+			delete(f.paused, id)
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Updated)
+
+	f.adapters, f.configs, f.auths = adapters, configs, auths
+	return result, nil
+}
+
+// Configs returns the configs of the enabled SSPs ordered by ID
+// 按ID顺序返回已启用SSP的配置
+func (f *SSPAdapterFactory) Configs() []config.SSPConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	configs := make([]config.SSPConfig, 0, len(f.configs))
+	for _, cfg := range f.configs {
+		configs = append(configs, *cfg)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].ID < configs[j].ID })
+	return configs
+}
+
+// Pause stops bidding for the SSP until Resume, its requests get a no-bid reply.
+// The pause survives reloads as long as the SSP stays enabled.
+// 暂停SSP竞价直到Resume，暂停期间请求直接返回不出价；SSP保持启用时重载不影响暂停状态
+func (f *SSPAdapterFactory) Pause(sspID string) error {
+	return f.setPaused(sspID, true)
+}
+
+// Resume resumes bidding for a paused SSP
+// 恢复已暂停SSP的竞价
+func (f *SSPAdapterFactory) Resume(sspID string) error {
+	return f.setPaused(sspID, false)
+}
+
+func (f *SSPAdapterFactory) setPaused(sspID string, paused bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.configs[sspID]; !exists {
+		return fmt.Errorf("%w: %s", ErrSSPNotFound, sspID)
+	}
+	if f.paused == nil {
+		f.paused = make(map[string]bool)
+	}
+	if paused {
+		f.paused[sspID] = true
+	} else {
+		delete(f.paused, sspID)
+	}
+	return nil
+}
+
+// IsPaused reports whether bidding is paused for the SSP
+// SSP是否已暂停竞价
+func (f *SSPAdapterFactory) IsPaused(sspID string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.paused[sspID]
+}
+
+// build creates the adapters, configs and authenticators of the enabled SSPs, an SSP whose
+// authenticator fails to build gets one rejecting all requests and is reported in the error
+// 创建已启用SSP的适配器、配置和鉴权器；鉴权器创建失败的SSP拒绝所有请求，并在错误中返回
+func (f *SSPAdapterFactory) build(sspConfigs []config.SSPConfig) (
+	map[string]adxcore.ISSPAdapter, map[string]*config.SSPConfig, map[string]Authenticator, error) {
+	var errs ConfigErrors
+	adapters := make(map[string]adxcore.ISSPAdapter)
+	configs := make(map[string]*config.SSPConfig)
+	auths := make(map[string]Authenticator)
 	for _, cfg := range sspConfigs {
-		if cfg.Enabled {
-			factory.configs[cfg.ID] = &cfg
-			factory.adapters[cfg.ID] = factory.createAdapter(cfg.ID, cfg.Protocol)
+		if !cfg.Enabled {
+			continue
+		}
+		configs[cfg.ID] = &cfg
+		adapters[cfg.ID] = f.createAdapter(cfg.ID, cfg.Protocol)
+
+		auth, err := newSSPAuthenticator(adapters[cfg.ID], cfg)
+		if err != nil {
+			errs = append(errs, &ConfigError{SSPID: cfg.ID, Field: "auth", Reason: err.Error()})
+			auth = denyAll{}
+		}
+		if auth != nil {
+			auths[cfg.ID] = auth
 		}
 	}
+	if len(errs) > 0 {
+		return adapters, configs, auths, errs
+	}
+	return adapters, configs, auths, nil
+}
 
-	return factory
+// newSSPAuthenticator uses the adapter's own scheme when it provides one, otherwise the
+// schemes configured in cfg.Auth
+// 适配器提供鉴权方式时使用适配器的，否则按 cfg.Auth 配置创建
+func newSSPAuthenticator(adapter adxcore.ISSPAdapter, cfg config.SSPConfig) (Authenticator, error) {
+	if provider, ok := adapter.(AuthenticatorProvider); ok {
+		return provider.NewAuthenticator(cfg)
+	}
+	return NewAuthenticator(cfg.Auth)
+}
+
+// Authenticate checks a bid request against the SSP's authenticator, SSPs without
+// configured auth accept every request
+// 按SSP的鉴权器校验竞价请求，未配置鉴权的SSP不校验
+func (f *SSPAdapterFactory) Authenticate(sspID string, req *AuthRequest) error {
+	f.mu.RLock()
+	auth := f.auths[sspID]
+	f.mu.RUnlock()
+
+	if auth == nil {
+		return nil
+	}
+	return auth.Authenticate(req)
+}
+
+// AuthConfigured reports whether requests of the SSP are authenticated
+// SSP是否配置了鉴权
+func (f *SSPAdapterFactory) AuthConfigured(sspID string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.auths[sspID] != nil
 }
 
 // GetAdapter returns the SSP adapter for the given SSP ID
@@ -63,19 +233,19 @@ func (f *SSPAdapterFactory) GetAdapter(sspID string) (adxcore.ISSPAdapter, *conf
 // 根据协议类型创建新的适配器实例
 func (f *SSPAdapterFactory) createAdapter(sspID, protocol string) adxcore.ISSPAdapter {
 	switch protocol {
-	case "kuaishou":
+	case ProtocolKuaishou:
 		// Import and use Kuaishou adapter from kuaishou package
 		// 从kuaishou包导入并使用快手适配器
 		return kuaishou.NewKuaishouAdapter(sspID)
-	case "tencent":
+	case ProtocolTencent:
 		// TODO: Import and use Tencent adapter when implemented
 		// TODO: 实现后从tencent包导入并使用腾讯适配器
 		return NewStubSSPAdapter(sspID)
-	case "baidu":
+	case ProtocolBaidu:
 		// TODO: Import and use Baidu adapter when implemented
 		// TODO: 实现后从baidu包导入并使用百度适配器
 		return NewStubSSPAdapter(sspID)
-	case "openrtb":
+	case ProtocolOpenRTB:
 		// TODO: Import and use OpenRTB adapter when implemented
 		// TODO: 实现后从openrtb包导入并使用OpenRTB适配器
 		return NewStubSSPAdapter(sspID)
@@ -86,6 +256,10 @@ func (f *SSPAdapterFactory) createAdapter(sspID, protocol string) adxcore.ISSPAd
 	}
 }
 
+// contentTypeProtobuf is the encoding of AdMux standard requests and responses
+// AdMux标准协议的编码
+const contentTypeProtobuf = "application/x-protobuf"
+
 // NewStubSSPAdapter creates a new stub SSP adapter
 // 创建新的存根SSP适配器
 func NewStubSSPAdapter(sspID string) *AdMuxStdAdapter {
@@ -119,3 +293,41 @@ func (a *AdMuxStdAdapter) PackSSPResponse(ctx *adxcore.BidRequestCtx) ([]byte, e
 	// 对于存根适配器，直接序列化为JSON
 	return proto.Marshal(ctx.Response)
 }
+
+// ContentType returns the protobuf content type
+// 响应为protobuf编码
+func (a *AdMuxStdAdapter) ContentType() string {
+	return contentTypeProtobuf
+}
+
+// PackNoBid answers with an empty HTTP 204, the OpenRTB no-bid
+// 按OpenRTB约定以空的 HTTP 204 表示不出价
+func (a *AdMuxStdAdapter) PackNoBid([]byte) adxcore.SSPReply {
+	return adxcore.SSPReply{StatusCode: http.StatusNoContent}
+}
+
+// PackInvalidRequest answers with a bid response whose nbr is INVALID_REQUEST
+// 非法请求返回 nbr=INVALID_REQUEST 的竞价响应
+func (a *AdMuxStdAdapter) PackInvalidRequest(data []byte, err error) adxcore.SSPReply {
+	return a.noBidReply(data, adxcore.InvalidRequestStatus(err), admux_rtb.NoBidReason_INVALID_REQUEST)
+}
+
+// PackInternalError answers with a bid response whose nbr is TECHNICAL_ERROR
+// 竞价失败返回 nbr=TECHNICAL_ERROR 的竞价响应
+func (a *AdMuxStdAdapter) PackInternalError(data []byte) adxcore.SSPReply {
+	return a.noBidReply(data, http.StatusOK, admux_rtb.NoBidReason_TECHNICAL_ERROR)
+}
+
+// noBidReply encodes a bid response carrying only the no-bid reason, the request id is
+// echoed when it can be read
+// 编码只带不出价原因的竞价响应，请求可解析时回填请求ID
+func (a *AdMuxStdAdapter) noBidReply(data []byte, status int, reason admux_rtb.NoBidReason) adxcore.SSPReply {
+	var bidReq admux_rtb.BidRequest
+	_ = proto.UnmarshalOptions{AllowPartial: true}.Unmarshal(data, &bidReq)
+
+	body, err := proto.Marshal(&admux_rtb.BidResponse{Id: proto.String(bidReq.GetId()), Nbr: reason.Enum()})
+	if err != nil {
+		return adxcore.SSPReply{StatusCode: status}
+	}
+	return adxcore.SSPReply{StatusCode: status, ContentType: contentTypeProtobuf, Body: body}
+}
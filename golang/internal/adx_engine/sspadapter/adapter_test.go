@@ -0,0 +1,77 @@
+package sspadapter
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/echoface/admux/internal/adx_engine/sspadapter/kuaishou"
+	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
+)
+
+func TestKuaishouAdapter_Replies(t *testing.T) {
+	adapter := kuaishou.NewKuaishouAdapter("kuaishou")
+	data := []byte(`{"request_id":"r1","imp":[{"imp_id":"1"}]}`)
+	assert.Equal(t, "application/json", adapter.ContentType())
+
+	reply := adapter.PackNoBid(data)
+	assert.Equal(t, http.StatusOK, reply.StatusCode)
+	assert.Equal(t, "application/json", reply.ContentType)
+	assert.JSONEq(t, `{"request_id":"r1","status":1}`, string(reply.Body))
+
+	reply = adapter.PackInternalError(data)
+	assert.Equal(t, http.StatusOK, reply.StatusCode)
+	assert.JSONEq(t, `{"request_id":"r1","status":1,"noBidReason":1}`, string(reply.Body))
+
+	for _, c := range []struct {
+		err    error
+		status int
+		body   string
+	}{
+		{&AuthError{Reason: AuthIPNotAllowed}, http.StatusForbidden, `{"request_id":"r1","status":1,"noBidReason":5}`},
+		{&AuthError{Reason: AuthInvalidToken}, http.StatusUnauthorized, `{"request_id":"r1","status":1,"noBidReason":2}`},
+		{errors.New("bad request"), http.StatusBadRequest, `{"request_id":"r1","status":1,"noBidReason":2}`},
+	} {
+		reply = adapter.PackInvalidRequest(data, c.err)
+		assert.Equal(t, c.status, reply.StatusCode, c.err.Error())
+		assert.JSONEq(t, c.body, string(reply.Body), c.err.Error())
+	}
+
+	// 无法解析的请求不回填 request_id
+	reply = adapter.PackInvalidRequest([]byte("{"), errors.New("bad request"))
+	assert.JSONEq(t, `{"request_id":"","status":1,"noBidReason":2}`, string(reply.Body))
+}
+
+func TestAdMuxStdAdapter_Replies(t *testing.T) {
+	adapter := NewStubSSPAdapter("xiaomi")
+	data, err := proto.Marshal(&admux_rtb.BidRequest{Id: proto.String("r2")})
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-protobuf", adapter.ContentType())
+
+	reply := adapter.PackNoBid(data)
+	assert.Equal(t, http.StatusNoContent, reply.StatusCode)
+	assert.Nil(t, reply.Body)
+
+	decode := func(body []byte) *admux_rtb.BidResponse {
+		var resp admux_rtb.BidResponse
+		require.NoError(t, proto.Unmarshal(body, &resp))
+		return &resp
+	}
+
+	reply = adapter.PackInternalError(data)
+	assert.Equal(t, http.StatusOK, reply.StatusCode)
+	assert.Equal(t, "application/x-protobuf", reply.ContentType)
+	resp := decode(reply.Body)
+	assert.Equal(t, "r2", resp.GetId())
+	assert.Equal(t, admux_rtb.NoBidReason_TECHNICAL_ERROR, resp.GetNbr())
+
+	reply = adapter.PackInvalidRequest([]byte("not protobuf"), &AuthError{Reason: AuthMissingToken})
+	assert.Equal(t, http.StatusUnauthorized, reply.StatusCode)
+	resp = decode(reply.Body)
+	assert.Empty(t, resp.GetId())
+	assert.Equal(t, admux_rtb.NoBidReason_INVALID_REQUEST, resp.GetNbr())
+}
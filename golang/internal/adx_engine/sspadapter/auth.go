@@ -0,0 +1,189 @@
+package sspadapter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/pkg/netx"
+)
+
+// HeaderSSPSignature carries the HMAC signature of the request body, see SignBody
+// 请求体签名头
+const HeaderSSPSignature = "X-SSP-Signature"
+
+const defaultSignatureMaxAge = 5 * time.Minute
+
+// Auth rejection reasons, used as metrics labels
+// 鉴权失败原因，用作指标标签
+const (
+	AuthIPNotAllowed     = "ip_not_allowed"
+	AuthMissingToken     = "missing_token"
+	AuthInvalidToken     = "invalid_token"
+	AuthMissingSignature = "missing_signature"
+	AuthInvalidSignature = "invalid_signature"
+	AuthExpiredSignature = "expired_signature"
+	AuthMisconfigured    = "misconfigured"
+	AuthUnknownSSP       = "unknown_ssp"
+)
+
+// AuthRequest is the part of an SSP bid request checked by authenticators
+// 鉴权所需的请求信息
+type AuthRequest struct {
+	Header   http.Header
+	ClientIP string
+	Body     []byte
+}
+
+// AuthError is returned for rejected requests, Reason is one of the Auth* reasons
+// 鉴权失败，Reason 为上面的失败原因之一
+type AuthError struct {
+	Reason string
+}
+
+func (e *AuthError) Error() string {
+	return "ssp auth failed: " + e.Reason
+}
+
+// Unwrap maps the reason to adxcore.ErrSSPForbidden or adxcore.ErrSSPUnauthorized, so that
+// adapters can reply in their protocol without knowing the auth schemes
+// 将失败原因映射为 adxcore 的鉴权错误，适配器据此按协议编码响应
+func (e *AuthError) Unwrap() error {
+	if e.Reason == AuthIPNotAllowed {
+		return adxcore.ErrSSPForbidden
+	}
+	return adxcore.ErrSSPUnauthorized
+}
+
+// Authenticator verifies that a bid request really comes from the SSP
+// 校验竞价请求确实来自该SSP
+type Authenticator interface {
+	Authenticate(req *AuthRequest) error
+}
+
+// AuthenticatorProvider is implemented by adapters whose SSP authenticates requests in
+// its own way, the returned authenticator replaces the schemes configured in SSPConfig.Auth
+// 媒体有自己的鉴权方式时由适配器实现，返回的鉴权器替代 SSPConfig.Auth 配置的方式
+type AuthenticatorProvider interface {
+	NewAuthenticator(cfg config.SSPConfig) (Authenticator, error)
+}
+
+// NewAuthenticator builds the schemes configured in cfg, all of them must pass;
+// nil when no scheme is configured
+// 按配置创建鉴权器，配置的方式需全部通过；未配置任何方式时返回nil
+func NewAuthenticator(cfg config.SSPAuthConfig) (Authenticator, error) {
+	var chain authChain
+
+	nets, err := netx.ParseIPAllowlist(cfg.AllowIPs)
+	if err != nil {
+		return nil, err
+	}
+	if len(nets) > 0 {
+		chain = append(chain, ipAllowlist(nets))
+	}
+	if cfg.Token != "" {
+		chain = append(chain, bearerToken(cfg.Token))
+	}
+	if cfg.HMACSecret != "" {
+		maxAge := cfg.MaxAge
+		if maxAge <= 0 {
+			maxAge = defaultSignatureMaxAge
+		}
+		chain = append(chain, &hmacSignature{secret: []byte(cfg.HMACSecret), maxAge: maxAge, now: time.Now})
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// authChain requires every authenticator to pass
+type authChain []Authenticator
+
+func (c authChain) Authenticate(req *AuthRequest) error {
+	for _, auth := range c {
+		if err := auth.Authenticate(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ipAllowlist accepts requests from the listed networks
+type ipAllowlist netx.IPAllowlist
+
+func (l ipAllowlist) Authenticate(req *AuthRequest) error {
+	if netx.IPAllowlist(l).Contains(req.ClientIP) {
+		return nil
+	}
+	return &AuthError{Reason: AuthIPNotAllowed}
+}
+
+// bearerToken accepts requests carrying "Authorization: Bearer {token}"
+type bearerToken string
+
+func (t bearerToken) Authenticate(req *AuthRequest) error {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return &AuthError{Reason: AuthMissingToken}
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(t)) != 1 {
+		return &AuthError{Reason: AuthInvalidToken}
+	}
+	return nil
+}
+
+// hmacSignature accepts requests whose body is signed within maxAge, see SignBody
+type hmacSignature struct {
+	secret []byte
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+func (s *hmacSignature) Authenticate(req *AuthRequest) error {
+	value := req.Header.Get(HeaderSSPSignature)
+	if value == "" {
+		return &AuthError{Reason: AuthMissingSignature}
+	}
+	tsValue, _, ok := strings.Cut(value, ".")
+	if !ok {
+		return &AuthError{Reason: AuthInvalidSignature}
+	}
+	ts, err := strconv.ParseInt(tsValue, 10, 64)
+	if err != nil {
+		return &AuthError{Reason: AuthInvalidSignature}
+	}
+	if !hmac.Equal([]byte(value), []byte(SignBody(s.secret, req.Body, time.Unix(ts, 0)))) {
+		return &AuthError{Reason: AuthInvalidSignature}
+	}
+	// 签名正确后再检查时间，过期和伪造分开统计
+	if age := s.now().Sub(time.Unix(ts, 0)); age > s.maxAge || age < -s.maxAge {
+		return &AuthError{Reason: AuthExpiredSignature}
+	}
+	return nil
+}
+
+// SignBody returns the X-SSP-Signature value of a request body signed at the given time
+// 返回请求体在指定时间的签名头取值
+func SignBody(secret, body []byte, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return ts + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// denyAll rejects every request, installed when the authenticator of an SSP cannot be built
+type denyAll struct{}
+
+func (denyAll) Authenticate(*AuthRequest) error {
+	return &AuthError{Reason: AuthMisconfigured}
+}
@@ -0,0 +1,117 @@
+package sspadapter
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/adxcore"
+	"github.com/echoface/admux/internal/adx_engine/config"
+)
+
+func authReason(err error) string {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return authErr.Reason
+	}
+	return ""
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	auth, err := NewAuthenticator(config.SSPAuthConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, auth)
+
+	_, err = NewAuthenticator(config.SSPAuthConfig{AllowIPs: []string{"10.0.0.300"}})
+	assert.Error(t, err)
+
+	secret := []byte("secret")
+	auth, err = NewAuthenticator(config.SSPAuthConfig{
+		AllowIPs:   []string{"10.0.0.0/8", "192.0.2.1"},
+		Token:      "token",
+		HMACSecret: string(secret),
+		MaxAge:     time.Minute,
+	})
+	require.NoError(t, err)
+
+	body := []byte(`{"request_id":"r1"}`)
+	request := func(clientIP, token, signature string, body []byte) *AuthRequest {
+		header := http.Header{}
+		if token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+		if signature != "" {
+			header.Set(HeaderSSPSignature, signature)
+		}
+		return &AuthRequest{Header: header, ClientIP: clientIP, Body: body}
+	}
+	now := time.Now()
+	valid := SignBody(secret, body, now)
+
+	assert.NoError(t, auth.Authenticate(request("10.1.2.3", "token", valid, body)))
+	assert.NoError(t, auth.Authenticate(request("192.0.2.1", "token", valid, body)))
+
+	for _, c := range []struct {
+		name   string
+		req    *AuthRequest
+		reason string
+	}{
+		{"ip", request("192.0.2.2", "token", valid, body), AuthIPNotAllowed},
+		{"no token", request("10.1.2.3", "", valid, body), AuthMissingToken},
+		{"wrong token", request("10.1.2.3", "other", valid, body), AuthInvalidToken},
+		{"no signature", request("10.1.2.3", "token", "", body), AuthMissingSignature},
+		{"tampered body", request("10.1.2.3", "token", valid, []byte(`{"request_id":"r2"}`)), AuthInvalidSignature},
+		{"wrong secret", request("10.1.2.3", "token", SignBody([]byte("other"), body, now), body), AuthInvalidSignature},
+		{"malformed", request("10.1.2.3", "token", "abc", body), AuthInvalidSignature},
+		{"expired", request("10.1.2.3", "token", SignBody(secret, body, now.Add(-2*time.Minute)), body), AuthExpiredSignature},
+	} {
+		assert.Equal(t, c.reason, authReason(auth.Authenticate(c.req)), c.name)
+	}
+}
+
+// customAuthAdapter 自带鉴权方式的适配器
+type customAuthAdapter struct {
+	adxcore.ISSPAdapter
+	err error
+}
+
+func (a *customAuthAdapter) NewAuthenticator(cfg config.SSPConfig) (Authenticator, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	return bearerToken(cfg.ID + "-token"), nil
+}
+
+func TestSSPAuthenticator(t *testing.T) {
+	cfg := config.SSPConfig{ID: "custom", Auth: config.SSPAuthConfig{Token: "ignored"}}
+	auth, err := newSSPAuthenticator(&customAuthAdapter{}, cfg)
+	require.NoError(t, err)
+	assert.NoError(t, auth.Authenticate(&AuthRequest{Header: http.Header{"Authorization": {"Bearer custom-token"}}}))
+	assert.Equal(t, AuthInvalidToken, authReason(auth.Authenticate(&AuthRequest{Header: http.Header{"Authorization": {"Bearer ignored"}}})))
+
+	_, err = newSSPAuthenticator(&customAuthAdapter{err: errors.New("no key")}, cfg)
+	assert.Error(t, err)
+
+	factory := NewSSPAdapterFactory([]config.SSPConfig{
+		{ID: "open", Protocol: ProtocolOpenRTB, Enabled: true},
+		{ID: "secured", Protocol: ProtocolOpenRTB, Enabled: true, Auth: config.SSPAuthConfig{Token: "token"}},
+		// 未经校验的配置创建鉴权器失败时拒绝所有请求
+		{ID: "broken", Protocol: ProtocolOpenRTB, Enabled: true, Auth: config.SSPAuthConfig{AllowIPs: []string{"bad"}}},
+	})
+	req := &AuthRequest{Header: http.Header{"Authorization": {"Bearer token"}}}
+	assert.NoError(t, factory.Authenticate("open", req))
+	assert.False(t, factory.AuthConfigured("open"))
+	assert.NoError(t, factory.Authenticate("secured", req))
+	assert.True(t, factory.AuthConfigured("secured"))
+	assert.Equal(t, AuthMisconfigured, authReason(factory.Authenticate("broken", req)))
+
+	var configErrs ConfigErrors
+	_, err = factory.Reload([]config.SSPConfig{{ID: "secured", Protocol: ProtocolOpenRTB, Auth: config.SSPAuthConfig{AllowIPs: []string{"bad"}, MaxAge: -1}}})
+	require.ErrorAs(t, err, &configErrs)
+	assert.Equal(t, "auth.allow_ips", configErrs[0].Field)
+	assert.Equal(t, "auth.max_age", configErrs[1].Field)
+}
@@ -0,0 +1,101 @@
+package sspadapter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/pkg/netx"
+	"github.com/echoface/admux/pkg/pricecrypto"
+)
+
+// Supported SSP protocols
+// 支持的SSP协议
+const (
+	ProtocolOpenRTB  = "openrtb"
+	ProtocolKuaishou = "kuaishou"
+	ProtocolTencent  = "tencent"
+	ProtocolBaidu    = "baidu"
+)
+
+// maxSSPTimeout 单个SSP请求的处理超时上限
+const maxSSPTimeout = 5 * time.Second
+
+var sspIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_\-.]{0,63}$`)
+
+// ConfigError is an invalid field of an SSP config
+// 单个SSP配置字段的校验错误
+type ConfigError struct {
+	SSPID  string `json:"ssp_id"`
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("ssp %q %s: %s", e.SSPID, e.Field, e.Reason)
+}
+
+// ConfigErrors are all invalid fields of an SSP config list
+// SSP配置列表的全部校验错误
+type ConfigErrors []*ConfigError
+
+func (errs ConfigErrors) Error() string {
+	reasons := make([]string, 0, len(errs))
+	for _, err := range errs {
+		reasons = append(reasons, err.Error())
+	}
+	return "invalid ssp configs: " + strings.Join(reasons, "; ")
+}
+
+// ValidateConfigs validates an SSP config list, disabled SSPs are validated as well so
+// that enabling them later cannot fail
+// 校验SSP配置列表，返回所有不合法的字段；禁用的SSP同样校验，启用时不会再失败
+func ValidateConfigs(configs []config.SSPConfig) error {
+	var errs ConfigErrors
+	seen := make(map[string]struct{}, len(configs))
+	for _, cfg := range configs {
+		addErr := func(field, format string, args ...any) {
+			errs = append(errs, &ConfigError{SSPID: cfg.ID, Field: field, Reason: fmt.Sprintf(format, args...)})
+		}
+
+		if cfg.ID == "" {
+			addErr("id", "is required")
+		} else if !sspIDPattern.MatchString(cfg.ID) {
+			addErr("id", "must match %s", sspIDPattern.String())
+		} else if _, ok := seen[cfg.ID]; ok {
+			addErr("id", "is duplicated")
+		}
+		seen[cfg.ID] = struct{}{}
+
+		switch cfg.Protocol {
+		case ProtocolOpenRTB, ProtocolKuaishou, ProtocolTencent, ProtocolBaidu:
+		default:
+			addErr("protocol", "must be one of [%s %s %s %s], got %q",
+				ProtocolOpenRTB, ProtocolKuaishou, ProtocolTencent, ProtocolBaidu, cfg.Protocol)
+		}
+
+		// 0 表示使用默认超时
+		if cfg.Timeout < 0 || cfg.Timeout > maxSSPTimeout {
+			addErr("timeout", "must be within [0, %s], got %s", maxSSPTimeout, cfg.Timeout)
+		}
+		if cfg.QPSLimit < 0 {
+			addErr("qps_limit", "must not be negative")
+		}
+		if _, err := pricecrypto.New(cfg.PriceCrypto); err != nil {
+			addErr("price_crypto", "%v", err)
+		}
+		if _, err := netx.ParseIPAllowlist(cfg.Auth.AllowIPs); err != nil {
+			addErr("auth.allow_ips", "%v", err)
+		}
+		if cfg.Auth.MaxAge < 0 {
+			addErr("auth.max_age", "must not be negative")
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
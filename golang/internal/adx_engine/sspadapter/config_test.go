@@ -0,0 +1,76 @@
+package sspadapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/adx_engine/config"
+	"github.com/echoface/admux/internal/adx_engine/sspadapter/kuaishou"
+	"github.com/echoface/admux/pkg/pricecrypto"
+)
+
+func TestValidateConfigs(t *testing.T) {
+	valid := config.SSPConfig{ID: "kuaishou", Protocol: ProtocolKuaishou, Timeout: 50 * time.Millisecond, Enabled: true}
+	assert.NoError(t, ValidateConfigs([]config.SSPConfig{valid}))
+
+	err := ValidateConfigs([]config.SSPConfig{
+		valid,
+		valid,
+		{ID: "bad id", Protocol: "custom", Timeout: -time.Second, QPSLimit: -1},
+		{ID: "crypto", Protocol: ProtocolOpenRTB, PriceCrypto: pricecrypto.Config{Scheme: "rot13"}},
+	})
+	var configErrs ConfigErrors
+	require.ErrorAs(t, err, &configErrs)
+
+	fields := make(map[string][]string)
+	for _, configErr := range configErrs {
+		fields[configErr.SSPID] = append(fields[configErr.SSPID], configErr.Field)
+	}
+	assert.Equal(t, map[string][]string{
+		"kuaishou": {"id"},
+		"bad id":   {"id", "protocol", "timeout", "qps_limit"},
+		"crypto":   {"price_crypto"},
+	}, fields)
+}
+
+func TestSSPAdapterFactory_Reload(t *testing.T) {
+	factory := NewSSPAdapterFactory(nil)
+	result, err := factory.Reload([]config.SSPConfig{
+		{ID: "kuaishou", Protocol: ProtocolKuaishou, Timeout: 50 * time.Millisecond, Enabled: true},
+		{ID: "xiaomi", Protocol: ProtocolOpenRTB, Enabled: true},
+		{ID: "maoyan", Protocol: ProtocolOpenRTB},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ReloadResult{Added: []string{"kuaishou", "xiaomi"}}, result)
+
+	adapter, inflight, err := factory.GetAdapter("kuaishou")
+	require.NoError(t, err)
+	assert.IsType(t, &kuaishou.KuaishouAdapter{}, adapter)
+	_, _, err = factory.GetAdapter("maoyan")
+	assert.Error(t, err)
+
+	result, err = factory.Reload([]config.SSPConfig{
+		{ID: "kuaishou", Protocol: ProtocolKuaishou, Timeout: 80 * time.Millisecond, QPSLimit: 100, Enabled: true},
+		{ID: "maoyan", Protocol: ProtocolOpenRTB, Enabled: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ReloadResult{Added: []string{"maoyan"}, Removed: []string{"xiaomi"}, Updated: []string{"kuaishou"}}, result)
+
+	// 协议未变时沿用适配器实例，处理中的请求仍持有旧配置
+	reloaded, cfg, err := factory.GetAdapter("kuaishou")
+	require.NoError(t, err)
+	assert.Same(t, adapter, reloaded)
+	assert.Equal(t, 80*time.Millisecond, cfg.Timeout)
+	assert.Equal(t, 100, cfg.QPSLimit)
+	assert.Equal(t, 50*time.Millisecond, inflight.Timeout)
+	_, _, err = factory.GetAdapter("xiaomi")
+	assert.Error(t, err)
+
+	// 不合法的配置整体拒绝
+	_, err = factory.Reload([]config.SSPConfig{{ID: "kuaishou", Protocol: "custom", Enabled: true}})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"kuaishou", "maoyan"}, []string{factory.Configs()[0].ID, factory.Configs()[1].ID})
+}
@@ -2,14 +2,24 @@ package kuaishou
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/echoface/admux/internal/adx_engine/adxcore"
 	admux_rtb "github.com/echoface/admux/pkg/protogen/admux"
 	kuaishou_rtb "github.com/echoface/admux/pkg/protogen/kuaishou"
+	"github.com/echoface/admux/pkg/tracking"
 	"google.golang.org/protobuf/proto"
 )
 
+// contentTypeJSON 快手请求和响应的编码
+const contentTypeJSON = "application/json"
+
+// kuaishouWinPriceMacro 快手成交价宏
+const kuaishouWinPriceMacro = "${WIN_PRICE}"
+
 // KuaishouAdapter implements SSP adapter for Kuaishou protocol
 // 快手协议适配器
 type KuaishouAdapter struct {
@@ -49,7 +59,7 @@ func (a *KuaishouAdapter) PackSSPResponse(ctx *adxcore.BidRequestCtx) ([]byte, e
 	}
 
 	// Convert internal response to Kuaishou format
-	kuaishouResp, err := a.convertToKuaishouResponse(ctx.Response)
+	kuaishouResp, err := a.convertToKuaishouResponse(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert internal response to Kuaishou format: %v", err)
 	}
@@ -57,6 +67,54 @@ func (a *KuaishouAdapter) PackSSPResponse(ctx *adxcore.BidRequestCtx) ([]byte, e
 	return json.Marshal(kuaishouResp)
 }
 
+// ContentType returns the JSON content type of Kuaishou responses
+// 快手响应为JSON
+func (a *KuaishouAdapter) ContentType() string {
+	return contentTypeJSON
+}
+
+// PackNoBid answers with status 1 (no ad)
+// 快手以 status=1 表示不出价
+func (a *KuaishouAdapter) PackNoBid(data []byte) adxcore.SSPReply {
+	return a.noBidReply(data, http.StatusOK, nil)
+}
+
+// PackInvalidRequest answers with status 1 and noBidReason ILLEGAL_IP for sources not
+// allowed, INVALID_REQUEST otherwise
+// 非法请求以 status=1 返回，不允许的来源IP原因为 ILLEGAL_IP，其余为 INVALID_REQUEST
+func (a *KuaishouAdapter) PackInvalidRequest(data []byte, err error) adxcore.SSPReply {
+	reason := kuaishou_rtb.BidResponse_INVALID_REQUEST
+	if errors.Is(err, adxcore.ErrSSPForbidden) {
+		reason = kuaishou_rtb.BidResponse_ILLEGAL_IP
+	}
+	return a.noBidReply(data, adxcore.InvalidRequestStatus(err), reason.Enum())
+}
+
+// PackInternalError answers with status 1 and noBidReason TECHNICAL_ERROR
+// 竞价失败以 status=1 返回，原因为 TECHNICAL_ERROR
+func (a *KuaishouAdapter) PackInternalError(data []byte) adxcore.SSPReply {
+	return a.noBidReply(data, http.StatusOK, kuaishou_rtb.BidResponse_TECHNICAL_ERROR.Enum())
+}
+
+// noBidReply encodes a no-bid response, the request id is echoed when it can be read
+// 编码不出价响应，请求可解析时回填 request_id
+func (a *KuaishouAdapter) noBidReply(data []byte, status int, reason *kuaishou_rtb.BidResponse_NoBidReason) adxcore.SSPReply {
+	var req struct {
+		RequestId string `json:"request_id"`
+	}
+	_ = json.Unmarshal(data, &req)
+
+	body, err := json.Marshal(&kuaishou_rtb.BidResponse{
+		RequestId:   proto.String(req.RequestId),
+		Status:      proto.Uint32(1), // No bid
+		NoBidReason: reason,
+	})
+	if err != nil {
+		return adxcore.SSPReply{StatusCode: status}
+	}
+	return adxcore.SSPReply{StatusCode: status, ContentType: contentTypeJSON, Body: body}
+}
+
 // convertToInternalRequest converts Kuaishou bid request to internal OpenRTB format
 // 将快手竞价请求转换为内部OpenRTB格式
 func (a *KuaishouAdapter) convertToInternalRequest(kuaishouReq *kuaishou_rtb.BidRequest) (*admux_rtb.BidRequest, error) {
@@ -69,20 +127,184 @@ func (a *KuaishouAdapter) convertToInternalRequest(kuaishouReq *kuaishou_rtb.Bid
 		Id:  kuaishouReq.RequestId,
 		Imp: []*admux_rtb.BidRequest_Imp{},
 	}
+	if kuaishouReq.Device != nil || kuaishouReq.Network != nil {
+		internalReq.Device = a.convertDevice(kuaishouReq.Device, kuaishouReq.Network)
+	}
+	if udid := kuaishouReq.GetDevice().GetUdid(); udid != nil {
+		a.convertUdid(internalReq, udid)
+	}
 
 	return internalReq, nil
 }
 
+// convertDevice converts Kuaishou device and network info to OpenRTB device
+// 转换设备信息，IP地理等特征补全依赖这里的IP和UA
+func (a *KuaishouAdapter) convertDevice(device *kuaishou_rtb.Device, network *kuaishou_rtb.Network) *admux_rtb.BidRequest_Device {
+	internalDevice := &admux_rtb.BidRequest_Device{}
+
+	ip := device.GetIp()
+	if ip == "" {
+		ip = network.GetIpv4()
+	}
+	if ip != "" {
+		internalDevice.Ip = proto.String(ip)
+	}
+	if ipv6 := device.GetIpv6(); ipv6 != "" {
+		internalDevice.Ipv6 = proto.String(ipv6)
+	}
+
+	ua := device.GetUserAgent()
+	if ua == "" {
+		ua = device.GetUa()
+	}
+	if ua != "" {
+		internalDevice.Ua = proto.String(ua)
+	}
+
+	if device != nil {
+		internalDevice.Os = proto.String(a.mapOSType(device.GetOsType()))
+		if osv := a.getOSVersion(device.GetOsVersion()); osv != "" {
+			internalDevice.Osv = proto.String(osv)
+		}
+		if w := a.getScreenWidth(device.GetScreenSize()); w > 0 {
+			internalDevice.W = proto.Int32(w)
+		}
+		if h := a.getScreenHeight(device.GetScreenSize()); h > 0 {
+			internalDevice.H = proto.Int32(h)
+		}
+	}
+
+	return internalDevice
+}
+
 // convertToKuaishouResponse converts internal bid response to Kuaishou format
-// 将内部竞价响应转换为快手格式
-func (a *KuaishouAdapter) convertToKuaishouResponse(internalResp *admux_rtb.BidResponse) (*kuaishou_rtb.BidResponse, error) {
-	if len(internalResp.Seatbid) == 0 || len(internalResp.Seatbid[0].Bid) == 0 {
+// 将内部竞价响应转换为快手格式：ADX监测链接中的成交价宏替换为快手的 ${WIN_PRICE}，
+// 计费和曝光监测一起放在曝光事件中
+func (a *KuaishouAdapter) convertToKuaishouResponse(ctx *adxcore.BidRequestCtx) (*kuaishou_rtb.BidResponse, error) {
+	internalResp := ctx.Response
+	winners := ctx.Winners()
+	if len(internalResp.Seatbid) == 0 || len(internalResp.Seatbid[0].Bid) == 0 || len(winners) == 0 {
 		return &kuaishou_rtb.BidResponse{
-			Status: proto.Uint32(1), // No bid
+			RequestId: proto.String(ctx.Request.GetId()),
+			Status:    proto.Uint32(1), // No bid
 		}, nil
 	}
 
-	return &kuaishou_rtb.BidResponse{}, nil
+	// 胜出候选与响应中的出价按 bid id 对应
+	tracks := make(map[string]adxcore.TrackingURLs, len(winners))
+	for _, winner := range winners {
+		tracks[winner.Bid.GetId()] = winner.Tracking
+	}
+
+	resp := &kuaishou_rtb.BidResponse{
+		RequestId: proto.String(ctx.Request.GetId()),
+		BidId:     proto.String(ctx.Request.GetId()),
+		Status:    proto.Uint32(0),
+	}
+	for _, seatBid := range internalResp.GetSeatbid() {
+		seat := &kuaishou_rtb.SeatBid{Seat: proto.String(seatBid.GetSeat())}
+		for _, bid := range seatBid.GetBid() {
+			seat.Bid = append(seat.Bid, a.convertBid(bid, tracks[bid.GetId()]))
+		}
+		resp.SeatBid = append(resp.SeatBid, seat)
+	}
+	return resp, nil
+}
+
+// convertBid converts a single winning bid
+// 转换单个胜出出价
+func (a *KuaishouAdapter) convertBid(bid *admux_rtb.BidResponse_SeatBid_Bid, track adxcore.TrackingURLs) *kuaishou_rtb.Bid {
+	adID := bid.GetAdid()
+	if adID == "" {
+		adID = bid.GetCrid()
+	}
+
+	out := &kuaishou_rtb.Bid{
+		BidId:      proto.String(bid.GetId()),
+		ImpId:      proto.String(bid.GetImpid()),
+		Price:      proto.Float64(bid.GetPrice()),
+		AdId:       proto.String(adID),
+		CreativeId: proto.String(bid.GetCrid()),
+		Adomain:    bid.GetAdomain(),
+	}
+	if bundle := bid.GetBundle(); bundle != "" {
+		out.Bundle = proto.String(bundle)
+	}
+	if nurl := bid.GetNurl(); nurl != "" {
+		out.NoticeUrl = proto.String(kuaishouPriceMacro(nurl))
+	}
+
+	var exposure []string
+	for _, u := range []string{track.Imp, bid.GetBurl()} {
+		if u != "" {
+			exposure = append(exposure, kuaishouPriceMacro(u))
+		}
+	}
+	if len(exposure) > 0 {
+		out.AdTracking = append(out.AdTracking, &kuaishou_rtb.Tracking{
+			TrackingEvent: kuaishou_rtb.Tracking_AD_EXPOSURE.Enum(),
+			TrackingUrl:   exposure,
+		})
+	}
+	if track.Click != "" {
+		out.AdTracking = append(out.AdTracking, &kuaishou_rtb.Tracking{
+			TrackingEvent: kuaishou_rtb.Tracking_AD_CLICK.Enum(),
+			TrackingUrl:   []string{track.Click},
+		})
+	}
+	return out
+}
+
+// kuaishouPriceMacro 快手的成交价宏为 ${WIN_PRICE}
+func kuaishouPriceMacro(u string) string {
+	return strings.ReplaceAll(u, tracking.MacroAuctionPrice, kuaishouWinPriceMacro)
+}
+
+// convertUdid maps Kuaishou device ids to OpenRTB device fields and user.eids
+// 设备ID映射：ifa 为 IDFA/OAID，didmd5/dpidmd5 为 IMEI/Android ID 的MD5，其余形式放入 user.eids，
+// 由设备ID特征提供者统一归一化
+func (a *KuaishouAdapter) convertUdid(internalReq *admux_rtb.BidRequest, udid *kuaishou_rtb.Udid) {
+	device := internalReq.Device
+	if device == nil {
+		device = &admux_rtb.BidRequest_Device{}
+		internalReq.Device = device
+	}
+
+	ifa := udid.GetOaid()
+	if device.GetOs() == "ios" {
+		ifa = udid.GetIdfa()
+	}
+	if ifa != "" {
+		device.Ifa = proto.String(ifa)
+	}
+	if imeiMD5 := udid.GetImeiMd5(); imeiMD5 != "" {
+		device.Didmd5 = proto.String(imeiMD5)
+	}
+	if androidIDMD5 := udid.GetAndroididMd5(); androidIDMD5 != "" {
+		device.Dpidmd5 = proto.String(androidIDMD5)
+	}
+
+	var eids []*admux_rtb.BidRequest_User_EID
+	for _, id := range []struct{ source, value string }{
+		{adxcore.DeviceIDIMEI, udid.GetImei()},
+		{adxcore.DeviceIDAndroidID, udid.GetAndroidId()},
+		{adxcore.DeviceIDIDFAMD5, udid.GetIdfaMd5()},
+		{adxcore.DeviceIDCAID, udid.GetCurrentCaid()},
+	} {
+		if id.value == "" {
+			continue
+		}
+		eids = append(eids, &admux_rtb.BidRequest_User_EID{
+			Source: proto.String(id.source),
+			Uids:   []*admux_rtb.BidRequest_User_EID_UID{{Id: proto.String(id.value)}},
+		})
+	}
+	if len(eids) > 0 {
+		if internalReq.User == nil {
+			internalReq.User = &admux_rtb.BidRequest_User{}
+		}
+		internalReq.User.Eids = append(internalReq.User.Eids, eids...)
+	}
 }
 
 // Helper methods for conversion
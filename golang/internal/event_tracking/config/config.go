@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/echoface/admux/pkg/config"
+	"github.com/echoface/admux/pkg/notifier"
+	"github.com/echoface/admux/pkg/pricecrypto"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+// TrackingServerConfig 监测服务配置
+type TrackingServerConfig struct {
+	config.BaseConfig `yaml:",inline"`
+
+	Tracking TrackingConfig `yaml:"tracking"`
+}
+
+// TrackingConfig 事件监测配置
+type TrackingConfig struct {
+	// SigningKeys 监测链接签名密钥，按链接中的 kid 选择
+	SigningKeys []SigningKey `yaml:"signing_keys"`
+	// MaxEventAge 监测链接有效期，为空时不校验
+	MaxEventAge time.Duration `yaml:"max_event_age"`
+
+	Dedup         DedupConfig         `yaml:"dedup"`
+	EventLog      EventLogConfig      `yaml:"event_log"`
+	ClickTracking ClickTrackingConfig `yaml:"click_tracking"`
+	// SSPPriceCrypto 各SSP回传成交价(cp)的加密方式，未配置的SSP按明文解析
+	SSPPriceCrypto []SSPPriceCrypto `yaml:"ssp_price_crypto"`
+	// DSPPriceCrypto 转发给各DSP的成交价宏(${AUCTION_PRICE})加密方式，未配置的DSP下发明文
+	DSPPriceCrypto []DSPPriceCrypto `yaml:"dsp_price_crypto"`
+	// Notifier 转发DSP nurl/burl 的投递配置
+	Notifier notifier.Config `yaml:"notifier"`
+}
+
+// SSPPriceCrypto SSP成交价加密配置，需与ADX中对应SSP的 price_crypto 一致
+type SSPPriceCrypto struct {
+	SSPID              string `yaml:"ssp_id"`
+	pricecrypto.Config `yaml:",inline"`
+}
+
+// DSPPriceCrypto DSP成交价加密配置，需与ADX中对应DSP的 price_crypto 一致
+type DSPPriceCrypto struct {
+	DSPID              string `yaml:"dsp_id"`
+	pricecrypto.Config `yaml:",inline"`
+}
+
+// SigningKey 签名密钥
+type SigningKey struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+}
+
+// DedupConfig 事件去重配置
+type DedupConfig struct {
+	// TTL 去重窗口，同一事件在窗口内只记录一次
+	TTL time.Duration `yaml:"ttl"`
+	// MaxEntries 单个窗口的最大去重键数量，超出时提前切换窗口
+	MaxEntries int `yaml:"max_entries"`
+}
+
+// EventLogConfig 事件日志配置
+type EventLogConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+	MaxAgeDays int    `yaml:"max_age_days"`
+	Compress   bool   `yaml:"compress"`
+}
+
+// ClickTrackingConfig 点击监测配置
+type ClickTrackingConfig struct {
+	// Enabled 开启后点击事件按链接中的 curl 跳转到落地页
+	Enabled bool `yaml:"enabled"`
+}
+
+// KeyRing 创建只用于校验的签名密钥环
+func (c *TrackingConfig) KeyRing() (*tracking.KeyRing, error) {
+	keys := make([]tracking.Key, 0, len(c.SigningKeys))
+	for _, key := range c.SigningKeys {
+		keys = append(keys, tracking.Key{ID: key.ID, Secret: []byte(key.Secret)})
+	}
+	return tracking.NewKeyRing("", keys...)
+}
+
+// PriceCodecs 按SSP创建成交价解密器
+func (c *TrackingConfig) PriceCodecs() (map[string]pricecrypto.Codec, error) {
+	codecs := make(map[string]pricecrypto.Codec, len(c.SSPPriceCrypto))
+	for _, item := range c.SSPPriceCrypto {
+		if err := addPriceCodec(codecs, "ssp_price_crypto", "ssp_id", item.SSPID, item.Config); err != nil {
+			return nil, err
+		}
+	}
+	return codecs, nil
+}
+
+// DSPPriceCodecs 按DSP创建成交价加密器
+func (c *TrackingConfig) DSPPriceCodecs() (map[string]pricecrypto.Codec, error) {
+	codecs := make(map[string]pricecrypto.Codec, len(c.DSPPriceCrypto))
+	for _, item := range c.DSPPriceCrypto {
+		if err := addPriceCodec(codecs, "dsp_price_crypto", "dsp_id", item.DSPID, item.Config); err != nil {
+			return nil, err
+		}
+	}
+	return codecs, nil
+}
+
+func addPriceCodec(codecs map[string]pricecrypto.Codec, section, idField, id string, cfg pricecrypto.Config) error {
+	if id == "" {
+		return fmt.Errorf("%s: %s is required", section, idField)
+	}
+	if _, ok := codecs[id]; ok {
+		return fmt.Errorf("%s: duplicate %s %s", section, idField, id)
+	}
+	codec, err := pricecrypto.New(cfg)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", section, id, err)
+	}
+	codecs[id] = codec
+	return nil
+}
+
+// LoadTrackingConfig 加载监测服务配置
+func LoadTrackingConfig() (*TrackingServerConfig, error) {
+	loader := config.NewLoader("tracking")
+	var cfg TrackingServerConfig
+
+	if err := loader.Load(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to load tracking config: %w", err)
+	}
+
+	return &cfg, nil
+}
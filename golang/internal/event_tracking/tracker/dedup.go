@@ -0,0 +1,72 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultDedupTTL        = 24 * time.Hour
+	defaultDedupMaxEntries = 1000000
+)
+
+// Deduper 按键去重，使用新旧两代集合滚动淘汰：
+// 当前代超过TTL或数量上限时成为旧一代，因此同一键至少在一个TTL内不会被重复记录
+type Deduper struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+
+	current   map[string]struct{}
+	previous  map[string]struct{}
+	rotatedAt time.Time
+
+	now func() time.Time
+}
+
+// NewDeduper 创建去重器，ttl<=0 或 maxEntries<=0 时使用默认值
+func NewDeduper(ttl time.Duration, maxEntries int) *Deduper {
+	if ttl <= 0 {
+		ttl = defaultDedupTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultDedupMaxEntries
+	}
+	return &Deduper{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		current:    make(map[string]struct{}),
+		previous:   make(map[string]struct{}),
+		rotatedAt:  time.Now(),
+		now:        time.Now,
+	}
+}
+
+// Seen 判断键是否已出现过，未出现时记录该键
+func (d *Deduper) Seen(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now := d.now(); now.Sub(d.rotatedAt) >= d.ttl || len(d.current) >= d.maxEntries {
+		d.previous, d.current = d.current, make(map[string]struct{})
+		d.rotatedAt = now
+	}
+
+	if _, ok := d.current[key]; ok {
+		return true
+	}
+	if _, ok := d.previous[key]; ok {
+		return true
+	}
+	d.current[key] = struct{}{}
+	return false
+}
+
+// Forget 移除键，用于记录失败后允许重试
+func (d *Deduper) Forget(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.current, key)
+	delete(d.previous, key)
+}
@@ -0,0 +1,90 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/echoface/admux/internal/event_tracking/config"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+const defaultEventLogMaxSizeMB = 256
+
+// EventRecord 事件日志中的一条记录
+type EventRecord struct {
+	*tracking.Event
+
+	ReceivedAt time.Time `json:"received_at"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	// ClearPriceCPM 解密后的成交价(CPM，元)，PriceError 为解密失败原因
+	ClearPriceCPM *float64 `json:"clear_price_cpm,omitempty"`
+	PriceError    string   `json:"price_error,omitempty"`
+}
+
+// EventLog 本地追加写的事件日志，每行一个JSON记录，按大小切分并保留有限的历史文件
+type EventLog struct {
+	mu     sync.Mutex
+	writer *lumberjack.Logger
+}
+
+// NewEventLog 创建事件日志，日志目录不存在时自动创建
+func NewEventLog(cfg config.EventLogConfig) (*EventLog, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("event log path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create event log dir: %w", err)
+	}
+
+	maxSize := cfg.MaxSizeMB
+	if maxSize <= 0 {
+		maxSize = defaultEventLogMaxSizeMB
+	}
+	return &EventLog{
+		writer: &lumberjack.Logger{
+			Filename:   cfg.Path,
+			MaxSize:    maxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		},
+	}, nil
+}
+
+// Append 追加一条记录，每条记录一次写入
+func (l *EventLog) Append(record *EventRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal event record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.writer.Write(line); err != nil {
+		return fmt.Errorf("write event log: %w", err)
+	}
+	return nil
+}
+
+// Rotate 立即切分当前日志文件
+func (l *EventLog) Rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.writer.Rotate()
+}
+
+// Close 关闭日志文件
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.writer.Close()
+}
@@ -0,0 +1,255 @@
+package tracker
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/echoface/admux/internal/event_tracking/config"
+	"github.com/echoface/admux/pkg/notifier"
+	"github.com/echoface/admux/pkg/pricecrypto"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+// 事件处理结果，用作指标标签
+const (
+	resultAccepted  = "accepted"
+	resultDuplicate = "duplicate"
+	resultRejected  = "rejected"
+	resultError     = "error"
+)
+
+// plainPrice 未配置加密的SSP按明文解析成交价，未配置加密的DSP按明文下发
+var plainPrice, _ = pricecrypto.New(pricecrypto.Config{})
+
+// transparentGIF 1x1 透明像素，曝光监测以图片方式加载
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// Server 事件监测服务：校验监测链接签名，按 事件类型+请求ID+广告位ID+出价ID 去重后写入事件日志
+type Server struct {
+	verifier      *tracking.Verifier
+	dedup         *Deduper
+	eventLog      *EventLog
+	clickRedirect bool
+	priceCodecs   map[string]pricecrypto.Codec
+	dspCodecs     map[string]pricecrypto.Codec
+	notifier      *notifier.Notifier
+
+	events *prometheus.CounterVec
+	now    func() time.Time
+}
+
+// NewServer 创建监测服务，notices为nil时不转发DSP通知，reg为nil时注册到默认Registry
+func NewServer(cfg *config.TrackingConfig, eventLog *EventLog, notices *notifier.Notifier, namespace, subsystem string, reg prometheus.Registerer) (*Server, error) {
+	keys, err := cfg.KeyRing()
+	if err != nil {
+		return nil, err
+	}
+	priceCodecs, err := cfg.PriceCodecs()
+	if err != nil {
+		return nil, err
+	}
+	dspCodecs, err := cfg.DSPPriceCodecs()
+	if err != nil {
+		return nil, err
+	}
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	events := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "events_total",
+		Help:      "Total number of tracking events by event type and result",
+	}, []string{"event", "result"})
+	if err := reg.Register(events); err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		verifier:      tracking.NewVerifier(keys, cfg.MaxEventAge),
+		dedup:         NewDeduper(cfg.Dedup.TTL, cfg.Dedup.MaxEntries),
+		eventLog:      eventLog,
+		clickRedirect: cfg.ClickTracking.Enabled,
+		priceCodecs:   priceCodecs,
+		dspCodecs:     dspCodecs,
+		notifier:      notices,
+		events:        events,
+		now:           time.Now,
+	}, nil
+}
+
+// RegisterRoutes 注册监测路由 /track/{event}，nurl/burl 可能以POST方式通知
+func (s *Server) RegisterRoutes(r gin.IRouter) {
+	group := r.Group(tracking.PathPrefix)
+	group.GET("/:event", s.HandleEvent)
+	group.POST("/:event", s.HandleEvent)
+}
+
+// HandleEvent 处理监测事件，签名非法时返回403；重复事件不再记录但仍正常响应，点击仍然跳转
+func (s *Server) HandleEvent(c *gin.Context) {
+	eventType := tracking.EventType(c.Param("event"))
+	if !isKnownEvent(eventType) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	event, err := s.verifier.Verify(eventType, c.Request.URL.Query(), s.now())
+	if err != nil {
+		s.events.WithLabelValues(string(eventType), resultRejected).Inc()
+		status := http.StatusForbidden
+		if errors.Is(err, tracking.ErrInvalidParams) {
+			status = http.StatusBadRequest
+		}
+		c.String(status, err.Error())
+		return
+	}
+
+	record, result := s.record(c, event)
+	s.events.WithLabelValues(string(eventType), result).Inc()
+	if result == resultError {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if result == resultAccepted {
+		s.forwardNotice(record)
+	}
+	s.respond(c, event)
+}
+
+// record 去重并写入事件日志，写入失败时撤销去重标记，媒体重试时可以再次记录
+func (s *Server) record(c *gin.Context, event *tracking.Event) (*EventRecord, string) {
+	if s.dedup.Seen(event.DedupKey()) {
+		return nil, resultDuplicate
+	}
+
+	record := &EventRecord{
+		Event:      event,
+		ReceivedAt: s.now(),
+		ClientIP:   c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	s.decryptClearPrice(record)
+	if err := s.eventLog.Append(record); err != nil {
+		log.Printf("append tracking event failed, key:%s err:%v", event.DedupKey(), err)
+		s.dedup.Forget(event.DedupKey())
+		return nil, resultError
+	}
+	return record, resultAccepted
+}
+
+// decryptClearPrice 按SSP配置解密媒体回传的成交价，解密失败时记录原因，事件仍然记录
+func (s *Server) decryptClearPrice(record *EventRecord) {
+	if record.ClearPrice == "" {
+		return
+	}
+	codec, ok := s.priceCodecs[record.SSPID]
+	if !ok {
+		codec = plainPrice
+	}
+	price, err := codec.Decrypt(record.ClearPrice)
+	if err != nil {
+		record.PriceError = err.Error()
+		return
+	}
+	record.ClearPriceCPM = &price
+}
+
+// forwardNotice 转发胜出DSP的 nurl/burl/lurl，重复事件不会再次转发
+func (s *Server) forwardNotice(record *EventRecord) {
+	event := record.Event
+	if s.notifier == nil || !isRedirectURL(event.NoticeURL) {
+		return
+	}
+	notice := &notifier.Notice{
+		DSPID:     event.DSPID,
+		RequestID: event.RequestID,
+	}
+	switch event.Type {
+	case tracking.EventWin:
+		notice.Kind = notifier.KindWin
+		notice.URL = strings.ReplaceAll(event.NoticeURL, tracking.MacroAuctionPrice, s.noticePrice(record))
+	case tracking.EventBilling:
+		notice.Kind = notifier.KindBilling
+		notice.URL = strings.ReplaceAll(event.NoticeURL, tracking.MacroAuctionPrice, s.noticePrice(record))
+	case tracking.EventLoss:
+		notice.Kind = notifier.KindLoss
+		notice.LossReason = lossReason(event)
+		notice.URL = strings.ReplaceAll(event.NoticeURL, tracking.MacroAuctionLoss, strconv.Itoa(int(notice.LossReason)))
+	default:
+		return
+	}
+	s.notifier.Notify(notice)
+}
+
+// lossReason 媒体回传的竞价失败原因，未回传或无法解析时按出价低于其他竞价处理
+func lossReason(event *tracking.Event) tracking.LossReason {
+	reason, err := strconv.Atoi(event.LossReason)
+	if err != nil || reason <= 0 {
+		return tracking.LossLostToHigherBid
+	}
+	return tracking.LossReason(reason)
+}
+
+// noticePrice 通知DSP的成交价宏取值，按DSP配置加密；
+// 媒体未回传或解密失败时按签名中的出价(一价结算)下发
+func (s *Server) noticePrice(record *EventRecord) string {
+	price := float64(record.BidPrice) / 100
+	if record.ClearPriceCPM != nil {
+		price = *record.ClearPriceCPM
+	}
+	codec, ok := s.dspCodecs[record.DSPID]
+	if !ok {
+		codec = plainPrice
+	}
+	encrypted, err := codec.Encrypt(price)
+	if err != nil {
+		// 加密失败时不下发明文价格
+		log.Printf("encrypt notice price failed, dsp:%s err:%v", record.DSPID, err)
+		return ""
+	}
+	return url.QueryEscape(encrypted)
+}
+
+func (s *Server) respond(c *gin.Context, event *tracking.Event) {
+	switch event.Type {
+	case tracking.EventImp:
+		c.Header("Cache-Control", "no-store")
+		c.Data(http.StatusOK, "image/gif", transparentGIF)
+	case tracking.EventClick:
+		if s.clickRedirect && isRedirectURL(event.ClickURL) {
+			c.Redirect(http.StatusFound, event.ClickURL)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func isKnownEvent(eventType tracking.EventType) bool {
+	for _, known := range tracking.EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// isRedirectURL 只跳转或转发到 http(s) 地址，落地页和通知地址已包含在签名中
+func isRedirectURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
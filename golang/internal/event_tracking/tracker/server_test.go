@@ -0,0 +1,240 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/internal/event_tracking/config"
+	"github.com/echoface/admux/pkg/notifier"
+	"github.com/echoface/admux/pkg/pricecrypto"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+const testPriceKey = "0123456789abcdef"
+
+func newTestServer(t *testing.T) (*Server, *gin.Engine, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	logPath := filepath.Join(t.TempDir(), "events.log")
+	eventLog, err := NewEventLog(config.EventLogConfig{Path: logPath})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = eventLog.Close() })
+
+	cfg := &config.TrackingConfig{
+		SigningKeys:   []config.SigningKey{{ID: "k1", Secret: "secret"}},
+		MaxEventAge:   time.Hour,
+		ClickTracking: config.ClickTrackingConfig{Enabled: true},
+		SSPPriceCrypto: []config.SSPPriceCrypto{{
+			SSPID:  "ssp_a",
+			Config: pricecrypto.Config{Scheme: pricecrypto.SchemeAESECBBase64URL, EncryptionKey: testPriceKey},
+		}},
+	}
+	server, err := NewServer(cfg, eventLog, nil, "admux", "tracking", prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	r := gin.New()
+	server.RegisterRoutes(r)
+	return server, r, logPath
+}
+
+func signedPath(t *testing.T, event *tracking.Event) string {
+	t.Helper()
+	signer := &tracking.Signer{KeyID: "k1", Secret: []byte("secret")}
+	return signer.URL("", event)
+}
+
+func serve(r http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func readRecords(t *testing.T, path string) []EventRecord {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []EventRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record EventRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestServer_RecordsAndDedups(t *testing.T) {
+	server, r, logPath := newTestServer(t)
+	now := time.Now()
+
+	win := signedPath(t, &tracking.Event{
+		Type: tracking.EventWin, RequestID: "req-1", ImpID: "imp-1", BidID: "bid-1",
+		DSPID: "dsp_a", BidPrice: 1200, Timestamp: now.Unix(),
+	})
+	assert.Equal(t, http.StatusNoContent, serve(r, win+"&cp=1100").Code)
+	assert.Equal(t, http.StatusNoContent, serve(r, win+"&cp=1100").Code)
+
+	imp := signedPath(t, &tracking.Event{Type: tracking.EventImp, RequestID: "req-1", ImpID: "imp-1", BidID: "bid-1", Timestamp: now.Unix()})
+	w := serve(r, imp)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/gif", w.Header().Get("Content-Type"))
+
+	records := readRecords(t, logPath)
+	require.Len(t, records, 2)
+	assert.Equal(t, tracking.EventWin, records[0].Type)
+	assert.Equal(t, "1100", records[0].ClearPrice)
+	require.NotNil(t, records[0].ClearPriceCPM)
+	assert.Equal(t, 1100.0, *records[0].ClearPriceCPM)
+	assert.Equal(t, int64(1200), records[0].BidPrice)
+	assert.Equal(t, tracking.EventImp, records[1].Type)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(server.events.WithLabelValues("win", resultAccepted)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(server.events.WithLabelValues("win", resultDuplicate)))
+}
+
+func TestServer_RetryAfterAppendFailure(t *testing.T) {
+	_, r, logPath := newTestServer(t)
+	win := signedPath(t, &tracking.Event{
+		Type: tracking.EventWin, RequestID: "req-1", ImpID: "imp-1", BidID: "bid-1",
+		DSPID: "dsp_a", BidPrice: 1200, Timestamp: time.Now().Unix(),
+	})
+
+	// 日志目录被同名文件占用，首次写入失败
+	logDir := filepath.Dir(logPath)
+	require.NoError(t, os.RemoveAll(logDir))
+	require.NoError(t, os.WriteFile(logDir, nil, 0o644))
+	assert.Equal(t, http.StatusInternalServerError, serve(r, win).Code)
+
+	// 恢复后媒体重试的同一事件被记录，不被当作重复
+	require.NoError(t, os.Remove(logDir))
+	require.NoError(t, os.MkdirAll(logDir, 0o755))
+	assert.Equal(t, http.StatusNoContent, serve(r, win).Code)
+	require.Len(t, readRecords(t, logPath), 1)
+	assert.Equal(t, http.StatusNoContent, serve(r, win).Code)
+	require.Len(t, readRecords(t, logPath), 1)
+}
+
+func TestServer_DecryptsClearPrice(t *testing.T) {
+	_, r, logPath := newTestServer(t)
+	codec, err := pricecrypto.New(pricecrypto.Config{Scheme: pricecrypto.SchemeAESECBBase64URL, EncryptionKey: testPriceKey})
+	require.NoError(t, err)
+	cp, err := codec.Encrypt(11.8)
+	require.NoError(t, err)
+
+	event := func(bidID string) string {
+		return signedPath(t, &tracking.Event{
+			Type: tracking.EventBilling, RequestID: "req-4", ImpID: "imp-1", BidID: bidID,
+			DSPID: "dsp_a", SSPID: "ssp_a", BidPrice: 1250, Timestamp: time.Now().Unix(),
+		})
+	}
+	assert.Equal(t, http.StatusNoContent, serve(r, event("bid-1")+"&cp="+cp).Code)
+	// 媒体未替换宏时仍记录事件，标记解密失败
+	assert.Equal(t, http.StatusNoContent, serve(r, event("bid-2")+"&cp=${WIN_PRICE}").Code)
+
+	records := readRecords(t, logPath)
+	require.Len(t, records, 2)
+	require.NotNil(t, records[0].ClearPriceCPM)
+	assert.Equal(t, 11.8, *records[0].ClearPriceCPM)
+	assert.Empty(t, records[0].PriceError)
+	assert.Nil(t, records[1].ClearPriceCPM)
+	assert.NotEmpty(t, records[1].PriceError)
+}
+
+func TestServer_ForwardsDSPNotice(t *testing.T) {
+	var hits atomic.Int32
+	dsp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/win":
+			// 媒体未回传成交价时按出价替换价格宏
+			assert.Equal(t, "12.5", r.URL.Query().Get("p"))
+		case "/loss":
+			// 按媒体回传的失败原因替换宏
+			assert.Equal(t, "103", r.URL.Query().Get("r"))
+		default:
+			t.Errorf("unexpected notice %s", r.URL)
+		}
+		hits.Add(1)
+	}))
+	defer dsp.Close()
+
+	server, r, _ := newTestServer(t)
+	notices, err := notifier.New(notifier.Config{Workers: 1}, nil)
+	require.NoError(t, err)
+	server.notifier = notices
+
+	win := signedPath(t, &tracking.Event{
+		Type: tracking.EventWin, RequestID: "req-5", ImpID: "imp-1", BidID: "bid-1",
+		DSPID: "dsp_a", BidPrice: 1250, Timestamp: time.Now().Unix(), NoticeURL: dsp.URL + "/win?p=${AUCTION_PRICE}",
+	})
+	loss := signedPath(t, &tracking.Event{
+		Type: tracking.EventLoss, RequestID: "req-6", ImpID: "imp-1", BidID: "bid-1",
+		DSPID: "dsp_a", Timestamp: time.Now().Unix(), NoticeURL: dsp.URL + "/loss?r=${AUCTION_LOSS}",
+	})
+	// 重复事件不再转发
+	assert.Equal(t, http.StatusNoContent, serve(r, win).Code)
+	assert.Equal(t, http.StatusNoContent, serve(r, win).Code)
+	assert.Equal(t, http.StatusNoContent, serve(r, loss+"&lr=103").Code)
+	require.NoError(t, notices.Close(context.Background()))
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestServer_ClickRedirect(t *testing.T) {
+	_, r, logPath := newTestServer(t)
+	click := signedPath(t, &tracking.Event{
+		Type: tracking.EventClick, RequestID: "req-2", ImpID: "imp-1",
+		Timestamp: time.Now().Unix(), ClickURL: "https://landing.example.com/p?id=1",
+	})
+
+	// 重复点击不记录但仍跳转
+	for i := 0; i < 2; i++ {
+		w := serve(r, click)
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://landing.example.com/p?id=1", w.Header().Get("Location"))
+	}
+	assert.Len(t, readRecords(t, logPath), 1)
+}
+
+func TestServer_RejectsInvalid(t *testing.T) {
+	server, r, _ := newTestServer(t)
+	loss := signedPath(t, &tracking.Event{Type: tracking.EventLoss, RequestID: "req-3", ImpID: "imp-1", Timestamp: time.Now().Unix()})
+
+	assert.Equal(t, http.StatusForbidden, serve(r, loss+"&dsp=dsp_b").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, "/track/billing"+loss[len("/track/loss"):]).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, "/track/unknown").Code)
+	assert.Equal(t, 1.0, testutil.ToFloat64(server.events.WithLabelValues("loss", resultRejected)))
+}
+
+func TestDeduper_Window(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	dedup := NewDeduper(time.Minute, 10)
+	dedup.now = func() time.Time { return now }
+	dedup.rotatedAt = now
+
+	assert.False(t, dedup.Seen("a"))
+	assert.True(t, dedup.Seen("a"))
+
+	// 一个窗口后仍在旧一代中
+	now = now.Add(time.Minute)
+	assert.True(t, dedup.Seen("a"))
+
+	// 两个窗口后淘汰
+	now = now.Add(time.Minute)
+	assert.False(t, dedup.Seen("a"))
+}
@@ -20,8 +20,8 @@ func NewLoader(serviceName string) *Loader {
 	}
 }
 
-// Load 加载配置文件并解析到目标结构体
-func (l *Loader) Load(configStruct interface{}) error {
+// ConfigFile 返回当前运行类型对应的配置文件路径，如 conf/test.yaml
+func (l *Loader) ConfigFile() (string, error) {
 	// 获取运行类型环境变量
 	runType := os.Getenv("RUN_TYPE")
 	if runType == "" {
@@ -30,16 +30,24 @@ func (l *Loader) Load(configStruct interface{}) error {
 
 	// 验证运行类型
 	if runType != "test" && runType != "prod" && runType != "dev" {
-		return fmt.Errorf("invalid RUN_TYPE: %s, must be 'test', 'prod', or 'dev'", runType)
+		return "", fmt.Errorf("invalid RUN_TYPE: %s, must be 'test', 'prod', or 'dev'", runType)
 	}
 
 	// 构建配置文件路径
-	configDir := l.getConfigDir()
-	configFile := filepath.Join(configDir, fmt.Sprintf("%s.yaml", runType))
+	configFile := filepath.Join(l.getConfigDir(), fmt.Sprintf("%s.yaml", runType))
 
 	// 检查配置文件是否存在
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		return fmt.Errorf("config file not found: %s", configFile)
+		return "", fmt.Errorf("config file not found: %s", configFile)
+	}
+	return configFile, nil
+}
+
+// Load 加载配置文件并解析到目标结构体
+func (l *Loader) Load(configStruct interface{}) error {
+	configFile, err := l.ConfigFile()
+	if err != nil {
+		return err
 	}
 
 	// 设置viper配置
@@ -61,7 +69,7 @@ func (l *Loader) Load(configStruct interface{}) error {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	fmt.Printf("Loaded %s config from: %s (run_type: %s)\n", l.ServiceName, configFile, runType)
+	fmt.Printf("Loaded %s config from: %s (run_type: %s)\n", l.ServiceName, configFile, GetRunType())
 
 	return nil
 }
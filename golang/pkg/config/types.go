@@ -39,7 +39,6 @@ type LoggingConfig struct {
 type MonitoringConfig struct {
 	Prometheus  PrometheusConfig  `yaml:"prometheus"`
 	HealthCheck HealthCheckConfig `yaml:"health_check"`
-	Jaeger      JaegerConfig      `yaml:"jaeger"`
 }
 
 // PrometheusConfig Prometheus配置
@@ -58,12 +57,6 @@ type HealthCheckConfig struct {
 	Timeout  time.Duration `yaml:"timeout"`
 }
 
-// JaegerConfig Jaeger配置
-type JaegerConfig struct {
-	Enabled  bool   `yaml:"enabled"`
-	Endpoint string `yaml:"endpoint"`
-}
-
 // DefaultBaseConfig 获取默认基础配置
 func DefaultBaseConfig() *BaseConfig {
 	return &BaseConfig{
@@ -0,0 +1,46 @@
+// Package netx 网络相关的通用工具
+package netx
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IPAllowlist 由单个IP或网段(CIDR)组成的来源IP白名单
+type IPAllowlist []*net.IPNet
+
+// ParseIPAllowlist 解析IP和网段，单个IP只允许其本身
+func ParseIPAllowlist(allowIPs []string) (IPAllowlist, error) {
+	nets := make(IPAllowlist, 0, len(allowIPs))
+	for _, allow := range allowIPs {
+		cidr := allow
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip or cidr %q", allow)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// Contains IP是否在白名单内，无法解析的IP不在白名单内
+func (l IPAllowlist) Contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range l {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package netx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	allowlist, err := ParseIPAllowlist([]string{"10.0.0.0/8", "192.168.1.10", "2001:db8::1"})
+	require.NoError(t, err)
+
+	assert.True(t, allowlist.Contains("10.1.2.3"))
+	assert.True(t, allowlist.Contains("192.168.1.10"))
+	assert.True(t, allowlist.Contains("2001:db8::1"))
+	// 单个IP只允许其本身
+	assert.False(t, allowlist.Contains("192.168.1.11"))
+	assert.False(t, allowlist.Contains("2001:db8::2"))
+	assert.False(t, allowlist.Contains("not-an-ip"))
+
+	_, err = ParseIPAllowlist([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = ParseIPAllowlist([]string{"example.com"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/echoface/admux/pkg/concurrent"
+)
+
+const (
+	defaultDeadLetterBatchSize     = 64
+	defaultDeadLetterFlushInterval = time.Second
+	defaultDeadLetterMaxSizeMB     = 128
+)
+
+// DeadLetterConfig 死信文件配置
+type DeadLetterConfig struct {
+	// Path 死信文件路径，为空时不落盘
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+	// BatchSize 批量写入条数，FlushInterval 未满批时的最长等待
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// DeadLetterRecord 死信文件中的一条记录，可用于离线补发
+type DeadLetterRecord struct {
+	*Notice
+
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// DeadLetter 投递失败通知的本地死信文件，每行一个JSON记录，通过 BatchProcessor 批量写入
+type DeadLetter struct {
+	mu     sync.Mutex
+	writer *lumberjack.Logger
+
+	batch   *concurrent.BatchProcessor[*DeadLetterRecord]
+	pending sync.WaitGroup
+
+	closed atomic.Bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewDeadLetter 创建死信文件，目录不存在时自动创建
+func NewDeadLetter(cfg DeadLetterConfig) (*DeadLetter, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("dead letter path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create dead letter dir: %w", err)
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = defaultDeadLetterMaxSizeMB
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultDeadLetterBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultDeadLetterFlushInterval
+	}
+
+	d := &DeadLetter{
+		writer: &lumberjack.Logger{
+			Filename:   cfg.Path,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	d.batch = concurrent.NewBatchProcessor(cfg.BatchSize, d.write)
+	go d.flushLoop(cfg.FlushInterval)
+	return d, nil
+}
+
+// Add 记录一条最终失败的通知，d为nil或已关闭时忽略
+func (d *DeadLetter) Add(notice *Notice, attempts int, reason string) {
+	if d == nil {
+		return
+	}
+	if d.closed.Load() {
+		log.Printf("dead letter closed, notice dropped, dsp:%s kind:%s", notice.DSPID, notice.Kind)
+		return
+	}
+	d.pending.Add(1)
+	d.batch.Add(&DeadLetterRecord{Notice: notice, Attempts: attempts, Error: reason, FailedAt: time.Now()})
+}
+
+// Close 写入剩余记录并关闭文件
+func (d *DeadLetter) Close() error {
+	if d == nil || d.closed.Swap(true) {
+		return nil
+	}
+	close(d.stop)
+	<-d.done
+	d.batch.Flush()
+	d.pending.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.writer.Close()
+}
+
+func (d *DeadLetter) flushLoop(interval time.Duration) {
+	defer close(d.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.batch.Flush()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// write BatchProcessor 的批处理回调，在独立goroutine中执行
+func (d *DeadLetter) write(records []*DeadLetterRecord) {
+	defer d.pending.Add(-len(records))
+
+	var buf []byte
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			log.Printf("marshal dead letter failed, dsp:%s err:%v", record.DSPID, err)
+			continue
+		}
+		buf = append(append(buf, data...), '\n')
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.writer.Write(buf); err != nil {
+		log.Printf("write dead letter failed, records:%d err:%v", len(records), err)
+	}
+}
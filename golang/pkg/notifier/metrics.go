@@ -0,0 +1,78 @@
+package notifier
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics 按DSP统计的通知投递指标
+type Metrics struct {
+	// 投递结果(按DSP、通知类型、结果: delivered/failed/dropped)
+	Notices *prometheus.CounterVec
+
+	// 重试次数(按DSP、通知类型)
+	Retries *prometheus.CounterVec
+
+	// 投递耗时，含重试(按DSP、通知类型)
+	Latency *prometheus.HistogramVec
+}
+
+// NewMetrics 创建投递指标，reg为nil时注册到默认Registry；同名指标已注册时复用
+func NewMetrics(namespace, subsystem string, reg prometheus.Registerer) *Metrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	return &Metrics{
+		Notices: registerOrReuse(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dsp_notices_total",
+			Help:      "Total number of DSP win/billing/loss notices by result",
+		}, []string{"dsp", "kind", "result"})),
+		Retries: registerOrReuse(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dsp_notice_retries_total",
+			Help:      "Total number of DSP notice retries",
+		}, []string{"dsp", "kind"})),
+		Latency: registerOrReuse(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dsp_notice_latency_seconds",
+			Help:      "DSP notice delivery latency in seconds, including retries",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.2, 0.5, 1, 2, 5, 10},
+		}, []string{"dsp", "kind"})),
+	}
+}
+
+// observe 记录一次投递结果，m为nil时忽略
+func (m *Metrics) observe(notice *Notice, result string, attempts int, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	kind := string(notice.Kind)
+	m.Notices.WithLabelValues(notice.DSPID, kind, result).Inc()
+	if attempts == 0 {
+		return
+	}
+	if attempts > 1 {
+		m.Retries.WithLabelValues(notice.DSPID, kind).Add(float64(attempts - 1))
+	}
+	m.Latency.WithLabelValues(notice.DSPID, kind).Observe(latency.Seconds())
+}
+
+func registerOrReuse[T prometheus.Collector](reg prometheus.Registerer, collector T) T {
+	if err := reg.Register(collector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return collector
+}
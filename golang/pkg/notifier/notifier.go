@@ -0,0 +1,236 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/echoface/admux/pkg/retry"
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+// Kind 通知类型
+type Kind string
+
+const (
+	KindWin     Kind = "win"     // 竞价成功通知(nurl)
+	KindBilling Kind = "billing" // 计费通知(burl)
+	KindLoss    Kind = "loss"    // 竞价失败通知(lurl)
+)
+
+// 投递结果，用作指标标签
+const (
+	resultDelivered = "delivered"
+	resultFailed    = "failed"
+	resultDropped   = "dropped"
+)
+
+const (
+	defaultWorkers       = 16
+	defaultQueueSize     = 10000
+	defaultTimeout       = 2 * time.Second
+	defaultRetryDelay    = 200 * time.Millisecond
+	defaultMaxRetryDelay = 2 * time.Second
+)
+
+// Notice 一条发往DSP的通知，URL中的宏已替换
+type Notice struct {
+	Kind       Kind                `json:"kind"`
+	DSPID      string              `json:"dsp_id"`
+	URL        string              `json:"url"`
+	RequestID  string              `json:"request_id,omitempty"`
+	LossReason tracking.LossReason `json:"loss_reason,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// Config 通知投递配置
+type Config struct {
+	// Disabled 关闭通知投递(如离线回放)，不向DSP发出任何请求
+	Disabled bool `yaml:"disabled"`
+	// Workers 并发投递的worker数
+	Workers int `yaml:"workers"`
+	// QueueSize 待投递队列长度，队列满时通知直接进入死信
+	QueueSize int `yaml:"queue_size"`
+	// Timeout 单次请求超时
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxRetries 失败后的最大重试次数，4xx(408/429除外)不重试
+	MaxRetries int `yaml:"max_retries"`
+	// RetryDelay 首次重试延迟，之后指数退避，不超过 MaxRetryDelay
+	RetryDelay    time.Duration `yaml:"retry_delay"`
+	MaxRetryDelay time.Duration `yaml:"max_retry_delay"`
+
+	DeadLetter DeadLetterConfig `yaml:"dead_letter"`
+}
+
+// Notifier 异步DSP通知器：有界worker池投递，失败按退避重试，最终失败的通知写入死信文件
+type Notifier struct {
+	client     *http.Client
+	retry      *retry.RetryConfig
+	deadLetter *DeadLetter
+	metrics    *Metrics
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *Notice
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建并启动通知器，metrics为nil时不记录指标，未配置死信路径时最终失败的通知只计数
+func New(cfg Config, metrics *Metrics) (*Notifier, error) {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaultRetryDelay
+	}
+	if cfg.MaxRetryDelay < cfg.RetryDelay {
+		cfg.MaxRetryDelay = max(cfg.RetryDelay, defaultMaxRetryDelay)
+	}
+
+	var deadLetter *DeadLetter
+	if cfg.DeadLetter.Path != "" {
+		var err error
+		if deadLetter, err = NewDeadLetter(cfg.DeadLetter); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Notifier{
+		client: &http.Client{Timeout: cfg.Timeout},
+		retry: &retry.RetryConfig{
+			MaxRetries:        cfg.MaxRetries,
+			InitialDelay:      cfg.RetryDelay,
+			MaxDelay:          cfg.MaxRetryDelay,
+			BackoffMultiplier: 2,
+		},
+		deadLetter: deadLetter,
+		metrics:    metrics,
+		queue:      make(chan *Notice, cfg.QueueSize),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		n.wg.Add(1)
+		go n.worker()
+	}
+	return n, nil
+}
+
+// Notify 提交通知，不阻塞调用方；队列已满或通知器已关闭时写入死信并返回false，n为nil时忽略
+func (n *Notifier) Notify(notice *Notice) bool {
+	if n == nil || notice.URL == "" {
+		return false
+	}
+	if notice.CreatedAt.IsZero() {
+		notice.CreatedAt = time.Now()
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if !n.closed {
+		select {
+		case n.queue <- notice:
+			return true
+		default:
+		}
+	}
+	n.metrics.observe(notice, resultDropped, 0, 0)
+	n.deadLetter.Add(notice, 0, "queue full")
+	return false
+}
+
+// Close 停止接收新通知并等待队列中的通知投递完成；ctx到期后放弃重试，剩余通知写入死信
+func (n *Notifier) Close(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return nil
+	}
+	n.closed = true
+	close(n.queue)
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		n.cancel()
+		<-done
+		err = ctx.Err()
+	}
+	n.cancel()
+	if closeErr := n.deadLetter.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (n *Notifier) worker() {
+	defer n.wg.Done()
+	for notice := range n.queue {
+		n.deliver(notice)
+	}
+}
+
+// deliver 带重试投递单条通知，最终失败时写入死信
+func (n *Notifier) deliver(notice *Notice) {
+	start := time.Now()
+	attempts := 0
+	_, err := retry.Retry(n.ctx, func(ctx context.Context) (struct{}, error) {
+		attempts++
+		return struct{}{}, n.send(ctx, notice)
+	}, n.retry)
+
+	if err == nil {
+		n.metrics.observe(notice, resultDelivered, attempts, time.Since(start))
+		return
+	}
+	n.metrics.observe(notice, resultFailed, attempts, time.Since(start))
+	n.deadLetter.Add(notice, attempts, err.Error())
+}
+
+func (n *Notifier) send(ctx context.Context, notice *Notice) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, notice.URL, nil)
+	if err != nil {
+		return &retry.RetryableError{Type: retry.ProtocolError, Message: err.Error()}
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &retry.RetryableError{Type: retry.RateLimitError, Message: resp.Status}
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		return &retry.RetryableError{Type: retry.NetworkError, Message: resp.Status}
+	default:
+		return &retry.RetryableError{Type: retry.ProtocolError, Message: fmt.Sprintf("unexpected status %s", resp.Status)}
+	}
+}
@@ -0,0 +1,129 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/echoface/admux/pkg/tracking"
+)
+
+func newTestNotifier(t *testing.T, cfg Config) (*Notifier, *Metrics, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dead_letter.log")
+	cfg.DeadLetter = DeadLetterConfig{Path: path, BatchSize: 2, FlushInterval: 10 * time.Millisecond}
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = time.Millisecond
+	}
+	metrics := NewMetrics("admux", "notifier", prometheus.NewRegistry())
+	n, err := New(cfg, metrics)
+	require.NoError(t, err)
+	return n, metrics, path
+}
+
+func readDeadLetters(t *testing.T, path string) []DeadLetterRecord {
+	t.Helper()
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []DeadLetterRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record DeadLetterRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNotifier_DeliverAndRetry(t *testing.T) {
+	var flaky atomic.Int32
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/win":
+			w.WriteHeader(http.StatusNoContent)
+		case "/flaky":
+			// 前两次失败，第三次成功
+			if flaky.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	n, metrics, path := newTestNotifier(t, Config{Workers: 2, MaxRetries: 2})
+	assert.True(t, n.Notify(&Notice{Kind: KindWin, DSPID: "dsp_a", URL: server.URL + "/win"}))
+	assert.True(t, n.Notify(&Notice{Kind: KindBilling, DSPID: "dsp_a", URL: server.URL + "/flaky"}))
+	assert.True(t, n.Notify(&Notice{Kind: KindLoss, DSPID: "dsp_b", URL: server.URL + "/gone", LossReason: tracking.LossLostToHigherBid}))
+	assert.False(t, n.Notify(&Notice{Kind: KindLoss, DSPID: "dsp_b"}))
+	require.NoError(t, n.Close(context.Background()))
+
+	// 404 不重试
+	assert.Equal(t, int32(5), hits.Load())
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Notices.WithLabelValues("dsp_a", "win", resultDelivered)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Notices.WithLabelValues("dsp_a", "billing", resultDelivered)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.Retries.WithLabelValues("dsp_a", "billing")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Notices.WithLabelValues("dsp_b", "loss", resultFailed)))
+
+	records := readDeadLetters(t, path)
+	require.Len(t, records, 1)
+	assert.Equal(t, "dsp_b", records[0].DSPID)
+	assert.Equal(t, tracking.LossLostToHigherBid, records[0].LossReason)
+	assert.Equal(t, 1, records[0].Attempts)
+	assert.Contains(t, records[0].Error, "404")
+}
+
+func TestNotifier_QueueFullAndClose(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	n, metrics, path := newTestNotifier(t, Config{Workers: 1, QueueSize: 1, MaxRetries: 5, RetryDelay: time.Second})
+	notice := func() *Notice { return &Notice{Kind: KindWin, DSPID: "dsp_a", URL: server.URL} }
+
+	// 第一条被worker取走，第二条占满队列
+	assert.True(t, n.Notify(notice()))
+	require.Eventually(t, func() bool { return len(n.queue) == 0 }, time.Second, time.Millisecond)
+	assert.True(t, n.Notify(notice()))
+	assert.False(t, n.Notify(notice()))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Notices.WithLabelValues("dsp_a", "win", resultDropped)))
+
+	// 关闭超时后放弃重试，剩余通知全部进入死信
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, n.Close(ctx), context.DeadlineExceeded)
+	assert.Len(t, readDeadLetters(t, path), 3)
+
+	// 关闭后提交的通知直接进入死信，不会panic
+	assert.False(t, n.Notify(notice()))
+	assert.NoError(t, n.Close(context.Background()))
+}
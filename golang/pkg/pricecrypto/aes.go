@@ -0,0 +1,118 @@
+package pricecrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// aesCodec AES 价格加密，明文为价格字符串(如 "12.5")，PKCS7 填充；
+// CBC 未配置固定向量时随机生成向量放在密文前
+type aesCodec struct {
+	block    cipher.Block
+	iv       []byte
+	cbc      bool
+	encoding *base64.Encoding
+}
+
+func newAESCodec(key, iv []byte, cbc bool, encoding *base64.Encoding) (*aesCodec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("pricecrypto: %w", err)
+	}
+	if iv != nil && len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("pricecrypto: iv must be %d bytes", aes.BlockSize)
+	}
+	return &aesCodec{block: block, iv: iv, cbc: cbc, encoding: encoding}, nil
+}
+
+func (c *aesCodec) Encrypt(price float64) (string, error) {
+	plain := pkcs7Pad([]byte(strconv.FormatFloat(price, 'f', -1, 64)))
+	out := make([]byte, len(plain))
+
+	if !c.cbc {
+		for i := 0; i < len(plain); i += aes.BlockSize {
+			c.block.Encrypt(out[i:i+aes.BlockSize], plain[i:i+aes.BlockSize])
+		}
+		return c.encoding.EncodeToString(out), nil
+	}
+
+	iv := c.iv
+	if iv == nil {
+		iv = make([]byte, aes.BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return "", err
+		}
+	}
+	cipher.NewCBCEncrypter(c.block, iv).CryptBlocks(out, plain)
+	if c.iv == nil {
+		out = append(iv, out...)
+	}
+	return c.encoding.EncodeToString(out), nil
+}
+
+func (c *aesCodec) Decrypt(ciphertext string) (float64, error) {
+	data, err := c.encoding.DecodeString(ciphertext)
+	if err != nil {
+		// 兼容媒体带/不带 padding 的两种写法
+		if data, err = decodeBase64(ciphertext); err != nil {
+			return 0, ErrInvalidCiphertext
+		}
+	}
+
+	iv := c.iv
+	if c.cbc && iv == nil {
+		if len(data) < aes.BlockSize {
+			return 0, ErrInvalidCiphertext
+		}
+		iv, data = data[:aes.BlockSize], data[aes.BlockSize:]
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return 0, ErrInvalidCiphertext
+	}
+
+	plain := make([]byte, len(data))
+	if c.cbc {
+		cipher.NewCBCDecrypter(c.block, iv).CryptBlocks(plain, data)
+	} else {
+		for i := 0; i < len(data); i += aes.BlockSize {
+			c.block.Decrypt(plain[i:i+aes.BlockSize], data[i:i+aes.BlockSize])
+		}
+	}
+
+	plain, ok := pkcs7Unpad(plain)
+	if !ok {
+		return 0, ErrInvalidCiphertext
+	}
+	price, err := strconv.ParseFloat(strings.TrimSpace(string(plain)), 64)
+	if err != nil {
+		return 0, ErrInvalidCiphertext
+	}
+	return price, nil
+}
+
+func pkcs7Pad(data []byte) []byte {
+	n := aes.BlockSize - len(data)%aes.BlockSize
+	return append(data, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func pkcs7Unpad(data []byte) ([]byte, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize || n > len(data) {
+		return nil, false
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, false
+		}
+	}
+	return data[:len(data)-n], true
+}
@@ -0,0 +1,87 @@
+package pricecrypto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	googleIVSize   = 16
+	googlePadSize  = 8
+	googleSigSize  = 4
+	googleTotalLen = googleIVSize + googlePadSize + googleSigSize
+)
+
+// googleCodec Google(DoubleClick) 价格加密：
+// pad = HMAC-SHA1(ekey, iv)[:8]，enc = micros ^ pad，sig = HMAC-SHA1(ikey, micros || iv)[:4]，
+// 密文为 websafe_base64(iv || enc || sig)
+type googleCodec struct {
+	ekey, ikey []byte
+	randIV     func([]byte) error
+}
+
+func newGoogleCodec(ekey, ikey []byte) (*googleCodec, error) {
+	return &googleCodec{
+		ekey: ekey,
+		ikey: ikey,
+		randIV: func(iv []byte) error {
+			_, err := rand.Read(iv)
+			return err
+		},
+	}, nil
+}
+
+func (c *googleCodec) Encrypt(price float64) (string, error) {
+	if price < 0 {
+		return "", errors.New("pricecrypto: negative price")
+	}
+	var plain [googlePadSize]byte
+	binary.BigEndian.PutUint64(plain[:], uint64(math.Round(price*1e6)))
+
+	out := make([]byte, googleTotalLen)
+	iv := out[:googleIVSize]
+	if err := c.randIV(iv); err != nil {
+		return "", err
+	}
+
+	pad := hmacSHA1(c.ekey, iv)
+	for i := 0; i < googlePadSize; i++ {
+		out[googleIVSize+i] = plain[i] ^ pad[i]
+	}
+	sig := hmacSHA1(c.ikey, plain[:], iv)
+	copy(out[googleIVSize+googlePadSize:], sig[:googleSigSize])
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+func (c *googleCodec) Decrypt(ciphertext string) (float64, error) {
+	data, err := decodeBase64(ciphertext)
+	if err != nil || len(data) != googleTotalLen {
+		return 0, ErrInvalidCiphertext
+	}
+	iv := data[:googleIVSize]
+	enc := data[googleIVSize : googleIVSize+googlePadSize]
+
+	pad := hmacSHA1(c.ekey, iv)
+	var plain [googlePadSize]byte
+	for i := range plain {
+		plain[i] = enc[i] ^ pad[i]
+	}
+	sig := hmacSHA1(c.ikey, plain[:], iv)
+	if !hmac.Equal(sig[:googleSigSize], data[googleIVSize+googlePadSize:]) {
+		return 0, ErrIntegrity
+	}
+	return float64(binary.BigEndian.Uint64(plain[:])) / 1e6, nil
+}
+
+func hmacSHA1(key []byte, parts ...[]byte) []byte {
+	mac := hmac.New(sha1.New, key)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
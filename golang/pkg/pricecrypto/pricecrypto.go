@@ -0,0 +1,148 @@
+package pricecrypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 加密方案
+const (
+	SchemeNone            = "none"              // 不加密，价格明文
+	SchemeGoogle          = "google"            // Google(DoubleClick) HMAC-SHA1 异或加密，websafe base64
+	SchemeAESECB          = "aes_ecb"           // AES-ECB PKCS7，标准 base64
+	SchemeAESECBBase64URL = "aes_ecb_base64url" // AES-ECB PKCS7，base64url
+	SchemeAESCBC          = "aes_cbc"           // AES-CBC PKCS7，标准 base64
+	SchemeAESCBCBase64URL = "aes_cbc_base64url" // AES-CBC PKCS7，base64url
+)
+
+// 密钥编码方式
+const (
+	KeyEncodingRaw    = "raw"
+	KeyEncodingBase64 = "base64"
+	KeyEncodingHex    = "hex"
+)
+
+var (
+	ErrInvalidCiphertext = errors.New("pricecrypto: invalid ciphertext")
+	ErrIntegrity         = errors.New("pricecrypto: integrity check failed")
+)
+
+// Config 价格加密配置，SSP(yaml)和DSP(json)配置共用
+type Config struct {
+	// Scheme 加密方案，为空时不加密
+	Scheme string `yaml:"scheme" json:"scheme"`
+	// EncryptionKey 加密密钥，AES方案为16/24/32字节
+	EncryptionKey string `yaml:"encryption_key" json:"encryption_key,omitempty"`
+	// IntegrityKey 完整性校验密钥，仅 google 方案使用
+	IntegrityKey string `yaml:"integrity_key" json:"integrity_key,omitempty"`
+	// IV AES-CBC 固定向量(16字节)，为空时每次随机生成并放在密文前
+	IV string `yaml:"iv" json:"iv,omitempty"`
+	// KeyEncoding 密钥和向量的编码方式: raw | base64 | hex，为空时为 raw
+	KeyEncoding string `yaml:"key_encoding" json:"key_encoding,omitempty"`
+}
+
+// Redacted 返回隐去密钥和向量的配置副本，用于日志输出
+func (c Config) Redacted() Config {
+	c.EncryptionKey = redact(c.EncryptionKey)
+	c.IntegrityKey = redact(c.IntegrityKey)
+	c.IV = redact(c.IV)
+	return c
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "******"
+}
+
+// Codec 价格加解密，价格为CPM，单位为对应币种的元
+type Codec interface {
+	Encrypt(price float64) (string, error)
+	Decrypt(ciphertext string) (float64, error)
+}
+
+// New 按配置创建加解密器，未配置方案时返回明文codec
+func New(cfg Config) (Codec, error) {
+	switch cfg.Scheme {
+	case "", SchemeNone:
+		return plainCodec{}, nil
+	case SchemeGoogle:
+		ekey, err := decodeKey(cfg.EncryptionKey, cfg.KeyEncoding)
+		if err != nil {
+			return nil, fmt.Errorf("encryption_key: %w", err)
+		}
+		ikey, err := decodeKey(cfg.IntegrityKey, cfg.KeyEncoding)
+		if err != nil {
+			return nil, fmt.Errorf("integrity_key: %w", err)
+		}
+		return newGoogleCodec(ekey, ikey)
+	case SchemeAESECB, SchemeAESECBBase64URL, SchemeAESCBC, SchemeAESCBCBase64URL:
+		key, err := decodeKey(cfg.EncryptionKey, cfg.KeyEncoding)
+		if err != nil {
+			return nil, fmt.Errorf("encryption_key: %w", err)
+		}
+		var iv []byte
+		if cfg.IV != "" {
+			if iv, err = decodeKey(cfg.IV, cfg.KeyEncoding); err != nil {
+				return nil, fmt.Errorf("iv: %w", err)
+			}
+		}
+		encoding := base64.StdEncoding
+		if strings.HasSuffix(cfg.Scheme, "_base64url") {
+			encoding = base64.RawURLEncoding
+		}
+		return newAESCodec(key, iv, strings.HasPrefix(cfg.Scheme, "aes_cbc"), encoding)
+	default:
+		return nil, fmt.Errorf("pricecrypto: unknown scheme %q", cfg.Scheme)
+	}
+}
+
+// Enabled 是否配置了加密方案
+func (c Config) Enabled() bool {
+	return c.Scheme != "" && c.Scheme != SchemeNone
+}
+
+func decodeKey(value, encoding string) ([]byte, error) {
+	if value == "" {
+		return nil, errors.New("is required")
+	}
+	switch encoding {
+	case "", KeyEncodingRaw:
+		return []byte(value), nil
+	case KeyEncodingBase64:
+		return decodeBase64(value)
+	case KeyEncodingHex:
+		return hex.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unknown key encoding %q", encoding)
+	}
+}
+
+// decodeBase64 兼容标准/websafe、带或不带padding的base64
+func decodeBase64(value string) ([]byte, error) {
+	value = strings.TrimRight(value, "=")
+	if strings.ContainsAny(value, "-_") {
+		return base64.RawURLEncoding.DecodeString(value)
+	}
+	return base64.RawStdEncoding.DecodeString(value)
+}
+
+// plainCodec 明文价格
+type plainCodec struct{}
+
+func (plainCodec) Encrypt(price float64) (string, error) {
+	return strconv.FormatFloat(price, 'f', -1, 64), nil
+}
+
+func (plainCodec) Decrypt(ciphertext string) (float64, error) {
+	price, err := strconv.ParseFloat(strings.TrimSpace(ciphertext), 64)
+	if err != nil {
+		return 0, ErrInvalidCiphertext
+	}
+	return price, nil
+}
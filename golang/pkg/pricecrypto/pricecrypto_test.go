@@ -0,0 +1,88 @@
+package pricecrypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec_RoundTrip(t *testing.T) {
+	key := "0123456789abcdef"
+	configs := []Config{
+		{},
+		{Scheme: SchemeNone},
+		{Scheme: SchemeGoogle, EncryptionKey: "ekey", IntegrityKey: "ikey"},
+		{Scheme: SchemeAESECB, EncryptionKey: key},
+		{Scheme: SchemeAESECBBase64URL, EncryptionKey: key},
+		{Scheme: SchemeAESCBC, EncryptionKey: key},
+		{Scheme: SchemeAESCBCBase64URL, EncryptionKey: key, IV: "fedcba9876543210"},
+		{Scheme: SchemeAESCBC, EncryptionKey: hex.EncodeToString([]byte(key)), KeyEncoding: KeyEncodingHex},
+		{Scheme: SchemeAESECB, EncryptionKey: base64.StdEncoding.EncodeToString([]byte(key)), KeyEncoding: KeyEncodingBase64},
+	}
+	for _, cfg := range configs {
+		t.Run(cfg.Scheme, func(t *testing.T) {
+			codec, err := New(cfg)
+			require.NoError(t, err)
+			for _, price := range []float64{0, 0.01, 12.5, 1234.567891} {
+				ciphertext, err := codec.Encrypt(price)
+				require.NoError(t, err)
+				if strings.HasSuffix(cfg.Scheme, "_base64url") || cfg.Scheme == SchemeGoogle {
+					assert.False(t, strings.ContainsAny(ciphertext, "+/="), ciphertext)
+				}
+				got, err := codec.Decrypt(ciphertext)
+				require.NoError(t, err)
+				assert.InDelta(t, price, got, 1e-6)
+			}
+		})
+	}
+}
+
+func TestGoogleCodec_KnownVector(t *testing.T) {
+	// DoubleClick 文档中的示例密钥和密文
+	codec, err := New(Config{
+		Scheme:        SchemeGoogle,
+		EncryptionKey: "skU7Ax_NL5pPAFyKdkfZjZz2-VhIN8bjj1rVFOaJ_5o=",
+		IntegrityKey:  "arO23ykdNqUQ5LEoQ0FVmPkBd7xB5CO89PDZlSjpFxo=",
+		KeyEncoding:   KeyEncodingBase64,
+	})
+	require.NoError(t, err)
+
+	price, err := codec.Decrypt("YWJjMTIzZGVmNDU2Z2hpN7fhCuPemCce_6msaw")
+	require.NoError(t, err)
+	assert.Equal(t, 0.0001, price) // 100 micros
+
+	_, err = codec.Decrypt("YWJjMTIzZGVmNDU2Z2hpN7fhCuPemCce_6mbaw")
+	assert.ErrorIs(t, err, ErrIntegrity)
+}
+
+func TestCodec_Invalid(t *testing.T) {
+	_, err := New(Config{Scheme: "rot13"})
+	assert.Error(t, err)
+	_, err = New(Config{Scheme: SchemeAESECB, EncryptionKey: "short"})
+	assert.Error(t, err)
+	_, err = New(Config{Scheme: SchemeGoogle, EncryptionKey: "ekey"})
+	assert.Error(t, err)
+	_, err = New(Config{Scheme: SchemeAESCBC, EncryptionKey: "0123456789abcdef", IV: "short"})
+	assert.Error(t, err)
+
+	codec, err := New(Config{Scheme: SchemeAESECB, EncryptionKey: "0123456789abcdef"})
+	require.NoError(t, err)
+	for _, ciphertext := range []string{"", "not base64!", "YWJj", "${AUCTION_PRICE}"} {
+		_, err := codec.Decrypt(ciphertext)
+		assert.ErrorIs(t, err, ErrInvalidCiphertext, ciphertext)
+	}
+
+	other, err := New(Config{Scheme: SchemeAESECB, EncryptionKey: "fedcba9876543210"})
+	require.NoError(t, err)
+	ciphertext, err := codec.Encrypt(12.5)
+	require.NoError(t, err)
+	_, err = other.Decrypt(ciphertext)
+	assert.Error(t, err)
+
+	assert.False(t, Config{Scheme: SchemeNone}.Enabled())
+	assert.True(t, Config{Scheme: SchemeGoogle}.Enabled())
+}
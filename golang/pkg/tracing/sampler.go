@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"fmt"
+	"maps"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// KeyedSampler 按根span的某个属性值选择采样率，如按SSP采样；采样率可在运行时整体替换，
+// 子span跟随父span的采样结果
+type KeyedSampler struct {
+	key    attribute.Key
+	parent sdktrace.Sampler
+	state  atomic.Pointer[keyedState]
+}
+
+type keyedState struct {
+	defaultRate float64
+	rates       map[string]float64
+	fallback    sdktrace.Sampler
+	samplers    map[string]sdktrace.Sampler
+}
+
+// keyedRoot 根span的采样决策
+type keyedRoot struct {
+	*KeyedSampler
+}
+
+// NewKeyedSampler 根span的 key 属性(需在 Start 时传入)命中 rates 时使用对应采样率，否则使用 defaultRate；
+// 子span跟随父span的采样结果
+func NewKeyedSampler(key attribute.Key, defaultRate float64, rates map[string]float64) *KeyedSampler {
+	s := &KeyedSampler{key: key}
+	s.parent = sdktrace.ParentBased(keyedRoot{s})
+	s.SetRates(defaultRate, rates)
+	return s
+}
+
+// SetRates 替换采样率，之后开始的根span按新采样率采样
+func (s *KeyedSampler) SetRates(defaultRate float64, rates map[string]float64) {
+	state := &keyedState{
+		defaultRate: defaultRate,
+		rates:       maps.Clone(rates),
+		fallback:    sdktrace.TraceIDRatioBased(defaultRate),
+		samplers:    make(map[string]sdktrace.Sampler, len(rates)),
+	}
+	for value, rate := range rates {
+		state.samplers[value] = sdktrace.TraceIDRatioBased(rate)
+	}
+	s.state.Store(state)
+}
+
+// Rates 获取当前的默认采样率和按属性值覆盖的采样率
+func (s *KeyedSampler) Rates() (float64, map[string]float64) {
+	state := s.state.Load()
+	return state.defaultRate, maps.Clone(state.rates)
+}
+
+// ShouldSample 实现 sdktrace.Sampler
+func (s *KeyedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.parent.ShouldSample(p)
+}
+
+// Description 实现 sdktrace.Sampler
+func (s *KeyedSampler) Description() string {
+	return s.parent.Description()
+}
+
+// ShouldSample 实现 sdktrace.Sampler
+func (r keyedRoot) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	state := r.state.Load()
+	for _, attr := range p.Attributes {
+		if attr.Key != r.key {
+			continue
+		}
+		if sampler, ok := state.samplers[attr.Value.Emit()]; ok {
+			return sampler.ShouldSample(p)
+		}
+		break
+	}
+	return state.fallback.ShouldSample(p)
+}
+
+// Description 实现 sdktrace.Sampler
+func (r keyedRoot) Description() string {
+	return fmt.Sprintf("KeyedSampler{%s,%s}", r.key, r.state.Load().fallback.Description())
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// 导出方式
+const (
+	ExporterOTLP   = "otlp"   // OTLP/HTTP，发往 collector
+	ExporterStdout = "stdout" // 每个span一行JSON输出到标准输出，本地调试用
+)
+
+const defaultServiceName = "admux"
+
+// Config 链路追踪配置
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Exporter 导出方式: otlp / stdout
+	Exporter string `yaml:"exporter"`
+	// Endpoint OTLP/HTTP 地址，如 http://localhost:4318，为空时使用 OTEL_EXPORTER_OTLP_* 环境变量
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"service_name"`
+	// SampleRate 根span的默认采样率 [0,1]，子span跟随父span
+	SampleRate float64 `yaml:"sample_rate"`
+}
+
+// Provider 链路追踪：导出器、采样器和 W3C traceparent 传播；
+// nil Provider 返回不记录的 tracer，调用方无需判断是否开启
+type Provider struct {
+	tp *sdktrace.TracerProvider
+}
+
+// New 按配置创建 Provider，未开启时返回 nil；
+// sampler 为空时按 SampleRate 采样，并把传播方式设置为全局的 W3C trace context
+func New(cfg Config, sampler sdktrace.Sampler) (*Provider, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case ExporterStdout, "":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		err = fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sampler == nil {
+		sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRate))
+	}
+	return NewWithExporter(cfg.ServiceName, exporter, sampler, sdktrace.WithBatcher(exporter)), nil
+}
+
+// NewWithExporter 使用指定导出器创建 Provider，opts 为空时同步导出(测试用)
+func NewWithExporter(serviceName string, exporter sdktrace.SpanExporter, sampler sdktrace.Sampler, opts ...sdktrace.TracerProviderOption) *Provider {
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	if len(opts) == 0 {
+		opts = append(opts, sdktrace.WithSyncer(exporter))
+	}
+	res, _ := resource.Merge(resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", serviceName)))
+
+	opts = append(opts, sdktrace.WithSampler(sampler), sdktrace.WithResource(res))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return &Provider{tp: sdktrace.NewTracerProvider(opts...)}
+}
+
+// Tracer 获取 tracer
+func (p *Provider) Tracer(name string) trace.Tracer {
+	if p == nil {
+		return noop.NewTracerProvider().Tracer(name)
+	}
+	return p.tp.Tracer(name)
+}
+
+// Shutdown 导出剩余的span并关闭导出器
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	return p.tp.Shutdown(ctx)
+}
+
+// Inject 把 ctx 中的 span 以 traceparent 头传给下游，未采样时 sampled 标志为0，未开启追踪时不写入
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestKeyedSampler(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	sampler := NewKeyedSampler("ssp.id", 0, map[string]float64{"kuaishou": 1})
+	tracer := NewWithExporter("test", exporter, sampler).Tracer("test")
+
+	start := func(sspID string) {
+		ctx, root := tracer.Start(context.Background(), "bid", trace.WithAttributes(attribute.String("ssp.id", sspID)))
+		_, child := tracer.Start(ctx, "stage")
+		child.End()
+		root.End()
+	}
+	start("kuaishou")
+	start("xiaomi")
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		assert.Equal(t, spans[0].SpanContext.TraceID(), span.SpanContext.TraceID())
+	}
+}
+
+func TestKeyedSampler_SetRates(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	sampler := NewKeyedSampler("ssp.id", 0, nil)
+	tracer := NewWithExporter("test", exporter, sampler).Tracer("test")
+
+	start := func(sspID string) {
+		_, span := tracer.Start(context.Background(), "bid", trace.WithAttributes(attribute.String("ssp.id", sspID)))
+		span.End()
+	}
+	start("kuaishou")
+	assert.Empty(t, exporter.GetSpans())
+
+	sampler.SetRates(0, map[string]float64{"kuaishou": 1})
+	start("kuaishou")
+	start("xiaomi")
+	assert.Len(t, exporter.GetSpans(), 1)
+
+	defaultRate, rates := sampler.Rates()
+	assert.Equal(t, 0.0, defaultRate)
+	assert.Equal(t, map[string]float64{"kuaishou": 1}, rates)
+}
+
+func TestInject(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := NewWithExporter("test", exporter, NewKeyedSampler("ssp.id", 1, nil)).Tracer("test")
+
+	ctx, span := tracer.Start(context.Background(), "dsp")
+	defer span.End()
+
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.Equal(t, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01", header.Get("traceparent"))
+
+	header = http.Header{}
+	Inject(context.Background(), header)
+	assert.Empty(t, header.Get("traceparent"))
+}
+
+func TestNew_OTLP(t *testing.T) {
+	var received atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			received.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	provider, err := New(Config{Enabled: true, Exporter: ExporterOTLP, Endpoint: collector.URL, SampleRate: 1}, nil)
+	require.NoError(t, err)
+	_, span := provider.Tracer("test").Start(context.Background(), "bid")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, provider.Shutdown(ctx))
+	assert.Equal(t, int32(1), received.Load())
+}
+
+func TestNew_Disabled(t *testing.T) {
+	provider, err := New(Config{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+
+	_, span := provider.Tracer("test").Start(context.Background(), "bid")
+	assert.False(t, span.SpanContext().IsValid())
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	_, err = New(Config{Enabled: true, Exporter: "jaeger"}, nil)
+	assert.Error(t, err)
+}
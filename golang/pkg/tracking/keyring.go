@@ -0,0 +1,75 @@
+package tracking
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Key 签名密钥
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// keySet 一组签名密钥及当前用于签名的密钥
+type keySet struct {
+	active string
+	keys   map[string][]byte
+}
+
+// KeyRing 签名密钥环：使用当前密钥签名，使用所有密钥校验。
+// 轮换密钥的顺序为：监测服务先加入新密钥 -> 签名方切换当前密钥 -> 链接有效期过后移除旧密钥
+type KeyRing struct {
+	set atomic.Pointer[keySet]
+}
+
+// NewKeyRing 创建密钥环，activeID 为签名使用的密钥，为空时只能用于校验
+func NewKeyRing(activeID string, keys ...Key) (*KeyRing, error) {
+	ring := &KeyRing{}
+	if err := ring.Update(activeID, keys...); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// Update 原子替换密钥，用于不停机轮换
+func (r *KeyRing) Update(activeID string, keys ...Key) error {
+	set := &keySet{active: activeID, keys: make(map[string][]byte, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" || len(key.Secret) == 0 {
+			return fmt.Errorf("tracking: signing key id and secret are required")
+		}
+		if _, dup := set.keys[key.ID]; dup {
+			return fmt.Errorf("tracking: duplicate signing key id: %s", key.ID)
+		}
+		set.keys[key.ID] = key.Secret
+	}
+	if len(set.keys) == 0 {
+		return fmt.Errorf("tracking: no signing key configured")
+	}
+	if _, ok := set.keys[activeID]; activeID != "" && !ok {
+		return fmt.Errorf("tracking: active signing key %s not found", activeID)
+	}
+	r.set.Store(set)
+	return nil
+}
+
+// ActiveID 当前签名密钥ID
+func (r *KeyRing) ActiveID() string {
+	return r.set.Load().active
+}
+
+// Signer 使用当前密钥的签名器，未指定当前密钥时返回nil
+func (r *KeyRing) Signer() *Signer {
+	set := r.set.Load()
+	if set.active == "" {
+		return nil
+	}
+	return &Signer{KeyID: set.active, Secret: set.keys[set.active]}
+}
+
+// lookup 按密钥ID查找密钥
+func (r *KeyRing) lookup(id string) ([]byte, bool) {
+	secret, ok := r.set.Load().keys[id]
+	return secret, ok
+}
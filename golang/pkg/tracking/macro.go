@@ -0,0 +1,82 @@
+package tracking
+
+import (
+	"strconv"
+	"strings"
+)
+
+// OpenRTB 标准替换宏
+const (
+	MacroAuctionID       = "${AUCTION_ID}"
+	MacroAuctionBidID    = "${AUCTION_BID_ID}"
+	MacroAuctionImpID    = "${AUCTION_IMP_ID}"
+	MacroAuctionSeatID   = "${AUCTION_SEAT_ID}"
+	MacroAuctionAdID     = "${AUCTION_AD_ID}"
+	MacroAuctionPrice    = "${AUCTION_PRICE}"
+	MacroAuctionCurrency = "${AUCTION_CURRENCY}"
+	MacroAuctionMBR      = "${AUCTION_MBR}"
+	MacroAuctionLoss     = "${AUCTION_LOSS}"
+	MacroAuctionMinToWin = "${AUCTION_MIN_TO_WIN}"
+)
+
+// LossReason OpenRTB 竞价失败原因码
+type LossReason int
+
+const (
+	LossWon                LossReason = 0   // 竞价成功
+	LossInternalError      LossReason = 1   // 内部错误
+	LossExpired            LossReason = 2   // 曝光机会过期
+	LossInvalidResponse    LossReason = 3   // 无效的竞价响应
+	LossBelowFloor         LossReason = 100 // 出价低于底价
+	LossLostToHigherBid    LossReason = 102 // 出价低于其他竞价
+	LossCreativeFiltered   LossReason = 200 // 创意被过滤
+	LossCreativeNotAllowed LossReason = 204 // 创意类型不被允许
+)
+
+// MacroValues 替换宏的取值，为空的字段对应的宏替换为空串；
+// Price、MinToWin 由调用方按DSP的币种格式化(或加密)后传入
+type MacroValues struct {
+	AuctionID string
+	BidID     string
+	ImpID     string
+	SeatID    string
+	AdID      string
+	Price     string
+	Currency  string
+	MBR       string
+	Loss      LossReason
+	MinToWin  string
+}
+
+// ExpandMacros 替换字符串中的 OpenRTB 宏，未识别的宏保持原样
+func ExpandMacros(s string, values *MacroValues) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return strings.NewReplacer(
+		MacroAuctionID, values.AuctionID,
+		MacroAuctionBidID, values.BidID,
+		MacroAuctionImpID, values.ImpID,
+		MacroAuctionSeatID, values.SeatID,
+		MacroAuctionAdID, values.AdID,
+		MacroAuctionPrice, values.Price,
+		MacroAuctionCurrency, values.Currency,
+		MacroAuctionMBR, values.MBR,
+		MacroAuctionLoss, strconv.Itoa(int(values.Loss)),
+		MacroAuctionMinToWin, values.MinToWin,
+	).Replace(s)
+}
+
+// ExpandMacrosKeeping 替换宏，keep 对应的宏保持原样，留给下游(如监测服务)替换
+func ExpandMacrosKeeping(s string, values *MacroValues, keep string) string {
+	parts := strings.Split(s, keep)
+	for i, part := range parts {
+		parts[i] = ExpandMacros(part, values)
+	}
+	return strings.Join(parts, keep)
+}
+
+// FormatPrice 将以分为单位的CPM价格格式化为宏取值，如 1250 -> "12.5"
+func FormatPrice(cpm int64) string {
+	return strconv.FormatFloat(float64(cpm)/100, 'f', -1, 64)
+}
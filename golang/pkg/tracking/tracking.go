@@ -0,0 +1,206 @@
+package tracking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType 监测事件类型
+type EventType string
+
+const (
+	EventWin     EventType = "win"     // 竞价成功通知(nurl)
+	EventBilling EventType = "billing" // 计费通知(burl)
+	EventImp     EventType = "imp"     // 曝光
+	EventClick   EventType = "click"   // 点击
+	EventLoss    EventType = "loss"    // 竞价失败通知(lurl)
+)
+
+// EventTypes 所有监测事件类型
+var EventTypes = []EventType{EventWin, EventBilling, EventImp, EventClick, EventLoss}
+
+// PathPrefix 监测服务的路由前缀，事件地址为 {base}/track/{event}
+const PathPrefix = "/track/"
+
+// 监测链接的查询参数
+const (
+	ParamEvent     = "ev"   // 事件类型，需与路由一致
+	ParamRequestID = "rid"  // 竞价请求ID
+	ParamImpID     = "imp"  // 广告位ID
+	ParamBidID     = "bid"  // DSP出价ID
+	ParamDSPID     = "dsp"  // DSP ID
+	ParamSSPID     = "ssp"  // SSP ID
+	ParamBidPrice  = "bp"   // DSP出价(CPM，单位分)
+	ParamTimestamp = "ts"   // 生成时间(unix秒)
+	ParamKeyID     = "kid"  // 签名密钥ID
+	ParamClickURL  = "curl" // 点击跳转地址
+	ParamNoticeURL = "nurl" // DSP通知地址(成交价、失败原因宏除外已替换)，由监测服务收到事件后替换并转发
+	ParamSignature = "sig"  // 签名
+
+	// 以下参数由媒体或DSP在下发后通过宏替换填写，不参与签名
+	ParamClearPrice = "cp" // 成交价
+	ParamLossReason = "lr" // 竞价失败原因
+)
+
+// unsignedParams 不参与签名的参数
+var unsignedParams = map[string]struct{}{
+	ParamSignature:  {},
+	ParamClearPrice: {},
+	ParamLossReason: {},
+}
+
+var (
+	ErrMissingSignature = errors.New("tracking: missing signature")
+	ErrUnknownKey       = errors.New("tracking: unknown signing key")
+	ErrBadSignature     = errors.New("tracking: signature mismatch")
+	ErrExpired          = errors.New("tracking: tracking url expired")
+	ErrInvalidParams    = errors.New("tracking: invalid tracking params")
+)
+
+// Event 监测链接携带的事件数据
+type Event struct {
+	Type      EventType `json:"event"`
+	RequestID string    `json:"request_id"`
+	ImpID     string    `json:"imp_id"`
+	BidID     string    `json:"bid_id,omitempty"`
+	DSPID     string    `json:"dsp_id,omitempty"`
+	SSPID     string    `json:"ssp_id,omitempty"`
+	BidPrice  int64     `json:"bid_price,omitempty"`
+	Timestamp int64     `json:"ts"`
+	ClickURL  string    `json:"click_url,omitempty"`
+	NoticeURL string    `json:"notice_url,omitempty"`
+
+	// 宏替换填写的参数，原样保留由使用方解析
+	ClearPrice string `json:"clear_price,omitempty"`
+	LossReason string `json:"loss_reason,omitempty"`
+}
+
+// DedupKey 事件去重键：同一请求、广告位、出价的同类事件只记录一次
+func (e *Event) DedupKey() string {
+	return strings.Join([]string{string(e.Type), e.RequestID, e.ImpID, e.BidID}, "|")
+}
+
+// values 需要签名的参数
+func (e *Event) values() url.Values {
+	values := url.Values{}
+	values.Set(ParamEvent, string(e.Type))
+	values.Set(ParamRequestID, e.RequestID)
+	values.Set(ParamImpID, e.ImpID)
+	values.Set(ParamTimestamp, strconv.FormatInt(e.Timestamp, 10))
+	setIfNotEmpty(values, ParamBidID, e.BidID)
+	setIfNotEmpty(values, ParamDSPID, e.DSPID)
+	setIfNotEmpty(values, ParamSSPID, e.SSPID)
+	setIfNotEmpty(values, ParamClickURL, e.ClickURL)
+	setIfNotEmpty(values, ParamNoticeURL, e.NoticeURL)
+	if e.BidPrice > 0 {
+		values.Set(ParamBidPrice, strconv.FormatInt(e.BidPrice, 10))
+	}
+	return values
+}
+
+// Sign 计算签名：对除签名和宏参数外的所有参数按key排序编码后做 HMAC-SHA256
+func Sign(secret []byte, values url.Values) string {
+	signed := url.Values{}
+	for key, vals := range values {
+		if _, skip := unsignedParams[key]; !skip {
+			signed[key] = vals
+		}
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Signer 使用指定密钥生成签名监测链接
+type Signer struct {
+	KeyID  string
+	Secret []byte
+}
+
+// URL 生成事件的签名监测链接，baseURL 为监测服务地址，如 https://t.admux.com
+func (s *Signer) URL(baseURL string, event *Event) string {
+	values := event.values()
+	values.Set(ParamKeyID, s.KeyID)
+	values.Set(ParamSignature, Sign(s.Secret, values))
+	return strings.TrimRight(baseURL, "/") + PathPrefix + string(event.Type) + "?" + values.Encode()
+}
+
+// Verifier 校验监测链接签名，按链接中的密钥ID从密钥环中选择密钥
+type Verifier struct {
+	keys   *KeyRing
+	maxAge time.Duration
+}
+
+// NewVerifier 创建签名校验器，maxAge<=0 时不校验链接时效
+func NewVerifier(keys *KeyRing, maxAge time.Duration) *Verifier {
+	return &Verifier{keys: keys, maxAge: maxAge}
+}
+
+// Verify 校验签名和时效并解析事件，eventType 为路由中的事件类型
+func (v *Verifier) Verify(eventType EventType, values url.Values, now time.Time) (*Event, error) {
+	sig := values.Get(ParamSignature)
+	if sig == "" {
+		return nil, ErrMissingSignature
+	}
+	secret, ok := v.keys.lookup(values.Get(ParamKeyID))
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if !hmac.Equal([]byte(sig), []byte(Sign(secret, values))) {
+		return nil, ErrBadSignature
+	}
+
+	event, err := parseEvent(values)
+	if err != nil {
+		return nil, err
+	}
+	if event.Type != eventType {
+		return nil, fmt.Errorf("%w: event %q does not match %q", ErrInvalidParams, event.Type, eventType)
+	}
+	if v.maxAge > 0 && now.Sub(time.Unix(event.Timestamp, 0)) > v.maxAge {
+		return nil, ErrExpired
+	}
+	return event, nil
+}
+
+func parseEvent(values url.Values) (*Event, error) {
+	event := &Event{
+		Type:       EventType(values.Get(ParamEvent)),
+		RequestID:  values.Get(ParamRequestID),
+		ImpID:      values.Get(ParamImpID),
+		BidID:      values.Get(ParamBidID),
+		DSPID:      values.Get(ParamDSPID),
+		SSPID:      values.Get(ParamSSPID),
+		ClickURL:   values.Get(ParamClickURL),
+		NoticeURL:  values.Get(ParamNoticeURL),
+		ClearPrice: values.Get(ParamClearPrice),
+		LossReason: values.Get(ParamLossReason),
+	}
+	if event.RequestID == "" || event.ImpID == "" {
+		return nil, fmt.Errorf("%w: missing %s or %s", ErrInvalidParams, ParamRequestID, ParamImpID)
+	}
+
+	var err error
+	if event.Timestamp, err = strconv.ParseInt(values.Get(ParamTimestamp), 10, 64); err != nil {
+		return nil, fmt.Errorf("%w: bad %s", ErrInvalidParams, ParamTimestamp)
+	}
+	if bp := values.Get(ParamBidPrice); bp != "" {
+		if event.BidPrice, err = strconv.ParseInt(bp, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: bad %s", ErrInvalidParams, ParamBidPrice)
+		}
+	}
+	return event, nil
+}
+
+func setIfNotEmpty(values url.Values, key, value string) {
+	if value != "" {
+		values.Set(key, value)
+	}
+}
@@ -0,0 +1,135 @@
+package tracking
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTrackingURL(t *testing.T, raw string) (string, url.Values) {
+	t.Helper()
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u.Path, u.Query()
+}
+
+func newTestKeyRing(t *testing.T, activeID string) *KeyRing {
+	t.Helper()
+	ring, err := NewKeyRing(activeID, Key{ID: "k1", Secret: []byte("secret")})
+	require.NoError(t, err)
+	return ring
+}
+
+func TestSignerAndVerifier(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := &Signer{KeyID: "k1", Secret: []byte("secret")}
+	event := &Event{
+		Type:      EventClick,
+		RequestID: "req-1",
+		ImpID:     "imp-1",
+		BidID:     "bid-1",
+		DSPID:     "dsp_a",
+		BidPrice:  1250,
+		Timestamp: now.Unix(),
+		ClickURL:  "https://landing.example.com/?a=1",
+	}
+
+	path, values := parseTrackingURL(t, signer.URL("https://t.admux.com/", event))
+	assert.Equal(t, "/track/click", path)
+
+	verifier := NewVerifier(newTestKeyRing(t, ""), time.Hour)
+	got, err := verifier.Verify(EventClick, values, now)
+	require.NoError(t, err)
+	assert.Equal(t, event, got)
+
+	// 宏参数不参与签名
+	values.Set(ParamClearPrice, "1100")
+	got, err = verifier.Verify(EventClick, values, now)
+	require.NoError(t, err)
+	assert.Equal(t, "1100", got.ClearPrice)
+	assert.Equal(t, "click|req-1|imp-1|bid-1", got.DedupKey())
+}
+
+func TestVerifier_Rejects(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := &Signer{KeyID: "k1", Secret: []byte("secret")}
+	verifier := NewVerifier(newTestKeyRing(t, ""), time.Hour)
+	event := &Event{Type: EventWin, RequestID: "req-1", ImpID: "imp-1", BidPrice: 100, Timestamp: now.Unix()}
+
+	_, values := parseTrackingURL(t, signer.URL("https://t.admux.com", event))
+	values.Set(ParamBidPrice, "1000")
+	_, err := verifier.Verify(EventWin, values, now)
+	assert.ErrorIs(t, err, ErrBadSignature)
+
+	_, values = parseTrackingURL(t, signer.URL("https://t.admux.com", event))
+	_, err = verifier.Verify(EventClick, values, now)
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	_, err = verifier.Verify(EventWin, values, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, ErrExpired)
+
+	values.Set(ParamKeyID, "k2")
+	_, err = verifier.Verify(EventWin, values, now)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	values.Del(ParamSignature)
+	_, err = verifier.Verify(EventWin, values, now)
+	assert.ErrorIs(t, err, ErrMissingSignature)
+}
+
+func TestKeyRing_Rotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	event := &Event{Type: EventImp, RequestID: "req-1", ImpID: "imp-1", Timestamp: now.Unix()}
+
+	ring := newTestKeyRing(t, "k1")
+	_, oldValues := parseTrackingURL(t, ring.Signer().URL("https://t.admux.com", event))
+
+	// 加入新密钥并切换签名密钥，旧链接仍可校验
+	require.NoError(t, ring.Update("k2", Key{ID: "k1", Secret: []byte("secret")}, Key{ID: "k2", Secret: []byte("secret2")}))
+	assert.Equal(t, "k2", ring.ActiveID())
+	_, newValues := parseTrackingURL(t, ring.Signer().URL("https://t.admux.com", event))
+	assert.Equal(t, "k2", newValues.Get(ParamKeyID))
+
+	verifier := NewVerifier(ring, 0)
+	_, err := verifier.Verify(EventImp, oldValues, now)
+	assert.NoError(t, err)
+	_, err = verifier.Verify(EventImp, newValues, now)
+	assert.NoError(t, err)
+
+	// 移除旧密钥后旧链接失效
+	require.NoError(t, ring.Update("k2", Key{ID: "k2", Secret: []byte("secret2")}))
+	_, err = verifier.Verify(EventImp, oldValues, now)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	assert.Error(t, ring.Update("k3", Key{ID: "k2", Secret: []byte("secret2")}))
+	assert.Nil(t, newTestKeyRing(t, "").Signer())
+}
+
+func TestExpandMacros(t *testing.T) {
+	values := &MacroValues{
+		AuctionID: "req-1",
+		BidID:     "bid-1",
+		ImpID:     "imp-1",
+		SeatID:    "seat-1",
+		AdID:      "ad-1",
+		Price:     FormatPrice(1250),
+		Currency:  "CNY",
+		Loss:      LossLostToHigherBid,
+		MinToWin:  FormatPrice(1300),
+	}
+
+	got := ExpandMacros("https://dsp.example.com/win?id=${AUCTION_ID}&b=${AUCTION_BID_ID}&i=${AUCTION_IMP_ID}"+
+		"&s=${AUCTION_SEAT_ID}&a=${AUCTION_AD_ID}&p=${AUCTION_PRICE}&c=${AUCTION_CURRENCY}"+
+		"&l=${AUCTION_LOSS}&m=${AUCTION_MIN_TO_WIN}&x=${UNKNOWN}", values)
+	assert.Equal(t, "https://dsp.example.com/win?id=req-1&b=bid-1&i=imp-1&s=seat-1&a=ad-1&p=12.5&c=CNY&l=102&m=13&x=${UNKNOWN}", got)
+	assert.Equal(t, "no macros", ExpandMacros("no macros", values))
+}
+
+func TestExpandMacrosKeeping(t *testing.T) {
+	values := &MacroValues{AuctionID: "req-1", Loss: LossLostToHigherBid}
+	got := ExpandMacrosKeeping("https://dsp.example.com/loss?id=${AUCTION_ID}&l=${AUCTION_LOSS}&p=${AUCTION_PRICE}", values, MacroAuctionLoss)
+	assert.Equal(t, "https://dsp.example.com/loss?id=req-1&l=${AUCTION_LOSS}&p=", got)
+}